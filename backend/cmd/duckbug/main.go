@@ -16,15 +16,33 @@ import (
 	"github.com/duckbugio/duckbug/internal/modules/app"
 	"github.com/duckbugio/duckbug/internal/storage/sql"
 
+	appConfig "github.com/duckbugio/duckbug/internal/config"
 	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/duckbugio/duckbug/internal/metrics"
+	moduleAudit "github.com/duckbugio/duckbug/internal/modules/audit"
+	moduleBackup "github.com/duckbugio/duckbug/internal/modules/backup"
+	moduleBulk "github.com/duckbugio/duckbug/internal/modules/bulk"
+	moduleErasure "github.com/duckbugio/duckbug/internal/modules/erasure"
 	moduleError "github.com/duckbugio/duckbug/internal/modules/errors"
 	moduleGroupError "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	moduleIngest "github.com/duckbugio/duckbug/internal/modules/ingest"
+	moduleJobs "github.com/duckbugio/duckbug/internal/modules/jobs"
+	moduleKeys "github.com/duckbugio/duckbug/internal/modules/keys"
 	moduleLog "github.com/duckbugio/duckbug/internal/modules/log"
 	moduleGroupLog "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	moduleMonitors "github.com/duckbugio/duckbug/internal/modules/monitors"
+	modulePartition "github.com/duckbugio/duckbug/internal/modules/partition"
 	moduleProject "github.com/duckbugio/duckbug/internal/modules/project"
+	moduleRelease "github.com/duckbugio/duckbug/internal/modules/release"
+	moduleRetention "github.com/duckbugio/duckbug/internal/modules/retention"
+	moduleSavedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
+	moduleScrubbing "github.com/duckbugio/duckbug/internal/modules/scrubbing"
 	moduleTechnology "github.com/duckbugio/duckbug/internal/modules/technology"
+	moduleTraces "github.com/duckbugio/duckbug/internal/modules/traces"
 	moduleUser "github.com/duckbugio/duckbug/internal/modules/users"
 	server "github.com/duckbugio/duckbug/internal/server/http"
+	"github.com/duckbugio/duckbug/internal/tracing"
+	"github.com/duckbugio/duckbug/internal/worker"
 )
 
 var configFile string
@@ -33,7 +51,14 @@ func init() {
 	flag.StringVar(&configFile, "config", "configs/duckbug/config.json", "Path to configuration file")
 }
 
-const serverShutdownTimeout = 3 * time.Second
+const (
+	serverShutdownTimeout  = 3 * time.Second
+	partitionCheckInterval = time.Hour
+	retentionPurgeInterval = 15 * time.Minute
+	jobsPollInterval       = 5 * time.Second
+	ingestFlushInterval    = 10 * time.Second
+	monitorsCheckInterval  = 30 * time.Second
+)
 
 // @title DuckBug API
 // @version 1.0.0
@@ -50,7 +75,7 @@ const serverShutdownTimeout = 3 * time.Second
 func main() {
 	flag.Parse()
 
-	config, err := LoadConfig(configFile)
+	config, err := appConfig.Load(configFile)
 	if err != nil {
 		fmt.Println("Error loading config: ", err)
 		return
@@ -62,7 +87,19 @@ func main() {
 
 	appLogger := logger.New(config.Logger.Level, nil)
 
-	db, err := sqlx.Connect("postgres", config.Postgres.Dsn)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    config.Tracing.Endpoint,
+		Insecure:    config.Tracing.Insecure,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		appLogger.Error(fmt.Sprintf("failed to set up tracing: %v", err))
+		return
+	}
+
+	appMetrics := metrics.New()
+
+	db, err := sql.Connect(ctx, config.Postgres.Dsn, appMetrics)
 	if err != nil {
 		appLogger.Error(fmt.Sprintf("failed to connect to database: %v", err))
 		return
@@ -71,8 +108,9 @@ func main() {
 	defer func(db *sqlx.DB) {
 		_ = db.Close()
 	}(db)
+	appMetrics.RegisterDB(db.DB)
 
-	if err := sql.RunMigrations(db, appLogger); err != nil {
+	if err := sql.RunMigrations(ctx, db, appLogger); err != nil {
 		appLogger.Error(fmt.Sprintf("failed to run migrations: %v", err))
 		return
 	}
@@ -80,14 +118,104 @@ func main() {
 	secret := config.Jwt.Secret
 	jwtKey := []byte(secret)
 
-	appService := app.New(appLogger)
 	userService := moduleUser.NewService(moduleUser.NewRepository(db, appLogger), jwtKey, appLogger)
-	logService := moduleLog.NewService(moduleLog.NewRepository(db, appLogger), appLogger)
+	scrubbingService := moduleScrubbing.NewService(moduleScrubbing.NewRepository(db, appLogger), appLogger)
+	logService := moduleLog.NewService(moduleLog.NewRepository(db, appLogger), appLogger, scrubbingService)
 	logGroupService := moduleGroupLog.NewService(moduleGroupLog.NewRepository(db, appLogger), appLogger)
-	errorService := moduleError.NewService(moduleError.NewRepository(db, appLogger), appLogger)
+	errorService := moduleError.NewService(moduleError.NewRepository(db, appLogger), appLogger, scrubbingService)
 	errorGroupService := moduleGroupError.NewService(moduleGroupError.NewRepository(db, appLogger), appLogger)
 	technologyService := moduleTechnology.NewService(moduleTechnology.NewRepository(db, appLogger), appLogger)
-	projectService := moduleProject.NewService(moduleProject.NewRepository(db, appLogger), appLogger, config.Domain)
+	partitionService := modulePartition.NewService(
+		modulePartition.NewRepository(db, appLogger),
+		appLogger,
+		modulePartition.Config{
+			Interval:      config.Partitions.Interval,
+			Premake:       config.Partitions.Premake,
+			RetentionDays: config.Partitions.RetentionDays,
+		},
+	)
+	partitionWorker := worker.New("partitions", partitionCheckInterval, partitionService.Maintain, appLogger)
+	go partitionWorker.Run(ctx)
+
+	retentionService := moduleRetention.NewService(
+		moduleRetention.NewRepository(db, appLogger),
+		moduleGroupLog.NewRepository(db, appLogger),
+		moduleGroupError.NewRepository(db, appLogger),
+		appLogger,
+		moduleRetention.Config{
+			BatchSize:          config.Retention.BatchSize,
+			MaxBatches:         config.Retention.MaxBatches,
+			DeletedProjectDays: config.Retention.DeletedProjectDays,
+		},
+	)
+	retentionWorker := worker.New("retention", retentionPurgeInterval, func(ctx context.Context) error {
+		_, err := retentionService.Purge(ctx)
+		return err
+	}, appLogger)
+	go retentionWorker.Run(ctx)
+
+	jobService := moduleJobs.NewService(moduleJobs.NewRepository(db, appLogger), appLogger, appMetrics)
+	erasureService := moduleErasure.NewService(
+		moduleErasure.NewRepository(db, appLogger),
+		jobService,
+		moduleGroupLog.NewRepository(db, appLogger),
+		moduleGroupError.NewRepository(db, appLogger),
+		appLogger,
+	)
+	// Erasure params hold the identifiers being erased, so they are not kept after the run
+	jobService.Register(moduleErasure.JobType, erasureService.Run, true)
+	bulkService := moduleBulk.NewService(
+		jobService,
+		moduleError.NewRepository(db, appLogger),
+		moduleLog.NewRepository(db, appLogger),
+		moduleGroupError.NewRepository(db, appLogger),
+		moduleGroupLog.NewRepository(db, appLogger),
+		appLogger,
+	)
+	jobService.Register(moduleBulk.JobType, bulkService.Run, false)
+	jobsWorker := worker.New("jobs", jobsPollInterval, jobService.RunPending, appLogger)
+	go jobsWorker.Run(ctx)
+
+	ingestService := moduleIngest.NewService(
+		moduleIngest.NewRepository(db, appLogger),
+		appLogger,
+		moduleIngest.Config{
+			RatePerSecond: config.Ingest.RatePerSecond,
+			Burst:         config.Ingest.Burst,
+		},
+		appMetrics,
+	)
+	ingestWorker := worker.New("ingest-stats", ingestFlushInterval, ingestService.Flush, appLogger)
+	go ingestWorker.Run(ctx)
+
+	monitorService := moduleMonitors.NewService(
+		moduleMonitors.NewRepository(db, appLogger),
+		errorService,
+		appLogger,
+		config.Domain,
+	)
+	monitorsWorker := worker.New("monitors", monitorsCheckInterval, monitorService.Run, appLogger)
+	go monitorsWorker.Run(ctx)
+
+	appMetrics.RegisterWorkers(partitionWorker, retentionWorker, jobsWorker, ingestWorker, monitorsWorker)
+
+	migrationVersion, err := sql.LatestVersion()
+	if err != nil {
+		appLogger.Error(fmt.Sprintf("failed to read migrations: %v", err))
+		return
+	}
+	appService := app.New(app.NewRepository(db, appLogger), appLogger, app.Config{
+		MigrationVersion: migrationVersion,
+		Workers:          []app.WorkerReporter{partitionWorker, retentionWorker, jobsWorker, ingestWorker, monitorsWorker},
+	})
+
+	projectService := moduleProject.NewService(
+		moduleProject.NewRepository(db, appLogger),
+		ingestService,
+		appLogger,
+		config.Domain,
+		config.Retention.DeletedProjectDays,
+	)
 	// Wire repos for aggregated stats in projects listing
 	// We rely on concrete service type to set optional repositories
 	if ps, ok := projectService.(interface {
@@ -96,6 +224,13 @@ func main() {
 		ps.SetStatsRepos(moduleError.NewRepository(db, appLogger), moduleGroupError.NewRepository(db, appLogger), moduleLog.NewRepository(db, appLogger))
 	}
 
+	keyService := moduleKeys.NewService(moduleKeys.NewRepository(db, appLogger), ingestService, appLogger, config.Domain)
+	auditService := moduleAudit.NewService(moduleAudit.NewRepository(db, appLogger), appLogger)
+	backupService := moduleBackup.NewService(moduleBackup.NewRepository(db, appLogger), appLogger)
+	savedSearchService := moduleSavedSearch.NewService(moduleSavedSearch.NewRepository(db, appLogger), appLogger, config.Domain)
+	releaseService := moduleRelease.NewService(moduleRelease.NewRepository(db, appLogger), appLogger)
+	traceService := moduleTraces.NewService(moduleTraces.NewRepository(db, appLogger), appLogger)
+
 	s := server.New(
 		appLogger,
 		appService,
@@ -106,9 +241,25 @@ func main() {
 		errorGroupService,
 		technologyService,
 		projectService,
+		scrubbingService,
+		jobService,
+		erasureService,
+		ingestService,
+		keyService,
+		auditService,
+		backupService,
+		bulkService,
+		savedSearchService,
+		releaseService,
+		monitorService,
+		traceService,
+		appMetrics,
 		"",
 		config.Port,
 		jwtKey,
+		config.Cors.AllowedOrigins,
+		config.Metrics.Token,
+		config.Metrics.Public,
 	)
 
 	go func() {
@@ -120,6 +271,18 @@ func main() {
 		if err := s.Stop(shutdownCtx); err != nil {
 			appLogger.Error("failed to stop http server: " + err.Error())
 		}
+
+		// Ingest counters of the last seconds would be lost otherwise
+		flushCtx, flushCancel := context.WithTimeout(context.WithoutCancel(ctx), serverShutdownTimeout)
+		defer flushCancel()
+
+		if err := ingestService.Flush(flushCtx); err != nil {
+			appLogger.Error("failed to flush ingest stats: " + err.Error())
+		}
+
+		if err := shutdownTracing(flushCtx); err != nil {
+			appLogger.Error("failed to flush spans: " + err.Error())
+		}
 	}()
 
 	appLogger.Info(fmt.Sprintf("Service listening on port: %d", config.Port))
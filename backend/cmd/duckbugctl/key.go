@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	moduleKeys "github.com/duckbugio/duckbug/internal/modules/keys"
+)
+
+const (
+	defaultOverlapHours = 24
+	maxOverlapHours     = 720
+)
+
+// keyCacheInvalidator is used by the CLI, which has no key cache. Running servers reload keys
+// once their cache entry expires.
+type keyCacheInvalidator struct{}
+
+func (keyCacheInvalidator) Invalidate(string) {}
+
+func keyCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	fs := flag.NewFlagSet("key "+subcommand, flag.ContinueOnError)
+	projectID := fs.String("project", "", "Id of the project")
+	keys := moduleKeys.NewService(moduleKeys.NewRepository(c.db, c.logger), keyCacheInvalidator{}, c.logger, c.config.Domain)
+
+	switch subcommand {
+	case "list":
+		if err := parseFlags(fs, args, "project"); err != nil {
+			return err
+		}
+
+		items, err := keys.GetAll(ctx, *projectID)
+		if err != nil {
+			return err
+		}
+
+		rows := []string{"ID\tNAME\tSCOPE\tSTATUS\tDSN"}
+		for _, key := range items {
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\t%s", key.ID, key.Name, key.Scope, key.Status, key.Dsn))
+		}
+		return printTable(os.Stdout, rows...)
+	case "rotate":
+		keyID := fs.String("key", "", "Id of the key to rotate")
+		overlap := fs.Int("overlap-hours", defaultOverlapHours, "Hours the old key keeps working, 0 revokes it at once")
+		if err := parseFlags(fs, args, "project", "key"); err != nil {
+			return err
+		}
+		if *overlap < 0 || *overlap > maxOverlapHours {
+			return fmt.Errorf("%w: -overlap-hours must be between 0 and %d", errUsage, maxOverlapHours)
+		}
+
+		key, err := keys.Rotate(ctx, *projectID, *keyID, &moduleKeys.Rotate{OverlapHours: *overlap})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Key %s created\nDSN: %s\n", key.ID, key.Dsn)
+		return nil
+	default:
+		return unknownSubcommand("key", subcommand)
+	}
+}
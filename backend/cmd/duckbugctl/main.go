@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+
+	appConfig "github.com/duckbugio/duckbug/internal/config"
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/duckbugio/duckbug/internal/storage/sql"
+)
+
+const usage = `duckbugctl administers a DuckBug instance directly through its database.
+
+Usage:
+  duckbugctl [-config path] <command> <subcommand> [flags]
+
+Commands:
+  user create-admin -email <email> [-password <password>]
+  user reset-password -email <email> [-password <password>]
+  project list -owner <email>
+  project create -owner <email> -name <name> -technology <id>
+  project delete -id <id>
+  project undelete -id <id>
+  project dsn -id <id>
+  project dump -id <id> -out <file|-> [-from <ms>] [-to <ms>]
+  project restore -in <file|->
+  project import -in <file|-> (-project <id> | -owner <email> [-name <name>])
+  key list -project <id>
+  key rotate -project <id> -key <id> [-overlap-hours <hours>]
+  migrate up
+  migrate down [-steps <n>]
+  migrate status
+  migrate force -version <version>
+  retention purge
+  search backfill [-batch-size <n>]
+
+Passwords that are not given as a flag are read from the first line of stdin.
+The configuration is read like the server does, from the file and the environment.
+`
+
+var configFile string
+
+func init() {
+	flag.StringVar(&configFile, "config", "configs/duckbug/config.json", "Path to configuration file")
+}
+
+const (
+	exitError = 1
+	exitUsage = 2
+
+	// minArgs are the command and its subcommand
+	minArgs      = 2
+	tablePadding = 2
+)
+
+var errUsage = errors.New("invalid usage")
+
+// cli holds what every command needs
+type cli struct {
+	config appConfig.Config
+	db     *sqlx.DB
+	logger *logger.Logger
+}
+
+type command func(ctx context.Context, c *cli, subcommand string, args []string) error
+
+var commands = map[string]command{
+	"user":      userCommand,
+	"project":   projectCommand,
+	"key":       keyCommand,
+	"migrate":   migrateCommand,
+	"retention": retentionCommand,
+	"search":    searchCommand,
+}
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+	flag.Parse()
+	os.Exit(run(flag.Args()))
+}
+
+func run(args []string) int {
+	if len(args) < minArgs {
+		flag.Usage()
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		flag.Usage()
+		return exitUsage
+	}
+
+	config, err := appConfig.Load(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return exitError
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Logs go to stderr, so the output of a command can be piped
+	appLogger := logger.New(config.Logger.Level, os.Stderr)
+
+	db, err := sql.Connect(ctx, config.Postgres.Dsn, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return exitError
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	c := &cli{config: config, db: db, logger: appLogger}
+	if err := cmd(ctx, c, args[1], args[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+			flag.Usage()
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return 0
+}
+
+// parseFlags parses the flags of a subcommand and checks that the required ones are set
+func parseFlags(fs *flag.FlagSet, args []string, required ...string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, name := range required {
+		if !set[name] {
+			return fmt.Errorf("%w: %s requires -%s", errUsage, fs.Name(), name)
+		}
+	}
+	return nil
+}
+
+func unknownSubcommand(command, subcommand string) error {
+	return fmt.Errorf("%w: unknown subcommand %q of %s", errUsage, subcommand, command)
+}
+
+// printTable aligns tab separated rows, the tabwriter buffers them and reports write errors on Flush
+func printTable(out io.Writer, rows ...string) error {
+	w := tabwriter.NewWriter(out, 0, 0, tablePadding, ' ', 0)
+	for _, row := range rows {
+		_, _ = io.WriteString(w, row+"\n")
+	}
+	return w.Flush()
+}
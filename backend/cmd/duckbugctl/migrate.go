@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/duckbugio/duckbug/internal/storage/sql"
+)
+
+func migrateCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	fs := flag.NewFlagSet("migrate "+subcommand, flag.ContinueOnError)
+
+	switch subcommand {
+	case "up":
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		return sql.RunMigrations(ctx, c.db, c.logger)
+	case "down":
+		steps := fs.Int("steps", 1, "Number of migrations to revert")
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+
+		if err := sql.RollbackMigrations(ctx, c.db, *steps); err != nil {
+			return err
+		}
+		return printMigrationStatus(ctx, c)
+	case "status":
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		return printMigrationStatus(ctx, c)
+	case "force":
+		version := fs.Int("version", 0, "Version to record, -1 for none")
+		if err := parseFlags(fs, args, "version"); err != nil {
+			return err
+		}
+
+		if err := sql.ForceMigrationVersion(ctx, c.db, *version); err != nil {
+			return err
+		}
+		return printMigrationStatus(ctx, c)
+	default:
+		return unknownSubcommand("migrate", subcommand)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, c *cli) error {
+	version, dirty, err := sql.MigrationVersion(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	latest, err := sql.LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Version: %d\nLatest: %d\nDirty: %t\n", version, latest, dirty)
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	moduleBackup "github.com/duckbugio/duckbug/internal/modules/backup"
+	moduleProject "github.com/duckbugio/duckbug/internal/modules/project"
+	moduleUser "github.com/duckbugio/duckbug/internal/modules/users"
+)
+
+// stdio selects stdout or stdin as the file of dump and restore
+const stdio = "-"
+
+func projectCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	fs := flag.NewFlagSet("project "+subcommand, flag.ContinueOnError)
+	projects := moduleProject.NewService(
+		moduleProject.NewRepository(c.db, c.logger),
+		keyCacheInvalidator{},
+		c.logger,
+		c.config.Domain,
+		c.config.Retention.DeletedProjectDays,
+	)
+
+	switch subcommand {
+	case "list":
+		owner := fs.String("owner", "", "Email of the user owning the projects")
+		if err := parseFlags(fs, args, "owner"); err != nil {
+			return err
+		}
+
+		ctx, err := actAs(ctx, c, *owner)
+		if err != nil {
+			return err
+		}
+		return listProjects(ctx, projects)
+	case "create":
+		owner := fs.String("owner", "", "Email of the user owning the project")
+		name := fs.String("name", "", "Name of the project")
+		technology := fs.Int("technology", 0, "Technology id of the project")
+		if err := parseFlags(fs, args, "owner", "name", "technology"); err != nil {
+			return err
+		}
+
+		ctx, err := actAs(ctx, c, *owner)
+		if err != nil {
+			return err
+		}
+		project, err := projects.Create(ctx, &moduleProject.Create{Name: *name, TechnologyID: *technology})
+		if err != nil {
+			return err
+		}
+		dsn, err := projects.GetDSNByID(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Project %s created\nDSN: %s\n", project.ID, dsn)
+		return nil
+	case "delete":
+		id := fs.String("id", "", "Id of the project")
+		if err := parseFlags(fs, args, "id"); err != nil {
+			return err
+		}
+
+		if err := projects.Delete(ctx, *id); err != nil {
+			return err
+		}
+		fmt.Printf("Project %s deleted, it can be restored with project undelete\n", *id)
+		return nil
+	case "undelete":
+		id := fs.String("id", "", "Id of the project")
+		if err := parseFlags(fs, args, "id"); err != nil {
+			return err
+		}
+
+		if _, err := projects.Restore(ctx, *id); err != nil {
+			return err
+		}
+		fmt.Printf("Project %s restored\n", *id)
+		return nil
+	case "dsn":
+		id := fs.String("id", "", "Id of the project")
+		if err := parseFlags(fs, args, "id"); err != nil {
+			return err
+		}
+
+		dsn, err := projects.GetDSNByID(ctx, *id)
+		if err != nil {
+			return err
+		}
+		fmt.Println(dsn)
+		return nil
+	case "dump":
+		id := fs.String("id", "", "Id of the project")
+		out := fs.String("out", "", "Archive to write, - for stdout")
+		from := fs.Int64("from", 0, "Events from, unix milliseconds")
+		to := fs.Int64("to", 0, "Events to, unix milliseconds")
+		if err := parseFlags(fs, args, "id", "out"); err != nil {
+			return err
+		}
+		return dumpProject(ctx, c, moduleBackup.ExportParams{ProjectID: *id, TimeFrom: *from, TimeTo: *to}, *out)
+	case "restore":
+		in := fs.String("in", "", "Archive to read, - for stdin")
+		if err := parseFlags(fs, args, "in"); err != nil {
+			return err
+		}
+		return restoreProject(ctx, c, *in, nil)
+	case "import":
+		in := fs.String("in", "", "Archive to read, - for stdin")
+		target := fs.String("project", "", "Id of the project to import into")
+		owner := fs.String("owner", "", "Email of the user owning the new project")
+		name := fs.String("name", "", "Name of the new project, the archived one by default")
+		if err := parseFlags(fs, args, "in"); err != nil {
+			return err
+		}
+		if (*target == "") == (*owner == "") {
+			return fmt.Errorf("%w: project import requires either -project or -owner", errUsage)
+		}
+
+		// The owner only matters for a new project
+		if *owner != "" {
+			var err error
+			if ctx, err = actAs(ctx, c, *owner); err != nil {
+				return err
+			}
+		}
+		return restoreProject(ctx, c, *in, &moduleBackup.ImportParams{ProjectID: *target, Name: *name})
+	default:
+		return unknownSubcommand("project", subcommand)
+	}
+}
+
+// actAs puts the user into the context, projects are listed and created per owner
+func actAs(ctx context.Context, c *cli, email string) (context.Context, error) {
+	user, err := moduleUser.NewRepository(c.db, c.logger).FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: %s", moduleUser.ErrUserNotFound, email)
+	}
+	return middleware.WithUserID(ctx, user.ID), nil
+}
+
+func listProjects(ctx context.Context, projects moduleProject.Service) error {
+	items, _, err := projects.GetAll(ctx, moduleProject.GetAllParams{SortOrder: "asc", Limit: math.MaxInt32})
+	if err != nil {
+		return err
+	}
+
+	rows := []string{"ID\tNAME\tTECHNOLOGY\tOPEN ERRORS"}
+	for _, project := range items {
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%d\t%d", project.ID, project.Name, project.TechnologyID, project.OpenErrors))
+	}
+	return printTable(os.Stdout, rows...)
+}
+
+func dumpProject(ctx context.Context, c *cli, params moduleBackup.ExportParams, path string) (err error) {
+	var w io.Writer = os.Stdout
+	if path != stdio {
+		file, err := os.Create(filepath.Clean(path))
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to write archive: %w", closeErr)
+			}
+		}()
+		w = file
+	}
+
+	report, err := backupService(c).Dump(ctx, params, w)
+	if err != nil {
+		return err
+	}
+	printReport(report, false)
+	return nil
+}
+
+// restoreProject restores the archive with its original ids, or imports it as params tells when they are set
+func restoreProject(ctx context.Context, c *cli, path string, params *moduleBackup.ImportParams) error {
+	var r io.Reader = os.Stdin
+	if path != stdio {
+		file, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		r = file
+	}
+
+	var report *moduleBackup.Report
+	var err error
+	if params != nil {
+		report, err = backupService(c).Import(ctx, r, *params)
+	} else {
+		report, err = backupService(c).Restore(ctx, r)
+	}
+	if err != nil {
+		return err
+	}
+	printReport(report, true)
+	return nil
+}
+
+func backupService(c *cli) moduleBackup.Service {
+	return moduleBackup.NewService(moduleBackup.NewRepository(c.db, c.logger), c.logger)
+}
+
+// printReport writes to stderr, a dump may be written to stdout
+func printReport(report *moduleBackup.Report, skipped bool) {
+	rows := []string{"Project " + report.ProjectID}
+	for _, table := range report.Tables {
+		if skipped {
+			rows = append(rows, fmt.Sprintf("%s\t%d rows\t%d skipped", table.Table, table.Rows, table.Skipped))
+		} else {
+			rows = append(rows, fmt.Sprintf("%s\t%d rows", table.Table, table.Rows))
+		}
+	}
+	_ = printTable(os.Stderr, rows...)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	moduleGroupError "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	moduleGroupLog "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	moduleRetention "github.com/duckbugio/duckbug/internal/modules/retention"
+)
+
+func retentionCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	if subcommand != "purge" {
+		return unknownSubcommand("retention", subcommand)
+	}
+	fs := flag.NewFlagSet("retention purge", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	service := moduleRetention.NewService(
+		moduleRetention.NewRepository(c.db, c.logger),
+		moduleGroupLog.NewRepository(c.db, c.logger),
+		moduleGroupError.NewRepository(c.db, c.logger),
+		c.logger,
+		moduleRetention.Config{
+			BatchSize:          c.config.Retention.BatchSize,
+			MaxBatches:         c.config.Retention.MaxBatches,
+			DeletedProjectDays: c.config.Retention.DeletedProjectDays,
+		},
+	)
+
+	// A single purge stops after MaxBatches, so it runs until nothing is left
+	var total moduleRetention.Report
+	for {
+		report, err := service.Purge(ctx)
+		if err != nil {
+			return err
+		}
+		if report.IsEmpty() {
+			break
+		}
+		total.Add(report)
+	}
+
+	fmt.Printf("Removed %s\n", total)
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/duckbugio/duckbug/internal/storage/sql"
+)
+
+const defaultSearchBatchSize = 1000
+
+func searchCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	if subcommand != "backfill" {
+		return unknownSubcommand("search", subcommand)
+	}
+	fs := flag.NewFlagSet("search backfill", flag.ContinueOnError)
+	batchSize := fs.Int("batch-size", defaultSearchBatchSize, "Rows updated per statement")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	return sql.BackfillSearch(ctx, c.db, c.logger, *batchSize)
+}
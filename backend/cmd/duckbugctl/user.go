@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	moduleUser "github.com/duckbugio/duckbug/internal/modules/users"
+)
+
+func userCommand(ctx context.Context, c *cli, subcommand string, args []string) error {
+	fs := flag.NewFlagSet("user "+subcommand, flag.ContinueOnError)
+	email := fs.String("email", "", "Email of the user")
+	password := fs.String("password", "", "New password, read from stdin when empty")
+
+	var run func(service moduleUser.Service, email, password string) error
+	switch subcommand {
+	case "create-admin":
+		run = func(service moduleUser.Service, email, password string) error {
+			if err := service.CreateAdmin(ctx, &moduleUser.Signup{Email: email, Password: password}); err != nil {
+				return err
+			}
+			fmt.Printf("Admin %s created\n", email)
+			return nil
+		}
+	case "reset-password":
+		run = func(service moduleUser.Service, email, password string) error {
+			if err := service.ResetPassword(ctx, email, password); err != nil {
+				return err
+			}
+			fmt.Printf("Password of %s reset\n", email)
+			return nil
+		}
+	default:
+		return unknownSubcommand("user", subcommand)
+	}
+
+	if err := parseFlags(fs, args, "email"); err != nil {
+		return err
+	}
+
+	pass := *password
+	if pass == "" {
+		var err error
+		if pass, err = readPassword(); err != nil {
+			return err
+		}
+	}
+
+	service := moduleUser.NewService(moduleUser.NewRepository(c.db, c.logger), []byte(c.config.Jwt.Secret), c.logger)
+	return run(service, strings.TrimSpace(*email), pass)
+}
+
+// readPassword reads the first line of stdin, so passwords stay out of the shell history
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	return password, nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultCorsOrigins allow the frontend dev server
+var defaultCorsOrigins = []string{"http://127.0.0.1", "http://localhost"}
+
+// ErrCorsWildcard rejects * in the management origins, browsers send them the session with credentials
+var ErrCorsWildcard = errors.New("CORS_ALLOWED_ORIGINS must list origins, * is not allowed")
+
+type Config struct {
+	Logger     loggerConf
+	Port       int
+	Postgres   postgresConf
+	Domain     string
+	Jwt        jwtConf
+	Partitions partitionsConf
+	Retention  retentionConf
+	Ingest     ingestConf
+	Cors       corsConf
+	Metrics    metricsConf
+	Tracing    tracingConf
+}
+
+type loggerConf struct {
+	Level string
+}
+
+type postgresConf struct {
+	Dsn string
+}
+
+type jwtConf struct {
+	Secret string
+}
+
+type partitionsConf struct {
+	Interval      string
+	Premake       int
+	RetentionDays int
+}
+
+type retentionConf struct {
+	BatchSize  int
+	MaxBatches int
+	// DeletedProjectDays is the restore window of deleted projects before their data is purged
+	DeletedProjectDays int
+}
+
+type corsConf struct {
+	// AllowedOrigins of the management API, an entry without a port allows any port
+	AllowedOrigins []string
+}
+
+type metricsConf struct {
+	// Token is required as a bearer token on /metrics when set
+	Token string
+	// Public serves /metrics without a token, without either the endpoint is not served
+	Public bool
+}
+
+type tracingConf struct {
+	// Endpoint of an OTLP/HTTP collector as host:port, empty disables tracing
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+type ingestConf struct {
+	RatePerSecond int
+	Burst         int
+}
+
+// Load reads the config file at path, environment variables override its values
+func Load(path string) (Config, error) {
+	config := Config{}
+
+	if path != "" {
+		viper.SetConfigFile(path)
+		if err := viper.ReadInConfig(); err != nil {
+			return config, err
+		}
+	}
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	// Every new trace is recorded unless a ratio is configured
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+
+	// Bind environment variables so they override config file
+	_ = viper.BindEnv("logger.level", "LOGGER_LEVEL")
+	_ = viper.BindEnv("port", "PORT")
+	_ = viper.BindEnv("postgres.dsn", "POSTGRES_DSN")
+	_ = viper.BindEnv("domain", "DOMAIN")
+	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
+	_ = viper.BindEnv("partitions.interval", "PARTITIONS_INTERVAL")
+	_ = viper.BindEnv("partitions.premake", "PARTITIONS_PREMAKE")
+	_ = viper.BindEnv("partitions.retentionDays", "PARTITIONS_RETENTION_DAYS")
+	_ = viper.BindEnv("retention.batchSize", "RETENTION_BATCH_SIZE")
+	_ = viper.BindEnv("retention.maxBatches", "RETENTION_MAX_BATCHES")
+	_ = viper.BindEnv("retention.deletedProjectDays", "RETENTION_DELETED_PROJECT_DAYS")
+	_ = viper.BindEnv("ingest.ratePerSecond", "INGEST_RATE_PER_SECOND")
+	_ = viper.BindEnv("ingest.burst", "INGEST_BURST")
+	_ = viper.BindEnv("cors.allowedOrigins", "CORS_ALLOWED_ORIGINS")
+	_ = viper.BindEnv("metrics.token", "METRICS_TOKEN")
+	_ = viper.BindEnv("metrics.public", "METRICS_PUBLIC")
+	_ = viper.BindEnv("tracing.endpoint", "TRACING_ENDPOINT")
+	_ = viper.BindEnv("tracing.insecure", "TRACING_INSECURE")
+	_ = viper.BindEnv("tracing.sampleRatio", "TRACING_SAMPLE_RATIO")
+
+	err := viper.Unmarshal(&config)
+	if len(config.Cors.AllowedOrigins) == 0 {
+		config.Cors.AllowedOrigins = defaultCorsOrigins
+	}
+	for _, origin := range config.Cors.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return config, ErrCorsWildcard
+		}
+	}
+	return config, err
+}
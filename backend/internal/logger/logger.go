@@ -1,10 +1,14 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
 	"strings"
+
+	"github.com/duckbugio/duckbug/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Logger struct {
@@ -21,10 +25,10 @@ func New(level string, writer io.Writer) *Logger {
 	}
 
 	loggerJSON := slog.New(
-		slog.NewJSONHandler(
+		contextHandler{slog.NewJSONHandler(
 			writer,
 			&opts,
-		),
+		)},
 	)
 
 	return &Logger{loggerJSON}
@@ -46,6 +50,46 @@ func (l Logger) Error(msg string) {
 	l.logger.Error(msg)
 }
 
+// DebugContext logs msg with key/value pairs in args and the request id of ctx
+func (l Logger) DebugContext(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.DebugContext(ctx, msg, args...)
+}
+
+func (l Logger) InfoContext(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.InfoContext(ctx, msg, args...)
+}
+
+func (l Logger) WarnContext(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.WarnContext(ctx, msg, args...)
+}
+
+func (l Logger) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	l.logger.ErrorContext(ctx, msg, args...)
+}
+
+// contextHandler adds the request id and the trace of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := requestid.Get(ctx); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
 func getSlogLevel(level string) slog.Level {
 	level = strings.ToUpper(level)
 
@@ -2,8 +2,11 @@ package logger
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/duckbugio/duckbug/pkg/requestid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,3 +53,22 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New("debug", buf)
+
+	ctx := requestid.With(context.Background(), "req-1")
+	logger.InfoContext(ctx, "http request", "route", "/v1/projects/{id}", "status", 200)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "http request", record["msg"])
+	assert.Equal(t, "/v1/projects/{id}", record["route"])
+	assert.InDelta(t, 200, record["status"], 0)
+	assert.Equal(t, "req-1", record["request_id"])
+
+	buf.Reset()
+	logger.DebugContext(context.Background(), "sql query", "query", "SELECT 1")
+	assert.NotContains(t, buf.String(), "request_id")
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	stdsql "database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/worker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace   = "duckbug"
+	statusLabel = "status"
+)
+
+// Metrics collects the Prometheus metrics of the instance in its own registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	ingestEvents  *prometheus.CounterVec
+	queryDuration *prometheus.HistogramVec
+	jobRuns       *prometheus.CounterVec
+	jobDuration   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template and status code.",
+		}, []string{"method", "route", statusLabel}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		ingestEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ingest_events_total",
+			Help:      "Ingested events by project, kind and outcome.",
+		}, []string{"project_id", "kind", "outcome"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database statement latency by operation and table.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"operation", "table", statusLabel}),
+		jobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_finished_total",
+			Help:      "Finished background jobs by type and status.",
+		}, []string{"type", statusLabel}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_duration_seconds",
+			Help:      "Background job run time by type.",
+			Buckets:   []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900, 3600},
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.ingestEvents,
+		m.queryDuration,
+		m.jobRuns,
+		m.jobDuration,
+	)
+	return m
+}
+
+// RegisterDB exports the connection pool stats of db
+func (m *Metrics) RegisterDB(db *stdsql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// RegisterWorkers exports the status of the background workers
+func (m *Metrics) RegisterWorkers(workers ...*worker.Worker) {
+	m.registry.MustRegister(&workerCollector{workers: workers})
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) RecordIngest(projectID, kind, outcome string) {
+	m.ingestEvents.WithLabelValues(projectID, kind, outcome).Inc()
+}
+
+func (m *Metrics) ObserveQuery(operation, table string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.queryDuration.WithLabelValues(operation, table, status).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveJob(jobType, status string, duration time.Duration) {
+	m.jobRuns.WithLabelValues(jobType, status).Inc()
+	m.jobDuration.WithLabelValues(jobType).Observe(duration.Seconds())
+}
+
+// Handler serves the metrics in the Prometheus text format, a non empty token is required as a bearer token.
+// Without a token the metrics are only served when public is set.
+func (m *Metrics) Handler(token string, public bool) http.Handler {
+	metrics := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		// Route names, project ids and worker state are not for everyone, so the endpoint has to be opened on purpose
+		if public {
+			return metrics
+		}
+		return http.NotFoundHandler()
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
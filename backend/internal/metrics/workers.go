@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/duckbugio/duckbug/internal/worker"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// workerLabels name the worker of every status metric
+var workerLabels = []string{"worker"}
+
+var (
+	workerUpDesc = prometheus.NewDesc(
+		namespace+"_worker_up",
+		"Whether the background worker loop is running.",
+		workerLabels, nil,
+	)
+	workerRunsDesc = prometheus.NewDesc(
+		namespace+"_worker_runs_total",
+		"Runs of the background worker since start.",
+		workerLabels, nil,
+	)
+	workerLastRunDesc = prometheus.NewDesc(
+		namespace+"_worker_last_run_timestamp_seconds",
+		"Unix time of the last finished run of the background worker.",
+		workerLabels, nil,
+	)
+	workerLastFailedDesc = prometheus.NewDesc(
+		namespace+"_worker_last_run_failed",
+		"Whether the last run of the background worker returned an error.",
+		workerLabels, nil,
+	)
+)
+
+// workerCollector reads the worker status on every scrape
+type workerCollector struct {
+	workers []*worker.Worker
+}
+
+func (c *workerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workerUpDesc
+	ch <- workerRunsDesc
+	ch <- workerLastRunDesc
+	ch <- workerLastFailedDesc
+}
+
+func (c *workerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, w := range c.workers {
+		status := w.Status()
+
+		var lastRun float64
+		if !status.LastRunAt.IsZero() {
+			lastRun = float64(status.LastRunAt.Unix())
+		}
+
+		ch <- prometheus.MustNewConstMetric(workerUpDesc, prometheus.GaugeValue, boolValue(status.Running), status.Name)
+		ch <- prometheus.MustNewConstMetric(workerRunsDesc, prometheus.CounterValue, float64(status.Runs), status.Name)
+		ch <- prometheus.MustNewConstMetric(workerLastRunDesc, prometheus.GaugeValue, lastRun, status.Name)
+		ch <- prometheus.MustNewConstMetric(workerLastFailedDesc, prometheus.GaugeValue, boolValue(status.LastError != ""), status.Name)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+)
+
+const auditRecordKey = contextKey("audit_record")
+
+// AuditRecord collects who made a mutating request and what it changed, the server stores it in the
+// audit log once the request is done
+type AuditRecord struct {
+	mu         sync.Mutex
+	actorID    string
+	action     string
+	targetType string
+	targetID   string
+	before     interface{}
+	after      interface{}
+}
+
+func WithAuditRecord(ctx context.Context, record *AuditRecord) context.Context {
+	return context.WithValue(ctx, auditRecordKey, record)
+}
+
+func GetAuditRecord(ctx context.Context) (*AuditRecord, bool) {
+	record, ok := ctx.Value(auditRecordKey).(*AuditRecord)
+	return record, ok
+}
+
+// Audit describes the change of the current request, before and after are the states of the target
+// and may be nil when it is created or deleted. It does nothing outside of audited requests.
+func Audit(ctx context.Context, action, targetType, targetID string, before, after interface{}) {
+	record, ok := GetAuditRecord(ctx)
+	if !ok {
+		return
+	}
+
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	record.action = action
+	record.targetType = targetType
+	record.targetID = targetID
+	record.before = before
+	record.after = after
+}
+
+func (a *AuditRecord) setActor(userID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actorID = userID
+}
+
+func (a *AuditRecord) ActorID() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.actorID
+}
+
+// Action returns what Audit recorded, an empty action means the request did not describe its change
+func (a *AuditRecord) Action() (action, targetType, targetID string, before, after interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.action, a.targetType, a.targetID, a.before, a.after
+}
@@ -10,7 +10,10 @@ import (
 
 type contextKey string
 
-const userIDKey = contextKey("user_id")
+const (
+	userIDKey   = contextKey("user_id")
+	userRoleKey = contextKey("role")
+)
 
 func Auth(jwtKey []byte) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -32,13 +35,44 @@ func Auth(jwtKey []byte) func(http.Handler) http.Handler {
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			// Tokens issued before roles were added carry none and are treated as regular users
+			role, _ := claims["role"].(string)
+
+			if record, ok := GetAuditRecord(r.Context()); ok {
+				record.setActor(userID)
+			}
+
+			ctx := WithUserID(r.Context(), userID)
+			ctx = context.WithValue(ctx, userRoleKey, role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// WithUserID acts as the user outside of HTTP requests, e.g. in the admin CLI
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
 func GetUserID(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(userIDKey).(string)
 	return id, ok
 }
+
+func GetUserRole(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(userRoleKey).(string)
+	return role, ok
+}
+
+// RequireRole rejects requests of users without the role, it must run after Auth
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if userRole, ok := GetUserRole(r.Context()); !ok || userRole != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
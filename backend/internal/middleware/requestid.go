@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/duckbugio/duckbug/pkg/requestid"
+	"github.com/google/uuid"
+)
+
+// validRequestID keeps ids of proxies and clients that are safe to echo and to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID keeps the X-Request-ID of the caller or generates one, the id is put into the request
+// context and echoed in the response header
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+	})
+}
@@ -2,10 +2,19 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/worker"
 )
 
+// readyTimeout keeps a hanging database from blocking the probe past the kubelet timeout
+const readyTimeout = 2 * time.Second
+
 type service struct {
+	repo   Repository
 	logger Logger
+	config Config
 }
 
 type Logger interface {
@@ -13,11 +22,17 @@ type Logger interface {
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
-func New(logger Logger) Service {
+func New(repo Repository, logger Logger, config Config) Service {
 	a := &service{
+		repo:   repo,
 		logger: logger,
+		config: config,
 	}
 
 	return a
@@ -26,3 +41,69 @@ func New(logger Logger) Service {
 func (s *service) Health(_ context.Context) []byte {
 	return []byte("OK")
 }
+
+func (s *service) Live(_ context.Context) *Liveness {
+	return &Liveness{Status: StatusOK}
+}
+
+func (s *service) Ready(ctx context.Context) *Readiness {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	readiness := &Readiness{
+		Status:     StatusOK,
+		Database:   s.checkDatabase(ctx),
+		Migrations: s.checkMigrations(ctx),
+		Workers:    make([]worker.Status, 0, len(s.config.Workers)),
+	}
+	if readiness.Database.Status != StatusOK || readiness.Migrations.Status != StatusOK {
+		readiness.Status = StatusUnavailable
+	}
+
+	for _, w := range s.config.Workers {
+		readiness.Workers = append(readiness.Workers, w.Status())
+	}
+	return readiness
+}
+
+func (s *service) checkDatabase(ctx context.Context) DatabaseCheck {
+	startedAt := time.Now()
+	err := s.repo.Ping(ctx)
+	check := DatabaseCheck{
+		Status:    StatusOK,
+		LatencyMs: time.Since(startedAt).Milliseconds(),
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "readiness: database ping failed", "error", err)
+		check.Status = StatusUnavailable
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (s *service) checkMigrations(ctx context.Context) MigrationsCheck {
+	check := MigrationsCheck{
+		Status:   StatusOK,
+		Expected: s.config.MigrationVersion,
+	}
+
+	version, dirty, err := s.repo.MigrationVersion(ctx)
+	if err != nil {
+		check.Status = StatusUnavailable
+		check.Error = err.Error()
+		return check
+	}
+	check.Version = version
+	check.Dirty = dirty
+
+	// A newer schema is fine, pods of the previous release keep serving during a rolling update
+	switch {
+	case dirty:
+		check.Status = StatusUnavailable
+		check.Error = "a migration failed halfway and needs a manual fix"
+	case version < s.config.MigrationVersion:
+		check.Status = StatusUnavailable
+		check.Error = fmt.Sprintf("schema is at version %d, this build expects %d", version, s.config.MigrationVersion)
+	}
+	return check
+}
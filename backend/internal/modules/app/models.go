@@ -0,0 +1,46 @@
+package app
+
+import "github.com/duckbugio/duckbug/internal/worker"
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// WorkerReporter is a background worker whose state is reported by the readiness probe
+type WorkerReporter interface {
+	Status() worker.Status
+}
+
+// Config holds what the readiness probe compares the instance against
+type Config struct {
+	// MigrationVersion is the newest migration embedded in this build
+	MigrationVersion uint
+	Workers          []WorkerReporter
+}
+
+type DatabaseCheck struct {
+	Status    string `json:"status" example:"ok"`
+	LatencyMs int64  `json:"latencyMs" example:"2"`
+	Error     string `json:"error,omitempty"`
+}
+
+type MigrationsCheck struct {
+	Status   string `json:"status" example:"ok"`
+	Version  uint   `json:"version" example:"27"`
+	Expected uint   `json:"expected" example:"27"`
+	Dirty    bool   `json:"dirty" example:"false"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Readiness is ok when the database answers and its schema is migrated, worker states are informational
+type Readiness struct {
+	Status     string          `json:"status" example:"ok"`
+	Database   DatabaseCheck   `json:"database"`
+	Migrations MigrationsCheck `json:"migrations"`
+	Workers    []worker.Status `json:"workers"`
+}
+
+type Liveness struct {
+	Status string `json:"status" example:"ok"`
+}
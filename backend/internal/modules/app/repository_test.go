@@ -0,0 +1,27 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/duckbugio/duckbug/internal/storage/sql/sqltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationVersionReadsTheTable(t *testing.T) {
+	db := &sqltest.Recorder{}
+	repo := NewRepository(db.DB(), logger.New("error", &bytes.Buffer{}))
+
+	version, dirty, err := repo.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, version, "an empty table means nothing was applied")
+	assert.False(t, dirty)
+
+	// The probe must not queue behind a running migration on its advisory lock
+	require.Len(t, db.Queries, 1)
+	assert.Contains(t, db.Queries[0].SQL, "FROM schema_migrations")
+	assert.NotContains(t, db.Queries[0].SQL, "pg_advisory_lock")
+}
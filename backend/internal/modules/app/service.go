@@ -4,4 +4,8 @@ import "context"
 
 type Service interface {
 	Health(ctx context.Context) []byte
+	// Live only tells that the process serves requests, it never touches dependencies
+	Live(ctx context.Context) *Liveness
+	// Ready checks the database and the schema version and reports the background workers
+	Ready(ctx context.Context) *Readiness
 }
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// FieldChange is the old and the new value of one field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// diff compares the JSON fields of two states of an object, nil stands for an object that does not exist.
+// Only changed fields are returned, nested objects are compared as a whole.
+func diff(before, after interface{}) (map[string]FieldChange, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]FieldChange)
+	for name, value := range old {
+		if newValue, ok := updated[name]; !ok || !reflect.DeepEqual(value, newValue) {
+			changes[name] = FieldChange{From: value, To: updated[name]}
+		}
+	}
+	for name, value := range updated {
+		if _, ok := old[name]; !ok {
+			changes[name] = FieldChange{To: value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(state interface{}) (map[string]interface{}, error) {
+	if state == nil || reflect.ValueOf(state).Kind() == reflect.Pointer && reflect.ValueOf(state).IsNil() {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit state: %w", err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, fmt.Errorf("audit state is not an object: %w", err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type project struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"`
+	Tags   []string `json:"tags"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   interface{}
+		after    interface{}
+		expected map[string]FieldChange
+	}{
+		{
+			name:   "Changed fields only",
+			before: project{Name: "Shop", Status: "active", Tags: []string{"a"}},
+			after:  project{Name: "Shop", Status: "archived", Tags: []string{"a"}},
+			expected: map[string]FieldChange{
+				"status": {From: "active", To: "archived"},
+			},
+		},
+		{
+			name:   "Created",
+			before: nil,
+			after:  &project{Name: "Shop"},
+			expected: map[string]FieldChange{
+				"name":   {To: "Shop"},
+				"status": {To: ""},
+				"tags":   {To: nil},
+			},
+		},
+		{
+			name:   "Deleted",
+			before: &project{Name: "Shop", Tags: []string{"a"}},
+			after:  (*project)(nil),
+			expected: map[string]FieldChange{
+				"name":   {From: "Shop"},
+				"status": {From: ""},
+				"tags":   {From: []interface{}{"a"}},
+			},
+		},
+		{
+			name:     "Unchanged",
+			before:   map[string]string{"role": "admin"},
+			after:    map[string]string{"role": "admin"},
+			expected: map[string]FieldChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := diff(tt.before, tt.after)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, changes)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package audit
+
+type Entry struct {
+	ID      string  `db:"id"`
+	ActorID *string `db:"actor_id"`
+	Action  string  `db:"action"`
+	Method  string  `db:"method"`
+	Route   string  `db:"route"`
+	// TargetType and TargetID name the changed object, e.g. project and its id
+	TargetType *string `db:"target_type"`
+	TargetID   *string `db:"target_id"`
+	// Changes is raw JSON of the changed fields with their old and new values
+	Changes *string `db:"changes"`
+	Status  int     `db:"status"`
+	IP      string  `db:"ip"`
+	// ForwardedFor is the X-Forwarded-For header as sent, it is not verified
+	ForwardedFor *string `db:"forwarded_for"`
+	UserAgent    *string `db:"user_agent"`
+	RequestID    *string `db:"request_id"`
+	CreatedAt    int64   `db:"created_at"`
+}
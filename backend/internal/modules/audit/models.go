@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Record is a finished mutating request, before and after are the states of its target if it described them
+type Record struct {
+	ActorID      string
+	Action       string
+	Method       string
+	Route        string
+	TargetType   string
+	TargetID     string
+	Before       interface{}
+	After        interface{}
+	Status       int
+	IP           string
+	ForwardedFor string
+	UserAgent    string
+	RequestID    string
+}
+
+type FilterParams struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	TimeFrom   int64
+	TimeTo     int64
+}
+
+type GetAllParams struct {
+	FilterParams
+	Limit  int
+	Offset int
+}
+
+type Entity struct {
+	ID         string  `json:"id" example:"01890a5d-ac96-774b-bcce-b302099a8057"`
+	ActorID    *string `json:"actorId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Action     string  `json:"action" example:"project.delete"`
+	Method     string  `json:"method" example:"DELETE"`
+	Route      string  `json:"route" example:"/v1/projects/{id}"`
+	TargetType *string `json:"targetType" example:"project"`
+	TargetID   *string `json:"targetId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	// Changes maps every changed field to its old and new value
+	Changes      json.RawMessage `json:"changes,omitempty" swaggertype:"object"`
+	Status       int             `json:"status" example:"204"`
+	IP           string          `json:"ip" example:"203.0.113.7"`
+	ForwardedFor *string         `json:"forwardedFor,omitempty" example:"198.51.100.1"`
+	UserAgent    *string         `json:"userAgent,omitempty" example:"Mozilla/5.0"`
+	RequestID    *string         `json:"requestId,omitempty" example:"0b5e0c1c-6d0c-4d1a-9f5e-0c8b7a1c2d3e"`
+	CreatedAt    int64           `json:"createdAt" example:"1704067200"`
+}
+
+type EntityList struct {
+	Count int      `json:"count"`
+	Items []Entity `json:"items"`
+}
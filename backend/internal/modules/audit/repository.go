@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const entryColumns = `id, actor_id, action, method, route, target_type, target_id, changes, status, ip, forwarded_for,
+	user_agent, request_id, created_at`
+
+// Repository only appends entries, the audit log is never updated
+type Repository interface {
+	Create(ctx context.Context, entry *Entry) error
+	GetAll(ctx context.Context, params GetAllParams) ([]*Entry, error)
+	Count(ctx context.Context, params FilterParams) (int, error)
+	// Scan calls fn with every matching entry, oldest first, without loading them all
+	Scan(ctx context.Context, params FilterParams, fn func(entry *Entry) error) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, entry *Entry) error {
+	const query = `
+		INSERT INTO audit_log (
+			id, actor_id, action, method, route, target_type, target_id, changes, status, ip, forwarded_for,
+			user_agent, request_id, created_at
+		) VALUES (
+			:id, :actor_id, :action, :method, :route, :target_type, :target_id, CAST(:changes AS jsonb), :status, :ip,
+			:forwarded_for, :user_agent, :request_id, :created_at
+		)`
+
+	if _, err := r.db.NamedExecContext(ctx, query, entry); err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM audit_log WHERE 1=1`
+
+	args := map[string]interface{}{
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	}
+	query, args = applyFilters(query, params.FilterParams, args)
+	query += " ORDER BY created_at DESC, id DESC LIMIT :limit OFFSET :offset"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var entries []*Entry
+	if err = r.db.SelectContext(ctx, &entries, query, namedArgs...); err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *repository) Count(ctx context.Context, params FilterParams) (int, error) {
+	query, args := applyFilters("SELECT COUNT(*) FROM audit_log WHERE 1=1", params, make(map[string]interface{}))
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+	query = r.db.Rebind(query)
+
+	var count int
+	if err = r.db.GetContext(ctx, &count, query, namedArgs...); err != nil {
+		return 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	return count, nil
+}
+
+func (r *repository) Scan(ctx context.Context, params FilterParams, fn func(entry *Entry) error) error {
+	query, args := applyFilters(`SELECT `+entryColumns+` FROM audit_log WHERE 1=1`, params, make(map[string]interface{}))
+	query += " ORDER BY created_at, id"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	sqlRows, err := r.db.QueryContext(ctx, query, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	defer func() {
+		_ = sqlRows.Close()
+	}()
+
+	rows := &sqlx.Rows{Rows: sqlRows, Mapper: r.db.Mapper}
+	for rows.Next() {
+		var entry Entry
+		if err = rows.StructScan(&entry); err != nil {
+			return fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err = fn(&entry); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	return nil
+}
+
+func applyFilters(baseQuery string, params FilterParams, args map[string]interface{}) (string, map[string]interface{}) {
+	query := baseQuery
+
+	if params.ActorID != "" {
+		query += " AND actor_id = CAST(:actorId AS uuid)"
+		args["actorId"] = params.ActorID
+	}
+
+	if params.Action != "" {
+		query += " AND action = :action"
+		args["action"] = params.Action
+	}
+
+	if params.TargetType != "" {
+		query += " AND target_type = :targetType"
+		args["targetType"] = params.TargetType
+	}
+
+	if params.TargetID != "" {
+		query += " AND target_id = :targetId"
+		args["targetId"] = params.TargetID
+	}
+
+	if params.TimeFrom != 0 {
+		query += " AND created_at >= :timeFrom"
+		args["timeFrom"] = params.TimeFrom
+	}
+
+	if params.TimeTo != 0 {
+		query += " AND created_at <= :timeTo"
+		args["timeTo"] = params.TimeTo
+	}
+
+	return query, args
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	// Record appends a finished request to the audit log
+	Record(ctx context.Context, record *Record) error
+	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
+	// Export writes the matching entries as NDJSON, oldest first
+	Export(ctx context.Context, params FilterParams, w io.Writer) error
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+}
+
+func NewService(repo Repository, logger Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Record(ctx context.Context, record *Record) error {
+	// Time ordered ids keep entries of the same second in order
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate audit entry id: %w", err)
+	}
+
+	entry := &Entry{
+		ID:           id.String(),
+		ActorID:      optional(record.ActorID),
+		Action:       record.Action,
+		Method:       record.Method,
+		Route:        record.Route,
+		TargetType:   optional(record.TargetType),
+		TargetID:     optional(record.TargetID),
+		Status:       record.Status,
+		IP:           record.IP,
+		ForwardedFor: optional(record.ForwardedFor),
+		UserAgent:    optional(record.UserAgent),
+		RequestID:    optional(record.RequestID),
+		CreatedAt:    time.Now().Unix(),
+	}
+
+	if record.Before != nil || record.After != nil {
+		changes, err := diff(record.Before, record.After)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit changes: %w", err)
+		}
+		entry.Changes = optional(string(encoded))
+	}
+
+	return s.repo.Create(ctx, entry)
+}
+
+func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error) {
+	entries, err := s.repo.GetAll(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.Count(ctx, params.FilterParams)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entities := make([]*Entity, 0, len(entries))
+	for _, entry := range entries {
+		entities = append(entities, toEntity(entry))
+	}
+	return entities, total, nil
+}
+
+func (s *service) Export(ctx context.Context, params FilterParams, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return s.repo.Scan(ctx, params, func(entry *Entry) error {
+		if err := encoder.Encode(toEntity(entry)); err != nil {
+			return fmt.Errorf("failed to write audit entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func toEntity(entry *Entry) *Entity {
+	entity := &Entity{
+		ID:           entry.ID,
+		ActorID:      entry.ActorID,
+		Action:       entry.Action,
+		Method:       entry.Method,
+		Route:        entry.Route,
+		TargetType:   entry.TargetType,
+		TargetID:     entry.TargetID,
+		Status:       entry.Status,
+		IP:           entry.IP,
+		ForwardedFor: entry.ForwardedFor,
+		UserAgent:    entry.UserAgent,
+		RequestID:    entry.RequestID,
+		CreatedAt:    entry.CreatedAt,
+	}
+	if entry.Changes != nil {
+		entity.Changes = json.RawMessage(*entry.Changes)
+	}
+	return entity
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
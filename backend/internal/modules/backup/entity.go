@@ -0,0 +1,48 @@
+package backup
+
+const (
+	projectIDColumn = "project_id"
+	keysTable       = "project_keys"
+)
+
+// projectTable holds rows of a single project, column is the one referencing the project.
+// orderBy is set for tables referencing their own rows, so referenced rows are restored first.
+// timeColumn is set for events, it is filtered by the time range of an export.
+type projectTable struct {
+	name       string
+	column     string
+	orderBy    string
+	timeColumn string
+}
+
+// Project is the state of a project an archive is written from or imported into
+type Project struct {
+	ID        string `db:"id"`
+	DeletedAt *int64 `db:"deleted_at"`
+}
+
+// projectTables are dumped and restored in this order, so groups come before their events
+var projectTables = []projectTable{
+	{name: "projects", column: "id"},
+	{name: "scrubbing_settings", column: projectIDColumn},
+	{name: "scrubbing_rules", column: projectIDColumn},
+	{name: "ingest_limits", column: projectIDColumn},
+	{name: keysTable, column: projectIDColumn},
+	{name: "error_groups", column: projectIDColumn},
+	{name: "error_group_comments", column: projectIDColumn, orderBy: "id"},
+	{name: "error_group_activity", column: projectIDColumn},
+	{name: "log_groups", column: projectIDColumn},
+	{name: "errors", column: projectIDColumn, timeColumn: "time"},
+	{name: "logs", column: projectIDColumn, timeColumn: "time"},
+	{name: "sessions", column: projectIDColumn, timeColumn: "started_at"},
+	{name: "spans", column: projectIDColumn, timeColumn: "start_time"},
+}
+
+func findTable(name string) (projectTable, bool) {
+	for _, table := range projectTables {
+		if table.name == name {
+			return table, true
+		}
+	}
+	return projectTable{}, false
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// FormatVersion is written to the header of every archive, archives of other versions are rejected
+const FormatVersion = 1
+
+var (
+	ErrNotFound       = errors.New("project not found")
+	ErrInvalidArchive = errors.New("invalid backup archive")
+	// ErrProjectDeleted is returned by an import into a project which was deleted, it has to be restored first
+	ErrProjectDeleted = errors.New("project is deleted")
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// ExportParams selects the project of an archive, events are limited to the time range in ms when it is set
+type ExportParams struct {
+	ProjectID string
+	TimeFrom  int64
+	TimeTo    int64
+}
+
+// ImportParams selects the project an archive is imported into. An empty ProjectID imports into a new project
+// of the current user, named Name or after the archived project.
+type ImportParams struct {
+	ProjectID string
+	Name      string
+}
+
+// Header is the first line of an archive
+type Header struct {
+	Version   int    `json:"version"`
+	ProjectID string `json:"projectId"`
+	CreatedAt int64  `json:"createdAt"`
+	TimeFrom  int64  `json:"timeFrom,omitempty"`
+	TimeTo    int64  `json:"timeTo,omitempty"`
+}
+
+// Record is one table row of an archive, every following line holds one
+type Record struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+type TableReport struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+	// Skipped rows already existed on restore
+	Skipped int `json:"skipped"`
+}
+
+type Report struct {
+	ProjectID string `json:"projectId"`
+	// Created is set when an import created the project
+	Created bool           `json:"created"`
+	Tables  []*TableReport `json:"tables"`
+}
+
+func (r *Report) table(name string) *TableReport {
+	for _, table := range r.Tables {
+		if table.Table == name {
+			return table
+		}
+	}
+	table := &TableReport{Table: name}
+	r.Tables = append(r.Tables, table)
+	return table
+}
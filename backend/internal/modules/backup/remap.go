@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	moduleErrors "github.com/duckbugio/duckbug/internal/modules/errors"
+	moduleLog "github.com/duckbugio/duckbug/internal/modules/log"
+	"github.com/google/uuid"
+)
+
+const keyLength = 64
+
+// remapper moves archived rows into the target project. Ids of rows are derived from the target and the
+// archived id, so importing the same archive again hits the same rows, which are skipped. Group ids are
+// fingerprints of the project, they are calculated again the way ingest does.
+type remapper struct {
+	target uuid.UUID
+	// identity keeps the archived ids, the archive is imported into the project it was written from.
+	// Rows are still moved into the target and checked like any other import.
+	identity bool
+	// create inserts the archived project as the target, otherwise it exists and keeps its settings
+	create    bool
+	creatorID string
+	name      string
+	now       int64
+
+	groups     map[string]string
+	users      map[string]bool
+	userExists func(userID string) (bool, error)
+}
+
+// remap returns the records to insert for record, none when the row is left out
+func (m *remapper) remap(record *Record) ([]*Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(record.Row))
+	decoder.UseNumber()
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidArchive, record.Table, err)
+	}
+
+	var extra []*Record
+	switch record.Table {
+	case "projects":
+		if !m.create {
+			return nil, nil
+		}
+		key, err := m.project(row)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, key)
+	case keysTable:
+		// Keys belong to the DSN of the archived project, the target has its own
+		return nil, nil
+	case "scrubbing_settings", "ingest_limits", "sessions", "spans":
+		row[projectIDColumn] = m.target.String()
+	case "scrubbing_rules":
+		m.ids(row, "id")
+		row[projectIDColumn] = m.target.String()
+	case "error_groups":
+		m.group(row, moduleErrors.Fingerprint(m.target.String(), str(row, "message"), str(row, "file"), integer(row, "line")))
+		if err := m.user(row, "assignee_id"); err != nil {
+			return nil, err
+		}
+	case "log_groups":
+		m.group(row, moduleLog.Fingerprint(m.target.String(), moduleLog.Level(str(row, "level")), str(row, "message")))
+	case "error_group_comments":
+		m.ids(row, "id", "parent_id")
+		if err := m.groupOf(row, "group_id"); err != nil {
+			return nil, err
+		}
+		if err := m.user(row, "author_id"); err != nil {
+			return nil, err
+		}
+		if err := m.mentions(row); err != nil {
+			return nil, err
+		}
+	case "error_group_activity":
+		m.ids(row, "id")
+		if err := m.groupOf(row, "group_id"); err != nil {
+			return nil, err
+		}
+		if err := m.user(row, "actor_id"); err != nil {
+			return nil, err
+		}
+	case "errors":
+		m.event(row, func() string {
+			return moduleErrors.Fingerprint(m.target.String(), str(row, "message"), str(row, "file"), integer(row, "line"))
+		})
+	case "logs":
+		m.event(row, func() string {
+			return moduleLog.Fingerprint(m.target.String(), moduleLog.Level(str(row, "level")), str(row, "message"))
+		})
+	default:
+		return nil, fmt.Errorf("%w: unknown table %q", ErrInvalidArchive, record.Table)
+	}
+
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", record.Table, err)
+	}
+	return append([]*Record{{Table: record.Table, Row: encoded}}, extra...), nil
+}
+
+// project turns the archived project into the new one and returns its first ingest key
+func (m *remapper) project(row map[string]interface{}) (*Record, error) {
+	b := make([]byte, keyLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	publicKey := base64.RawURLEncoding.EncodeToString(b)
+
+	row["id"] = m.target.String()
+	row["creator_id"] = m.creatorID
+	row["public_key"] = publicKey
+	row["created_at"] = m.now
+	row["updated_at"] = m.now
+	row["deleted_at"] = nil
+	if m.name != "" {
+		row["name"] = m.name
+	}
+
+	key, err := json.Marshal(map[string]interface{}{
+		"id":              m.id(keysTable),
+		projectIDColumn:   m.target.String(),
+		"name":            "Default",
+		"public_key":      publicKey,
+		"scope":           "all",
+		"allowed_origins": []string{},
+		"created_at":      m.now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode project key: %w", err)
+	}
+	return &Record{Table: keysTable, Row: key}, nil
+}
+
+func (m *remapper) group(row map[string]interface{}, fingerprint string) {
+	m.groups[str(row, "id")] = fingerprint
+	row["id"] = fingerprint
+	row[projectIDColumn] = m.target.String()
+}
+
+// groupOf points a row to the imported group, which comes earlier in the archive
+func (m *remapper) groupOf(row map[string]interface{}, key string) error {
+	fingerprint, ok := m.groups[str(row, key)]
+	if !ok {
+		return fmt.Errorf("%w: row of unknown group %q", ErrInvalidArchive, str(row, key))
+	}
+	row[key] = fingerprint
+	row[projectIDColumn] = m.target.String()
+	return nil
+}
+
+// event moves an event into the target, its group is fingerprinted again when it was not archived
+func (m *remapper) event(row map[string]interface{}, fingerprint func() string) {
+	m.ids(row, "id")
+	row[projectIDColumn] = m.target.String()
+	if imported, ok := m.groups[str(row, "fingerprint")]; ok {
+		row["fingerprint"] = imported
+	} else {
+		row["fingerprint"] = fingerprint()
+	}
+}
+
+func (m *remapper) ids(row map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		if value := str(row, key); value != "" {
+			row[key] = m.id(value)
+		}
+	}
+}
+
+func (m *remapper) id(archived string) string {
+	if m.identity {
+		return archived
+	}
+	return uuid.NewSHA1(m.target, []byte(archived)).String()
+}
+
+// user keeps a reference to a user only when the user exists on this instance
+func (m *remapper) user(row map[string]interface{}, key string) error {
+	userID := str(row, key)
+	if userID == "" {
+		return nil
+	}
+	exists, err := m.known(userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		row[key] = nil
+	}
+	return nil
+}
+
+func (m *remapper) mentions(row map[string]interface{}) error {
+	mentions, _ := row["mentions"].([]interface{})
+	kept := make([]interface{}, 0, len(mentions))
+	for _, mention := range mentions {
+		userID, _ := mention.(string)
+		exists, err := m.known(userID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			kept = append(kept, userID)
+		}
+	}
+	row["mentions"] = kept
+	return nil
+}
+
+func (m *remapper) known(userID string) (bool, error) {
+	if exists, ok := m.users[userID]; ok {
+		return exists, nil
+	}
+	exists, err := m.userExists(userID)
+	if err != nil {
+		return false, err
+	}
+	m.users[userID] = exists
+	return exists, nil
+}
+
+func str(row map[string]interface{}, key string) string {
+	value, _ := row[key].(string)
+	return value
+}
+
+func integer(row map[string]interface{}, key string) int {
+	value, _ := row[key].(json.Number)
+	number, _ := value.Int64()
+	return int(number)
+}
@@ -0,0 +1,134 @@
+package backup
+
+import (
+	"encoding/json"
+	"testing"
+
+	moduleErrors "github.com/duckbugio/duckbug/internal/modules/errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sourceProject = "a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"
+	knownUser     = "0194a3c2-7f10-7000-8000-000000000001"
+	unknownUser   = "0194a3c2-7f10-7000-8000-000000000002"
+)
+
+func newRemapper(create bool) *remapper {
+	return &remapper{
+		target:    uuid.MustParse("5b7c3f51-3f0e-4cde-9a3c-7c1f8e2d9a10"),
+		create:    create,
+		creatorID: knownUser,
+		now:       1735689600,
+		groups:    make(map[string]string),
+		users:     make(map[string]bool),
+		userExists: func(userID string) (bool, error) {
+			return userID == knownUser, nil
+		},
+	}
+}
+
+func remapRow(t *testing.T, m *remapper, table, row string) []map[string]interface{} {
+	t.Helper()
+
+	records, err := m.remap(&Record{Table: table, Row: json.RawMessage(row)})
+	require.NoError(t, err)
+
+	rows := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(record.Row, &decoded))
+		decoded["_table"] = record.Table
+		rows = append(rows, decoded)
+	}
+	return rows
+}
+
+func TestRemapProject(t *testing.T) {
+	const row = `{"id":"` + sourceProject + `","creator_id":"` + unknownUser + `","name":"Shop",` +
+		`"public_key":"old","technology_id":1,"created_at":1,"updated_at":1,"deleted_at":null}`
+
+	t.Run("New project gets a key", func(t *testing.T) {
+		m := newRemapper(true)
+		m.name = "Shop staging"
+
+		rows := remapRow(t, m, "projects", row)
+		require.Len(t, rows, 2)
+		assert.Equal(t, m.target.String(), rows[0]["id"])
+		assert.Equal(t, knownUser, rows[0]["creator_id"])
+		assert.Equal(t, "Shop staging", rows[0]["name"])
+		assert.NotEqual(t, "old", rows[0]["public_key"])
+
+		assert.Equal(t, "project_keys", rows[1]["_table"])
+		assert.Equal(t, m.target.String(), rows[1]["project_id"])
+		assert.Equal(t, rows[0]["public_key"], rows[1]["public_key"])
+	})
+
+	t.Run("Existing project keeps its settings", func(t *testing.T) {
+		assert.Empty(t, remapRow(t, newRemapper(false), "projects", row))
+	})
+
+	t.Run("Archived keys are left out", func(t *testing.T) {
+		assert.Empty(t, remapRow(t, newRemapper(true), "project_keys", `{"id":"`+knownUser+`"}`))
+	})
+}
+
+func TestRemapGroupsAndEvents(t *testing.T) {
+	m := newRemapper(false)
+	target := m.target.String()
+	archivedGroup := moduleErrors.Fingerprint(sourceProject, "Timeout after 30s", "app.go", 42)
+	importedGroup := moduleErrors.Fingerprint(target, "Timeout after 30s", "app.go", 42)
+
+	groups := remapRow(t, m, "error_groups", `{"id":"`+archivedGroup+`","project_id":"`+sourceProject+
+		`","message":"Timeout after 30s","file":"app.go","line":42,"assignee_id":"`+unknownUser+`"}`)
+	require.Len(t, groups, 1)
+	assert.Equal(t, importedGroup, groups[0]["id"])
+	assert.Equal(t, target, groups[0]["project_id"])
+	assert.Nil(t, groups[0]["assignee_id"], "users of another instance are dropped")
+
+	comments := remapRow(t, m, "error_group_comments", `{"id":"`+unknownUser+`","group_id":"`+archivedGroup+
+		`","author_id":"`+knownUser+`","mentions":["`+knownUser+`","`+unknownUser+`"]}`)
+	require.Len(t, comments, 1)
+	assert.Equal(t, importedGroup, comments[0]["group_id"])
+	assert.Equal(t, knownUser, comments[0]["author_id"])
+	assert.Equal(t, []interface{}{knownUser}, comments[0]["mentions"])
+
+	const event = `{"id":"` + knownUser + `","project_id":"` + sourceProject + `","fingerprint":"not-archived",` +
+		`"message":"Timeout after 31s","file":"app.go","line":42,"time":1735689600123}`
+
+	first := remapRow(t, m, "errors", event)
+	again := remapRow(t, newRemapper(false), "errors", event)
+	require.Len(t, first, 1)
+	assert.Equal(t, importedGroup, first[0]["fingerprint"], "the group is fingerprinted for the target")
+	assert.NotEqual(t, knownUser, first[0]["id"])
+	assert.Equal(t, first[0]["id"], again[0]["id"], "ids are the same on every import")
+	assert.InDelta(t, 1735689600123, first[0]["time"], 0)
+
+	_, err := m.remap(&Record{Table: "error_group_activity", Row: json.RawMessage(`{"group_id":"unknown"}`)})
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+func TestRemapIdentity(t *testing.T) {
+	m := newRemapper(false)
+	m.target = uuid.MustParse(sourceProject)
+	m.identity = true
+	group := moduleErrors.Fingerprint(sourceProject, "Timeout after 30s", "app.go", 42)
+
+	assert.Empty(t, remapRow(t, m, "project_keys", `{"id":"`+knownUser+`","project_id":"`+sourceProject+`"}`),
+		"keys are not imported into the same project either")
+
+	groups := remapRow(t, m, "error_groups", `{"id":"`+group+`","project_id":"`+sourceProject+
+		`","message":"Timeout after 30s","file":"app.go","line":42,"assignee_id":"`+unknownUser+`"}`)
+	require.Len(t, groups, 1)
+	assert.Equal(t, group, groups[0]["id"])
+	assert.Nil(t, groups[0]["assignee_id"])
+
+	events := remapRow(t, m, "errors", `{"id":"`+knownUser+`","project_id":"`+unknownUser+`","fingerprint":"`+group+
+		`","message":"Timeout after 30s","file":"app.go","line":42,"time":1735689600123}`)
+	require.Len(t, events, 1)
+	assert.Equal(t, knownUser, events[0]["id"], "archived ids are kept")
+	assert.Equal(t, sourceProject, events[0]["project_id"], "rows of another project are moved into the target")
+	assert.Equal(t, group, events[0]["fingerprint"])
+}
@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// GetProject returns the project, deleted ones included
+	GetProject(ctx context.Context, projectID string) (*Project, error)
+	UserExists(ctx context.Context, userID string) (bool, error)
+	// Scan calls fn with every row of the project in table as a JSON object, derived columns are left out
+	Scan(ctx context.Context, table projectTable, params ExportParams, fn func(row json.RawMessage) error) error
+	// Restore inserts the records returned by next until it returns io.EOF, in one transaction.
+	// Rows that already exist are counted as skipped.
+	Restore(ctx context.Context, next func() (*Record, error), report *Report) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetProject(ctx context.Context, projectID string) (*Project, error) {
+	const query = `SELECT id, deleted_at FROM projects WHERE id = $1`
+
+	var project Project
+	if err := r.db.GetContext(ctx, &project, query, projectID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return &project, nil
+}
+
+func (r *repository) UserExists(ctx context.Context, userID string) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, userID); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
+
+// columns lists the writable columns of table in their order. Search vectors are left out as well,
+// triggers compute them again when rows are inserted.
+func columns(ctx context.Context, q sqlx.QueryerContext, table string) (string, error) {
+	const query = `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'
+			AND column_name <> 'search_vector'
+		ORDER BY ordinal_position`
+
+	var names []string
+	if err := sqlx.SelectContext(ctx, q, &names, query, table); err != nil {
+		return "", fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("table %s does not exist", table)
+	}
+
+	for i, name := range names {
+		names[i] = pq.QuoteIdentifier(name)
+	}
+	return strings.Join(names, ", "), nil
+}
+
+func (r *repository) Scan(ctx context.Context, table projectTable, params ExportParams, fn func(row json.RawMessage) error) error {
+	cols, err := columns(ctx, r.db, table.name)
+	if err != nil {
+		return err
+	}
+
+	conditions := pq.QuoteIdentifier(table.column) + " = $1"
+	args := []interface{}{params.ProjectID}
+	if table.timeColumn != "" && params.TimeFrom != 0 {
+		args = append(args, params.TimeFrom)
+		conditions += fmt.Sprintf(" AND %s >= $%d", pq.QuoteIdentifier(table.timeColumn), len(args))
+	}
+	if table.timeColumn != "" && params.TimeTo != 0 {
+		args = append(args, params.TimeTo)
+		conditions += fmt.Sprintf(" AND %s <= $%d", pq.QuoteIdentifier(table.timeColumn), len(args))
+	}
+
+	var order string
+	if table.orderBy != "" {
+		order = " ORDER BY " + pq.QuoteIdentifier(table.orderBy)
+	}
+	query := fmt.Sprintf(
+		`SELECT row_to_json(t) FROM (SELECT %s FROM %s WHERE %s%s) t`,
+		cols, pq.QuoteIdentifier(table.name), conditions, order,
+	)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var row json.RawMessage
+		if err = rows.Scan(&row); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table.name, err)
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, next func() (*Record, error), report *Report) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	// json_populate_record converts the archived values back to the column types
+	queries := make(map[string]string, len(projectTables))
+	for {
+		var record *Record
+		record, err = next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		query, ok := queries[record.Table]
+		if !ok {
+			var cols string
+			if cols, err = columns(ctx, tx, record.Table); err != nil {
+				return err
+			}
+			table := pq.QuoteIdentifier(record.Table)
+			query = fmt.Sprintf(
+				`INSERT INTO %s (%s) SELECT %s FROM json_populate_record(CAST(NULL AS %s), CAST($1 AS json)) ON CONFLICT DO NOTHING`,
+				table, cols, cols, table,
+			)
+			r.logger.DebugContext(ctx, "sql query", "query", query)
+			queries[record.Table] = query
+		}
+
+		var result sql.Result
+		if result, err = tx.ExecContext(ctx, query, []byte(record.Row)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", record.Table, err)
+		}
+		var rowsAffected int64
+		if rowsAffected, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		tableReport := report.table(record.Table)
+		tableReport.Rows++
+		if rowsAffected == 0 {
+			tableReport.Skipped++
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxLineSize bounds a single archived row, large events with stack traces fit comfortably
+	maxLineSize     = 64 << 20
+	initialLineSize = 64 << 10
+)
+
+type Service interface {
+	// Dump writes the project with its settings, keys, groups and events as gzip compressed NDJSON,
+	// events are limited to the time range of params
+	Dump(ctx context.Context, params ExportParams, w io.Writer) (*Report, error)
+	// Restore recreates an archived project with its original ids, rows that already exist are skipped
+	Restore(ctx context.Context, r io.Reader) (*Report, error)
+	// Import copies an archived project into an existing or a new project of the current user with new ids.
+	// Importing the same archive again skips the rows imported before.
+	Import(ctx context.Context, r io.Reader, params ImportParams) (*Report, error)
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+}
+
+func NewService(repo Repository, logger Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Dump(ctx context.Context, params ExportParams, w io.Writer) (*Report, error) {
+	project, err := s.repo.GetProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.DeletedAt != nil {
+		return nil, ErrProjectDeleted
+	}
+
+	zw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(zw)
+
+	header := Header{
+		Version:   FormatVersion,
+		ProjectID: params.ProjectID,
+		CreatedAt: time.Now().Unix(),
+		TimeFrom:  params.TimeFrom,
+		TimeTo:    params.TimeTo,
+	}
+	if err = encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write archive header: %w", err)
+	}
+
+	report := &Report{ProjectID: params.ProjectID}
+	for _, table := range projectTables {
+		tableReport := report.table(table.name)
+		err = s.repo.Scan(ctx, table, params, func(row json.RawMessage) error {
+			tableReport.Rows++
+			if err := encoder.Encode(Record{Table: table.name, Row: row}); err != nil {
+				return fmt.Errorf("failed to write archive: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	s.logger.InfoContext(ctx, "project dumped", "project_id", params.ProjectID)
+	return report, nil
+}
+
+func (s *service) Restore(ctx context.Context, r io.Reader) (*Report, error) {
+	archive, err := openArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.close()
+
+	report := &Report{ProjectID: archive.header.ProjectID}
+	if err = s.repo.Restore(ctx, archive.next, report); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "project restored", "project_id", archive.header.ProjectID)
+	return report, nil
+}
+
+func (s *service) Import(ctx context.Context, r io.Reader, params ImportParams) (*Report, error) {
+	// The current user owns a new project, an import into an existing one does not need a user
+	userID, _ := middleware.GetUserID(ctx)
+
+	archive, err := openArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.close()
+
+	source, err := uuid.Parse(archive.header.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid project id: %w", ErrInvalidArchive, err)
+	}
+
+	m := &remapper{
+		creatorID:  userID,
+		name:       params.Name,
+		now:        time.Now().Unix(),
+		groups:     make(map[string]string),
+		users:      make(map[string]bool),
+		userExists: func(userID string) (bool, error) { return s.repo.UserExists(ctx, userID) },
+	}
+
+	if params.ProjectID != "" {
+		if m.target, err = uuid.Parse(params.ProjectID); err != nil {
+			return nil, ErrNotFound
+		}
+	} else {
+		if userID == "" {
+			return nil, fmt.Errorf("unauthorized")
+		}
+		// The new project is the same on every import of the archive by the user, so it is not copied twice
+		m.target = uuid.NewSHA1(source, []byte(userID))
+	}
+	m.identity = m.target == source
+
+	project, err := s.repo.GetProject(ctx, m.target.String())
+	switch {
+	case errors.Is(err, ErrNotFound) && params.ProjectID == "":
+		m.create = true
+	case err != nil:
+		return nil, err
+	case project.DeletedAt != nil:
+		return nil, ErrProjectDeleted
+	}
+
+	report := &Report{ProjectID: m.target.String(), Created: m.create}
+	var pending []*Record
+	next := func() (*Record, error) {
+		for len(pending) == 0 {
+			record, err := archive.next()
+			if err != nil {
+				return nil, err
+			}
+			if pending, err = m.remap(record); err != nil {
+				return nil, err
+			}
+			if len(pending) == 0 {
+				tableReport := report.table(record.Table)
+				tableReport.Rows++
+				tableReport.Skipped++
+			}
+		}
+		record := pending[0]
+		pending = pending[1:]
+		return record, nil
+	}
+
+	if err = s.repo.Restore(ctx, next, report); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "project.import", "project", m.target.String(), nil, report)
+	s.logger.InfoContext(ctx, "project imported",
+		"project_id", m.target.String(), "source_project_id", archive.header.ProjectID, "created", m.create)
+	return report, nil
+}
+
+// archive reads the records of an archive after its header
+type archive struct {
+	header  Header
+	zr      *gzip.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+func openArchive(r io.Reader) (*archive, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	a := &archive{zr: zr, scanner: bufio.NewScanner(zr), line: 1}
+	a.scanner.Buffer(make([]byte, 0, initialLineSize), maxLineSize)
+
+	if err = a.readHeader(); err != nil {
+		a.close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *archive) readHeader() error {
+	if !a.scanner.Scan() {
+		return fmt.Errorf("%w: missing header", ErrInvalidArchive)
+	}
+	if err := json.Unmarshal(a.scanner.Bytes(), &a.header); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	if a.header.Version != FormatVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, a.header.Version)
+	}
+	if a.header.ProjectID == "" {
+		return fmt.Errorf("%w: missing project id", ErrInvalidArchive)
+	}
+	return nil
+}
+
+func (a *archive) next() (*Record, error) {
+	if !a.scanner.Scan() {
+		if err := a.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		return nil, io.EOF
+	}
+	a.line++
+
+	var record Record
+	if err := json.Unmarshal(a.scanner.Bytes(), &record); err != nil {
+		return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidArchive, a.line, err)
+	}
+	// Only tables of a project are written, whatever the archive says
+	if _, ok := findTable(record.Table); !ok {
+		return nil, fmt.Errorf("%w: line %d: unknown table %q", ErrInvalidArchive, a.line, record.Table)
+	}
+	if len(record.Row) == 0 || record.Row[0] != '{' {
+		return nil, fmt.Errorf("%w: line %d: row is not an object", ErrInvalidArchive, a.line)
+	}
+	return &record, nil
+}
+
+func (a *archive) close() {
+	_ = a.zr.Close()
+}
@@ -0,0 +1,95 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	// JobType identifies bulk operations in the jobs queue
+	JobType = "bulk"
+
+	TargetErrors      = "errors"
+	TargetLogs        = "logs"
+	TargetErrorGroups = "error-groups"
+	TargetLogGroups   = "log-groups"
+
+	// ActionDelete removes the matching rows, deleting groups also deletes their events
+	ActionDelete = "delete"
+	// ActionStatus sets the status of the matching error groups
+	ActionStatus = "status"
+
+	// AssigneeMe filters error groups assigned to the requesting user
+	AssigneeMe = "me"
+)
+
+var ErrInvalidRequest = errors.New("invalid bulk operation")
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Filter selects rows the way the filters of the lists do. Filters which do not apply to the target are
+// rejected rather than ignored, so a mistaken filter never widens the operation.
+type Filter struct {
+	ProjectID string `json:"projectId" validate:"required,uuid" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	// GroupID limits errors or logs to one fingerprint
+	GroupID string `json:"groupId,omitempty" validate:"omitempty,max=64"`
+	// Level applies to logs and log groups
+	Level string `json:"level,omitempty" validate:"omitempty,oneof=DEBUG INFO WARN ERROR" example:"DEBUG"`
+	// Search matches the message, it is a full-text query for events in fulltext mode
+	Search     string `json:"search,omitempty" validate:"max=1000"`
+	SearchMode string `json:"searchMode,omitempty" validate:"omitempty,oneof=substring fulltext"`
+	// Status applies to error groups
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=resolved unresolved ignored"`
+	// Assignee applies to error groups: me, none or a user id
+	Assignee string `json:"assignee,omitempty" validate:"max=36"`
+	// TimeFrom and TimeTo are Unix seconds of the event, or of the last event of a group
+	TimeFrom int64 `json:"timeFrom,omitempty" validate:"gte=0" example:"1704067200"`
+	TimeTo   int64 `json:"timeTo,omitempty" validate:"gte=0" example:"1706745600"`
+}
+
+type Request struct {
+	Target string `json:"target" validate:"required,oneof=errors logs error-groups log-groups" example:"logs"`
+	Action string `json:"action" validate:"required,oneof=delete status" example:"delete"`
+	// Status is the new status of the status action
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=resolved unresolved ignored" example:"resolved"`
+	Filter Filter `json:"filter"`
+	// DryRun only counts the matching rows, nothing is queued
+	DryRun bool `json:"dryRun" example:"true"`
+}
+
+// DryRunResult is the answer to a dry run
+type DryRunResult struct {
+	Matched int `json:"matched" example:"4200"`
+}
+
+// jobParams is what gets queued
+type jobParams struct {
+	Request
+	RequestedBy string `json:"requestedBy"`
+}
+
+// Report is the result of a bulk job
+type Report struct {
+	Target string `json:"target" example:"logs"`
+	Action string `json:"action" example:"delete"`
+	Status string `json:"status,omitempty" example:"resolved"`
+	// Matched is the count at the start of the job
+	Matched int `json:"matched" example:"4200"`
+	// Processed counts the deleted rows or the groups the status was applied to
+	Processed int `json:"processed" example:"4200"`
+	// Events counts the events deleted together with their groups
+	Events int `json:"events" example:"18000"`
+	// Recounted counts groups whose counters were recalculated after their events were deleted
+	Recounted  int   `json:"recounted" example:"12"`
+	StartedAt  int64 `json:"startedAt" example:"1700000000"`
+	FinishedAt int64 `json:"finishedAt" example:"1700000042"`
+}
@@ -0,0 +1,466 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	moduleErrors "github.com/duckbugio/duckbug/internal/modules/errors"
+	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	"github.com/duckbugio/duckbug/internal/modules/jobs"
+	moduleLog "github.com/duckbugio/duckbug/internal/modules/log"
+	logGroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	"github.com/duckbugio/duckbug/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const batchSize = 500
+
+// Enqueuer queues the operation as a tracked job
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, params interface{}) (*jobs.Entity, error)
+}
+
+// ErrorEvents counts and deletes errors, it is the errors repository
+type ErrorEvents interface {
+	Count(ctx context.Context, params moduleErrors.FilterParams) (int, error)
+	DeleteMatching(ctx context.Context, params moduleErrors.FilterParams, limit int) ([]string, error)
+}
+
+// LogEvents counts and deletes logs, it is the logs repository
+type LogEvents interface {
+	Count(ctx context.Context, params moduleLog.FilterParams) (int, error)
+	DeleteMatching(ctx context.Context, params moduleLog.FilterParams, limit int) ([]string, error)
+}
+
+// ErrorGroups is the error groups repository
+type ErrorGroups interface {
+	Count(ctx context.Context, params errorsGroup.FilterParams) (int, error)
+	FindIDs(ctx context.Context, params errorsGroup.FilterParams, afterID string, limit int) ([]string, error)
+	BatchUpdateStatus(ctx context.Context, ids []string, status errorsGroup.Status, actorID *string) error
+	Decrement(ctx context.Context, removed map[string]int) error
+	DeleteByIDs(ctx context.Context, ids []string) error
+}
+
+// LogGroups is the log groups repository
+type LogGroups interface {
+	Count(ctx context.Context, params logGroup.FilterParams) (int, error)
+	FindIDs(ctx context.Context, params logGroup.FilterParams, afterID string, limit int) ([]string, error)
+	Decrement(ctx context.Context, removed map[string]int) error
+	DeleteByIDs(ctx context.Context, ids []string) error
+}
+
+type Service interface {
+	// Count returns how many rows the request matches, it answers dry runs
+	Count(ctx context.Context, req *Request) (int, error)
+	// Request queues the operation as a tracked job
+	Request(ctx context.Context, req *Request) (*jobs.Entity, error)
+	// Run performs a queued operation, it is the jobs handler for JobType
+	Run(ctx context.Context, params json.RawMessage) (interface{}, error)
+}
+
+type service struct {
+	jobs        Enqueuer
+	errors      ErrorEvents
+	logs        LogEvents
+	errorGroups ErrorGroups
+	logGroups   LogGroups
+	logger      Logger
+}
+
+func NewService(
+	jobs Enqueuer,
+	errorEvents ErrorEvents,
+	logEvents LogEvents,
+	errorGroups ErrorGroups,
+	logGroups LogGroups,
+	logger Logger,
+) Service {
+	return &service{
+		jobs:        jobs,
+		errors:      errorEvents,
+		logs:        logEvents,
+		errorGroups: errorGroups,
+		logGroups:   logGroups,
+		logger:      logger,
+	}
+}
+
+// Names of the filters, as they are named in requests
+const (
+	filterGroupID    = "groupId"
+	filterLevel      = "level"
+	filterSearch     = "search"
+	filterSearchMode = "searchMode"
+	filterStatus     = "status"
+	filterAssignee   = "assignee"
+	filterTimeFrom   = "timeFrom"
+	filterTimeTo     = "timeTo"
+)
+
+// filters lists the filters every target accepts besides the project
+var filters = map[string]map[string]bool{
+	TargetErrors: {
+		filterGroupID: true, filterSearch: true, filterSearchMode: true, filterTimeFrom: true, filterTimeTo: true,
+	},
+	TargetLogs: {
+		filterGroupID: true, filterLevel: true, filterSearch: true, filterSearchMode: true,
+		filterTimeFrom: true, filterTimeTo: true,
+	},
+	TargetErrorGroups: {
+		filterSearch: true, filterStatus: true, filterAssignee: true, filterTimeFrom: true, filterTimeTo: true,
+	},
+	TargetLogGroups: {filterLevel: true, filterSearch: true, filterTimeFrom: true, filterTimeTo: true},
+}
+
+func (s *service) Count(ctx context.Context, req *Request) (int, error) {
+	if err := s.resolve(ctx, req); err != nil {
+		return 0, err
+	}
+	return s.count(ctx, req)
+}
+
+func (s *service) Request(ctx context.Context, req *Request) (*jobs.Entity, error) {
+	if err := s.resolve(ctx, req); err != nil {
+		return nil, err
+	}
+
+	params := jobParams{Request: *req}
+	params.RequestedBy, _ = middleware.GetUserID(ctx)
+
+	job, err := s.jobs.Enqueue(ctx, JobType, params)
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "bulk."+req.Action, "project", req.Filter.ProjectID, nil, params)
+	return job, nil
+}
+
+func (s *service) Run(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params jobParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("invalid bulk params: %w", err)
+	}
+	req := &params.Request
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Target:    req.Target,
+		Action:    req.Action,
+		Status:    req.Status,
+		StartedAt: time.Now().Unix(),
+	}
+
+	var err error
+	if report.Matched, err = s.count(ctx, req); err != nil {
+		return nil, err
+	}
+	progress(ctx, report)
+
+	switch {
+	case req.Target == TargetErrors:
+		err = s.deleteErrors(ctx, report, errorFilter(req.Filter))
+	case req.Target == TargetLogs:
+		err = s.deleteLogs(ctx, report, logFilter(req.Filter))
+	case req.Target == TargetErrorGroups && req.Action == ActionStatus:
+		var actorID *string
+		if params.RequestedBy != "" {
+			actorID = &params.RequestedBy
+		}
+		err = s.eachGroupBatch(ctx, report, errorGroupFinder(s.errorGroups, errorGroupFilter(req.Filter)),
+			func(ids []string) error {
+				return s.errorGroups.BatchUpdateStatus(ctx, ids, errorsGroup.Status(req.Status), actorID)
+			})
+	case req.Target == TargetErrorGroups:
+		err = s.eachGroupBatch(ctx, report, errorGroupFinder(s.errorGroups, errorGroupFilter(req.Filter)),
+			func(ids []string) error {
+				for _, id := range ids {
+					filter := moduleErrors.FilterParams{ProjectID: req.Filter.ProjectID, Fingerprint: id}
+					if err := s.deleteGroupEvents(ctx, report, func() ([]string, error) {
+						return s.errors.DeleteMatching(ctx, filter, batchSize)
+					}); err != nil {
+						return err
+					}
+				}
+				return s.errorGroups.DeleteByIDs(ctx, ids)
+			})
+	default:
+		err = s.eachGroupBatch(ctx, report, logGroupFinder(s.logGroups, logGroupFilter(req.Filter)),
+			func(ids []string) error {
+				for _, id := range ids {
+					filter := moduleLog.FilterParams{ProjectID: req.Filter.ProjectID, Fingerprint: id}
+					if err := s.deleteGroupEvents(ctx, report, func() ([]string, error) {
+						return s.logs.DeleteMatching(ctx, filter, batchSize)
+					}); err != nil {
+						return err
+					}
+				}
+				return s.logGroups.DeleteByIDs(ctx, ids)
+			})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	s.logger.Info(fmt.Sprintf("bulk %s of %s in project %s requested by %s: %d processed, %d events",
+		req.Action, req.Target, req.Filter.ProjectID, params.RequestedBy, report.Processed, report.Events))
+
+	return report, nil
+}
+
+// resolve validates the request and replaces the me assignee with the current user
+func (s *service) resolve(ctx context.Context, req *Request) error {
+	if req.Filter.Assignee == AssigneeMe {
+		userID, ok := middleware.GetUserID(ctx)
+		if !ok {
+			return fmt.Errorf("%w: assignee me requires a user", ErrInvalidRequest)
+		}
+		req.Filter.Assignee = userID
+	}
+	return validate(req)
+}
+
+func validate(req *Request) error {
+	accepted, ok := filters[req.Target]
+	if !ok {
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidRequest, req.Target)
+	}
+
+	switch req.Action {
+	case ActionDelete:
+		if req.Status != "" {
+			return fmt.Errorf("%w: status is only set by the status action", ErrInvalidRequest)
+		}
+	case ActionStatus:
+		if req.Target != TargetErrorGroups {
+			return fmt.Errorf("%w: the status action applies to %s only", ErrInvalidRequest, TargetErrorGroups)
+		}
+		if req.Status == "" {
+			return fmt.Errorf("%w: status is required", ErrInvalidRequest)
+		}
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRequest, req.Action)
+	}
+
+	for _, name := range req.Filter.set() {
+		if !accepted[name] {
+			return fmt.Errorf("%w: filter %s does not apply to %s", ErrInvalidRequest, name, req.Target)
+		}
+	}
+
+	filter := req.Filter
+	if filter.Assignee != "" && filter.Assignee != errorsGroup.AssigneeNone {
+		if _, err := uuid.Parse(filter.Assignee); err != nil {
+			return fmt.Errorf("%w: invalid assignee", ErrInvalidRequest)
+		}
+	}
+	if filter.TimeFrom != 0 && filter.TimeTo != 0 && filter.TimeFrom > filter.TimeTo {
+		return fmt.Errorf("%w: timeFrom is after timeTo", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// set returns the names of the filters in use besides the project
+func (f Filter) set() []string {
+	var names []string
+	for name, value := range map[string]bool{
+		filterGroupID:    f.GroupID != "",
+		filterLevel:      f.Level != "",
+		filterSearch:     f.Search != "",
+		filterSearchMode: f.SearchMode != "",
+		filterStatus:     f.Status != "",
+		filterAssignee:   f.Assignee != "",
+		filterTimeFrom:   f.TimeFrom != 0,
+		filterTimeTo:     f.TimeTo != 0,
+	} {
+		if value {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *service) count(ctx context.Context, req *Request) (int, error) {
+	switch req.Target {
+	case TargetErrors:
+		return s.errors.Count(ctx, errorFilter(req.Filter))
+	case TargetLogs:
+		return s.logs.Count(ctx, logFilter(req.Filter))
+	case TargetErrorGroups:
+		return s.errorGroups.Count(ctx, errorGroupFilter(req.Filter))
+	default:
+		return s.logGroups.Count(ctx, logGroupFilter(req.Filter))
+	}
+}
+
+func (s *service) deleteErrors(ctx context.Context, report *Report, filter moduleErrors.FilterParams) error {
+	return s.deleteEvents(ctx, report, func() ([]string, error) {
+		return s.errors.DeleteMatching(ctx, filter, batchSize)
+	}, s.errorGroups.Decrement)
+}
+
+func (s *service) deleteLogs(ctx context.Context, report *Report, filter moduleLog.FilterParams) error {
+	return s.deleteEvents(ctx, report, func() ([]string, error) {
+		return s.logs.DeleteMatching(ctx, filter, batchSize)
+	}, s.logGroups.Decrement)
+}
+
+// deleteEvents deletes batches until none is left and decrements the groups of the deleted events
+func (s *service) deleteEvents(
+	ctx context.Context,
+	report *Report,
+	deleteBatch func() ([]string, error),
+	decrement func(ctx context.Context, removed map[string]int) error,
+) error {
+	recounted := make(map[string]struct{})
+	for {
+		fingerprints, err := deleteBatch()
+		if err != nil {
+			return err
+		}
+
+		removed := make(map[string]int)
+		for _, fingerprint := range fingerprints {
+			if fingerprint != "" {
+				recounted[fingerprint] = struct{}{}
+				removed[fingerprint]++
+			}
+		}
+		if err = decrement(ctx, removed); err != nil {
+			return err
+		}
+
+		report.Processed += len(fingerprints)
+		report.Recounted = len(recounted)
+		progress(ctx, report)
+
+		if len(fingerprints) < batchSize {
+			return nil
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// deleteGroupEvents deletes all events of a group which is deleted afterwards, so nothing is recounted
+func (s *service) deleteGroupEvents(ctx context.Context, report *Report, deleteBatch func() ([]string, error)) error {
+	for {
+		fingerprints, err := deleteBatch()
+		if err != nil {
+			return err
+		}
+		report.Events += len(fingerprints)
+
+		if len(fingerprints) < batchSize {
+			return nil
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// eachGroupBatch calls fn with the ids of the matching groups, batch by batch in the order of ids
+func (s *service) eachGroupBatch(
+	ctx context.Context,
+	report *Report,
+	find func(ctx context.Context, afterID string) ([]string, error),
+	fn func(ids []string) error,
+) error {
+	afterID := ""
+	for {
+		ids, err := find(ctx, afterID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err = fn(ids); err != nil {
+			return err
+		}
+
+		report.Processed += len(ids)
+		progress(ctx, report)
+
+		if len(ids) < batchSize {
+			return nil
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+func errorGroupFinder(groups ErrorGroups, filter errorsGroup.FilterParams) func(ctx context.Context, afterID string) ([]string, error) {
+	return func(ctx context.Context, afterID string) ([]string, error) {
+		return groups.FindIDs(ctx, filter, afterID, batchSize)
+	}
+}
+
+func logGroupFinder(groups LogGroups, filter logGroup.FilterParams) func(ctx context.Context, afterID string) ([]string, error) {
+	return func(ctx context.Context, afterID string) ([]string, error) {
+		return groups.FindIDs(ctx, filter, afterID, batchSize)
+	}
+}
+
+// progress reports the job progress, rows added while the job runs may push processed past matched
+func progress(ctx context.Context, report *Report) {
+	jobs.ReportProgress(ctx, int64(report.Processed), int64(max(report.Matched, report.Processed)))
+}
+
+// Events store milliseconds, the filter seconds
+func errorFilter(f Filter) moduleErrors.FilterParams {
+	return moduleErrors.FilterParams{
+		ProjectID:   f.ProjectID,
+		Fingerprint: f.GroupID,
+		TimeFrom:    utils.SecondsToMilliseconds(f.TimeFrom),
+		TimeTo:      utils.SecondsToMilliseconds(f.TimeTo),
+		Search:      f.Search,
+		SearchMode:  f.SearchMode,
+	}
+}
+
+func logFilter(f Filter) moduleLog.FilterParams {
+	return moduleLog.FilterParams{
+		ProjectID:   f.ProjectID,
+		Fingerprint: f.GroupID,
+		TimeFrom:    utils.SecondsToMilliseconds(f.TimeFrom),
+		TimeTo:      utils.SecondsToMilliseconds(f.TimeTo),
+		Level:       f.Level,
+		Search:      f.Search,
+		SearchMode:  f.SearchMode,
+	}
+}
+
+func errorGroupFilter(f Filter) errorsGroup.FilterParams {
+	return errorsGroup.FilterParams{
+		ProjectID: f.ProjectID,
+		TimeFrom:  f.TimeFrom,
+		TimeTo:    f.TimeTo,
+		Search:    f.Search,
+		Status:    f.Status,
+		Assignee:  f.Assignee,
+	}
+}
+
+func logGroupFilter(f Filter) logGroup.FilterParams {
+	return logGroup.FilterParams{
+		ProjectID: f.ProjectID,
+		TimeFrom:  f.TimeFrom,
+		TimeTo:    f.TimeTo,
+		Level:     f.Level,
+		Search:    f.Search,
+	}
+}
@@ -0,0 +1,140 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	moduleLog "github.com/duckbugio/duckbug/internal/modules/log"
+	logGroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const projectID = "a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   Request
+		valid bool
+	}{
+		{
+			name:  "Delete old debug logs",
+			req:   Request{Target: TargetLogs, Action: ActionDelete, Filter: Filter{ProjectID: projectID, Level: "DEBUG", TimeTo: 1704067200}},
+			valid: true,
+		},
+		{
+			name:  "Resolve groups matching a search",
+			req:   Request{Target: TargetErrorGroups, Action: ActionStatus, Status: "resolved", Filter: Filter{ProjectID: projectID, Search: "timeout"}},
+			valid: true,
+		},
+		{
+			name: "Filter of another target",
+			req:  Request{Target: TargetErrors, Action: ActionDelete, Filter: Filter{ProjectID: projectID, Level: "DEBUG"}},
+		},
+		{
+			name: "Status of logs",
+			req:  Request{Target: TargetLogs, Action: ActionStatus, Status: "resolved", Filter: Filter{ProjectID: projectID}},
+		},
+		{
+			name: "Status without a value",
+			req:  Request{Target: TargetErrorGroups, Action: ActionStatus, Filter: Filter{ProjectID: projectID}},
+		},
+		{
+			name: "Invalid assignee",
+			req:  Request{Target: TargetErrorGroups, Action: ActionDelete, Filter: Filter{ProjectID: projectID, Assignee: "jane"}},
+		},
+		{
+			name: "Inverted time range",
+			req:  Request{Target: TargetLogGroups, Action: ActionDelete, Filter: Filter{ProjectID: projectID, TimeFrom: 2, TimeTo: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(&tt.req)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidRequest)
+			}
+		})
+	}
+}
+
+type fakeLogs struct {
+	fingerprints []string
+	deleted      []moduleLog.FilterParams
+}
+
+func (f *fakeLogs) Count(context.Context, moduleLog.FilterParams) (int, error) {
+	return len(f.fingerprints), nil
+}
+
+func (f *fakeLogs) DeleteMatching(_ context.Context, params moduleLog.FilterParams, limit int) ([]string, error) {
+	f.deleted = append(f.deleted, params)
+	n := min(limit, len(f.fingerprints))
+	batch := f.fingerprints[:n]
+	f.fingerprints = f.fingerprints[n:]
+	return batch, nil
+}
+
+type fakeLogGroups struct {
+	decremented map[string]int
+}
+
+func (f *fakeLogGroups) Count(context.Context, logGroup.FilterParams) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeLogGroups) FindIDs(context.Context, logGroup.FilterParams, string, int) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeLogGroups) Decrement(_ context.Context, removed map[string]int) error {
+	if f.decremented == nil {
+		f.decremented = make(map[string]int)
+	}
+	for id, count := range removed {
+		f.decremented[id] += count
+	}
+	return nil
+}
+
+func (f *fakeLogGroups) DeleteByIDs(context.Context, []string) error {
+	return nil
+}
+
+func TestRunDeletesInBatches(t *testing.T) {
+	logs := &fakeLogs{}
+	for i := 0; i < batchSize+10; i++ {
+		logs.fingerprints = append(logs.fingerprints, []string{"a", "b", ""}[i%3])
+	}
+	groups := &fakeLogGroups{}
+	s := &service{logs: logs, logGroups: groups, logger: logger.New("error", &bytes.Buffer{})}
+
+	params, err := json.Marshal(jobParams{Request: Request{
+		Target: TargetLogs,
+		Action: ActionDelete,
+		Filter: Filter{ProjectID: projectID, Level: "DEBUG", TimeTo: 1704067200},
+	}})
+	require.NoError(t, err)
+
+	result, err := s.Run(context.Background(), params)
+	require.NoError(t, err)
+
+	report, ok := result.(*Report)
+	require.True(t, ok)
+	assert.Equal(t, batchSize+10, report.Matched)
+	assert.Equal(t, batchSize+10, report.Processed)
+	assert.Equal(t, 2, report.Recounted)
+	assert.Equal(t, map[string]int{"a": (batchSize + 12) / 3, "b": (batchSize + 11) / 3}, groups.decremented,
+		"every deleted event is subtracted from its group")
+
+	require.Len(t, logs.deleted, 2)
+	assert.Equal(t, "DEBUG", logs.deleted[0].Level)
+	assert.Equal(t, int64(1704067200000), logs.deleted[0].TimeTo, "events are filtered in milliseconds")
+}
@@ -0,0 +1,47 @@
+package erasure
+
+// Match is an event mentioning one of the identifiers
+type Match struct {
+	ID          string `db:"id"`
+	Time        int64  `db:"time"`
+	ProjectID   string `db:"project_id"`
+	Fingerprint string `db:"fingerprint"`
+}
+
+// SpanMatch is a trace span mentioning one of the identifiers
+type SpanMatch struct {
+	ProjectID string `db:"project_id"`
+	TraceID   string `db:"trace_id"`
+	SpanID    string `db:"span_id"`
+}
+
+// spanColumns are the free text columns of spans, attributes carry URLs, queries and user data
+var spanColumns = []string{"name", "attributes"}
+
+// eventTable describes where identifiers can show up in an event table and its groups
+type eventTable struct {
+	events string
+	// groups copy the message of their first event, so it is rewritten there as well
+	groups string
+	// columns are free text columns searched with the identifier pattern
+	columns []string
+	// hasIP tells whether the table stores the client IP in a column of its own
+	hasIP bool
+}
+
+var (
+	errorsTable = eventTable{
+		events: "errors",
+		groups: "error_groups",
+		columns: []string{
+			"message", "stacktrace", "context", "session", "headers", "url", "query_params", "body_params",
+			"cookies", "files", "env",
+		},
+		hasIP: true,
+	}
+	logsTable = eventTable{
+		events:  "logs",
+		groups:  "log_groups",
+		columns: []string{"message", "context"},
+	}
+)
@@ -0,0 +1,97 @@
+package erasure
+
+import "context"
+
+const (
+	// JobType identifies erasure jobs in the jobs queue
+	JobType = "erasure"
+
+	ModeDelete = "delete"
+	ModeRedact = "redact"
+
+	// Erased replaces identifiers in redact mode
+	Erased = "[Erased]"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Request names an end user by any of their identifiers, events mentioning one of them are erased
+type Request struct {
+	Emails  []string `json:"emails" validate:"omitempty,dive,email" example:"jane@example.com"`
+	UserIDs []string `json:"userIds" validate:"omitempty,dive,required,max=256" example:"42"`
+	IPs     []string `json:"ips" validate:"omitempty,dive,ip" example:"203.0.113.7"`
+	// Mode delete removes matching events, redact replaces the identifiers and keeps the events
+	Mode string `json:"mode" validate:"required,oneof=delete redact" example:"delete"`
+}
+
+func (r *Request) IsEmpty() bool {
+	return len(r.Emails) == 0 && len(r.UserIDs) == 0 && len(r.IPs) == 0
+}
+
+// jobParams is what gets queued, the identifiers are dropped from the job once it finished
+type jobParams struct {
+	Request
+	RequestedBy string `json:"requestedBy"`
+}
+
+// ProjectReport counts the events erased in one project
+type ProjectReport struct {
+	Errors int `json:"errors" example:"12"`
+	Logs   int `json:"logs" example:"40"`
+	Spans  int `json:"spans" example:"7"`
+}
+
+// Report is the audit record of an erasure, it holds hashes instead of the identifiers
+type Report struct {
+	Mode        string `json:"mode" example:"delete"`
+	RequestedBy string `json:"requestedBy" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	// IdentifierHashes are hex SHA-256 hashes of the lower-cased identifiers
+	IdentifierHashes []string `json:"identifierHashes"`
+	Errors           int      `json:"errors" example:"12"`
+	Logs             int      `json:"logs" example:"40"`
+	Spans            int      `json:"spans" example:"7"`
+	ErrorGroups      int      `json:"errorGroups" example:"3"`
+	LogGroups        int      `json:"logGroups" example:"5"`
+	// RedactedErrorGroups and RedactedLogGroups count groups whose message mentioned an identifier
+	RedactedErrorGroups int                       `json:"redactedErrorGroups" example:"1"`
+	RedactedLogGroups   int                       `json:"redactedLogGroups" example:"2"`
+	Projects            map[string]*ProjectReport `json:"projects"`
+	StartedAt           int64                     `json:"startedAt" example:"1700000000"`
+	FinishedAt          int64                     `json:"finishedAt" example:"1700000042"`
+}
+
+func (r *Report) add(projectID string, table eventTable, count int) {
+	project, ok := r.Projects[projectID]
+	if !ok {
+		project = &ProjectReport{}
+		r.Projects[projectID] = project
+	}
+
+	if table.events == errorsTable.events {
+		project.Errors += count
+		r.Errors += count
+	} else {
+		project.Logs += count
+		r.Logs += count
+	}
+}
+
+func (r *Report) addSpans(projectID string, count int) {
+	project, ok := r.Projects[projectID]
+	if !ok {
+		project = &ProjectReport{}
+		r.Projects[projectID] = project
+	}
+
+	project.Spans += count
+	r.Spans += count
+}
@@ -0,0 +1,246 @@
+package erasure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// firstID sorts before every uuid, it starts the scan together with time -1
+const firstID = "00000000-0000-0000-0000-000000000000"
+
+type Repository interface {
+	// FindBatch returns up to limit events after (afterTime, afterID) matching the pattern or one of the IPs
+	FindBatch(ctx context.Context, table eventTable, pattern string, ips []string,
+		afterTime int64, afterID string, limit int) ([]*Match, error)
+	Delete(ctx context.Context, table eventTable, matches []*Match) error
+	// Redact replaces the pattern in the searched columns and clears matching IPs
+	Redact(ctx context.Context, table eventTable, matches []*Match, pattern string, ips []string) error
+	// RedactGroups replaces the pattern in the messages of up to limit groups after afterID and returns their ids in order
+	RedactGroups(ctx context.Context, table eventTable, pattern, afterID string, limit int) ([]string, error)
+	// FindSpans returns up to limit spans after the given one matching the pattern, nil starts the scan
+	FindSpans(ctx context.Context, pattern string, after *SpanMatch, limit int) ([]*SpanMatch, error)
+	DeleteSpans(ctx context.Context, matches []*SpanMatch) error
+	// RedactSpans replaces the pattern in the searched span columns
+	RedactSpans(ctx context.Context, matches []*SpanMatch, pattern string) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) FindBatch(
+	ctx context.Context, table eventTable, pattern string, ips []string, afterTime int64, afterID string, limit int,
+) ([]*Match, error) {
+	conditions := make([]string, 0, len(table.columns)+1)
+	for _, column := range table.columns {
+		conditions = append(conditions, column+" ~* $1")
+	}
+	if table.hasIP {
+		conditions = append(conditions, "ip = ANY($5)")
+	}
+
+	// The scan is keyset paginated so deleted and redacted rows never shift the next batch
+	query := fmt.Sprintf(`
+		SELECT id, time, project_id, coalesce(fingerprint, '') AS fingerprint
+		FROM %s
+		WHERE (time, id) > ($2, CAST($3 AS uuid)) AND (%s)
+		ORDER BY time, id
+		LIMIT $4`, table.events, strings.Join(conditions, " OR "))
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	args := []interface{}{pattern, afterTime, afterID, limit}
+	if table.hasIP {
+		args = append(args, pq.Array(ips))
+	}
+
+	var matches []*Match
+	err := r.db.SelectContext(ctx, &matches, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s to erase: %w", table.events, err)
+	}
+	return matches, nil
+}
+
+func (r *repository) Delete(ctx context.Context, table eventTable, matches []*Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	ids, times := keys(matches)
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE (id, time) IN (SELECT * FROM unnest(CAST($1 AS uuid[]), CAST($2 AS bigint[])))`, table.events)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	_, err := r.db.ExecContext(ctx, query, pq.Array(ids), pq.Array(times))
+	if err != nil {
+		return fmt.Errorf("failed to erase %s: %w", table.events, err)
+	}
+	return nil
+}
+
+func (r *repository) Redact(ctx context.Context, table eventTable, matches []*Match, pattern string, ips []string) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	assignments := make([]string, 0, len(table.columns)+1)
+	for _, column := range table.columns {
+		assignments = append(assignments, fmt.Sprintf("%[1]s = regexp_replace(%[1]s, $3, $4, 'gi')", column))
+	}
+	if table.hasIP {
+		assignments = append(assignments, "ip = CASE WHEN ip = ANY($5) THEN NULL ELSE ip END")
+	}
+
+	ids, times := keys(matches)
+	query := fmt.Sprintf(`
+		UPDATE %s SET %s
+		WHERE (id, time) IN (SELECT * FROM unnest(CAST($1 AS uuid[]), CAST($2 AS bigint[])))`,
+		table.events, strings.Join(assignments, ", "))
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	args := []interface{}{pq.Array(ids), pq.Array(times), pattern, Erased}
+	if table.hasIP {
+		args = append(args, pq.Array(ips))
+	}
+
+	_, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to redact %s: %w", table.events, err)
+	}
+	return nil
+}
+
+func (r *repository) RedactGroups(
+	ctx context.Context, table eventTable, pattern, afterID string, limit int,
+) ([]string, error) {
+	// Walking the ids in byte order keeps the scan going forward even if a replacement matches the pattern again
+	query := fmt.Sprintf(`
+		WITH batch AS (
+			SELECT id FROM %[1]s
+			WHERE id COLLATE "C" > $2 AND message ~* $1
+			ORDER BY id COLLATE "C"
+			LIMIT $3
+		)
+		UPDATE %[1]s g SET message = regexp_replace(g.message, $1, $4, 'gi')
+		FROM batch b
+		WHERE g.id = b.id
+		RETURNING g.id`, table.groups)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, query, pattern, afterID, limit, Erased)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact %s: %w", table.groups, err)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (r *repository) FindSpans(ctx context.Context, pattern string, after *SpanMatch, limit int) ([]*SpanMatch, error) {
+	if after == nil {
+		after = &SpanMatch{ProjectID: firstID}
+	}
+
+	conditions := make([]string, 0, len(spanColumns))
+	for _, column := range spanColumns {
+		conditions = append(conditions, column+" ~* $1")
+	}
+
+	// Spans are walked in primary key order, the same keyset pagination as events
+	query := fmt.Sprintf(`
+		SELECT project_id, trace_id, span_id
+		FROM spans
+		WHERE (project_id, trace_id, span_id) > (CAST($2 AS uuid), $3, $4) AND (%s)
+		ORDER BY project_id, trace_id, span_id
+		LIMIT $5`, strings.Join(conditions, " OR "))
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var matches []*SpanMatch
+	err := r.db.SelectContext(ctx, &matches, query, pattern, after.ProjectID, after.TraceID, after.SpanID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find spans to erase: %w", err)
+	}
+	return matches, nil
+}
+
+func (r *repository) DeleteSpans(ctx context.Context, matches []*SpanMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	projectIDs, traceIDs, spanIDs := spanKeys(matches)
+	const query = `
+		DELETE FROM spans
+		WHERE (project_id, trace_id, span_id) IN (
+			SELECT * FROM unnest(CAST($1 AS uuid[]), CAST($2 AS bpchar[]), CAST($3 AS bpchar[]))
+		)`
+
+	_, err := r.db.ExecContext(ctx, query, pq.Array(projectIDs), pq.Array(traceIDs), pq.Array(spanIDs))
+	if err != nil {
+		return fmt.Errorf("failed to erase spans: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RedactSpans(ctx context.Context, matches []*SpanMatch, pattern string) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	// The replacement may be longer than the identifier, name has to stay within its column
+	projectIDs, traceIDs, spanIDs := spanKeys(matches)
+	const query = `
+		UPDATE spans SET
+			name = left(regexp_replace(name, $4, $5, 'gi'), 255),
+			attributes = regexp_replace(attributes, $4, $5, 'gi')
+		WHERE (project_id, trace_id, span_id) IN (
+			SELECT * FROM unnest(CAST($1 AS uuid[]), CAST($2 AS bpchar[]), CAST($3 AS bpchar[]))
+		)`
+
+	_, err := r.db.ExecContext(ctx, query, pq.Array(projectIDs), pq.Array(traceIDs), pq.Array(spanIDs), pattern, Erased)
+	if err != nil {
+		return fmt.Errorf("failed to redact spans: %w", err)
+	}
+	return nil
+}
+
+func spanKeys(matches []*SpanMatch) ([]string, []string, []string) {
+	projectIDs := make([]string, 0, len(matches))
+	traceIDs := make([]string, 0, len(matches))
+	spanIDs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		projectIDs = append(projectIDs, match.ProjectID)
+		traceIDs = append(traceIDs, match.TraceID)
+		spanIDs = append(spanIDs, match.SpanID)
+	}
+	return projectIDs, traceIDs, spanIDs
+}
+
+func keys(matches []*Match) ([]string, []int64) {
+	ids := make([]string, 0, len(matches))
+	times := make([]int64, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+		times = append(times, match.Time)
+	}
+	return ids, times
+}
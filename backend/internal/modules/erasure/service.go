@@ -0,0 +1,270 @@
+package erasure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/jobs"
+)
+
+const batchSize = 500
+
+var ErrNoIdentifiers = errors.New("at least one email, user id or ip is required")
+
+// Enqueuer queues the erasure as a tracked job
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, params interface{}) (*jobs.Entity, error)
+}
+
+// GroupCounter keeps group counters in sync after events were removed
+type GroupCounter interface {
+	Decrement(ctx context.Context, removed map[string]int) error
+}
+
+type Service interface {
+	// Request queues the erasure of every event mentioning one of the identifiers
+	Request(ctx context.Context, req *Request) (*jobs.Entity, error)
+	// Run performs a queued erasure, it is the jobs handler for JobType
+	Run(ctx context.Context, params json.RawMessage) (interface{}, error)
+}
+
+type service struct {
+	repo        Repository
+	jobs        Enqueuer
+	logGroups   GroupCounter
+	errorGroups GroupCounter
+	logger      Logger
+}
+
+func NewService(repo Repository, jobs Enqueuer, logGroups, errorGroups GroupCounter, logger Logger) Service {
+	return &service{
+		repo:        repo,
+		jobs:        jobs,
+		logGroups:   logGroups,
+		errorGroups: errorGroups,
+		logger:      logger,
+	}
+}
+
+func (s *service) Request(ctx context.Context, req *Request) (*jobs.Entity, error) {
+	if req.IsEmpty() {
+		return nil, ErrNoIdentifiers
+	}
+
+	params := jobParams{Request: *req}
+	params.RequestedBy, _ = middleware.GetUserID(ctx)
+
+	return s.jobs.Enqueue(ctx, JobType, params)
+}
+
+func (s *service) Run(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params jobParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("invalid erasure params: %w", err)
+	}
+
+	identifiers := normalize(params.Emails, params.UserIDs, params.IPs)
+	if len(identifiers) == 0 {
+		return nil, ErrNoIdentifiers
+	}
+
+	report := &Report{
+		Mode:             params.Mode,
+		RequestedBy:      params.RequestedBy,
+		IdentifierHashes: hashes(identifiers),
+		Projects:         make(map[string]*ProjectReport),
+		StartedAt:        time.Now().Unix(),
+	}
+
+	pattern := identifierPattern(identifiers)
+	ips := normalize(params.IPs)
+
+	var err error
+	report.ErrorGroups, err = s.eraseTable(ctx, errorsTable, pattern, ips, params.Mode, report)
+	if err != nil {
+		return nil, err
+	}
+	report.LogGroups, err = s.eraseTable(ctx, logsTable, pattern, ips, params.Mode, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.eraseSpans(ctx, pattern, params.Mode, report); err != nil {
+		return nil, err
+	}
+
+	// Group messages are rewritten in both modes, a group outlives the events it was created from
+	report.RedactedErrorGroups, err = s.redactGroups(ctx, errorsTable, pattern)
+	if err != nil {
+		return nil, err
+	}
+	report.RedactedLogGroups, err = s.redactGroups(ctx, logsTable, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	s.logger.Info(fmt.Sprintf("erasure (%s) requested by %s: %d errors, %d logs, %d spans in %d projects",
+		report.Mode, report.RequestedBy, report.Errors, report.Logs, report.Spans, len(report.Projects)))
+
+	return report, nil
+}
+
+// eraseTable walks the whole table batch by batch and returns how many groups were recounted
+func (s *service) eraseTable(
+	ctx context.Context, table eventTable, pattern string, ips []string, mode string, report *Report,
+) (int, error) {
+	recounted := make(map[string]struct{})
+	afterTime, afterID := int64(-1), firstID
+
+	for {
+		matches, err := s.repo.FindBatch(ctx, table, pattern, ips, afterTime, afterID, batchSize)
+		if err != nil {
+			return len(recounted), err
+		}
+		if len(matches) == 0 {
+			return len(recounted), nil
+		}
+
+		if mode == ModeRedact {
+			err = s.repo.Redact(ctx, table, matches, pattern, ips)
+		} else {
+			err = s.repo.Delete(ctx, table, matches)
+		}
+		if err != nil {
+			return len(recounted), err
+		}
+
+		removed := make(map[string]int)
+		for _, match := range matches {
+			report.add(match.ProjectID, table, 1)
+			if match.Fingerprint != "" && mode == ModeDelete {
+				recounted[match.Fingerprint] = struct{}{}
+				removed[match.Fingerprint]++
+			}
+		}
+
+		if err := s.groupsOf(table).Decrement(ctx, removed); err != nil {
+			return len(recounted), err
+		}
+
+		last := matches[len(matches)-1]
+		afterTime, afterID = last.Time, last.ID
+
+		if len(matches) < batchSize {
+			return len(recounted), nil
+		}
+		if err := ctx.Err(); err != nil {
+			return len(recounted), err
+		}
+	}
+}
+
+// eraseSpans walks the trace spans batch by batch, they have no groups to keep in sync
+func (s *service) eraseSpans(ctx context.Context, pattern, mode string, report *Report) error {
+	var after *SpanMatch
+	for {
+		matches, err := s.repo.FindSpans(ctx, pattern, after, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+
+		if mode == ModeRedact {
+			err = s.repo.RedactSpans(ctx, matches, pattern)
+		} else {
+			err = s.repo.DeleteSpans(ctx, matches)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			report.addSpans(match.ProjectID, 1)
+		}
+		after = matches[len(matches)-1]
+
+		if len(matches) < batchSize {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// redactGroups rewrites the identifiers in group messages batch by batch and returns how many groups were changed
+func (s *service) redactGroups(ctx context.Context, table eventTable, pattern string) (int, error) {
+	redacted, afterID := 0, ""
+	for {
+		ids, err := s.repo.RedactGroups(ctx, table, pattern, afterID, batchSize)
+		if err != nil {
+			return redacted, err
+		}
+		redacted += len(ids)
+
+		if len(ids) < batchSize {
+			return redacted, nil
+		}
+		afterID = ids[len(ids)-1]
+
+		if err := ctx.Err(); err != nil {
+			return redacted, err
+		}
+	}
+}
+
+func (s *service) groupsOf(table eventTable) GroupCounter {
+	if table.events == errorsTable.events {
+		return s.errorGroups
+	}
+	return s.logGroups
+}
+
+// identifierPattern builds a Postgres regular expression matching any identifier as a whole token,
+// so erasing user 42 leaves 420 alone. It is used with case-insensitive operators.
+func identifierPattern(identifiers []string) string {
+	quoted := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		quoted = append(quoted, regexp.QuoteMeta(identifier))
+	}
+	return `(?<![[:alnum:]_])(` + strings.Join(quoted, "|") + `)(?![[:alnum:]_])`
+}
+
+// normalize trims, lower-cases and deduplicates identifiers
+func normalize(lists ...[]string) []string {
+	seen := make(map[string]struct{})
+	var result []string
+	for _, list := range lists {
+		for _, value := range list {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if _, ok := seen[value]; ok || value == "" {
+				continue
+			}
+			seen[value] = struct{}{}
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func hashes(identifiers []string) []string {
+	result := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		sum := sha256.Sum256([]byte(identifier))
+		result = append(result, hex.EncodeToString(sum[:]))
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,131 @@
+package erasure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository keeps groups in memory and rewrites their messages like regexp_replace does,
+// the lookarounds of the Postgres pattern become word boundaries for the Go engine
+type fakeRepository struct {
+	groups map[string]map[string]string
+}
+
+func (f *fakeRepository) FindBatch(context.Context, eventTable, string, []string, int64, string, int) ([]*Match, error) {
+	return nil, nil
+}
+
+func (f *fakeRepository) Delete(context.Context, eventTable, []*Match) error {
+	return nil
+}
+
+func (f *fakeRepository) Redact(context.Context, eventTable, []*Match, string, []string) error {
+	return nil
+}
+
+func (f *fakeRepository) FindSpans(context.Context, string, *SpanMatch, int) ([]*SpanMatch, error) {
+	return nil, nil
+}
+
+func (f *fakeRepository) DeleteSpans(context.Context, []*SpanMatch) error {
+	return nil
+}
+
+func (f *fakeRepository) RedactSpans(context.Context, []*SpanMatch, string) error {
+	return nil
+}
+
+func (f *fakeRepository) RedactGroups(_ context.Context, table eventTable, pattern, afterID string, limit int) ([]string, error) {
+	re := regexp.MustCompile("(?i)" + strings.NewReplacer(
+		"(?<![[:alnum:]_])", `\b`,
+		"(?![[:alnum:]_])", `\b`,
+	).Replace(pattern))
+
+	groups := f.groups[table.groups]
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var redacted []string
+	for _, id := range ids {
+		if id <= afterID || !re.MatchString(groups[id]) {
+			continue
+		}
+		groups[id] = re.ReplaceAllString(groups[id], Erased)
+		redacted = append(redacted, id)
+		if len(redacted) == limit {
+			break
+		}
+	}
+	return redacted, nil
+}
+
+type fakeGroupCounter struct{}
+
+func (fakeGroupCounter) Decrement(context.Context, map[string]int) error {
+	return nil
+}
+
+func TestRunRedactsGroupMessages(t *testing.T) {
+	repo := &fakeRepository{groups: map[string]map[string]string{
+		"error_groups": {
+			"e1": "Payment failed for jane@example.com",
+			"e2": "Payment failed for user 420",
+		},
+		"log_groups": {
+			"l1": "User 42 logged in",
+			"l2": "Cache warmed",
+		},
+	}}
+	s := &service{
+		repo:        repo,
+		logGroups:   fakeGroupCounter{},
+		errorGroups: fakeGroupCounter{},
+		logger:      logger.New("error", &bytes.Buffer{}),
+	}
+
+	for _, mode := range []string{ModeDelete, ModeRedact} {
+		params, err := json.Marshal(jobParams{Request: Request{
+			Emails:  []string{"Jane@Example.com"},
+			UserIDs: []string{"42"},
+			Mode:    mode,
+		}})
+		require.NoError(t, err)
+
+		result, err := s.Run(context.Background(), params)
+		require.NoError(t, err)
+
+		if mode == ModeDelete {
+			report := result.(*Report)
+			assert.Equal(t, 1, report.RedactedErrorGroups)
+			assert.Equal(t, 1, report.RedactedLogGroups)
+		}
+	}
+
+	assert.Equal(t, "Payment failed for [Erased]", repo.groups["error_groups"]["e1"])
+	assert.Equal(t, "Payment failed for user 420", repo.groups["error_groups"]["e2"])
+	assert.Equal(t, "User [Erased] logged in", repo.groups["log_groups"]["l1"])
+	assert.Equal(t, "Cache warmed", repo.groups["log_groups"]["l2"])
+}
+
+func TestSearchedColumns(t *testing.T) {
+	// Identifiers end up in stack traces, the environment and uploaded file names just as well
+	for _, column := range []string{"message", "stacktrace", "env", "files"} {
+		assert.Contains(t, errorsTable.columns, column)
+	}
+	assert.Equal(t, "error_groups", errorsTable.groups)
+	assert.Equal(t, "log_groups", logsTable.groups)
+	// Span attributes keep request URLs and user data of traced operations
+	assert.Contains(t, spanColumns, "attributes")
+}
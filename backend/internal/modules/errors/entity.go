@@ -1,5 +1,7 @@
 package errors
 
+import "github.com/duckbugio/duckbug/pkg/histogram"
+
 type Error struct {
 	ID          string  `db:"id"`
 	ProjectID   string  `db:"project_id"`
@@ -19,7 +21,15 @@ type Error struct {
 	Session     *string `db:"session"`
 	Files       *string `db:"files"`
 	Env         *string `db:"env"`
+	Release     *string `db:"release"`
+	TraceID     *string `db:"trace_id"`
 	Time        int64   `db:"time"`
 	CreatedAt   int64   `db:"created_at"`
 	UpdatedAt   int64   `db:"updated_at"`
+	// Rank and Highlight are only selected by full-text search queries
+	Rank      *float64 `db:"rank"`
+	Highlight *string  `db:"highlight"`
 }
+
+// HistogramRow is a single (bucket, series) counter returned by histogram queries
+type HistogramRow = histogram.Row
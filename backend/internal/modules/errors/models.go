@@ -1,25 +1,67 @@
 package errors
 
+import (
+	"context"
+
+	"github.com/duckbugio/duckbug/pkg/histogram"
+	"github.com/duckbugio/duckbug/pkg/pagination"
+)
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
+const (
+	// SearchModeSubstring matches the search string anywhere in the message (ILIKE).
+	SearchModeSubstring = "substring"
+	// SearchModeFullText matches the search query against the full-text search vector.
+	SearchModeFullText = "fulltext"
+)
+
+const (
+	OrderByTime      = "time"
+	OrderByRelevance = "relevance"
+)
+
+const (
+	HistogramSplitGroup   = "group"
+	HistogramSplitRelease = "release"
+)
+
 type FilterParams struct {
 	ProjectID   string
 	Fingerprint string
 	TimeFrom    int64
 	TimeTo      int64
 	Search      string
+	SearchMode  string
 }
 
 type GetAllParams struct {
 	FilterParams
 	SortOrder string `validate:"omitempty,oneof=asc desc"`
+	OrderBy   string `validate:"omitempty,oneof=time relevance"`
 	Limit     int
 	Offset    int
+	// Keyset switches to cursor pagination over (time, id), Cursor is nil for the first page
+	Keyset bool
+	Cursor *pagination.Cursor
+}
+
+// Page is a cursor paginated slice of entities
+type Page struct {
+	Items          []*Entity
+	Count          *int
+	CountEstimated bool
+	NextCursor     string
+	PrevCursor     string
 }
 
 type Create struct {
@@ -79,6 +121,7 @@ type Create struct {
 	//   example = `{"APP_ENV": "production", "DB_HOST": "db.example.com"}`
 	// )
 	Env       *map[string]interface{} `json:"env,omitempty"`
+	Release   *string                 `json:"release,omitempty" example:"1.4.2"`
 	ProjectID string                  `json:"-"`
 }
 
@@ -102,6 +145,9 @@ type Update struct {
 	Context *interface{} `json:"context"`
 }
 
+// ExportColumns are the CSV columns of an export which does not select its own
+var ExportColumns = []string{"id", "time", "message", "file", "line", "url", "method", "ip", "release", "context"}
+
 type Entity struct {
 	ID         string       `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 	Message    string       `json:"message" validate:"required" example:"Error: Division by zero"`
@@ -131,7 +177,12 @@ type Entity struct {
 	Session     *map[string]interface{} `json:"session"`
 	Files       *map[string]interface{} `json:"files"`
 	Env         *map[string]interface{} `json:"env"`
-	Time        int64                   `json:"time" example:"1704067200000"` // Unix timestamp in milliseconds
+	Release     *string                 `json:"release" example:"1.4.2"`
+	// TraceID is the trace_id of the context, the error links to that performance trace
+	TraceID *string `json:"traceId,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Time    int64   `json:"time" example:"1704067200000"` // Unix timestamp in milliseconds
+	// Highlight is a message snippet with matched terms wrapped in <mark> tags, set for full-text search only
+	Highlight *string `json:"highlight,omitempty" example:"connection <mark>timeout</mark> on db-1"`
 }
 
 type EntityList struct {
@@ -139,6 +190,24 @@ type EntityList struct {
 	Items []Entity `json:"items"`
 }
 
+type EntityPage struct {
+	Count          *int     `json:"count,omitempty" example:"1200"`
+	CountEstimated bool     `json:"countEstimated,omitempty" example:"false"`
+	Items          []Entity `json:"items"`
+	NextCursor     string   `json:"nextCursor,omitempty" example:"bjoxNzA0MDY3MjAwMDAwOmEwODkyOWI1"`
+	PrevCursor     string   `json:"prevCursor,omitempty" example:"cDoxNzA0MDY3MjAwMDAwOmEwODkyOWI1"`
+}
+
+type HistogramParams struct {
+	FilterParams
+	// Interval is the bucket size in milliseconds
+	Interval    int64
+	SplitBy     string `validate:"omitempty,oneof=group release"`
+	SeriesLimit int
+}
+
+type Histogram = histogram.Histogram
+
 type Stats struct {
 	Last24h int `json:"last24h"`
 	Last7d  int `json:"last7d"`
@@ -3,25 +3,38 @@ package errors
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	"github.com/duckbugio/duckbug/internal/tracing"
+	"github.com/duckbugio/duckbug/pkg/pagination"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// headlineOptions limits ts_headline output to a couple of short fragments around the matches
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=10, MaxFragments=2"
+
 type Repository interface {
 	GetAll(ctx context.Context, params GetAllParams) ([]*Error, error)
+	// Scan calls fn for the errors matching the filters in the order of GetAll, without paging.
+	// Rows are read as the database sends them, so the result is never held in memory.
+	Scan(ctx context.Context, params GetAllParams, fn func(*Error) error) error
 	Count(ctx context.Context, params FilterParams) (int, error)
+	EstimateCount(ctx context.Context, params FilterParams) (int, error)
 	GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error)
+	GetHistogram(ctx context.Context, params HistogramParams) ([]*HistogramRow, error)
 	GetByID(ctx context.Context, id string) (*Error, error)
 	Create(ctx context.Context, entity *Error) error
 	Update(ctx context.Context, id string, entity *Error) error
 	Delete(ctx context.Context, id string) error
+	// DeleteMatching deletes up to limit errors matching params and returns the fingerprints of the deleted rows
+	DeleteMatching(ctx context.Context, params FilterParams, limit int) ([]string, error)
 }
 
 type repository struct {
@@ -37,26 +50,54 @@ func NewRepository(db *sqlx.DB, logger Logger) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Error, error) {
+	fullText := isFullTextSearch(params.FilterParams)
+
 	query := `
         SELECT 
             id, project_id, fingerprint, message, stacktrace, file, line, context,
-            ip, url, method, headers, query_params, body_params, cookies, session, files, env,
+            ip, url, method, headers, query_params, body_params, cookies, session, files, env, release, trace_id,
             time, created_at, updated_at 
         FROM
             errors 
         WHERE 1=1
     `
 
+	if fullText {
+		query = `
+        SELECT
+            id, project_id, fingerprint, message, stacktrace, file, line, context,
+            ip, url, method, headers, query_params, body_params, cookies, session, files, env, release, trace_id,
+            time, created_at, updated_at,
+            ts_rank_cd(search_vector, websearch_to_tsquery('simple', :search)) AS rank
+        FROM
+            errors
+        WHERE 1=1
+    `
+	}
+
 	args := map[string]interface{}{
 		"limit":  params.Limit,
 		"offset": params.Offset,
 	}
 
 	query, args = applyFilters(query, params.FilterParams, args)
+	query, args = applyCursor(query, params, args)
 
-	query += " ORDER BY time " + params.SortOrder
+	orderBy := orderByClause(params)
+	query += orderBy
 	query += " LIMIT :limit OFFSET :offset"
 
+	if fullText {
+		// Headlines are expensive, so they are built only for the rows of the requested page
+		query = `
+        SELECT
+            page.*,
+            ts_headline('simple', page.message || ' ' || left(coalesce(page.context, ''), 4096),
+                websearch_to_tsquery('simple', :search), :headlineOptions) AS highlight
+        FROM (` + query + `) page` + orderBy
+		args["headlineOptions"] = headlineOptions
+	}
+
 	query, namedArgs, err := sqlx.Named(query, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare named query: %w", err)
@@ -64,7 +105,7 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Error,
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var entities []*Error
 	err = r.db.SelectContext(ctx, &entities, query, namedArgs...)
@@ -74,6 +115,49 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Error,
 	return entities, nil
 }
 
+func (r *repository) Scan(ctx context.Context, params GetAllParams, fn func(*Error) error) error {
+	query, args := applyFilters(`
+        SELECT id, project_id, fingerprint, message, stacktrace, file, line, context,
+            ip, url, method, headers, query_params, body_params, cookies, session, files, env, release, trace_id,
+            time, created_at, updated_at
+        FROM errors
+        WHERE 1=1
+    `, params.FilterParams, make(map[string]interface{}))
+	query += " ORDER BY time " + params.SortOrder + ", id " + params.SortOrder
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	sqlRows, err := r.db.QueryContext(ctx, query, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read errors: %w", err)
+	}
+	defer func() {
+		_ = sqlRows.Close()
+	}()
+
+	rows := &sqlx.Rows{Rows: sqlRows, Mapper: r.db.Mapper}
+	for rows.Next() {
+		var e Error
+		if err = rows.StructScan(&e); err != nil {
+			return fmt.Errorf("failed to scan errors: %w", err)
+		}
+		if err = fn(&e); err != nil {
+			return err
+		}
+	}
+	if err = sqlRows.Err(); err != nil {
+		return fmt.Errorf("failed to read errors: %w", err)
+	}
+	return nil
+}
+
 func (r *repository) Count(ctx context.Context, params FilterParams) (int, error) {
 	query := "SELECT COUNT(*) FROM errors WHERE 1=1"
 	query, args := applyFilters(query, params, make(map[string]interface{}))
@@ -85,7 +169,7 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var count int
 	err = r.db.GetContext(ctx, &count, query, namedArgs...)
@@ -96,6 +180,41 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 	return count, nil
 }
 
+// EstimateCount returns the planner row estimate for the filters, which avoids scanning large tables
+func (r *repository) EstimateCount(ctx context.Context, params FilterParams) (int, error) {
+	query := "EXPLAIN (FORMAT JSON) SELECT 1 FROM errors WHERE 1=1"
+	query, args := applyFilters(query, params, make(map[string]interface{}))
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var plan []byte
+	err = r.db.GetContext(ctx, &plan, query, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate errors count: %w", err)
+	}
+
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	if len(explain) == 0 {
+		return 0, errors.New("failed to parse query plan: empty plan")
+	}
+
+	return int(explain[0].Plan.Rows), nil
+}
+
 func (r *repository) GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error) {
 	// Precompute thresholds in ms to help planner use range conditions on indexed column "time"
 	nowMs := time.Now().UnixMilli()
@@ -131,7 +250,7 @@ func (r *repository) GetStats(ctx context.Context, projectID string, fingerprint
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var stats struct {
 		Last24h int `db:"last_24h"`
@@ -151,11 +270,54 @@ func (r *repository) GetStats(ctx context.Context, projectID string, fingerprint
 	}, nil
 }
 
+func (r *repository) GetHistogram(ctx context.Context, params HistogramParams) ([]*HistogramRow, error) {
+	series := "''"
+	switch params.SplitBy {
+	case HistogramSplitGroup:
+		series = "fingerprint"
+	case HistogramSplitRelease:
+		series = "coalesce(release, '')"
+	}
+
+	query := `
+        SELECT
+            (time / :interval) * :interval AS bucket,
+            ` + series + ` AS series,
+            COUNT(*) AS count
+        FROM errors
+        WHERE 1=1
+    `
+
+	args := map[string]interface{}{
+		"interval": params.Interval,
+	}
+
+	query, args = applyFilters(query, params.FilterParams, args)
+
+	query += " GROUP BY bucket, series ORDER BY bucket"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var rows []*HistogramRow
+	err = r.db.SelectContext(ctx, &rows, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get errors histogram: %w", err)
+	}
+	return rows, nil
+}
+
 func (r *repository) GetByID(ctx context.Context, id string) (*Error, error) {
 	const query = `
 		SELECT
 			id, project_id, fingerprint, message, stacktrace, file, line, context,
-			ip, url, method, headers, query_params, body_params, cookies, session, files, env,
+			ip, url, method, headers, query_params, body_params, cookies, session, files, env, release, trace_id,
 			time, created_at, updated_at 
 		FROM
 		    errors
@@ -173,7 +335,13 @@ func (r *repository) GetByID(ctx context.Context, id string) (*Error, error) {
 	return &entity, nil
 }
 
-func (r *repository) Create(ctx context.Context, e *Error) error {
+func (r *repository) Create(ctx context.Context, e *Error) (err error) {
+	// The group upsert and the insert share one transaction, row locks of hot groups show up here
+	ctx, span := tracer.Start(ctx, "errors.repository.Create")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -182,12 +350,16 @@ func (r *repository) Create(ctx context.Context, e *Error) error {
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
-				r.logger.Warn(fmt.Sprintf("failed to rollback transaction: %v", rbErr))
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
 			}
 		}
 	}()
 
+	// previous reads the status under lock, so of concurrent events only the first one sees a resolved group
 	const errorGroupQuery = `
+        WITH previous AS (
+            SELECT status FROM error_groups WHERE id = :id FOR UPDATE
+        )
         INSERT INTO error_groups (id, project_id, file, line, message, first_seen_at, last_seen_at, counter)
         VALUES (:id, :project_id, :file, :line, :message, :first_seen_at, :last_seen_at, 1)
         ON CONFLICT (id) DO UPDATE 
@@ -198,6 +370,7 @@ func (r *repository) Create(ctx context.Context, e *Error) error {
                 WHEN error_groups.status = 'resolved' THEN 'unresolved' 
                 ELSE error_groups.status 
             END
+        RETURNING (xmax = 0) AS inserted, (SELECT status FROM previous) AS previous_status
     `
 
 	now := time.Now().Unix()
@@ -213,19 +386,32 @@ func (r *repository) Create(ctx context.Context, e *Error) error {
 		Status:      errorsGroup.StatusUnresolved,
 	}
 
-	_, err = tx.NamedExecContext(ctx, errorGroupQuery, errorGroup)
+	groupQuery, groupArgs, err := sqlx.Named(errorGroupQuery, errorGroup)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	var upsert struct {
+		Inserted       bool    `db:"inserted"`
+		PreviousStatus *string `db:"previous_status"`
+	}
+	err = tx.QueryRowxContext(ctx, tx.Rebind(groupQuery), groupArgs...).StructScan(&upsert)
 	if err != nil {
 		return fmt.Errorf("failed to upsert error group: %w", err)
 	}
 
+	if err = r.recordGroupActivity(ctx, tx, &errorGroup, upsert.Inserted, upsert.PreviousStatus); err != nil {
+		return err
+	}
+
 	const query = `
 		INSERT INTO errors (
 			id, project_id, fingerprint, message, stacktrace, file, line, context,
-			ip, url, method, headers, query_params, body_params, cookies, session, files, env,
+			ip, url, method, headers, query_params, body_params, cookies, session, files, env, release, trace_id,
 			time, created_at, updated_at
 		) VALUES (
 		  	:id, :project_id, :fingerprint, :message, :stacktrace, :file, :line, :context,
-		  	:ip, :url, :method, :headers, :query_params, :body_params, :cookies, :session, :files, :env,
+		  	:ip, :url, :method, :headers, :query_params, :body_params, :cookies, :session, :files, :env, :release, :trace_id,
 		  	:time, :created_at, :updated_at
 		)
 	`
@@ -248,6 +434,21 @@ func (r *repository) Create(ctx context.Context, e *Error) error {
 	return nil
 }
 
+// recordGroupActivity puts a new group and a regression of a resolved one on the group timeline
+func (r *repository) recordGroupActivity(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	group *errorsGroup.Group,
+	inserted bool,
+	previousStatus *string,
+) error {
+	activity, err := errorsGroup.IngestActivity(group, inserted, previousStatus)
+	if err != nil || activity == nil {
+		return err
+	}
+	return errorsGroup.InsertActivity(ctx, tx, activity)
+}
+
 func (r *repository) Update(ctx context.Context, id string, updated *Error) error {
 	const query = `
 		UPDATE
@@ -259,6 +460,7 @@ func (r *repository) Update(ctx context.Context, id string, updated *Error) erro
 		    file = :file,
 		    line = :line,
 		    context = :context,
+		    trace_id = :trace_id,
 		    time = :time,
 		    updated_at = :updated_at
 		WHERE
@@ -301,6 +503,27 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *repository) DeleteMatching(ctx context.Context, params FilterParams, limit int) ([]string, error) {
+	query, args := applyFilters("SELECT id, time FROM errors WHERE 1=1", params, map[string]interface{}{"limit": limit})
+	query = "DELETE FROM errors WHERE (id, time) IN (" + query + " LIMIT :limit) RETURNING COALESCE(fingerprint, '')"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var fingerprints []string
+	err = r.db.SelectContext(ctx, &fingerprints, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete errors: %w", err)
+	}
+	return fingerprints, nil
+}
+
 func applyFilters(baseQuery string, params FilterParams, args map[string]interface{}) (string, map[string]interface{}) {
 	query := baseQuery
 
@@ -324,10 +547,43 @@ func applyFilters(baseQuery string, params FilterParams, args map[string]interfa
 		args["timeTo"] = params.TimeTo
 	}
 
-	if params.Search != "" {
+	if isFullTextSearch(params) {
+		query += " AND search_vector @@ websearch_to_tsquery('simple', :search)"
+		args["search"] = params.Search
+	} else if params.Search != "" {
 		query += " AND message ILIKE :search"
 		args["search"] = "%" + params.Search + "%"
 	}
 
 	return query, args
 }
+
+func isFullTextSearch(params FilterParams) bool {
+	return params.SearchMode == SearchModeFullText && params.Search != ""
+}
+
+func applyCursor(baseQuery string, params GetAllParams, args map[string]interface{}) (string, map[string]interface{}) {
+	if !params.Keyset || params.Cursor == nil {
+		return baseQuery, args
+	}
+
+	_, operator := pagination.KeysetOrder(params.SortOrder, params.Cursor)
+
+	query := baseQuery + " AND (time, id) " + operator + " (:cursorTime, CAST(:cursorId AS uuid))"
+	args["cursorTime"] = params.Cursor.Time
+	args["cursorId"] = params.Cursor.ID
+
+	return query, args
+}
+
+func orderByClause(params GetAllParams) string {
+	if params.Keyset {
+		direction, _ := pagination.KeysetOrder(params.SortOrder, params.Cursor)
+		return " ORDER BY time " + direction + ", id " + direction
+	}
+
+	if params.OrderBy == OrderByRelevance && isFullTextSearch(params.FilterParams) {
+		return " ORDER BY rank DESC, time " + params.SortOrder
+	}
+	return " ORDER BY time " + params.SortOrder
+}
@@ -5,30 +5,58 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
-
+	"slices"
+	"strings"
+
+	"github.com/duckbugio/duckbug/internal/modules/scrubbing"
+	"github.com/duckbugio/duckbug/internal/modules/traces"
+	"github.com/duckbugio/duckbug/internal/tracing"
+	"github.com/duckbugio/duckbug/pkg/histogram"
+	"github.com/duckbugio/duckbug/pkg/pagination"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/duckbugio/duckbug/internal/modules/errors")
+
+var ErrTooManyBuckets = errors.New("too many histogram buckets, use a larger interval")
+
+const maxHistogramBuckets = 1500
+
 type Service interface {
 	GetByID(ctx context.Context, id string) (*Entity, error)
 	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
+	// Export calls fn with every entity matching the filters of params, paging is ignored
+	Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error
+	GetPage(ctx context.Context, params GetAllParams, countMode string) (*Page, error)
 	GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error)
+	GetHistogram(ctx context.Context, params HistogramParams) (*Histogram, error)
 	Create(ctx context.Context, req *Create) (*Entity, error)
 	Update(ctx context.Context, id string, req *Update) (*Entity, error)
 	Delete(ctx context.Context, id string) error
 }
 
+// Scrubber provides the rules that filter sensitive data out of incoming events
+type Scrubber interface {
+	ForProject(ctx context.Context, projectID string) (*scrubbing.Scrubber, error)
+}
+
 type service struct {
-	repo   Repository
-	logger Logger
+	repo     Repository
+	logger   Logger
+	scrubber Scrubber
 }
 
-func NewService(repo Repository, logger Logger) Service {
+func NewService(repo Repository, logger Logger, scrubber Scrubber) Service {
 	return &service{
-		repo:   repo,
-		logger: logger,
+		repo:     repo,
+		logger:   logger,
+		scrubber: scrubber,
 	}
 }
 
@@ -58,6 +86,70 @@ func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]*Entity, i
 	return responses, total, nil
 }
 
+func (s *service) Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error {
+	return s.repo.Scan(ctx, params, func(e *Error) error {
+		return fn(toResponse(e))
+	})
+}
+
+func (s *service) GetPage(ctx context.Context, params GetAllParams, countMode string) (*Page, error) {
+	pageSize := params.Limit
+	backward := params.Cursor != nil && params.Cursor.Backward
+
+	params.Keyset = true
+	params.Limit = pageSize + 1
+
+	entities, err := s.repo.GetAll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entities) > pageSize
+	if hasMore {
+		entities = entities[:pageSize]
+	}
+
+	if backward {
+		slices.Reverse(entities)
+	}
+
+	page := &Page{
+		Items: make([]*Entity, 0, len(entities)),
+	}
+	for _, entity := range entities {
+		page.Items = append(page.Items, toResponse(entity))
+	}
+
+	if len(entities) > 0 {
+		first, last := entities[0], entities[len(entities)-1]
+		// Walking backward always leaves the page we came from after the current one and vice versa
+		if hasMore || backward {
+			page.NextCursor = pagination.Cursor{Time: last.Time, ID: last.ID}.Encode()
+		}
+		if (backward && hasMore) || (!backward && params.Cursor != nil) {
+			page.PrevCursor = pagination.Cursor{Time: first.Time, ID: first.ID, Backward: true}.Encode()
+		}
+	}
+
+	switch countMode {
+	case pagination.CountExact:
+		total, err := s.repo.Count(ctx, params.FilterParams)
+		if err != nil {
+			return nil, err
+		}
+		page.Count = &total
+	case pagination.CountEstimated:
+		total, err := s.repo.EstimateCount(ctx, params.FilterParams)
+		if err != nil {
+			return nil, err
+		}
+		page.Count = &total
+		page.CountEstimated = true
+	}
+
+	return page, nil
+}
+
 func (s *service) GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error) {
 	stats, err := s.repo.GetStats(ctx, projectID, fingerprint)
 	if err != nil {
@@ -67,7 +159,37 @@ func (s *service) GetStats(ctx context.Context, projectID string, fingerprint st
 	return stats, nil
 }
 
-func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
+func (s *service) GetHistogram(ctx context.Context, params HistogramParams) (*Histogram, error) {
+	// Align the range to bucket boundaries so buckets are stable between requests
+	params.TimeFrom -= params.TimeFrom % params.Interval
+	if (params.TimeTo-params.TimeFrom)/params.Interval >= maxHistogramBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	rows, err := s.repo.GetHistogram(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram.Build(histogram.Params{
+		TimeFrom:    params.TimeFrom,
+		TimeTo:      params.TimeTo,
+		Interval:    params.Interval,
+		SplitBy:     params.SplitBy,
+		SeriesLimit: params.SeriesLimit,
+	}, rows), nil
+}
+
+func (s *service) Create(ctx context.Context, req *Create) (_ *Entity, err error) {
+	ctx, span := tracer.Start(ctx, "errors.Create", trace.WithAttributes(attribute.String("project.id", req.ProjectID)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	if err := s.scrub(ctx, req); err != nil {
+		return nil, err
+	}
+
 	stacktrace, err := stacktraceToString(req.Stacktrace)
 	if err != nil {
 		return nil, err
@@ -131,10 +253,14 @@ func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
 		Session:     session,
 		Files:       files,
 		Env:         env,
+		Release:     req.Release,
+		TraceID:     traceIDFromContext(req.Context),
 		Time:        req.Time,
 	}
 
+	_, fingerprintSpan := tracer.Start(ctx, "errors.fingerprint")
 	entity.Fingerprint = generateFingerprint(entity)
+	fingerprintSpan.End()
 
 	if err := s.repo.Create(ctx, entity); err != nil {
 		return nil, err
@@ -143,6 +269,52 @@ func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
 	return toResponse(entity), nil
 }
 
+// scrub filters sensitive data out of the request in place, before anything is serialized or stored
+func (s *service) scrub(ctx context.Context, req *Create) error {
+	if s.scrubber == nil {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "errors.scrub")
+	defer span.End()
+
+	scrubber, err := s.scrubber.ForProject(ctx, req.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to load scrubbing rules: %w", err)
+	}
+
+	req.Message = scrubber.String(req.Message)
+	if req.Stacktrace != nil {
+		*req.Stacktrace = scrubber.Value(*req.Stacktrace)
+	}
+	if req.Context != nil {
+		*req.Context = scrubber.Value(*req.Context)
+	}
+	if req.URL != nil {
+		scrubbed := scrubber.URL(*req.URL)
+		req.URL = &scrubbed
+	}
+
+	if req.Headers != nil {
+		scrubber.Headers(*req.Headers)
+	}
+	for _, m := range []*map[string]interface{}{req.QueryParams, req.BodyParams, req.Session, req.Files, req.Env} {
+		if m != nil {
+			scrubber.Map(*m)
+		}
+	}
+
+	if req.Cookies != nil {
+		scrubber.Redact(*req.Cookies)
+	}
+
+	if scrubber.StripIP() {
+		req.IP = nil
+	}
+
+	return nil
+}
+
 func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity, error) {
 	entity, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -168,8 +340,11 @@ func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity,
 		return nil, err
 	}
 	entity.Context = contextStr
+	entity.TraceID = traceIDFromContext(req.Context)
 
+	_, fingerprintSpan := tracer.Start(ctx, "errors.fingerprint")
 	entity.Fingerprint = generateFingerprint(entity)
+	fingerprintSpan.End()
 
 	if err := s.repo.Update(ctx, id, entity); err != nil {
 		return nil, err
@@ -183,14 +358,19 @@ func (s *service) Delete(ctx context.Context, id string) error {
 }
 
 func generateFingerprint(e *Error) string {
-	cleanMsg := regexp.MustCompile(`\d+|0x[0-9a-f]+`).ReplaceAllString(e.Message, "*")
+	return Fingerprint(e.ProjectID, e.Message, e.File, e.Line)
+}
+
+// Fingerprint identifies the group of an error, numbers in the message do not split groups
+func Fingerprint(projectID, message, file string, line int) string {
+	cleanMsg := regexp.MustCompile(`\d+|0x[0-9a-f]+`).ReplaceAllString(message, "*")
 
 	data := fmt.Sprintf(
 		"%s:%s:%s:%d",
 		cleanMsg,
-		e.ProjectID,
-		e.File,
-		e.Line,
+		projectID,
+		file,
+		line,
 	)
 
 	hash := sha256.Sum256([]byte(data))
@@ -199,14 +379,17 @@ func generateFingerprint(e *Error) string {
 
 func toResponse(e *Error) *Entity {
 	response := &Entity{
-		ID:      e.ID,
-		Message: e.Message,
-		File:    e.File,
-		Line:    e.Line,
-		IP:      e.IP,
-		URL:     e.URL,
-		Method:  e.Method,
-		Time:    e.Time,
+		ID:        e.ID,
+		Message:   e.Message,
+		File:      e.File,
+		Line:      e.Line,
+		IP:        e.IP,
+		URL:       e.URL,
+		Method:    e.Method,
+		Release:   e.Release,
+		TraceID:   e.TraceID,
+		Time:      e.Time,
+		Highlight: e.Highlight,
 	}
 
 	if err := parseJSONField(&e.Stacktrace, &response.Stacktrace); err != nil {
@@ -282,6 +465,38 @@ func contextToStringPtr(context *interface{}) (*string, error) {
 	return nil, nil
 }
 
+// traceIDFromContext returns the trace_id or traceId of the context, also looked up in its trace object
+// the way SDKs attach the current trace. Only W3C trace ids, 32 hex digits, are taken.
+func traceIDFromContext(context *interface{}) *string {
+	if context == nil {
+		return nil
+	}
+	fields, ok := (*context).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if nested, ok := fields["trace"].(map[string]interface{}); ok {
+		if traceID := traceIDField(nested); traceID != nil {
+			return traceID
+		}
+	}
+	return traceIDField(fields)
+}
+
+func traceIDField(fields map[string]interface{}) *string {
+	for _, key := range []string{"trace_id", "traceId"} {
+		value, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(value)
+		if traces.ValidTraceID(value) {
+			return &value
+		}
+	}
+	return nil
+}
+
 func stacktraceToString(stacktrace interface{}) (string, error) {
 	jsonData, err := json.Marshal(stacktrace)
 	if err != nil {
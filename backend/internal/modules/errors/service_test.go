@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceIDFromContext(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	tests := []struct {
+		name     string
+		context  interface{}
+		expected *string
+	}{
+		{
+			name:     "Top level trace_id",
+			context:  map[string]interface{}{"trace_id": traceID},
+			expected: stringPtr(traceID),
+		},
+		{
+			name:     "Top level traceId",
+			context:  map[string]interface{}{"traceId": traceID},
+			expected: stringPtr(traceID),
+		},
+		{
+			name:     "Nested trace object",
+			context:  map[string]interface{}{"trace": map[string]interface{}{"trace_id": traceID}},
+			expected: stringPtr(traceID),
+		},
+		{
+			name: "Nested trace wins",
+			context: map[string]interface{}{
+				"trace":    map[string]interface{}{"traceId": traceID},
+				"trace_id": "0af7651916cd43dd8448eb211c80319c",
+			},
+			expected: stringPtr(traceID),
+		},
+		{
+			name: "Invalid nested trace falls back to the top level",
+			context: map[string]interface{}{
+				"trace":   map[string]interface{}{"trace_id": "not-a-trace"},
+				"traceId": traceID,
+			},
+			expected: stringPtr(traceID),
+		},
+		{
+			name:     "Uppercase is lowered",
+			context:  map[string]interface{}{"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736"},
+			expected: stringPtr(traceID),
+		},
+		{
+			name:    "All zero",
+			context: map[string]interface{}{"trace_id": "00000000000000000000000000000000"},
+		},
+		{
+			name:    "Span id length",
+			context: map[string]interface{}{"trace_id": "00f067aa0ba902b7"},
+		},
+		{
+			name:    "Not a string",
+			context: map[string]interface{}{"trace_id": 42},
+		},
+		{
+			name:    "Not an object",
+			context: []interface{}{traceID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, traceIDFromContext(&tt.context))
+		})
+	}
+	assert.Nil(t, traceIDFromContext(nil))
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
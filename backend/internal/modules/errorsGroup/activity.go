@@ -0,0 +1,117 @@
+package errorsgroup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Change is the data of status and assignment entries
+type Change struct {
+	From *string `json:"from"`
+	To   *string `json:"to"`
+}
+
+// NewActivity builds a timeline entry of a group, data is stored as JSON and may be nil
+func NewActivity(group *Group, activityType string, actorID *string, data interface{}) (*Activity, error) {
+	encoded := []byte("{}")
+	if data != nil {
+		var err error
+		if encoded, err = json.Marshal(data); err != nil {
+			return nil, fmt.Errorf("failed to encode activity: %w", err)
+		}
+	}
+
+	// Time ordered ids keep entries of the same second in order
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate activity id: %w", err)
+	}
+
+	return &Activity{
+		ID:        id.String(),
+		GroupID:   group.ID,
+		ProjectID: group.ProjectID,
+		Type:      activityType,
+		ActorID:   actorID,
+		Data:      string(encoded),
+		CreatedAt: time.Now().Unix(),
+	}, nil
+}
+
+// statusChanges returns the groups whose status differs from status together with their entries,
+// groups already in that status are left out
+func statusChanges(groups []*Group, status Status, actorID *string) ([]string, []*Activity, error) {
+	changed := make([]string, 0, len(groups))
+	activities := make([]*Activity, 0, len(groups))
+	for _, group := range groups {
+		if group.Status == status {
+			continue
+		}
+
+		activity, err := NewActivity(group, ActivityStatusChanged, actorID, Change{
+			From: stringPtr(string(group.Status)),
+			To:   stringPtr(string(status)),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		changed = append(changed, group.ID)
+		activities = append(activities, activity)
+	}
+	return changed, activities, nil
+}
+
+// assignment returns the entry of assigning group to assigneeID, nil when it is assigned to them already
+func assignment(group *Group, assigneeID, actorID *string) (*Activity, error) {
+	if equalIDs(group.AssigneeID, assigneeID) {
+		return nil, nil
+	}
+	return NewActivity(group, ActivityAssigned, actorID, Change{From: group.AssigneeID, To: assigneeID})
+}
+
+// IngestActivity returns the entry of an event stored in group by ingest: first seen for a new group and
+// a regression for a resolved one. It is nil when the event changes nothing on the timeline.
+func IngestActivity(group *Group, inserted bool, previousStatus *string) (*Activity, error) {
+	switch {
+	case inserted:
+		return NewActivity(group, ActivityFirstSeen, nil, nil)
+	case previousStatus != nil && *previousStatus == string(StatusResolved):
+		return NewActivity(group, ActivityRegressed, nil, Change{
+			From: previousStatus,
+			To:   stringPtr(string(StatusUnresolved)),
+		})
+	default:
+		return nil, nil
+	}
+}
+
+// InsertActivity appends entries to group timelines. It takes the transaction of the change
+// being recorded, so an entry is never written without it.
+func InsertActivity(ctx context.Context, tx sqlx.ExtContext, activities ...*Activity) error {
+	const query = `
+		INSERT INTO error_group_activity (id, group_id, project_id, type, actor_id, data, created_at)
+		VALUES (:id, :group_id, :project_id, :type, :actor_id, CAST(:data AS jsonb), :created_at)`
+
+	for _, activity := range activities {
+		if _, err := sqlx.NamedExecContext(ctx, tx, query, activity); err != nil {
+			return fmt.Errorf("failed to insert error group activity: %w", err)
+		}
+	}
+	return nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func equalIDs(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,136 @@
+package errorsgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestActivity(t *testing.T) {
+	group := &Group{ID: "fingerprint", ProjectID: "project", Status: StatusUnresolved}
+
+	tests := []struct {
+		name           string
+		inserted       bool
+		previousStatus *string
+		expectedType   string
+		expectedData   string
+	}{
+		{
+			name:         "New group",
+			inserted:     true,
+			expectedType: ActivityFirstSeen,
+			expectedData: `{}`,
+		},
+		{
+			name:           "Resolved group regresses",
+			previousStatus: stringPtr(string(StatusResolved)),
+			expectedType:   ActivityRegressed,
+			expectedData:   `{"from":"resolved","to":"unresolved"}`,
+		},
+		{
+			name:           "Unresolved group",
+			previousStatus: stringPtr(string(StatusUnresolved)),
+		},
+		{
+			name:           "Ignored group stays ignored",
+			previousStatus: stringPtr(string(StatusIgnored)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity, err := IngestActivity(group, tt.inserted, tt.previousStatus)
+			require.NoError(t, err)
+
+			if tt.expectedType == "" {
+				assert.Nil(t, activity)
+				return
+			}
+			require.NotNil(t, activity)
+			assert.Equal(t, tt.expectedType, activity.Type)
+			assert.JSONEq(t, tt.expectedData, activity.Data)
+			assert.Equal(t, group.ID, activity.GroupID)
+			assert.Equal(t, group.ProjectID, activity.ProjectID)
+			assert.Nil(t, activity.ActorID)
+		})
+	}
+}
+
+func TestStatusChanges(t *testing.T) {
+	actorID := stringPtr("actor")
+	groups := []*Group{
+		{ID: "a", ProjectID: "project", Status: StatusUnresolved},
+		{ID: "b", ProjectID: "project", Status: StatusResolved},
+		{ID: "c", ProjectID: "project", Status: StatusIgnored},
+	}
+
+	changed, activities, err := statusChanges(groups, StatusResolved, actorID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "c"}, changed)
+	require.Len(t, activities, 2)
+	for i, from := range []string{"unresolved", "ignored"} {
+		assert.Equal(t, changed[i], activities[i].GroupID)
+		assert.Equal(t, ActivityStatusChanged, activities[i].Type)
+		assert.Equal(t, actorID, activities[i].ActorID)
+		assert.JSONEq(t, `{"from":"`+from+`","to":"resolved"}`, activities[i].Data)
+	}
+	// Time ordered ids keep the entries of one batch in order
+	assert.Less(t, activities[0].ID, activities[1].ID)
+}
+
+func TestAssignment(t *testing.T) {
+	actorID := stringPtr("actor")
+
+	tests := []struct {
+		name         string
+		current      *string
+		assigneeID   *string
+		expectedData string
+	}{
+		{
+			name:         "Assign",
+			assigneeID:   stringPtr("jane"),
+			expectedData: `{"from":null,"to":"jane"}`,
+		},
+		{
+			name:         "Reassign",
+			current:      stringPtr("jane"),
+			assigneeID:   stringPtr("john"),
+			expectedData: `{"from":"jane","to":"john"}`,
+		},
+		{
+			name:         "Unassign",
+			current:      stringPtr("jane"),
+			expectedData: `{"from":"jane","to":null}`,
+		},
+		{
+			name:       "Same assignee",
+			current:    stringPtr("jane"),
+			assigneeID: stringPtr("jane"),
+		},
+		{
+			name: "Still unassigned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &Group{ID: "fingerprint", ProjectID: "project", AssigneeID: tt.current}
+
+			activity, err := assignment(group, tt.assigneeID, actorID)
+			require.NoError(t, err)
+
+			if tt.expectedData == "" {
+				assert.Nil(t, activity)
+				return
+			}
+			require.NotNil(t, activity)
+			assert.Equal(t, ActivityAssigned, activity.Type)
+			assert.Equal(t, actorID, activity.ActorID)
+			assert.JSONEq(t, tt.expectedData, activity.Data)
+		})
+	}
+}
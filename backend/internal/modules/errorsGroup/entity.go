@@ -1,5 +1,7 @@
 package errorsgroup
 
+import "github.com/lib/pq"
+
 type Status string
 
 const (
@@ -8,14 +10,48 @@ const (
 	StatusIgnored    Status = "ignored"
 )
 
+// Types of the entries of a group timeline. A group is the fingerprint of its events and groups can not be
+// merged, so there is no merge entry.
+const (
+	ActivityFirstSeen     = "first_seen"
+	ActivityRegressed     = "regressed"
+	ActivityStatusChanged = "status_changed"
+	ActivityAssigned      = "assigned"
+	ActivityCommented     = "commented"
+)
+
 type Group struct {
-	ID          string `db:"id"`
-	ProjectID   string `db:"project_id"`
-	File        string `db:"file"`
-	Line        int    `db:"line"`
-	Message     string `db:"message"`
-	FirstSeenAt int64  `db:"first_seen_at"`
-	LastSeenAt  int64  `db:"last_seen_at"`
-	Counter     int    `db:"counter"`
-	Status      Status `db:"status"`
+	ID          string  `db:"id"`
+	ProjectID   string  `db:"project_id"`
+	File        string  `db:"file"`
+	Line        int     `db:"line"`
+	Message     string  `db:"message"`
+	FirstSeenAt int64   `db:"first_seen_at"`
+	LastSeenAt  int64   `db:"last_seen_at"`
+	Counter     int     `db:"counter"`
+	Status      Status  `db:"status"`
+	AssigneeID  *string `db:"assignee_id"`
+}
+
+type Activity struct {
+	ID        string `db:"id"`
+	GroupID   string `db:"group_id"`
+	ProjectID string `db:"project_id"`
+	Type      string `db:"type"`
+	// ActorID is empty for entries written by ingest
+	ActorID *string `db:"actor_id"`
+	// Data is raw JSON
+	Data      string `db:"data"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+type Comment struct {
+	ID        string         `db:"id"`
+	GroupID   string         `db:"group_id"`
+	ProjectID string         `db:"project_id"`
+	ParentID  *string        `db:"parent_id"`
+	AuthorID  *string        `db:"author_id"`
+	Body      string         `db:"body"`
+	Mentions  pq.StringArray `db:"mentions"`
+	CreatedAt int64          `db:"created_at"`
 }
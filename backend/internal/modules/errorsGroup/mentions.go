@@ -0,0 +1,23 @@
+package errorsgroup
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @email, so @jane@example.com mentions jane@example.com
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+// parseMentions returns the lowercased emails mentioned in a comment, each once
+func parseMentions(body string) []string {
+	var emails []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(match[1])
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
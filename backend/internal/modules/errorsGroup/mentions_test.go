@@ -0,0 +1,47 @@
+package errorsgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "No mention",
+			input:    "Fixed in 1.2.3, see jane@example.com",
+			expected: nil,
+		},
+		{
+			name:     "Single mention",
+			input:    "@jane@example.com please look",
+			expected: []string{"jane@example.com"},
+		},
+		{
+			name:     "End of sentence",
+			input:    "Ask @Jane.Doe@Example.com.",
+			expected: []string{"jane.doe@example.com"},
+		},
+		{
+			name:     "Repeated mentions",
+			input:    "(@a@example.com, @b@example.org) and again @A@example.com",
+			expected: []string{"a@example.com", "b@example.org"},
+		},
+		{
+			name:     "Inside a word",
+			input:    "foo@bar@example.com",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseMentions(tt.input))
+		})
+	}
+}
@@ -1,10 +1,22 @@
 package errorsgroup
 
+import (
+	"context"
+	"encoding/json"
+)
+
+// AssigneeNone filters groups nobody is assigned to
+const AssigneeNone = "none"
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
 type FilterParams struct {
@@ -13,6 +25,8 @@ type FilterParams struct {
 	TimeTo    int64
 	Search    string
 	Status    string
+	// Assignee is a user id or AssigneeNone
+	Assignee string
 }
 
 type GetAllParams struct {
@@ -22,6 +36,9 @@ type GetAllParams struct {
 	Offset    int
 }
 
+// ExportColumns are the CSV columns of an export which does not select its own
+var ExportColumns = []string{"id", "message", "file", "line", "status", "counter", "firstSeenAt", "lastSeenAt", "assigneeId"}
+
 type Entity struct {
 	ID          string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 	Message     string `json:"message" validate:"required" example:"Error message"`
@@ -31,6 +48,8 @@ type Entity struct {
 	LastSeenAt  int64  `json:"lastSeenAt" example:"1704067200"`
 	Counter     int    `json:"counter" example:"18"`
 	Status      Status `json:"status" example:"unresolved"`
+	// AssigneeID is the id of the user triaging the group, null when unassigned
+	AssigneeID *string `json:"assigneeId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 }
 
 type EntityList struct {
@@ -48,3 +67,46 @@ type BatchUpdateStatusRequest struct {
 	IDs    []string `json:"ids"`
 	Status string   `json:"status" example:"resolved" enums:"resolved,unresolved,ignored"`
 }
+
+// AssignRequest assigns the group to a user, a null assigneeId unassigns it
+type AssignRequest struct {
+	AssigneeID *string `json:"assigneeId" validate:"omitempty,uuid" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+}
+
+// CreateCommentRequest adds a comment, users are mentioned as @email in the body
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000" example:"Looks like @jane@example.com broke the checkout"`
+	// ParentID answers another comment of the group
+	ParentID *string `json:"parentId" validate:"omitempty,uuid" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+}
+
+type CommentEntity struct {
+	ID       string  `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	ParentID *string `json:"parentId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	AuthorID *string `json:"authorId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Body     string  `json:"body" example:"Looks like @jane@example.com broke the checkout"`
+	// Mentions are the ids of the mentioned users
+	Mentions  []string         `json:"mentions"`
+	CreatedAt int64            `json:"createdAt" example:"1704067200"`
+	Replies   []*CommentEntity `json:"replies"`
+}
+
+type CommentList struct {
+	Count int             `json:"count"`
+	Items []CommentEntity `json:"items"`
+}
+
+type ActivityEntity struct {
+	ID string `json:"id" example:"01890a5d-ac96-774b-bcce-b302099a8057"`
+	// Type is first_seen, regressed, status_changed, assigned or commented
+	Type    string  `json:"type" example:"status_changed"`
+	ActorID *string `json:"actorId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	// Data holds from and to of changes and the commentId of comments
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+	CreatedAt int64           `json:"createdAt" example:"1704067200"`
+}
+
+type ActivityList struct {
+	Count int              `json:"count"`
+	Items []ActivityEntity `json:"items"`
+}
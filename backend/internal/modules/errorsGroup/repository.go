@@ -10,15 +10,43 @@ import (
 	"github.com/lib/pq"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrParentNotFound = errors.New("parent comment not found")
+)
+
+const groupColumns = `id, project_id, file, line, message, first_seen_at, last_seen_at, counter, status, assignee_id`
 
 type Repository interface {
 	GetAll(ctx context.Context, params GetAllParams) ([]*Group, error)
+	// Scan calls fn for the error groups matching the filters in the order of GetAll, without paging.
+	// Rows are read as the database sends them, so the result is never held in memory.
+	Scan(ctx context.Context, params GetAllParams, fn func(*Group) error) error
 	Count(ctx context.Context, params FilterParams) (int, error)
 	BatchCountByProjectIDs(ctx context.Context, projectIDs []string, status Status) (map[string]int, error)
 	GetByID(ctx context.Context, id string) (*Group, error)
-	UpdateStatus(ctx context.Context, id string, status Status) error
-	BatchUpdateStatus(ctx context.Context, ids []string, status Status) error
+	// UpdateStatus and BatchUpdateStatus record every actual change on the timeline of the group
+	UpdateStatus(ctx context.Context, id string, status Status, actorID *string) error
+	BatchUpdateStatus(ctx context.Context, ids []string, status Status, actorID *string) error
+	// Assign sets or, with a nil assignee, clears the assignee and records the change
+	Assign(ctx context.Context, id string, assigneeID, actorID *string) error
+	GetActivity(ctx context.Context, groupID string, limit, offset int) ([]*Activity, error)
+	CountActivity(ctx context.Context, groupID string) (int, error)
+	GetComments(ctx context.Context, groupID string) ([]*Comment, error)
+	GetComment(ctx context.Context, groupID, id string) (*Comment, error)
+	// CreateComment stores the comment and its timeline entry
+	CreateComment(ctx context.Context, comment *Comment) error
+	UserExists(ctx context.Context, id string) (bool, error)
+	// UserIDsByEmails returns the ids of the users with these emails, unknown emails are left out
+	UserIDsByEmails(ctx context.Context, emails []string) ([]string, error)
+	// Decrement lowers the counters of groups by the number of their events removed and moves
+	// first_seen_at to the oldest remaining event
+	Decrement(ctx context.Context, removed map[string]int) error
+	// FindIDs returns up to limit ids of groups matching params after afterID, in the order of ids
+	FindIDs(ctx context.Context, params FilterParams, afterID string, limit int) ([]string, error)
+	// DeleteByIDs removes groups, their events are deleted separately
+	DeleteByIDs(ctx context.Context, ids []string) error
 }
 
 type repository struct {
@@ -34,11 +62,7 @@ func NewRepository(db *sqlx.DB, logger Logger) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Group, error) {
-	query := `
-        SELECT id, project_id, file, line, message, first_seen_at, last_seen_at, counter, status 
-        FROM error_groups 
-        WHERE 1=1
-    `
+	query := `SELECT ` + groupColumns + ` FROM error_groups WHERE 1=1`
 
 	args := map[string]interface{}{
 		"limit":  params.Limit,
@@ -57,7 +81,7 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Group,
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var entities []*Group
 	err = r.db.SelectContext(ctx, &entities, query, namedArgs...)
@@ -67,6 +91,43 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Group,
 	return entities, nil
 }
 
+func (r *repository) Scan(ctx context.Context, params GetAllParams, fn func(*Group) error) error {
+	query, args := applyFilters(`SELECT `+groupColumns+` FROM error_groups WHERE 1=1`, params.FilterParams, make(map[string]interface{}))
+	query += " ORDER BY last_seen_at " + params.SortOrder + ", id " + params.SortOrder
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	sqlRows, err := r.db.QueryContext(ctx, query, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read error groups: %w", err)
+	}
+	defer func() {
+		_ = sqlRows.Close()
+	}()
+
+	rows := &sqlx.Rows{Rows: sqlRows, Mapper: r.db.Mapper}
+	for rows.Next() {
+		var g Group
+		if err = rows.StructScan(&g); err != nil {
+			return fmt.Errorf("failed to scan error groups: %w", err)
+		}
+		if err = fn(&g); err != nil {
+			return err
+		}
+	}
+	if err = sqlRows.Err(); err != nil {
+		return fmt.Errorf("failed to read error groups: %w", err)
+	}
+	return nil
+}
+
 func (r *repository) Count(ctx context.Context, params FilterParams) (int, error) {
 	query := "SELECT COUNT(*) FROM error_groups WHERE 1=1"
 	query, args := applyFilters(query, params, make(map[string]interface{}))
@@ -78,7 +139,7 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var count int
 	err = r.db.GetContext(ctx, &count, query, namedArgs...)
@@ -113,7 +174,7 @@ func (r *repository) BatchCountByProjectIDs(ctx context.Context, projectIDs []st
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	type resultRow struct {
 		ProjectID string `db:"project_id"`
@@ -142,8 +203,7 @@ func (r *repository) BatchCountByProjectIDs(ctx context.Context, projectIDs []st
 }
 
 func (r *repository) GetByID(ctx context.Context, id string) (*Group, error) {
-	const query = `SELECT id, project_id, file, line, message, first_seen_at, last_seen_at, counter, status 
-		FROM error_groups WHERE id = $1`
+	query := `SELECT ` + groupColumns + ` FROM error_groups WHERE id = $1`
 
 	var entity Group
 	err := r.db.GetContext(ctx, &entity, query, id)
@@ -156,30 +216,298 @@ func (r *repository) GetByID(ctx context.Context, id string) (*Group, error) {
 	return &entity, nil
 }
 
-func (r *repository) UpdateStatus(ctx context.Context, id string, status Status) error {
-	const query = `UPDATE error_groups SET status = $1 WHERE id = $2`
-	res, err := r.db.ExecContext(ctx, query, status, id)
+func (r *repository) UpdateStatus(ctx context.Context, id string, status Status, actorID *string) error {
+	return r.updateStatus(ctx, []string{id}, status, actorID, true)
+}
+
+func (r *repository) BatchUpdateStatus(ctx context.Context, ids []string, status Status, actorID *string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.updateStatus(ctx, ids, status, actorID, false)
+}
+
+func (r *repository) updateStatus(ctx context.Context, ids []string, status Status, actorID *string, mustExist bool) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	groups, err := lockGroups(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	if mustExist && len(groups) == 0 {
+		return ErrNotFound
+	}
+
+	changed, activities, err := statusChanges(groups, status, actorID)
+	if err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		const query = `UPDATE error_groups SET status = $1 WHERE id = ANY(CAST($2 AS bpchar[]))`
+		if _, err = tx.ExecContext(ctx, query, status, pq.StringArray(changed)); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		if err = InsertActivity(ctx, tx, activities...); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Assign(ctx context.Context, id string, assigneeID, actorID *string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to update status: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	affected, err := res.RowsAffected()
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	groups, err := lockGroups(ctx, tx, []string{id})
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
-	if affected == 0 {
+	if len(groups) == 0 {
 		return ErrNotFound
 	}
+	activity, err := assignment(groups[0], assigneeID, actorID)
+	if err != nil {
+		return err
+	}
+	if activity == nil {
+		return tx.Commit()
+	}
+
+	const query = `UPDATE error_groups SET assignee_id = $1 WHERE id = $2`
+	if _, err = tx.ExecContext(ctx, query, assigneeID, id); err != nil {
+		return fmt.Errorf("failed to assign error group: %w", err)
+	}
+	if err = InsertActivity(ctx, tx, activity); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockGroups reads the groups for update, so concurrent changes are recorded one after another
+func lockGroups(ctx context.Context, tx *sqlx.Tx, ids []string) ([]*Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM error_groups WHERE id = ANY(CAST($1 AS bpchar[])) ORDER BY id FOR UPDATE`
+
+	var groups []*Group
+	if err := tx.SelectContext(ctx, &groups, query, pq.StringArray(ids)); err != nil {
+		return nil, fmt.Errorf("failed to lock error groups: %w", err)
+	}
+	return groups, nil
+}
+
+func (r *repository) GetActivity(ctx context.Context, groupID string, limit, offset int) ([]*Activity, error) {
+	const query = `
+		SELECT id, group_id, project_id, type, actor_id, data, created_at
+		FROM error_group_activity
+		WHERE group_id = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3`
+
+	var activities []*Activity
+	if err := r.db.SelectContext(ctx, &activities, query, groupID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to get error group activity: %w", err)
+	}
+	return activities, nil
+}
+
+func (r *repository) CountActivity(ctx context.Context, groupID string) (int, error) {
+	const query = `SELECT COUNT(*) FROM error_group_activity WHERE group_id = $1`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, groupID); err != nil {
+		return 0, fmt.Errorf("failed to count error group activity: %w", err)
+	}
+	return count, nil
+}
+
+const commentColumns = `id, group_id, project_id, parent_id, author_id, body, mentions, created_at`
+
+func (r *repository) GetComments(ctx context.Context, groupID string) ([]*Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM error_group_comments WHERE group_id = $1 ORDER BY created_at, id`
+
+	var comments []*Comment
+	if err := r.db.SelectContext(ctx, &comments, query, groupID); err != nil {
+		return nil, fmt.Errorf("failed to get error group comments: %w", err)
+	}
+	return comments, nil
+}
+
+func (r *repository) GetComment(ctx context.Context, groupID, id string) (*Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM error_group_comments WHERE group_id = $1 AND id = $2`
+
+	var comment Comment
+	if err := r.db.GetContext(ctx, &comment, query, groupID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get error group comment: %w", err)
+	}
+	return &comment, nil
+}
+
+type commentData struct {
+	CommentID string `json:"commentId"`
+}
+
+func (r *repository) CreateComment(ctx context.Context, comment *Comment) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	const query = `
+		INSERT INTO error_group_comments (id, group_id, project_id, parent_id, author_id, body, mentions, created_at)
+		VALUES (:id, :group_id, :project_id, :parent_id, :author_id, :body, CAST(:mentions AS uuid[]), :created_at)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if _, err = tx.NamedExecContext(ctx, query, comment); err != nil {
+		return fmt.Errorf("failed to create error group comment: %w", err)
+	}
+
+	group := &Group{ID: comment.GroupID, ProjectID: comment.ProjectID}
+	activity, err := NewActivity(group, ActivityCommented, comment.AuthorID, commentData{CommentID: comment.ID})
+	if err != nil {
+		return err
+	}
+	if err = InsertActivity(ctx, tx, activity); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) UserExists(ctx context.Context, id string) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, id); err != nil {
+		return false, fmt.Errorf("failed to check user: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *repository) UserIDsByEmails(ctx context.Context, emails []string) ([]string, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	const query = `SELECT CAST(id AS text) FROM users WHERE LOWER(email) = ANY($1) ORDER BY id`
+
+	var ids []string
+	if err := r.db.SelectContext(ctx, &ids, query, pq.StringArray(emails)); err != nil {
+		return nil, fmt.Errorf("failed to find mentioned users: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *repository) Decrement(ctx context.Context, removed map[string]int) error {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(removed))
+	counts := make([]int64, 0, len(removed))
+	for id, count := range removed {
+		ids = append(ids, id)
+		counts = append(counts, int64(count))
+	}
+
+	// Groups store seconds, events milliseconds. The oldest remaining event is a single step
+	// on the (project_id, fingerprint, time) index.
+	const query = `
+		UPDATE error_groups g
+		SET counter = GREATEST(g.counter - d.removed, 0),
+		    first_seen_at = COALESCE((
+		        SELECT CAST(e.time / 1000 AS INTEGER) FROM errors e
+		        WHERE e.project_id = g.project_id AND e.fingerprint = g.id
+		        ORDER BY e.time
+		        LIMIT 1
+		    ), g.first_seen_at)
+		FROM unnest(CAST($1 AS bpchar[]), CAST($2 AS bigint[])) AS d(id, removed)
+		WHERE g.id = d.id`
+
+	_, err := r.db.ExecContext(ctx, query, pq.StringArray(ids), pq.Int64Array(counts))
+	if err != nil {
+		return fmt.Errorf("failed to decrement error groups: %w", err)
+	}
 	return nil
 }
 
-func (r *repository) BatchUpdateStatus(ctx context.Context, ids []string, status Status) error {
+func (r *repository) FindIDs(ctx context.Context, params FilterParams, afterID string, limit int) ([]string, error) {
+	query, args := applyFilters("SELECT id FROM error_groups WHERE id > :afterId", params, map[string]interface{}{
+		"afterId": afterID,
+		"limit":   limit,
+	})
+	query += " ORDER BY id LIMIT :limit"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var ids []string
+	err = r.db.SelectContext(ctx, &ids, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find error groups: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *repository) DeleteByIDs(ctx context.Context, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
-	const query = `UPDATE error_groups SET status = $1 WHERE id::varchar = ANY($2)`
-	_, err := r.db.ExecContext(ctx, query, status, pq.StringArray(ids))
+
+	const query = `DELETE FROM error_groups WHERE id = ANY(CAST($1 AS bpchar[]))`
+
+	_, err := r.db.ExecContext(ctx, query, pq.StringArray(ids))
 	if err != nil {
-		return fmt.Errorf("failed to batch update status: %w", err)
+		return fmt.Errorf("failed to delete error groups: %w", err)
 	}
 	return nil
 }
@@ -212,5 +540,14 @@ func applyFilters(baseQuery string, params FilterParams, args map[string]interfa
 		args["status"] = params.Status
 	}
 
+	switch params.Assignee {
+	case "":
+	case AssigneeNone:
+		query += " AND assignee_id IS NULL"
+	default:
+		query += " AND assignee_id = CAST(:assignee AS uuid)"
+		args["assignee"] = params.Assignee
+	}
+
 	return query, args
 }
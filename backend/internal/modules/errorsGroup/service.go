@@ -1,12 +1,41 @@
 package errorsgroup
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// auditTarget names error groups in the audit log
+const auditTarget = "error_group"
+
+type auditState struct {
+	Status     Status  `json:"status,omitempty"`
+	AssigneeID *string `json:"assigneeId,omitempty"`
+}
+
+type batchAuditState struct {
+	IDs    []string `json:"ids"`
+	Status Status   `json:"status"`
+}
 
 type Service interface {
 	GetByID(ctx context.Context, id string) (*Entity, error)
 	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
+	// Export calls fn with every entity matching the filters of params, paging is ignored
+	Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error
 	UpdateStatus(ctx context.Context, id string, status Status) error
 	BatchUpdateStatus(ctx context.Context, ids []string, status Status) error
+	Assign(ctx context.Context, id string, req *AssignRequest) (*Entity, error)
+	// GetActivity returns the timeline of a group, oldest entries first
+	GetActivity(ctx context.Context, id string, limit, offset int) ([]*ActivityEntity, int, error)
+	// GetComments returns the comments of a group as threads, replies are nested in their parent
+	GetComments(ctx context.Context, id string) ([]*CommentEntity, int, error)
+	CreateComment(ctx context.Context, id string, req *CreateCommentRequest) (*CommentEntity, error)
 }
 
 type service struct {
@@ -47,12 +76,167 @@ func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]*Entity, i
 	return responses, total, nil
 }
 
+func (s *service) Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error {
+	return s.repo.Scan(ctx, params, func(g *Group) error {
+		return fn(toResponse(g))
+	})
+}
+
 func (s *service) UpdateStatus(ctx context.Context, id string, status Status) error {
-	return s.repo.UpdateStatus(ctx, id, status)
+	group, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.UpdateStatus(ctx, id, status, actorID(ctx)); err != nil {
+		return err
+	}
+
+	middleware.Audit(ctx, "error_group.status", auditTarget, id, auditState{Status: group.Status}, auditState{Status: status})
+	return nil
 }
 
 func (s *service) BatchUpdateStatus(ctx context.Context, ids []string, status Status) error {
-	return s.repo.BatchUpdateStatus(ctx, ids, status)
+	if err := s.repo.BatchUpdateStatus(ctx, ids, status, actorID(ctx)); err != nil {
+		return err
+	}
+
+	// The previous status of every group is on its activity timeline
+	middleware.Audit(ctx, "error_group.status.batch", auditTarget, "", nil, batchAuditState{IDs: ids, Status: status})
+	return nil
+}
+
+func (s *service) Assign(ctx context.Context, id string, req *AssignRequest) (*Entity, error) {
+	if req.AssigneeID != nil {
+		exists, err := s.repo.UserExists(ctx, *req.AssigneeID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	group, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.Assign(ctx, id, req.AssigneeID, actorID(ctx)); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "error_group.assign", auditTarget, id,
+		auditState{AssigneeID: group.AssigneeID}, auditState{AssigneeID: req.AssigneeID})
+	return s.GetByID(ctx, id)
+}
+
+func (s *service) GetActivity(ctx context.Context, id string, limit, offset int) ([]*ActivityEntity, int, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	activities, err := s.repo.GetActivity(ctx, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.repo.CountActivity(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*ActivityEntity, 0, len(activities))
+	for _, activity := range activities {
+		responses = append(responses, &ActivityEntity{
+			ID:        activity.ID,
+			Type:      activity.Type,
+			ActorID:   activity.ActorID,
+			Data:      json.RawMessage(activity.Data),
+			CreatedAt: activity.CreatedAt,
+		})
+	}
+	return responses, total, nil
+}
+
+func (s *service) GetComments(ctx context.Context, id string) ([]*CommentEntity, int, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	comments, err := s.repo.GetComments(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Comments come oldest first, so a parent is always seen before its replies
+	byID := make(map[string]*CommentEntity, len(comments))
+	threads := make([]*CommentEntity, 0)
+	for _, comment := range comments {
+		entity := toComment(comment)
+		byID[comment.ID] = entity
+
+		if comment.ParentID != nil {
+			if parent, ok := byID[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, entity)
+				continue
+			}
+		}
+		threads = append(threads, entity)
+	}
+	return threads, len(comments), nil
+}
+
+func (s *service) CreateComment(ctx context.Context, id string, req *CreateCommentRequest) (*CommentEntity, error) {
+	group, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		if _, err = s.repo.GetComment(ctx, id, *req.ParentID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, ErrParentNotFound
+			}
+			return nil, err
+		}
+	}
+
+	mentions, err := s.repo.UserIDsByEmails(ctx, parseMentions(req.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	// Time ordered ids keep a reply after its parent, also when both are dumped and restored
+	commentID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &Comment{
+		ID:        commentID.String(),
+		GroupID:   group.ID,
+		ProjectID: group.ProjectID,
+		ParentID:  req.ParentID,
+		AuthorID:  actorID(ctx),
+		Body:      req.Body,
+		Mentions:  mentions,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err = s.repo.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "error_group.comment", auditTarget, id, nil, commentData{CommentID: comment.ID})
+	return toComment(comment), nil
+}
+
+// actorID is the user making a change, nil outside of authenticated requests
+func actorID(ctx context.Context) *string {
+	if id, ok := middleware.GetUserID(ctx); ok {
+		return &id
+	}
+	return nil
 }
 
 func toResponse(g *Group) *Entity {
@@ -65,5 +249,22 @@ func toResponse(g *Group) *Entity {
 		LastSeenAt:  g.LastSeenAt,
 		Counter:     g.Counter,
 		Status:      g.Status,
+		AssigneeID:  g.AssigneeID,
+	}
+}
+
+func toComment(c *Comment) *CommentEntity {
+	mentions := []string(c.Mentions)
+	if mentions == nil {
+		mentions = []string{}
+	}
+	return &CommentEntity{
+		ID:        c.ID,
+		ParentID:  c.ParentID,
+		AuthorID:  c.AuthorID,
+		Body:      c.Body,
+		Mentions:  mentions,
+		CreatedAt: c.CreatedAt,
+		Replies:   []*CommentEntity{},
 	}
 }
@@ -0,0 +1,43 @@
+package ingest
+
+import "github.com/lib/pq"
+
+// Key is an active ingest key of a project, see the keys module
+type Key struct {
+	ID             string         `db:"id"`
+	PublicKey      string         `db:"public_key"`
+	Scope          string         `db:"scope"`
+	AllowedOrigins pq.StringArray `db:"allowed_origins"`
+	ExpiresAt      *int64         `db:"expires_at"`
+}
+
+type Limits struct {
+	ProjectID        string `db:"project_id"`
+	RatePerSecond    *int   `db:"rate_per_second"`
+	Burst            *int   `db:"burst"`
+	KeyRatePerSecond *int   `db:"key_rate_per_second"`
+	KeyBurst         *int   `db:"key_burst"`
+	DailyQuota       *int64 `db:"daily_quota"`
+	MonthlyQuota     *int64 `db:"monthly_quota"`
+	// LogSampleRates is a JSON object of level to the share of logs kept
+	LogSampleRates *string `db:"log_sample_rates"`
+	UpdatedAt      int64   `db:"updated_at"`
+}
+
+// Stat counts ingest outcomes of one project, kind and UTC day
+type Stat struct {
+	ProjectID     string `db:"project_id"`
+	Day           int64  `db:"day"`
+	Kind          string `db:"kind"`
+	Accepted      int64  `db:"accepted"`
+	RateLimited   int64  `db:"rate_limited"`
+	QuotaExceeded int64  `db:"quota_exceeded"`
+	Sampled       int64  `db:"sampled"`
+}
+
+func (s *Stat) add(other *Stat) {
+	s.Accepted += other.Accepted
+	s.RateLimited += other.RateLimited
+	s.QuotaExceeded += other.QuotaExceeded
+	s.Sampled += other.Sampled
+}
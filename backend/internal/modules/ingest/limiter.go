@@ -0,0 +1,40 @@
+package ingest
+
+import "time"
+
+// bucket is a token bucket refilled at rate tokens per second up to burst
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst int) *bucket {
+	if burst < rate {
+		burst = rate
+	}
+	return &bucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (b *bucket) sameAs(rate, burst int) bool {
+	return b.rate == float64(rate) && b.burst == float64(max(burst, rate))
+}
+
+// take consumes a token, when none is left it returns how long until the next one
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
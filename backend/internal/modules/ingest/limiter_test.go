@@ -0,0 +1,33 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketTake(t *testing.T) {
+	b := newBucket(2, 3)
+	now := time.Unix(1700000000, 0)
+
+	for range 3 {
+		ok, _ := b.take(now)
+		assert.True(t, ok)
+	}
+
+	ok, wait := b.take(now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	ok, _ = b.take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+
+	// Refill never goes past the burst
+	for range 3 {
+		ok, _ = b.take(now.Add(time.Hour))
+		assert.True(t, ok)
+	}
+	ok, _ = b.take(now.Add(time.Hour))
+	assert.False(t, ok)
+}
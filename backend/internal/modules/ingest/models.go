@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	KindLogs   = "logs"
+	KindErrors = "errors"
+	// KindSessions are session updates for release health
+	KindSessions = "sessions"
+	// KindCheckins are check-ins of cron monitors
+	KindCheckins = "checkins"
+	// KindTraces are transactions and spans
+	KindTraces = "traces"
+)
+
+// Outcomes of an ingest request, the first four are also kept in the daily stats
+const (
+	OutcomeAccepted      = "accepted"
+	OutcomeSampled       = "sampled"
+	OutcomeRateLimited   = "rate_limited"
+	OutcomeQuotaExceeded = "quota_exceeded"
+	// OutcomeRejected is an unknown key or a key used out of its scope or origin
+	OutcomeRejected = "rejected"
+)
+
+// scopeAll lets a key send every kind of events, other scopes are named after the kind
+const scopeAll = "all"
+
+// AnyOrigin in the allowed origins of a project lets every browser origin send events
+const AnyOrigin = "*"
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrInvalidOrigin    = errors.New("origin must be * or http(s)://host[:port]")
+	ErrInvalidKey       = errors.New("invalid ingest key")
+	ErrKeyScope         = errors.New("ingest key is not allowed to send this kind of events")
+	ErrOriginNotAllowed = errors.New("origin is not allowed for this ingest key")
+)
+
+// LimitError rejects an event over a rate limit or a quota, clients should retry after RetryAfter
+type LimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Recorder receives the outcome of every ingest request, it is used to export metrics
+type Recorder interface {
+	RecordIngest(projectID, kind, outcome string)
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) RecordIngest(string, string, string) {}
+
+// Config holds the instance wide defaults for projects without own limits, zero is unlimited
+type Config struct {
+	RatePerSecond int
+	Burst         int
+}
+
+// LimitsEntity configures the ingest of a project, null fields are unlimited
+type LimitsEntity struct {
+	// RatePerSecond and Burst shape a token bucket shared by all keys of the project
+	RatePerSecond *int `json:"ratePerSecond" validate:"omitempty,min=1" example:"100"`
+	Burst         *int `json:"burst" validate:"omitempty,min=1" example:"500"`
+	// KeyRatePerSecond and KeyBurst shape a token bucket of every single key
+	KeyRatePerSecond *int   `json:"keyRatePerSecond" validate:"omitempty,min=1" example:"50"`
+	KeyBurst         *int   `json:"keyBurst" validate:"omitempty,min=1" example:"200"`
+	DailyQuota       *int64 `json:"dailyQuota" validate:"omitempty,min=1" example:"1000000"`
+	MonthlyQuota     *int64 `json:"monthlyQuota" validate:"omitempty,min=1" example:"20000000"`
+	// LogSampleRates is the share of logs kept per level, levels not listed are kept
+	LogSampleRates map[string]float64 `json:"logSampleRates" validate:"omitempty,dive,keys,oneof=DEBUG INFO WARN ERROR FATAL,endkeys,min=0,max=1"`
+}
+
+// AllowedOrigins are the browser origins allowed to send events to any key of a project
+type AllowedOrigins struct {
+	Origins []string `json:"origins" validate:"max=100,dive,required,max=255" example:"https://app.example.com"`
+}
+
+type StatEntity struct {
+	// Day is the unix time of the UTC day start
+	Day           int64  `json:"day" example:"1700006400"`
+	Kind          string `json:"kind" example:"logs"`
+	Accepted      int64  `json:"accepted" example:"1200"`
+	RateLimited   int64  `json:"rateLimited" example:"30"`
+	QuotaExceeded int64  `json:"quotaExceeded" example:"0"`
+	Sampled       int64  `json:"sampled" example:"400"`
+}
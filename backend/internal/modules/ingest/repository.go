@@ -0,0 +1,217 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// GetKeys returns the keys of a project which are neither revoked nor expired at now
+	GetKeys(ctx context.Context, projectID string, now int64) ([]*Key, error)
+	GetAllowedOrigins(ctx context.Context, projectID string) ([]string, error)
+	UpdateAllowedOrigins(ctx context.Context, projectID string, origins []string) error
+	// TouchKeys records when keys were last used
+	TouchKeys(ctx context.Context, usedAt map[string]int64) error
+	GetLimits(ctx context.Context, projectID string) (*Limits, error)
+	SaveLimits(ctx context.Context, limits *Limits) error
+	// AddStats adds the counters to the stored ones
+	AddStats(ctx context.Context, stats []*Stat) error
+	// GetAccepted sums accepted events since dayStart and since monthStart
+	GetAccepted(ctx context.Context, projectID string, dayStart, monthStart int64) (day int64, month int64, err error)
+	GetStats(ctx context.Context, projectID string, from int64) ([]*Stat, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetKeys(ctx context.Context, projectID string, now int64) ([]*Key, error) {
+	if _, err := uuid.Parse(projectID); err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	const query = `
+		SELECT k.id, k.public_key, k.scope, k.allowed_origins, k.expires_at
+		FROM project_keys k
+		JOIN projects p ON p.id = k.project_id AND p.deleted_at IS NULL
+		WHERE k.project_id = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > $2)`
+
+	var keys []*Key
+	err := r.db.SelectContext(ctx, &keys, query, projectID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *repository) GetAllowedOrigins(ctx context.Context, projectID string) ([]string, error) {
+	const query = `SELECT allowed_origins FROM projects WHERE id = $1 AND deleted_at IS NULL`
+
+	var origins pq.StringArray
+	err := r.db.GetContext(ctx, &origins, query, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get allowed origins: %w", err)
+	}
+	return origins, nil
+}
+
+func (r *repository) UpdateAllowedOrigins(ctx context.Context, projectID string, origins []string) error {
+	const query = `UPDATE projects SET allowed_origins = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, pq.StringArray(origins), time.Now().Unix(), projectID)
+	if err != nil {
+		return fmt.Errorf("failed to update allowed origins: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) TouchKeys(ctx context.Context, usedAt map[string]int64) error {
+	const query = `UPDATE project_keys SET last_used_at = GREATEST(COALESCE(last_used_at, 0), $1) WHERE id = $2`
+
+	for id, at := range usedAt {
+		if _, err := r.db.ExecContext(ctx, query, at, id); err != nil {
+			return fmt.Errorf("failed to update key usage: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *repository) GetLimits(ctx context.Context, projectID string) (*Limits, error) {
+	const query = `
+		SELECT project_id, rate_per_second, burst, key_rate_per_second, key_burst,
+			daily_quota, monthly_quota, log_sample_rates, updated_at
+		FROM ingest_limits WHERE project_id = $1`
+
+	var limits Limits
+	err := r.db.GetContext(ctx, &limits, query, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &Limits{ProjectID: projectID}, nil
+		}
+		return nil, fmt.Errorf("failed to get ingest limits: %w", err)
+	}
+	return &limits, nil
+}
+
+func (r *repository) SaveLimits(ctx context.Context, limits *Limits) error {
+	const query = `
+		INSERT INTO ingest_limits (project_id, rate_per_second, burst, key_rate_per_second, key_burst,
+			daily_quota, monthly_quota, log_sample_rates, updated_at)
+		VALUES (:project_id, :rate_per_second, :burst, :key_rate_per_second, :key_burst,
+			:daily_quota, :monthly_quota, CAST(:log_sample_rates AS jsonb), :updated_at)
+		ON CONFLICT (project_id) DO UPDATE SET
+			rate_per_second = EXCLUDED.rate_per_second,
+			burst = EXCLUDED.burst,
+			key_rate_per_second = EXCLUDED.key_rate_per_second,
+			key_burst = EXCLUDED.key_burst,
+			daily_quota = EXCLUDED.daily_quota,
+			monthly_quota = EXCLUDED.monthly_quota,
+			log_sample_rates = EXCLUDED.log_sample_rates,
+			updated_at = EXCLUDED.updated_at`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	_, err := r.db.NamedExecContext(ctx, query, limits)
+	if err != nil {
+		return fmt.Errorf("failed to save ingest limits: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) AddStats(ctx context.Context, stats []*Stat) (err error) {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	const query = `
+		INSERT INTO ingest_stats (project_id, day, kind, accepted, rate_limited, quota_exceeded, sampled)
+		VALUES (:project_id, :day, :kind, :accepted, :rate_limited, :quota_exceeded, :sampled)
+		ON CONFLICT (project_id, day, kind) DO UPDATE SET
+			accepted = ingest_stats.accepted + EXCLUDED.accepted,
+			rate_limited = ingest_stats.rate_limited + EXCLUDED.rate_limited,
+			quota_exceeded = ingest_stats.quota_exceeded + EXCLUDED.quota_exceeded,
+			sampled = ingest_stats.sampled + EXCLUDED.sampled`
+
+	for _, stat := range stats {
+		if _, err = tx.NamedExecContext(ctx, query, stat); err != nil {
+			return fmt.Errorf("failed to save ingest stats: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetAccepted(
+	ctx context.Context, projectID string, dayStart, monthStart int64,
+) (day int64, month int64, err error) {
+	const query = `
+		SELECT COALESCE(SUM(accepted) FILTER (WHERE day >= $2), 0) AS day,
+			COALESCE(SUM(accepted), 0) AS month
+		FROM ingest_stats WHERE project_id = $1 AND day >= $3`
+
+	var usage struct {
+		Day   int64 `db:"day"`
+		Month int64 `db:"month"`
+	}
+	err = r.db.GetContext(ctx, &usage, query, projectID, dayStart, monthStart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get ingest usage: %w", err)
+	}
+	return usage.Day, usage.Month, nil
+}
+
+func (r *repository) GetStats(ctx context.Context, projectID string, from int64) ([]*Stat, error) {
+	const query = `
+		SELECT project_id, day, kind, accepted, rate_limited, quota_exceeded, sampled
+		FROM ingest_stats WHERE project_id = $1 AND day >= $2
+		ORDER BY day, kind`
+
+	var stats []*Stat
+	err := r.db.SelectContext(ctx, &stats, query, projectID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,611 @@
+package ingest
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/duckbugio/duckbug/pkg/httputils"
+)
+
+const (
+	// cacheTTL bounds how long other instances keep applying limits that were changed
+	cacheTTL = 30 * time.Second
+	// idleTTL drops the state of projects which stopped sending events
+	idleTTL = 10 * time.Minute
+)
+
+type Service interface {
+	// Admit checks the key, the rate limits and the quotas before the body of an ingest request is read,
+	// origin is the Origin header of browser requests
+	Admit(ctx context.Context, projectID, key, kind, origin string) error
+	// Keep applies the log sample rate of the level and counts the event as accepted or sampled out
+	Keep(projectID, kind, level string) bool
+	// Flush stores the counters and the key usage collected since the previous flush
+	Flush(ctx context.Context) error
+	// Invalidate reloads the keys and limits of a project on its next event
+	Invalidate(projectID string)
+	// AllowsOrigin tells whether a browser on origin may send events with the key, used for CORS
+	AllowsOrigin(ctx context.Context, projectID, key, origin string) (bool, error)
+	GetAllowedOrigins(ctx context.Context, projectID string) (*AllowedOrigins, error)
+	UpdateAllowedOrigins(ctx context.Context, projectID string, req *AllowedOrigins) (*AllowedOrigins, error)
+	GetLimits(ctx context.Context, projectID string) (*LimitsEntity, error)
+	UpdateLimits(ctx context.Context, projectID string, req *LimitsEntity) (*LimitsEntity, error)
+	// GetStats returns the daily counters of the last days, including today
+	GetStats(ctx context.Context, projectID string, days int) ([]StatEntity, error)
+}
+
+// projectState holds the limits and the usage of a project on this instance
+type projectState struct {
+	mu          sync.Mutex
+	keys        []*Key
+	origins     []string
+	limits      *Limits
+	sampleRates map[string]float64
+	expiresAt   time.Time
+
+	bucket     *bucket
+	keyBuckets map[string]*bucket
+
+	dayStart      int64
+	monthStart    int64
+	acceptedToday int64
+	acceptedMonth int64
+}
+
+type statKey struct {
+	projectID string
+	day       int64
+	kind      string
+}
+
+type service struct {
+	repo     Repository
+	logger   Logger
+	config   Config
+	recorder Recorder
+
+	mu       sync.Mutex
+	projects map[string]*projectState
+	pending  map[statKey]*Stat
+	// keysUsedAt is the last use of every key since the previous flush
+	keysUsedAt map[string]int64
+}
+
+// NewService creates the ingest gate, recorder may be nil when outcomes are not exported as metrics
+func NewService(repo Repository, logger Logger, config Config, recorder Recorder) Service {
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+	return &service{
+		repo:       repo,
+		logger:     logger,
+		config:     config,
+		recorder:   recorder,
+		projects:   make(map[string]*projectState),
+		pending:    make(map[statKey]*Stat),
+		keysUsedAt: make(map[string]int64),
+	}
+}
+
+func (s *service) Admit(ctx context.Context, projectID, key, kind, origin string) error {
+	now := time.Now()
+	st, err := s.state(ctx, projectID, now)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	matched := st.findKey(key, now.Unix())
+	if matched == nil {
+		s.recorder.RecordIngest(projectID, kind, OutcomeRejected)
+		return ErrInvalidKey
+	}
+	if !scopeAllows(matched.Scope, kind) {
+		s.recorder.RecordIngest(projectID, kind, OutcomeRejected)
+		return ErrKeyScope
+	}
+	if origin != "" && !keyAllowsOrigin(matched, origin) {
+		s.recorder.RecordIngest(projectID, kind, OutcomeRejected)
+		return ErrOriginNotAllowed
+	}
+
+	s.mu.Lock()
+	s.keysUsedAt[matched.ID] = now.Unix()
+	s.mu.Unlock()
+
+	st.rollover(now)
+
+	if st.bucket != nil {
+		if ok, wait := st.bucket.take(now); !ok {
+			s.count(projectID, st.dayStart, kind, OutcomeRateLimited)
+			return &LimitError{Reason: "project rate limit exceeded", RetryAfter: wait}
+		}
+	}
+
+	if keyBucket := st.keyBucket(key); keyBucket != nil {
+		if ok, wait := keyBucket.take(now); !ok {
+			s.count(projectID, st.dayStart, kind, OutcomeRateLimited)
+			return &LimitError{Reason: "key rate limit exceeded", RetryAfter: wait}
+		}
+	}
+
+	if quota := st.limits.DailyQuota; quota != nil && st.acceptedToday >= *quota {
+		s.count(projectID, st.dayStart, kind, OutcomeQuotaExceeded)
+		nextDay := time.Unix(st.dayStart, 0).UTC().AddDate(0, 0, 1)
+		return &LimitError{Reason: "daily quota exceeded", RetryAfter: nextDay.Sub(now)}
+	}
+
+	if quota := st.limits.MonthlyQuota; quota != nil && st.acceptedMonth >= *quota {
+		s.count(projectID, st.dayStart, kind, OutcomeQuotaExceeded)
+		nextMonth := time.Unix(st.monthStart, 0).UTC().AddDate(0, 1, 0)
+		return &LimitError{Reason: "monthly quota exceeded", RetryAfter: nextMonth.Sub(now)}
+	}
+
+	return nil
+}
+
+func (s *service) Keep(projectID, kind, level string) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	st, ok := s.projects[projectID]
+	s.mu.Unlock()
+
+	if !ok {
+		dayStart, _ := periodStarts(now)
+		s.count(projectID, dayStart, kind, OutcomeAccepted)
+		return true
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.rollover(now)
+
+	if rate, ok := st.sampleRates[level]; ok && kind == KindLogs && rand.Float64() >= rate { //nolint:gosec
+		s.count(projectID, st.dayStart, kind, OutcomeSampled)
+		return false
+	}
+
+	st.acceptedToday++
+	st.acceptedMonth++
+	s.count(projectID, st.dayStart, kind, OutcomeAccepted)
+	return true
+}
+
+func (s *service) Flush(ctx context.Context) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[statKey]*Stat)
+	keysUsedAt := s.keysUsedAt
+	s.keysUsedAt = make(map[string]int64)
+	states := make(map[string]*projectState, len(s.projects))
+	for projectID, st := range s.projects {
+		states[projectID] = st
+	}
+	s.mu.Unlock()
+
+	s.dropIdle(states, now)
+
+	stats := make([]*Stat, 0, len(pending))
+	for _, stat := range pending {
+		stats = append(stats, stat)
+	}
+	// A stable order keeps concurrent flushes of several instances from deadlocking
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		return a.Kind < b.Kind
+	})
+
+	if err := s.repo.AddStats(ctx, stats); err != nil {
+		s.mu.Lock()
+		for key, stat := range pending {
+			s.counter(key).add(stat)
+		}
+		s.mu.Unlock()
+		return err
+	}
+
+	// Usage is informational, it is not retried
+	return s.repo.TouchKeys(ctx, keysUsedAt)
+}
+
+func (s *service) Invalidate(projectID string) {
+	s.mu.Lock()
+	st, ok := s.projects[projectID]
+	s.mu.Unlock()
+	if ok {
+		st.mu.Lock()
+		st.expiresAt = time.Time{}
+		st.mu.Unlock()
+	}
+}
+
+func (s *service) AllowsOrigin(ctx context.Context, projectID, key, origin string) (bool, error) {
+	now := time.Now()
+	st, err := s.state(ctx, projectID, now)
+	if err != nil {
+		if errors.Is(err, ErrInvalidKey) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	matched := st.findKey(key, now.Unix())
+	if matched == nil || !keyAllowsOrigin(matched, origin) {
+		return false, nil
+	}
+
+	// A key with own origins needs no project wide entry
+	if len(matched.AllowedOrigins) > 0 {
+		return true, nil
+	}
+	return slices.Contains(st.origins, AnyOrigin) || slices.Contains(st.origins, httputils.NormalizeOrigin(origin)), nil
+}
+
+func (s *service) GetAllowedOrigins(ctx context.Context, projectID string) (*AllowedOrigins, error) {
+	origins, err := s.repo.GetAllowedOrigins(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if origins == nil {
+		origins = []string{}
+	}
+	return &AllowedOrigins{Origins: origins}, nil
+}
+
+func (s *service) UpdateAllowedOrigins(ctx context.Context, projectID string, req *AllowedOrigins) (*AllowedOrigins, error) {
+	origins := make([]string, 0, len(req.Origins))
+	for _, origin := range req.Origins {
+		normalized, err := parseOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(origins, normalized) {
+			origins = append(origins, normalized)
+		}
+	}
+
+	if err := s.repo.UpdateAllowedOrigins(ctx, projectID, origins); err != nil {
+		return nil, err
+	}
+	s.Invalidate(projectID)
+
+	return &AllowedOrigins{Origins: origins}, nil
+}
+
+func (s *service) GetLimits(ctx context.Context, projectID string) (*LimitsEntity, error) {
+	limits, err := s.repo.GetLimits(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return toEntity(limits)
+}
+
+func (s *service) UpdateLimits(ctx context.Context, projectID string, req *LimitsEntity) (*LimitsEntity, error) {
+	limits := &Limits{
+		ProjectID:        projectID,
+		RatePerSecond:    req.RatePerSecond,
+		Burst:            req.Burst,
+		KeyRatePerSecond: req.KeyRatePerSecond,
+		KeyBurst:         req.KeyBurst,
+		DailyQuota:       req.DailyQuota,
+		MonthlyQuota:     req.MonthlyQuota,
+		UpdatedAt:        time.Now().Unix(),
+	}
+
+	if len(req.LogSampleRates) > 0 {
+		encoded, err := json.Marshal(req.LogSampleRates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode sample rates: %w", err)
+		}
+		rates := string(encoded)
+		limits.LogSampleRates = &rates
+	}
+
+	if err := s.repo.SaveLimits(ctx, limits); err != nil {
+		return nil, err
+	}
+
+	// This instance applies the new limits right away, others once their cache expired
+	s.Invalidate(projectID)
+
+	return toEntity(limits)
+}
+
+func (s *service) GetStats(ctx context.Context, projectID string, days int) ([]StatEntity, error) {
+	dayStart, _ := periodStarts(time.Now())
+	from := time.Unix(dayStart, 0).UTC().AddDate(0, 0, 1-days).Unix()
+
+	stats, err := s.repo.GetStats(ctx, projectID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]StatEntity, 0, len(stats))
+	for _, stat := range stats {
+		entities = append(entities, StatEntity{
+			Day:           stat.Day,
+			Kind:          stat.Kind,
+			Accepted:      stat.Accepted,
+			RateLimited:   stat.RateLimited,
+			QuotaExceeded: stat.QuotaExceeded,
+			Sampled:       stat.Sampled,
+		})
+	}
+	return entities, nil
+}
+
+// state returns the cached state of a project and reloads it once expired
+func (s *service) state(ctx context.Context, projectID string, now time.Time) (*projectState, error) {
+	s.mu.Lock()
+	st, ok := s.projects[projectID]
+	s.mu.Unlock()
+
+	if ok {
+		st.mu.Lock()
+		fresh := now.Before(st.expiresAt)
+		st.mu.Unlock()
+		if fresh {
+			return st, nil
+		}
+	}
+
+	keys, err := s.repo.GetKeys(ctx, projectID, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	origins, err := s.repo.GetAllowedOrigins(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	limits, err := s.repo.GetLimits(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleRates := make(map[string]float64)
+	if limits.LogSampleRates != nil {
+		if err := json.Unmarshal([]byte(*limits.LogSampleRates), &sampleRates); err != nil {
+			return nil, fmt.Errorf("invalid sample rates of project %s: %w", projectID, err)
+		}
+	}
+
+	dayStart, monthStart := periodStarts(now)
+	acceptedToday, acceptedMonth, err := s.repo.GetAccepted(ctx, projectID, dayStart, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	// Events accepted here but not flushed yet are not in the stored stats
+	for _, kind := range []string{KindLogs, KindErrors, KindSessions, KindCheckins, KindTraces} {
+		if stat, ok := s.pending[statKey{projectID: projectID, day: dayStart, kind: kind}]; ok {
+			acceptedToday += stat.Accepted
+			acceptedMonth += stat.Accepted
+		}
+	}
+	st, ok = s.projects[projectID]
+	if !ok {
+		st = &projectState{keyBuckets: make(map[string]*bucket)}
+		s.projects[projectID] = st
+	}
+	s.mu.Unlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	rate, burst := s.projectRate(limits)
+	st.bucket = reuseBucket(st.bucket, rate, burst)
+	if st.limits == nil || !equalInts(st.limits.KeyRatePerSecond, limits.KeyRatePerSecond) ||
+		!equalInts(st.limits.KeyBurst, limits.KeyBurst) {
+		st.keyBuckets = make(map[string]*bucket)
+	}
+
+	st.keys = keys
+	st.origins = origins
+	st.limits = limits
+	st.sampleRates = sampleRates
+	st.expiresAt = now.Add(cacheTTL)
+	st.dayStart, st.monthStart = dayStart, monthStart
+	st.acceptedToday, st.acceptedMonth = acceptedToday, acceptedMonth
+
+	return st, nil
+}
+
+// dropIdle forgets projects which stopped sending events. A state is never locked
+// while holding s.mu, Admit takes the locks the other way around.
+func (s *service) dropIdle(states map[string]*projectState, now time.Time) {
+	for projectID, st := range states {
+		st.mu.Lock()
+		idle := now.Sub(st.expiresAt) > idleTTL
+		st.mu.Unlock()
+		if !idle {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.projects[projectID] == st {
+			delete(s.projects, projectID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// projectRate applies the instance defaults to a project without own rate limit
+func (s *service) projectRate(limits *Limits) (int, int) {
+	rate, burst := s.config.RatePerSecond, s.config.Burst
+	if limits.RatePerSecond != nil {
+		rate, burst = *limits.RatePerSecond, 0
+	}
+	if limits.Burst != nil {
+		burst = *limits.Burst
+	}
+	return rate, burst
+}
+
+// count updates the pending counters, the caller must not hold s.mu
+func (s *service) count(projectID string, day int64, kind, outcome string) {
+	s.recorder.RecordIngest(projectID, kind, outcome)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := s.counter(statKey{projectID: projectID, day: day, kind: kind})
+	switch outcome {
+	case OutcomeAccepted:
+		stat.Accepted++
+	case OutcomeRateLimited:
+		stat.RateLimited++
+	case OutcomeQuotaExceeded:
+		stat.QuotaExceeded++
+	case OutcomeSampled:
+		stat.Sampled++
+	}
+}
+
+func (s *service) counter(key statKey) *Stat {
+	stat, ok := s.pending[key]
+	if !ok {
+		stat = &Stat{ProjectID: key.projectID, Day: key.day, Kind: key.kind}
+		s.pending[key] = stat
+	}
+	return stat
+}
+
+// findKey compares in constant time, so response times do not leak how much of a key matched
+func (st *projectState) findKey(key string, now int64) *Key {
+	for _, candidate := range st.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate.PublicKey)) != 1 {
+			continue
+		}
+		// A rotated key may expire while cached
+		if candidate.ExpiresAt != nil && *candidate.ExpiresAt <= now {
+			return nil
+		}
+		return candidate
+	}
+	return nil
+}
+
+func (st *projectState) keyBucket(key string) *bucket {
+	if st.limits.KeyRatePerSecond == nil {
+		return nil
+	}
+
+	b, ok := st.keyBuckets[key]
+	if !ok {
+		burst := 0
+		if st.limits.KeyBurst != nil {
+			burst = *st.limits.KeyBurst
+		}
+		b = newBucket(*st.limits.KeyRatePerSecond, burst)
+		st.keyBuckets[key] = b
+	}
+	return b
+}
+
+// rollover resets the usage when a new day or month started
+func (st *projectState) rollover(now time.Time) {
+	dayStart, monthStart := periodStarts(now)
+	if dayStart != st.dayStart {
+		st.dayStart = dayStart
+		st.acceptedToday = 0
+	}
+	if monthStart != st.monthStart {
+		st.monthStart = monthStart
+		st.acceptedMonth = 0
+	}
+}
+
+func reuseBucket(current *bucket, rate, burst int) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	if current != nil && current.sameAs(rate, burst) {
+		return current
+	}
+	return newBucket(rate, burst)
+}
+
+// periodStarts returns the unix time of the current UTC day and month start
+func periodStarts(now time.Time) (int64, int64) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day.Unix(), month.Unix()
+}
+
+// scopeAllows tells whether a key of the scope may send the kind
+func scopeAllows(scope, kind string) bool {
+	return scope == scopeAll || scope == kind
+}
+
+// keyAllowsOrigin checks the own origins of a key, a key without any leaves it to the project
+func keyAllowsOrigin(key *Key, origin string) bool {
+	return len(key.AllowedOrigins) == 0 || slices.Contains(key.AllowedOrigins, httputils.NormalizeOrigin(origin))
+}
+
+// parseOrigin accepts * or an origin as browsers send it, see httputils.ParseOrigin
+func parseOrigin(origin string) (string, error) {
+	if origin == AnyOrigin {
+		return origin, nil
+	}
+
+	normalized, err := httputils.ParseOrigin(origin)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOrigin, origin)
+	}
+	return normalized, nil
+}
+
+func equalInts(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func toEntity(limits *Limits) (*LimitsEntity, error) {
+	entity := &LimitsEntity{
+		RatePerSecond:    limits.RatePerSecond,
+		Burst:            limits.Burst,
+		KeyRatePerSecond: limits.KeyRatePerSecond,
+		KeyBurst:         limits.KeyBurst,
+		DailyQuota:       limits.DailyQuota,
+		MonthlyQuota:     limits.MonthlyQuota,
+		LogSampleRates:   map[string]float64{},
+	}
+
+	if limits.LogSampleRates != nil {
+		if err := json.Unmarshal([]byte(*limits.LogSampleRates), &entity.LogSampleRates); err != nil {
+			return nil, fmt.Errorf("invalid sample rates: %w", err)
+		}
+	}
+	return entity, nil
+}
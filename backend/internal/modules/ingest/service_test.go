@@ -0,0 +1,43 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/modules/keys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeAllows(t *testing.T) {
+	kinds := []string{KindLogs, KindErrors, KindSessions, KindCheckins, KindTraces}
+
+	for _, scope := range []string{keys.ScopeLogs, keys.ScopeErrors, keys.ScopeSessions, keys.ScopeCheckins, keys.ScopeTraces} {
+		for _, kind := range kinds {
+			assert.Equal(t, scope == kind, scopeAllows(scope, kind), "scope %s, kind %s", scope, kind)
+		}
+	}
+	for _, kind := range kinds {
+		assert.True(t, scopeAllows(keys.ScopeAll, kind), kind)
+	}
+}
+
+func TestParseOrigin(t *testing.T) {
+	for origin, want := range map[string]string{
+		"*":                        "*",
+		"https://App.Example.com/": "https://app.example.com",
+		"http://localhost:3000":    "http://localhost:3000",
+		"https://example.com/path": "",
+		"https://example.com?a=1":  "",
+		"ftp://example.com":        "",
+		"javascript:alert(1)":      "",
+		"https://user@example.com": "",
+		"example.com":              "",
+	} {
+		parsed, err := parseOrigin(origin)
+		if want == "" {
+			assert.ErrorIs(t, err, ErrInvalidOrigin, origin)
+			continue
+		}
+		assert.NoError(t, err, origin)
+		assert.Equal(t, want, parsed, origin)
+	}
+}
@@ -0,0 +1,18 @@
+package jobs
+
+type Job struct {
+	ID     string `db:"id"`
+	Type   string `db:"type"`
+	Status string `db:"status"`
+	// Params and Result are raw JSON
+	Params     *string `db:"params"`
+	Result     *string `db:"result"`
+	Error      *string `db:"error"`
+	Processed  *int64  `db:"processed"`
+	Total      *int64  `db:"total"`
+	CreatedBy  *string `db:"created_by"`
+	CreatedAt  int64   `db:"created_at"`
+	StartedAt  *int64  `db:"started_at"`
+	FinishedAt *int64  `db:"finished_at"`
+	UpdatedAt  int64   `db:"updated_at"`
+}
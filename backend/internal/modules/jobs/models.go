@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Recorder receives the outcome and the duration of every finished job, it is used to export metrics
+type Recorder interface {
+	ObserveJob(jobType, status string, duration time.Duration)
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) ObserveJob(string, string, time.Duration) {}
+
+// Handler runs a job of one type, the returned value is stored as the job result
+type Handler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+type GetAllParams struct {
+	// CreatedBy limits the list to jobs of one user, empty lists every job
+	CreatedBy string
+	Limit     int
+	Offset    int
+}
+
+// Progress is reported by jobs which know how much work is left, processed may exceed the expected total
+// when rows were added while the job ran
+type Progress struct {
+	Processed int64 `json:"processed" example:"1500"`
+	Total     int64 `json:"total" example:"4200"`
+}
+
+type Entity struct {
+	ID         string          `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Type       string          `json:"type" example:"erasure"`
+	Status     string          `json:"status" example:"succeeded"`
+	Result     json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error      *string         `json:"error,omitempty"`
+	Progress   *Progress       `json:"progress,omitempty"`
+	CreatedAt  int64           `json:"createdAt" example:"1700000000"`
+	StartedAt  *int64          `json:"startedAt,omitempty" example:"1700000001"`
+	FinishedAt *int64          `json:"finishedAt,omitempty" example:"1700000042"`
+}
+
+type EntityList struct {
+	Count int      `json:"count"`
+	Items []Entity `json:"items"`
+}
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrNotFound = errors.New("not found")
+
+const jobColumns = `id, type, status, params, result, error, processed, total, created_by, created_at, started_at,
+	finished_at, updated_at`
+
+type Repository interface {
+	Create(ctx context.Context, job *Job) error
+	GetByID(ctx context.Context, id string) (*Job, error)
+	GetAll(ctx context.Context, params GetAllParams) ([]*Job, error)
+	Count(ctx context.Context, params GetAllParams) (int, error)
+	// ClaimNext marks the oldest queued job of the given types as running, nil when there is none
+	ClaimNext(ctx context.Context, types []string) (*Job, error)
+	// Touch records that a running job is still alive
+	Touch(ctx context.Context, id string) error
+	// SetProgress stores the progress of a running job, which also counts as a touch
+	SetProgress(ctx context.Context, id string, processed, total int64) error
+	Finish(ctx context.Context, id, status string, result, errMsg *string, clearParams bool) error
+	// RequeueStale puts running jobs not touched since before back into the queue
+	RequeueStale(ctx context.Context, before int64) (int, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, job *Job) error {
+	query := `
+		INSERT INTO jobs (id, type, status, params, created_by, created_at, updated_at)
+		VALUES (:id, :type, :status, CAST(:params AS jsonb), :created_by, :created_at, :updated_at)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	_, err := r.db.NamedExecContext(ctx, query, job)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	var job Job
+	err := r.db.GetContext(ctx, &job, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE (:created_by = '' OR CAST(created_by AS text) = :created_by)
+		ORDER BY created_at DESC, id DESC LIMIT :limit OFFSET :offset`
+
+	args := map[string]interface{}{
+		"created_by": params.CreatedBy,
+		"limit":      params.Limit,
+		"offset":     params.Offset,
+	}
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var jobs []*Job
+	err = r.db.SelectContext(ctx, &jobs, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+func (r *repository) Count(ctx context.Context, params GetAllParams) (int, error) {
+	const query = `SELECT COUNT(*) FROM jobs WHERE ($1 = '' OR CAST(created_by AS text) = $1)`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, params.CreatedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	return count, nil
+}
+
+func (r *repository) ClaimNext(ctx context.Context, types []string) (*Job, error) {
+	// SKIP LOCKED lets several instances poll the queue without taking the same job
+	query := `
+		UPDATE jobs SET status = $1, started_at = $2, updated_at = $2
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = $3 AND type = ANY($4)
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var job Job
+	now := time.Now().Unix()
+	err := r.db.GetContext(ctx, &job, query, StatusRunning, now, StatusQueued, pq.Array(types))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return &job, nil
+}
+
+func (r *repository) Touch(ctx context.Context, id string) error {
+	const query = `UPDATE jobs SET updated_at = $1 WHERE id = $2 AND status = $3`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now().Unix(), id, StatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to touch job: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) SetProgress(ctx context.Context, id string, processed, total int64) error {
+	const query = `UPDATE jobs SET processed = $1, total = $2, updated_at = $3 WHERE id = $4 AND status = $5`
+
+	_, err := r.db.ExecContext(ctx, query, processed, total, time.Now().Unix(), id, StatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to set job progress: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Finish(ctx context.Context, id, status string, result, errMsg *string, clearParams bool) error {
+	const query = `
+		UPDATE jobs SET status = $1, result = CAST($2 AS jsonb), error = $3, finished_at = $4, updated_at = $4,
+			params = CASE WHEN $5 THEN NULL ELSE params END
+		WHERE id = $6`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	_, err := r.db.ExecContext(ctx, query, status, result, errMsg, time.Now().Unix(), clearParams, id)
+	if err != nil {
+		return fmt.Errorf("failed to finish job: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RequeueStale(ctx context.Context, before int64) (int, error) {
+	// A requeued job starts over, so its progress is reset
+	const query = `
+		UPDATE jobs SET status = $1, started_at = NULL, processed = NULL, total = NULL, updated_at = $2
+		WHERE status = $3 AND updated_at < $4`
+
+	result, err := r.db.ExecContext(ctx, query, StatusQueued, time.Now().Unix(), StatusRunning, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale jobs: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
@@ -0,0 +1,309 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/users"
+	"github.com/google/uuid"
+)
+
+const (
+	// touchInterval keeps running jobs fresh, a job not touched for staleAfter is assumed to be orphaned
+	touchInterval = time.Minute
+	staleAfter    = 10 * time.Minute
+)
+
+type Service interface {
+	// Register makes jobs of that type runnable by this instance. Params of sensitive job types
+	// are removed once the job finished, so only the result is kept.
+	Register(jobType string, handler Handler, sensitiveParams bool)
+	Enqueue(ctx context.Context, jobType string, params interface{}) (*Entity, error)
+	// GetByID returns a job of the current user, admins can see every job
+	GetByID(ctx context.Context, id string) (*Entity, error)
+	GetAll(ctx context.Context, params GetAllParams) ([]Entity, int, error)
+	// RunPending runs queued jobs one by one until the queue is empty
+	RunPending(ctx context.Context) error
+}
+
+// progressKey holds the progress reporter of the running job in its context
+type progressKey struct{}
+
+// ReportProgress stores the progress of the job running with ctx, it does nothing outside of a job
+func ReportProgress(ctx context.Context, processed, total int64) {
+	if report, ok := ctx.Value(progressKey{}).(func(processed, total int64)); ok {
+		report(processed, total)
+	}
+}
+
+type registration struct {
+	handler         Handler
+	sensitiveParams bool
+}
+
+type service struct {
+	repo     Repository
+	logger   Logger
+	recorder Recorder
+
+	mu       sync.RWMutex
+	handlers map[string]registration
+}
+
+// NewService creates the job queue, recorder may be nil when job runs are not exported as metrics
+func NewService(repo Repository, logger Logger, recorder Recorder) Service {
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+	return &service{
+		repo:     repo,
+		logger:   logger,
+		recorder: recorder,
+		handlers: make(map[string]registration),
+	}
+}
+
+func (s *service) Register(jobType string, handler Handler, sensitiveParams bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = registration{handler: handler, sensitiveParams: sensitiveParams}
+}
+
+func (s *service) Enqueue(ctx context.Context, jobType string, params interface{}) (*Entity, error) {
+	if _, ok := s.handler(jobType); !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job params: %w", err)
+	}
+	paramsJSON := string(encoded)
+
+	now := time.Now().Unix()
+	job := &Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		Status:    StatusQueued,
+		Params:    &paramsJSON,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if userID, ok := middleware.GetUserID(ctx); ok {
+		job.CreatedBy = &userID
+	}
+
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	entity := toEntity(job)
+	return &entity, nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string) (*Entity, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin(ctx) {
+		userID, _ := middleware.GetUserID(ctx)
+		if job.CreatedBy == nil || *job.CreatedBy != userID {
+			return nil, ErrNotFound
+		}
+	}
+
+	entity := toEntity(job)
+	return &entity, nil
+}
+
+func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]Entity, int, error) {
+	if !isAdmin(ctx) {
+		userID, ok := middleware.GetUserID(ctx)
+		if !ok {
+			return nil, 0, fmt.Errorf("unauthorized")
+		}
+		params.CreatedBy = userID
+	}
+
+	jobs, err := s.repo.GetAll(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.repo.Count(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entities := make([]Entity, 0, len(jobs))
+	for _, job := range jobs {
+		entities = append(entities, toEntity(job))
+	}
+	return entities, count, nil
+}
+
+func (s *service) RunPending(ctx context.Context) error {
+	requeued, err := s.repo.RequeueStale(ctx, time.Now().Add(-staleAfter).Unix())
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		s.logger.Warn(fmt.Sprintf("requeued %d stale jobs", requeued))
+	}
+
+	types := s.types()
+	if len(types) == 0 {
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		job, err := s.repo.ClaimNext(ctx, types)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+
+		if err := s.run(ctx, job); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// run executes a claimed job and stores its outcome, only storage errors are returned
+func (s *service) run(ctx context.Context, job *Job) error {
+	reg, _ := s.handler(job.Type)
+	s.logger.Info(fmt.Sprintf("job %s (%s) started", job.ID, job.Type))
+
+	startedAt := time.Now()
+	stop := s.keepAlive(ctx, job.ID)
+	jobCtx := context.WithValue(ctx, progressKey{}, func(processed, total int64) {
+		if err := s.repo.SetProgress(ctx, job.ID, processed, total); err != nil {
+			s.logger.Warn(err.Error())
+		}
+	})
+	result, runErr := s.call(jobCtx, reg.handler, job)
+	stop()
+	duration := time.Since(startedAt)
+
+	// A job interrupted by shutdown stays running and is requeued once stale
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	status := StatusSucceeded
+	var resultJSON, errMsg *string
+	if runErr != nil {
+		status = StatusFailed
+		msg := runErr.Error()
+		errMsg = &msg
+		s.logger.Error(fmt.Sprintf("job %s (%s) failed: %v", job.ID, job.Type, runErr))
+	} else {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode job result: %w", err)
+		}
+		value := string(encoded)
+		resultJSON = &value
+		s.logger.Info(fmt.Sprintf("job %s (%s) succeeded", job.ID, job.Type))
+	}
+	s.recorder.ObserveJob(job.Type, status, duration)
+
+	return s.repo.Finish(ctx, job.ID, status, resultJSON, errMsg, reg.sensitiveParams)
+}
+
+func (s *service) call(ctx context.Context, handler Handler, job *Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	var params json.RawMessage
+	if job.Params != nil {
+		params = json.RawMessage(*job.Params)
+	}
+	return handler(ctx, params)
+}
+
+// keepAlive touches the job until the returned stop function is called
+func (s *service) keepAlive(ctx context.Context, id string) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(touchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Touch(ctx, id); err != nil {
+					s.logger.Warn(err.Error())
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (s *service) handler(jobType string) (registration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reg, ok := s.handlers[jobType]
+	return reg, ok
+}
+
+func (s *service) types() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	types := make([]string, 0, len(s.handlers))
+	for jobType := range s.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func isAdmin(ctx context.Context) bool {
+	role, ok := middleware.GetUserRole(ctx)
+	return ok && role == users.RoleAdmin
+}
+
+func toEntity(job *Job) Entity {
+	entity := Entity{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Processed != nil && job.Total != nil {
+		entity.Progress = &Progress{Processed: *job.Processed, Total: *job.Total}
+	}
+	if job.Result != nil {
+		entity.Result = json.RawMessage(*job.Result)
+	}
+	return entity
+}
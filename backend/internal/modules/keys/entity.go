@@ -0,0 +1,16 @@
+package keys
+
+import "github.com/lib/pq"
+
+type Key struct {
+	ID             string         `db:"id"`
+	ProjectID      string         `db:"project_id"`
+	Name           string         `db:"name"`
+	PublicKey      string         `db:"public_key"`
+	Scope          string         `db:"scope"`
+	AllowedOrigins pq.StringArray `db:"allowed_origins"`
+	ExpiresAt      *int64         `db:"expires_at"`
+	RevokedAt      *int64         `db:"revoked_at"`
+	LastUsedAt     *int64         `db:"last_used_at"`
+	CreatedAt      int64          `db:"created_at"`
+}
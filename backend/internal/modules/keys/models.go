@@ -0,0 +1,62 @@
+package keys
+
+import "context"
+
+const (
+	ScopeAll    = "all"
+	ScopeLogs   = "logs"
+	ScopeErrors = "errors"
+	// The kinds sent by SDKs next to errors have scopes of their own, an errors key can not send them
+	ScopeSessions = "sessions"
+	ScopeCheckins = "checkins"
+	ScopeTraces   = "traces"
+
+	StatusActive   = "active"
+	StatusExpiring = "expiring"
+	StatusExpired  = "expired"
+	StatusRevoked  = "revoked"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type Create struct {
+	Name  string `json:"name" validate:"required,max=255" example:"Frontend"`
+	Scope string `json:"scope" validate:"omitempty,oneof=all logs errors sessions checkins traces" example:"errors"`
+	// AllowedOrigins restricts browser requests to these origins, empty allows any
+	AllowedOrigins []string `json:"allowedOrigins" validate:"max=50" example:"https://app.example.com"`
+}
+
+type Update struct {
+	Name           string   `json:"name" validate:"required,max=255" example:"Frontend"`
+	Scope          string   `json:"scope" validate:"required,oneof=all logs errors sessions checkins traces" example:"errors"`
+	AllowedOrigins []string `json:"allowedOrigins" validate:"max=50" example:"https://app.example.com"`
+}
+
+type Rotate struct {
+	// OverlapHours is how long the old key keeps working, 0 revokes it at once
+	OverlapHours int `json:"overlapHours" validate:"min=0,max=720" example:"24"`
+}
+
+type Entity struct {
+	ID             string   `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Name           string   `json:"name" example:"Frontend"`
+	PublicKey      string   `json:"publicKey" example:"dGhpcyBpcyBub3QgYSByZWFsIGtleQ"`
+	Dsn            string   `json:"dsn" example:"https://duckbug.io/api/ingest/a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c:dGhpcyBpcyBub3QgYSByZWFsIGtleQ"`
+	Scope          string   `json:"scope" example:"all"`
+	AllowedOrigins []string `json:"allowedOrigins" example:"https://app.example.com"`
+	// Status is active, expiring (rotated, works until expiresAt), expired or revoked
+	Status     string `json:"status" example:"active"`
+	ExpiresAt  *int64 `json:"expiresAt,omitempty" example:"1700086400"`
+	RevokedAt  *int64 `json:"revokedAt,omitempty"`
+	LastUsedAt *int64 `json:"lastUsedAt,omitempty" example:"1700000000"`
+	CreatedAt  int64  `json:"createdAt" example:"1700000000"`
+}
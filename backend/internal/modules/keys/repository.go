@@ -0,0 +1,147 @@
+package keys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotFound = errors.New("not found")
+
+const keyColumns = `id, project_id, name, public_key, scope, allowed_origins, expires_at, revoked_at, last_used_at, created_at`
+
+type Repository interface {
+	GetAll(ctx context.Context, projectID string) ([]*Key, error)
+	GetByID(ctx context.Context, projectID, id string) (*Key, error)
+	Create(ctx context.Context, key *Key) error
+	Update(ctx context.Context, key *Key) error
+	// Rotate stores the replacement and lets the old key expire at expiresAt, in one transaction
+	Rotate(ctx context.Context, old *Key, replacement *Key, expiresAt int64) error
+	Revoke(ctx context.Context, projectID, id string, revokedAt int64) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetAll(ctx context.Context, projectID string) ([]*Key, error) {
+	query := `SELECT ` + keyColumns + ` FROM project_keys WHERE project_id = $1 ORDER BY created_at, id`
+
+	var keys []*Key
+	err := r.db.SelectContext(ctx, &keys, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, projectID, id string) (*Key, error) {
+	query := `SELECT ` + keyColumns + ` FROM project_keys WHERE project_id = $1 AND id = $2`
+
+	var key Key
+	err := r.db.GetContext(ctx, &key, query, projectID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get project key: %w", err)
+	}
+	return &key, nil
+}
+
+const insertQuery = `
+	INSERT INTO project_keys (id, project_id, name, public_key, scope, allowed_origins, created_at)
+	VALUES (:id, :project_id, :name, :public_key, :scope, :allowed_origins, :created_at)`
+
+func (r *repository) Create(ctx context.Context, key *Key) error {
+	r.logger.DebugContext(ctx, "sql query", "query", insertQuery)
+
+	_, err := r.db.NamedExecContext(ctx, insertQuery, key)
+	if err != nil {
+		return fmt.Errorf("failed to create project key: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Update(ctx context.Context, key *Key) error {
+	const query = `
+		UPDATE project_keys SET name = :name, scope = :scope, allowed_origins = :allowed_origins
+		WHERE project_id = :project_id AND id = :id`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.NamedExecContext(ctx, query, key)
+	if err != nil {
+		return fmt.Errorf("failed to update project key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) Rotate(ctx context.Context, old *Key, replacement *Key, expiresAt int64) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.NamedExecContext(ctx, insertQuery, replacement); err != nil {
+		return fmt.Errorf("failed to create project key: %w", err)
+	}
+
+	// An earlier expiry of a key rotated twice is kept
+	const expireQuery = `
+		UPDATE project_keys SET expires_at = LEAST(COALESCE(expires_at, $1), $1)
+		WHERE project_id = $2 AND id = $3`
+	if _, err = tx.ExecContext(ctx, expireQuery, expiresAt, old.ProjectID, old.ID); err != nil {
+		return fmt.Errorf("failed to expire project key: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Revoke(ctx context.Context, projectID, id string, revokedAt int64) error {
+	const query = `UPDATE project_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE project_id = $2 AND id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, revokedAt, projectID, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke project key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
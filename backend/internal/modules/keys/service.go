@@ -0,0 +1,244 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/google/uuid"
+)
+
+const keyLength = 64
+
+var (
+	ErrInactive      = errors.New("key is revoked or expired")
+	ErrInvalidOrigin = errors.New("allowed origins must be http(s)://host[:port]")
+)
+
+// Invalidator drops cached keys of a project, so changes apply to ingest at once
+type Invalidator interface {
+	Invalidate(projectID string)
+}
+
+// auditTarget names ingest keys in the audit log
+const auditTarget = "key"
+
+type Service interface {
+	GetAll(ctx context.Context, projectID string) ([]Entity, error)
+	Create(ctx context.Context, projectID string, req *Create) (*Entity, error)
+	Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error)
+	// Rotate replaces a key by a new one with the same settings, the old key works during the overlap
+	Rotate(ctx context.Context, projectID, id string, req *Rotate) (*Entity, error)
+	Revoke(ctx context.Context, projectID, id string) error
+}
+
+type service struct {
+	repo        Repository
+	invalidator Invalidator
+	logger      Logger
+	domain      string
+}
+
+func NewService(repo Repository, invalidator Invalidator, logger Logger, domain string) Service {
+	return &service{
+		repo:        repo,
+		invalidator: invalidator,
+		logger:      logger,
+		domain:      domain,
+	}
+}
+
+func (s *service) GetAll(ctx context.Context, projectID string) ([]Entity, error) {
+	keys, err := s.repo.GetAll(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	entities := make([]Entity, 0, len(keys))
+	for _, key := range keys {
+		entities = append(entities, s.toEntity(key, now))
+	}
+	return entities, nil
+}
+
+func (s *service) Create(ctx context.Context, projectID string, req *Create) (*Entity, error) {
+	key, err := newKey(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Name = req.Name
+	key.Scope = req.Scope
+	if key.Scope == "" {
+		key.Scope = ScopeAll
+	}
+	key.AllowedOrigins, err = normalizeOrigins(req.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "key.create", auditTarget, key.ID, nil, toAuditState(key))
+
+	entity := s.toEntity(key, time.Now().Unix())
+	return &entity, nil
+}
+
+func (s *service) Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error) {
+	key, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := toAuditState(key)
+	key.Name = req.Name
+	key.Scope = req.Scope
+	key.AllowedOrigins, err = normalizeOrigins(req.AllowedOrigins)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+	s.invalidator.Invalidate(projectID)
+
+	middleware.Audit(ctx, "key.update", auditTarget, key.ID, before, toAuditState(key))
+
+	entity := s.toEntity(key, time.Now().Unix())
+	return &entity, nil
+}
+
+func (s *service) Rotate(ctx context.Context, projectID, id string, req *Rotate) (*Entity, error) {
+	old, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if current := status(old, now.Unix()); current == StatusRevoked || current == StatusExpired {
+		return nil, ErrInactive
+	}
+
+	replacement, err := newKey(projectID)
+	if err != nil {
+		return nil, err
+	}
+	replacement.Name = old.Name
+	replacement.Scope = old.Scope
+	replacement.AllowedOrigins = old.AllowedOrigins
+
+	expiresAt := now.Add(time.Duration(req.OverlapHours) * time.Hour).Unix()
+	if err := s.repo.Rotate(ctx, old, replacement, expiresAt); err != nil {
+		return nil, err
+	}
+	s.invalidator.Invalidate(projectID)
+
+	s.logger.InfoContext(ctx, "ingest key rotated", "key_id", old.ID, "project_id", projectID, "expires_at", expiresAt)
+	middleware.Audit(ctx, "key.rotate", auditTarget, old.ID, toAuditState(old), toAuditState(replacement))
+
+	entity := s.toEntity(replacement, now.Unix())
+	return &entity, nil
+}
+
+func (s *service) Revoke(ctx context.Context, projectID, id string) error {
+	if err := s.repo.Revoke(ctx, projectID, id, time.Now().Unix()); err != nil {
+		return err
+	}
+	s.invalidator.Invalidate(projectID)
+
+	s.logger.InfoContext(ctx, "ingest key revoked", "key_id", id, "project_id", projectID)
+	middleware.Audit(ctx, "key.revoke", auditTarget, id, nil, nil)
+	return nil
+}
+
+func (s *service) toEntity(key *Key, now int64) Entity {
+	origins := []string(key.AllowedOrigins)
+	if origins == nil {
+		origins = []string{}
+	}
+
+	return Entity{
+		ID:             key.ID,
+		Name:           key.Name,
+		PublicKey:      key.PublicKey,
+		Dsn:            "https://" + s.domain + "/api/ingest/" + key.ProjectID + ":" + key.PublicKey,
+		Scope:          key.Scope,
+		AllowedOrigins: origins,
+		Status:         status(key, now),
+		ExpiresAt:      key.ExpiresAt,
+		RevokedAt:      key.RevokedAt,
+		LastUsedAt:     key.LastUsedAt,
+		CreatedAt:      key.CreatedAt,
+	}
+}
+
+func status(key *Key, now int64) string {
+	switch {
+	case key.RevokedAt != nil:
+		return StatusRevoked
+	case key.ExpiresAt != nil && *key.ExpiresAt <= now:
+		return StatusExpired
+	case key.ExpiresAt != nil:
+		return StatusExpiring
+	default:
+		return StatusActive
+	}
+}
+
+func newKey(projectID string) (*Key, error) {
+	b := make([]byte, keyLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return &Key{
+		ID:             uuid.New().String(),
+		ProjectID:      projectID,
+		PublicKey:      base64.RawURLEncoding.EncodeToString(b),
+		Scope:          ScopeAll,
+		AllowedOrigins: []string{},
+		CreatedAt:      time.Now().Unix(),
+	}, nil
+}
+
+// normalizeOrigins parses origins the way ingest compares them, see httputils.ParseOrigin
+func normalizeOrigins(origins []string) ([]string, error) {
+	result := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		normalized, err := httputils.ParseOrigin(origin)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOrigin, origin)
+		}
+		result = append(result, normalized)
+	}
+	return result, nil
+}
+
+// auditState is what the audit log keeps of a key, the public key is a secret and left out
+type auditState struct {
+	ID             string   `json:"id"`
+	ProjectID      string   `json:"projectId"`
+	Name           string   `json:"name"`
+	Scope          string   `json:"scope"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+func toAuditState(key *Key) *auditState {
+	return &auditState{
+		ID:             key.ID,
+		ProjectID:      key.ProjectID,
+		Name:           key.Name,
+		Scope:          key.Scope,
+		AllowedOrigins: key.AllowedOrigins,
+	}
+}
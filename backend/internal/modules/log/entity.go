@@ -1,5 +1,7 @@
 package log
 
+import "github.com/duckbugio/duckbug/pkg/histogram"
+
 type Level string
 
 const (
@@ -17,7 +19,14 @@ type Log struct {
 	Level       Level   `db:"level"`
 	Message     string  `db:"message"`
 	Context     *string `db:"context"`
+	Release     *string `db:"release"`
 	Time        int64   `db:"time"`
 	CreatedAt   int64   `db:"created_at"`
 	UpdatedAt   int64   `db:"updated_at"`
+	// Rank and Highlight are only selected by full-text search queries
+	Rank      *float64 `db:"rank"`
+	Highlight *string  `db:"highlight"`
 }
+
+// HistogramRow is a single (bucket, series) counter returned by histogram queries
+type HistogramRow = histogram.Row
@@ -1,12 +1,41 @@
 package log
 
+import (
+	"context"
+
+	"github.com/duckbugio/duckbug/pkg/histogram"
+	"github.com/duckbugio/duckbug/pkg/pagination"
+)
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
+const (
+	// SearchModeSubstring matches the search string anywhere in the message (ILIKE).
+	SearchModeSubstring = "substring"
+	// SearchModeFullText matches the search query against the full-text search vector.
+	SearchModeFullText = "fulltext"
+)
+
+const (
+	OrderByTime      = "time"
+	OrderByRelevance = "relevance"
+)
+
+const (
+	HistogramSplitLevel   = "level"
+	HistogramSplitGroup   = "group"
+	HistogramSplitRelease = "release"
+)
+
 type FilterParams struct {
 	ProjectID   string
 	Fingerprint string
@@ -14,13 +43,27 @@ type FilterParams struct {
 	TimeTo      int64
 	Level       string
 	Search      string
+	SearchMode  string
 }
 
 type GetAllParams struct {
 	FilterParams
 	SortOrder string `validate:"omitempty,oneof=asc desc"`
+	OrderBy   string `validate:"omitempty,oneof=time relevance"`
 	Limit     int
 	Offset    int
+	// Keyset switches to cursor pagination over (time, id), Cursor is nil for the first page
+	Keyset bool
+	Cursor *pagination.Cursor
+}
+
+// Page is a cursor paginated slice of entities
+type Page struct {
+	Items          []*Entity
+	Count          *int
+	CountEstimated bool
+	NextCursor     string
+	PrevCursor     string
 }
 
 type Create struct {
@@ -40,6 +83,7 @@ type Create struct {
 	//   example={"key":"value"}
 	// )
 	Context   *interface{} `json:"context"`
+	Release   *string      `json:"release,omitempty" example:"1.4.2"`
 	ProjectID string       `json:"-"`
 }
 
@@ -61,6 +105,9 @@ type Update struct {
 	Context *interface{} `json:"context"`
 }
 
+// ExportColumns are the CSV columns of an export which does not select its own
+var ExportColumns = []string{"id", "time", "level", "message", "release", "context"}
+
 type Entity struct {
 	ID      string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 	Level   string `json:"level" example:"INFO"`
@@ -78,7 +125,10 @@ type Entity struct {
 	//   example={"key":"value"}
 	// )
 	Context *interface{} `json:"context"`
+	Release *string      `json:"release" example:"1.4.2"`
 	Time    int64        `json:"time" example:"1704067200000"`
+	// Highlight is a message snippet with matched terms wrapped in <mark> tags, set for full-text search only
+	Highlight *string `json:"highlight,omitempty" example:"connection <mark>timeout</mark> on db-1"`
 }
 
 type EntityList struct {
@@ -86,6 +136,24 @@ type EntityList struct {
 	Items []Entity `json:"items"`
 }
 
+type EntityPage struct {
+	Count          *int     `json:"count,omitempty" example:"1200"`
+	CountEstimated bool     `json:"countEstimated,omitempty" example:"false"`
+	Items          []Entity `json:"items"`
+	NextCursor     string   `json:"nextCursor,omitempty" example:"bjoxNzA0MDY3MjAwMDAwOmEwODkyOWI1"`
+	PrevCursor     string   `json:"prevCursor,omitempty" example:"cDoxNzA0MDY3MjAwMDAwOmEwODkyOWI1"`
+}
+
+type HistogramParams struct {
+	FilterParams
+	// Interval is the bucket size in milliseconds
+	Interval    int64
+	SplitBy     string `validate:"omitempty,oneof=level group release"`
+	SeriesLimit int
+}
+
+type Histogram = histogram.Histogram
+
 type Stats struct {
 	Last24h int `json:"last24h"`
 	Last7d  int `json:"last7d"`
@@ -3,11 +3,14 @@ package log
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	loggroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	"github.com/duckbugio/duckbug/internal/tracing"
+	"github.com/duckbugio/duckbug/pkg/pagination"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -15,15 +18,25 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
+// headlineOptions limits ts_headline output to a couple of short fragments around the matches
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=10, MaxFragments=2"
+
 type Repository interface {
 	GetAll(ctx context.Context, params GetAllParams) ([]*Log, error)
+	// Scan calls fn for the logs matching the filters in the order of GetAll, without paging.
+	// Rows are read as the database sends them, so the result is never held in memory.
+	Scan(ctx context.Context, params GetAllParams, fn func(*Log) error) error
 	Count(ctx context.Context, params FilterParams) (int, error)
+	EstimateCount(ctx context.Context, params FilterParams) (int, error)
 	GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error)
+	GetHistogram(ctx context.Context, params HistogramParams) ([]*HistogramRow, error)
 	BatchGetStatsByProjectIDs(ctx context.Context, projectIDs []string) (map[string]*Stats, error)
 	GetByID(ctx context.Context, id string) (*Log, error)
 	Create(ctx context.Context, log *Log) error
 	Update(ctx context.Context, id string, log *Log) error
 	Delete(ctx context.Context, id string) error
+	// DeleteMatching deletes up to limit logs matching params and returns the fingerprints of the deleted rows
+	DeleteMatching(ctx context.Context, params FilterParams, limit int) ([]string, error)
 }
 
 type repository struct {
@@ -39,22 +52,47 @@ func NewRepository(db *sqlx.DB, logger Logger) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Log, error) {
+	fullText := isFullTextSearch(params.FilterParams)
+
 	query := `
-        SELECT id, project_id, level, message, context, time, created_at, updated_at 
+        SELECT id, project_id, level, message, context, release, time, created_at, updated_at 
         FROM logs 
         WHERE 1=1
     `
 
+	if fullText {
+		query = `
+        SELECT
+            id, project_id, level, message, context, release, time, created_at, updated_at,
+            ts_rank_cd(search_vector, websearch_to_tsquery('simple', :search)) AS rank
+        FROM logs
+        WHERE 1=1
+    `
+	}
+
 	args := map[string]interface{}{
 		"limit":  params.Limit,
 		"offset": params.Offset,
 	}
 
 	query, args = applyFilters(query, params.FilterParams, args)
+	query, args = applyCursor(query, params, args)
 
-	query += " ORDER BY time " + params.SortOrder
+	orderBy := orderByClause(params)
+	query += orderBy
 	query += " LIMIT :limit OFFSET :offset"
 
+	if fullText {
+		// Headlines are expensive, so they are built only for the rows of the requested page
+		query = `
+        SELECT
+            page.*,
+            ts_headline('simple', page.message || ' ' || left(coalesce(page.context, ''), 4096),
+                websearch_to_tsquery('simple', :search), :headlineOptions) AS highlight
+        FROM (` + query + `) page` + orderBy
+		args["headlineOptions"] = headlineOptions
+	}
+
 	query, namedArgs, err := sqlx.Named(query, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare named query: %w", err)
@@ -62,7 +100,7 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Log, e
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var logs []*Log
 	err = r.db.SelectContext(ctx, &logs, query, namedArgs...)
@@ -72,6 +110,47 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Log, e
 	return logs, nil
 }
 
+func (r *repository) Scan(ctx context.Context, params GetAllParams, fn func(*Log) error) error {
+	query, args := applyFilters(`
+        SELECT id, project_id, fingerprint, level, message, context, release, time, created_at, updated_at
+        FROM logs
+        WHERE 1=1
+    `, params.FilterParams, make(map[string]interface{}))
+	query += " ORDER BY time " + params.SortOrder + ", id " + params.SortOrder
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	sqlRows, err := r.db.QueryContext(ctx, query, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read logs: %w", err)
+	}
+	defer func() {
+		_ = sqlRows.Close()
+	}()
+
+	rows := &sqlx.Rows{Rows: sqlRows, Mapper: r.db.Mapper}
+	for rows.Next() {
+		var l Log
+		if err = rows.StructScan(&l); err != nil {
+			return fmt.Errorf("failed to scan logs: %w", err)
+		}
+		if err = fn(&l); err != nil {
+			return err
+		}
+	}
+	if err = sqlRows.Err(); err != nil {
+		return fmt.Errorf("failed to read logs: %w", err)
+	}
+	return nil
+}
+
 func (r *repository) Count(ctx context.Context, params FilterParams) (int, error) {
 	query := "SELECT COUNT(*) FROM logs WHERE 1=1"
 	query, args := applyFilters(query, params, make(map[string]interface{}))
@@ -83,7 +162,7 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var count int
 	err = r.db.GetContext(ctx, &count, query, namedArgs...)
@@ -94,6 +173,41 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 	return count, nil
 }
 
+// EstimateCount returns the planner row estimate for the filters, which avoids scanning large tables
+func (r *repository) EstimateCount(ctx context.Context, params FilterParams) (int, error) {
+	query := "EXPLAIN (FORMAT JSON) SELECT 1 FROM logs WHERE 1=1"
+	query, args := applyFilters(query, params, make(map[string]interface{}))
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var plan []byte
+	err = r.db.GetContext(ctx, &plan, query, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate logs count: %w", err)
+	}
+
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	if len(explain) == 0 {
+		return 0, errors.New("failed to parse query plan: empty plan")
+	}
+
+	return int(explain[0].Plan.Rows), nil
+}
+
 func (r *repository) GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error) {
 	// Precompute thresholds in ms to help planner use range conditions on indexed column "time"
 	nowMs := time.Now().UnixMilli()
@@ -129,7 +243,7 @@ func (r *repository) GetStats(ctx context.Context, projectID string, fingerprint
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var stats struct {
 		Last24h int `db:"last_24h"`
@@ -185,7 +299,7 @@ func (r *repository) BatchGetStatsByProjectIDs(ctx context.Context, projectIDs [
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	type resultRow struct {
 		ProjectID string `db:"project_id"`
@@ -223,8 +337,53 @@ func (r *repository) BatchGetStatsByProjectIDs(ctx context.Context, projectIDs [
 	return result, nil
 }
 
+func (r *repository) GetHistogram(ctx context.Context, params HistogramParams) ([]*HistogramRow, error) {
+	series := "''"
+	switch params.SplitBy {
+	case HistogramSplitLevel:
+		series = "CAST(level AS text)"
+	case HistogramSplitGroup:
+		series = "fingerprint"
+	case HistogramSplitRelease:
+		series = "coalesce(release, '')"
+	}
+
+	query := `
+        SELECT
+            (time / :interval) * :interval AS bucket,
+            ` + series + ` AS series,
+            COUNT(*) AS count
+        FROM logs
+        WHERE 1=1
+    `
+
+	args := map[string]interface{}{
+		"interval": params.Interval,
+	}
+
+	query, args = applyFilters(query, params.FilterParams, args)
+
+	query += " GROUP BY bucket, series ORDER BY bucket"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var rows []*HistogramRow
+	err = r.db.SelectContext(ctx, &rows, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs histogram: %w", err)
+	}
+	return rows, nil
+}
+
 func (r *repository) GetByID(ctx context.Context, id string) (*Log, error) {
-	const query = `SELECT id, level, message, context, time, created_at, updated_at 
+	const query = `SELECT id, project_id, level, message, context, release, time, created_at, updated_at 
 		FROM logs WHERE id = $1`
 
 	var entity Log
@@ -238,7 +397,13 @@ func (r *repository) GetByID(ctx context.Context, id string) (*Log, error) {
 	return &entity, nil
 }
 
-func (r *repository) Create(ctx context.Context, l *Log) error {
+func (r *repository) Create(ctx context.Context, l *Log) (err error) {
+	// The group upsert and the insert share one transaction, row locks of hot groups show up here
+	ctx, span := tracer.Start(ctx, "log.repository.Create")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -247,7 +412,7 @@ func (r *repository) Create(ctx context.Context, l *Log) error {
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
-				r.logger.Warn(fmt.Sprintf("failed to rollback transaction: %v", rbErr))
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
 			}
 		}
 	}()
@@ -278,9 +443,9 @@ func (r *repository) Create(ctx context.Context, l *Log) error {
 
 	const query = `
 		INSERT INTO logs (
-	  		id, project_id, fingerprint, level, message, context, time, created_at, updated_at
+	  		id, project_id, fingerprint, level, message, context, release, time, created_at, updated_at
 		) VALUES (
-	  		:id, :project_id, :fingerprint, :level, :message, :context, :time, :created_at, :updated_at
+	  		:id, :project_id, :fingerprint, :level, :message, :context, :release, :time, :created_at, :updated_at
 		)
 	`
 
@@ -353,6 +518,27 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *repository) DeleteMatching(ctx context.Context, params FilterParams, limit int) ([]string, error) {
+	query, args := applyFilters("SELECT id, time FROM logs WHERE 1=1", params, map[string]interface{}{"limit": limit})
+	query = "DELETE FROM logs WHERE (id, time) IN (" + query + " LIMIT :limit) RETURNING COALESCE(fingerprint, '')"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var fingerprints []string
+	err = r.db.SelectContext(ctx, &fingerprints, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete logs: %w", err)
+	}
+	return fingerprints, nil
+}
+
 func applyFilters(baseQuery string, params FilterParams, args map[string]interface{}) (string, map[string]interface{}) {
 	query := baseQuery
 
@@ -381,10 +567,43 @@ func applyFilters(baseQuery string, params FilterParams, args map[string]interfa
 		args["level"] = params.Level
 	}
 
-	if params.Search != "" {
+	if isFullTextSearch(params) {
+		query += " AND search_vector @@ websearch_to_tsquery('simple', :search)"
+		args["search"] = params.Search
+	} else if params.Search != "" {
 		query += " AND message ILIKE :search"
 		args["search"] = "%" + params.Search + "%"
 	}
 
 	return query, args
 }
+
+func isFullTextSearch(params FilterParams) bool {
+	return params.SearchMode == SearchModeFullText && params.Search != ""
+}
+
+func applyCursor(baseQuery string, params GetAllParams, args map[string]interface{}) (string, map[string]interface{}) {
+	if !params.Keyset || params.Cursor == nil {
+		return baseQuery, args
+	}
+
+	_, operator := pagination.KeysetOrder(params.SortOrder, params.Cursor)
+
+	query := baseQuery + " AND (time, id) " + operator + " (:cursorTime, CAST(:cursorId AS uuid))"
+	args["cursorTime"] = params.Cursor.Time
+	args["cursorId"] = params.Cursor.ID
+
+	return query, args
+}
+
+func orderByClause(params GetAllParams) string {
+	if params.Keyset {
+		direction, _ := pagination.KeysetOrder(params.SortOrder, params.Cursor)
+		return " ORDER BY time " + direction + ", id " + direction
+	}
+
+	if params.OrderBy == OrderByRelevance && isFullTextSearch(params.FilterParams) {
+		return " ORDER BY rank DESC, time " + params.SortOrder
+	}
+	return " ORDER BY time " + params.SortOrder
+}
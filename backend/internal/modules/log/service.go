@@ -7,30 +7,58 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 
+	"github.com/duckbugio/duckbug/internal/modules/scrubbing"
+	"github.com/duckbugio/duckbug/internal/tracing"
+	"github.com/duckbugio/duckbug/pkg/histogram"
+	"github.com/duckbugio/duckbug/pkg/pagination"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var ErrInvalidLogLevel = errors.New("invalid log level")
+var tracer = otel.Tracer("github.com/duckbugio/duckbug/internal/modules/log")
+
+var (
+	ErrInvalidLogLevel = errors.New("invalid log level")
+	ErrTooManyBuckets  = errors.New("too many histogram buckets, use a larger interval")
+)
+
+const (
+	maxHistogramBuckets = 1500
+)
 
 type Service interface {
 	GetByID(ctx context.Context, id string) (*Entity, error)
 	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
+	// Export calls fn with every entity matching the filters of params, paging is ignored
+	Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error
+	GetPage(ctx context.Context, params GetAllParams, countMode string) (*Page, error)
 	GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error)
+	GetHistogram(ctx context.Context, params HistogramParams) (*Histogram, error)
 	Create(ctx context.Context, req *Create) (*Entity, error)
 	Update(ctx context.Context, id string, req *Update) (*Entity, error)
 	Delete(ctx context.Context, id string) error
 }
 
+// Scrubber provides the rules that filter sensitive data out of incoming events
+type Scrubber interface {
+	ForProject(ctx context.Context, projectID string) (*scrubbing.Scrubber, error)
+}
+
 type service struct {
-	repo   Repository
-	logger Logger
+	repo     Repository
+	logger   Logger
+	scrubber Scrubber
 }
 
-func NewService(repo Repository, logger Logger) Service {
+func NewService(repo Repository, logger Logger, scrubber Scrubber) Service {
 	return &service{
-		repo:   repo,
-		logger: logger,
+		repo:     repo,
+		logger:   logger,
+		scrubber: scrubber,
 	}
 }
 
@@ -61,6 +89,70 @@ func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]*Entity, i
 	return responses, total, nil
 }
 
+func (s *service) Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error {
+	return s.repo.Scan(ctx, params, func(l *Log) error {
+		return fn(toResponse(l))
+	})
+}
+
+func (s *service) GetPage(ctx context.Context, params GetAllParams, countMode string) (*Page, error) {
+	pageSize := params.Limit
+	backward := params.Cursor != nil && params.Cursor.Backward
+
+	params.Keyset = true
+	params.Limit = pageSize + 1
+
+	logs, err := s.repo.GetAll(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(logs) > pageSize
+	if hasMore {
+		logs = logs[:pageSize]
+	}
+
+	if backward {
+		slices.Reverse(logs)
+	}
+
+	page := &Page{
+		Items: make([]*Entity, 0, len(logs)),
+	}
+	for _, entity := range logs {
+		page.Items = append(page.Items, toResponse(entity))
+	}
+
+	if len(logs) > 0 {
+		first, last := logs[0], logs[len(logs)-1]
+		// Walking backward always leaves the page we came from after the current one and vice versa
+		if hasMore || backward {
+			page.NextCursor = pagination.Cursor{Time: last.Time, ID: last.ID}.Encode()
+		}
+		if (backward && hasMore) || (!backward && params.Cursor != nil) {
+			page.PrevCursor = pagination.Cursor{Time: first.Time, ID: first.ID, Backward: true}.Encode()
+		}
+	}
+
+	switch countMode {
+	case pagination.CountExact:
+		total, err := s.repo.Count(ctx, params.FilterParams)
+		if err != nil {
+			return nil, err
+		}
+		page.Count = &total
+	case pagination.CountEstimated:
+		total, err := s.repo.EstimateCount(ctx, params.FilterParams)
+		if err != nil {
+			return nil, err
+		}
+		page.Count = &total
+		page.CountEstimated = true
+	}
+
+	return page, nil
+}
+
 func (s *service) GetStats(ctx context.Context, projectID string, fingerprint string) (*Stats, error) {
 	stats, err := s.repo.GetStats(ctx, projectID, fingerprint)
 	if err != nil {
@@ -70,11 +162,41 @@ func (s *service) GetStats(ctx context.Context, projectID string, fingerprint st
 	return stats, nil
 }
 
-func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
+func (s *service) GetHistogram(ctx context.Context, params HistogramParams) (*Histogram, error) {
+	// Align the range to bucket boundaries so buckets are stable between requests
+	params.TimeFrom -= params.TimeFrom % params.Interval
+	if (params.TimeTo-params.TimeFrom)/params.Interval >= maxHistogramBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	rows, err := s.repo.GetHistogram(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram.Build(histogram.Params{
+		TimeFrom:    params.TimeFrom,
+		TimeTo:      params.TimeTo,
+		Interval:    params.Interval,
+		SplitBy:     params.SplitBy,
+		SeriesLimit: params.SeriesLimit,
+	}, rows), nil
+}
+
+func (s *service) Create(ctx context.Context, req *Create) (_ *Entity, err error) {
+	ctx, span := tracer.Start(ctx, "log.Create", trace.WithAttributes(attribute.String("project.id", req.ProjectID)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	if !isValidLogLevel(req.Level) {
 		return nil, ErrInvalidLogLevel
 	}
 
+	if err := s.scrub(ctx, req); err != nil {
+		return nil, err
+	}
+
 	contextStr, err := contextToStringPtr(req.Context)
 	if err != nil {
 		return nil, err
@@ -86,10 +208,13 @@ func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
 		Level:     Level(req.Level),
 		Message:   req.Message,
 		Context:   contextStr,
+		Release:   req.Release,
 		Time:      req.Time,
 	}
 
+	_, fingerprintSpan := tracer.Start(ctx, "log.fingerprint")
 	log.Fingerprint = generateFingerprint(log)
+	fingerprintSpan.End()
 
 	if err := s.repo.Create(ctx, log); err != nil {
 		return nil, err
@@ -98,6 +223,28 @@ func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
 	return toResponse(log), nil
 }
 
+// scrub filters sensitive data out of the request in place, before anything is serialized or stored
+func (s *service) scrub(ctx context.Context, req *Create) error {
+	if s.scrubber == nil {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "log.scrub")
+	defer span.End()
+
+	scrubber, err := s.scrubber.ForProject(ctx, req.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to load scrubbing rules: %w", err)
+	}
+
+	req.Message = scrubber.String(req.Message)
+	if req.Context != nil {
+		*req.Context = scrubber.Value(*req.Context)
+	}
+
+	return nil
+}
+
 func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity, error) {
 	log, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -120,7 +267,9 @@ func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity,
 	}
 	log.Context = contextStr
 
+	_, fingerprintSpan := tracer.Start(ctx, "log.fingerprint")
 	log.Fingerprint = generateFingerprint(log)
+	fingerprintSpan.End()
 
 	if err := s.repo.Update(ctx, id, log); err != nil {
 		return nil, err
@@ -143,11 +292,16 @@ func isValidLogLevel(level string) bool {
 }
 
 func generateFingerprint(e *Log) string {
+	return Fingerprint(e.ProjectID, e.Level, e.Message)
+}
+
+// Fingerprint identifies the group of a log
+func Fingerprint(projectID string, level Level, message string) string {
 	data := fmt.Sprintf(
 		"%s:%s:%s",
-		e.ProjectID,
-		e.Level,
-		e.Message,
+		projectID,
+		level,
+		message,
 	)
 
 	hash := sha256.Sum256([]byte(data))
@@ -156,10 +310,12 @@ func generateFingerprint(e *Log) string {
 
 func toResponse(l *Log) *Entity {
 	response := &Entity{
-		ID:      l.ID,
-		Level:   string(l.Level),
-		Message: l.Message,
-		Time:    l.Time,
+		ID:        l.ID,
+		Level:     string(l.Level),
+		Message:   l.Message,
+		Release:   l.Release,
+		Time:      l.Time,
+		Highlight: l.Highlight,
 	}
 
 	if err := parseJSONField(l.Context, &response.Context); err != nil {
@@ -1,10 +1,16 @@
 package loggroup
 
+import "context"
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
 type FilterParams struct {
@@ -22,6 +28,9 @@ type GetAllParams struct {
 	Offset    int
 }
 
+// ExportColumns are the CSV columns of an export which does not select its own
+var ExportColumns = []string{"id", "level", "message", "counter", "firstSeenAt", "lastSeenAt"}
+
 type Entity struct {
 	ID          string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 	Level       string `json:"level" example:"INFO"`
@@ -7,14 +7,25 @@ import (
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var ErrNotFound = errors.New("not found")
 
 type Repository interface {
 	GetAll(ctx context.Context, params GetAllParams) ([]*Group, error)
+	// Scan calls fn for the log groups matching the filters in the order of GetAll, without paging.
+	// Rows are read as the database sends them, so the result is never held in memory.
+	Scan(ctx context.Context, params GetAllParams, fn func(*Group) error) error
 	Count(ctx context.Context, params FilterParams) (int, error)
 	GetByID(ctx context.Context, id string) (*Group, error)
+	// Decrement lowers the counters of groups by the number of their events removed and moves
+	// first_seen_at to the oldest remaining event
+	Decrement(ctx context.Context, removed map[string]int) error
+	// FindIDs returns up to limit ids of groups matching params after afterID, in the order of ids
+	FindIDs(ctx context.Context, params FilterParams, afterID string, limit int) ([]string, error)
+	// DeleteByIDs removes groups, their events are deleted separately
+	DeleteByIDs(ctx context.Context, ids []string) error
 }
 
 type repository struct {
@@ -53,7 +64,7 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Group,
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var entities []*Group
 	err = r.db.SelectContext(ctx, &entities, query, namedArgs...)
@@ -63,6 +74,47 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Group,
 	return entities, nil
 }
 
+func (r *repository) Scan(ctx context.Context, params GetAllParams, fn func(*Group) error) error {
+	query, args := applyFilters(`
+        SELECT id, project_id, level, message, first_seen_at, last_seen_at, counter, status
+        FROM log_groups
+        WHERE 1=1
+    `, params.FilterParams, make(map[string]interface{}))
+	query += " ORDER BY last_seen_at " + params.SortOrder + ", id " + params.SortOrder
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	sqlRows, err := r.db.QueryContext(ctx, query, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read log groups: %w", err)
+	}
+	defer func() {
+		_ = sqlRows.Close()
+	}()
+
+	rows := &sqlx.Rows{Rows: sqlRows, Mapper: r.db.Mapper}
+	for rows.Next() {
+		var g Group
+		if err = rows.StructScan(&g); err != nil {
+			return fmt.Errorf("failed to scan log groups: %w", err)
+		}
+		if err = fn(&g); err != nil {
+			return err
+		}
+	}
+	if err = sqlRows.Err(); err != nil {
+		return fmt.Errorf("failed to read log groups: %w", err)
+	}
+	return nil
+}
+
 func (r *repository) Count(ctx context.Context, params FilterParams) (int, error) {
 	query := "SELECT COUNT(*) FROM log_groups WHERE 1=1"
 	query, args := applyFilters(query, params, make(map[string]interface{}))
@@ -74,7 +126,7 @@ func (r *repository) Count(ctx context.Context, params FilterParams) (int, error
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var count int
 	err = r.db.GetContext(ctx, &count, query, namedArgs...)
@@ -100,6 +152,77 @@ func (r *repository) GetByID(ctx context.Context, id string) (*Group, error) {
 	return &entity, nil
 }
 
+func (r *repository) Decrement(ctx context.Context, removed map[string]int) error {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(removed))
+	counts := make([]int64, 0, len(removed))
+	for id, count := range removed {
+		ids = append(ids, id)
+		counts = append(counts, int64(count))
+	}
+
+	// Groups store seconds, events milliseconds. The oldest remaining event is a single step
+	// on the (project_id, fingerprint, time) index.
+	const query = `
+		UPDATE log_groups g
+		SET counter = GREATEST(g.counter - d.removed, 0),
+		    first_seen_at = COALESCE((
+		        SELECT CAST(l.time / 1000 AS INTEGER) FROM logs l
+		        WHERE l.project_id = g.project_id AND l.fingerprint = CAST(g.id AS varchar)
+		        ORDER BY l.time
+		        LIMIT 1
+		    ), g.first_seen_at)
+		FROM unnest(CAST($1 AS bpchar[]), CAST($2 AS bigint[])) AS d(id, removed)
+		WHERE g.id = d.id`
+
+	_, err := r.db.ExecContext(ctx, query, pq.StringArray(ids), pq.Int64Array(counts))
+	if err != nil {
+		return fmt.Errorf("failed to decrement log groups: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) FindIDs(ctx context.Context, params FilterParams, afterID string, limit int) ([]string, error) {
+	query, args := applyFilters("SELECT id FROM log_groups WHERE id > :afterId", params, map[string]interface{}{
+		"afterId": afterID,
+		"limit":   limit,
+	})
+	query += " ORDER BY id LIMIT :limit"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var ids []string
+	err = r.db.SelectContext(ctx, &ids, query, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find log groups: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *repository) DeleteByIDs(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `DELETE FROM log_groups WHERE id = ANY(CAST($1 AS bpchar[]))`
+
+	_, err := r.db.ExecContext(ctx, query, pq.StringArray(ids))
+	if err != nil {
+		return fmt.Errorf("failed to delete log groups: %w", err)
+	}
+	return nil
+}
+
 func applyFilters(baseQuery string, params FilterParams, args map[string]interface{}) (string, map[string]interface{}) {
 	query := baseQuery
 
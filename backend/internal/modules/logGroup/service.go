@@ -5,6 +5,8 @@ import "context"
 type Service interface {
 	GetByID(ctx context.Context, id string) (*Entity, error)
 	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
+	// Export calls fn with every entity matching the filters of params, paging is ignored
+	Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error
 }
 
 type service struct {
@@ -45,6 +47,12 @@ func (s *service) GetAll(ctx context.Context, params GetAllParams) ([]*Entity, i
 	return responses, total, nil
 }
 
+func (s *service) Export(ctx context.Context, params GetAllParams, fn func(*Entity) error) error {
+	return s.repo.Scan(ctx, params, func(g *Group) error {
+		return fn(toResponse(g))
+	})
+}
+
 func toResponse(g *Group) *Entity {
 	return &Entity{
 		ID:          g.ID,
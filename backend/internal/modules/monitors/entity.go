@@ -0,0 +1,39 @@
+package monitors
+
+type Monitor struct {
+	ID                string `db:"id"`
+	ProjectID         string `db:"project_id"`
+	Slug              string `db:"slug"`
+	Name              string `db:"name"`
+	ScheduleType      string `db:"schedule_type"`
+	Schedule          string `db:"schedule"`
+	Timezone          string `db:"timezone"`
+	GraceMinutes      int    `db:"grace_minutes"`
+	MaxRuntimeMinutes *int   `db:"max_runtime_minutes"`
+	Paused            bool   `db:"paused"`
+	Status            string `db:"status"`
+	NextExpectedAt    int64  `db:"next_expected_at"`
+	LastCheckinAt     *int64 `db:"last_checkin_at"`
+	CreatedAt         int64  `db:"created_at"`
+	UpdatedAt         int64  `db:"updated_at"`
+}
+
+type Checkin struct {
+	ID        string `db:"id"`
+	MonitorID string `db:"monitor_id"`
+	ProjectID string `db:"project_id"`
+	Status    string `db:"status"`
+	// ExpectedAt is the scheduled time of a missed run
+	ExpectedAt *int64  `db:"expected_at"`
+	DurationMs *int64  `db:"duration_ms"`
+	ErrorID    *string `db:"error_id"`
+	CreatedAt  int64   `db:"created_at"`
+	UpdatedAt  int64   `db:"updated_at"`
+}
+
+// Overdue is a run still in progress past the max runtime of its monitor
+type Overdue struct {
+	Checkin
+	Slug              string `db:"slug"`
+	MaxRuntimeMinutes int    `db:"max_runtime_minutes"`
+}
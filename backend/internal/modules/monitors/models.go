@@ -0,0 +1,112 @@
+package monitors
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ScheduleCron     = "cron"
+	ScheduleInterval = "interval"
+)
+
+// Statuses of check-ins, a monitor has the status of its last check-in or pending before the first one
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusOK         = "ok"
+	StatusError      = "error"
+	// StatusMissed is a run nothing checked in for within the grace period
+	StatusMissed = "missed"
+	// StatusTimeout is a run in progress for longer than the max runtime
+	StatusTimeout = "timeout"
+)
+
+var (
+	ErrNotFound        = errors.New("monitor not found")
+	ErrCheckinNotFound = errors.New("check-in not found")
+	ErrSlugTaken       = errors.New("slug is used by another monitor of the project")
+	ErrInvalidSlug     = errors.New("slug must be lowercase letters, digits, - and _")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type Create struct {
+	// Slug names the monitor in the check-in URL
+	Slug string `json:"slug" validate:"required,max=64" example:"nightly-backup"`
+	Update
+}
+
+type Update struct {
+	Name         string `json:"name" validate:"required,max=255" example:"Nightly backup"`
+	ScheduleType string `json:"scheduleType" validate:"required,oneof=cron interval" example:"cron"`
+	// Schedule is a cron expression like "0 3 * * *" or @daily, or an interval like 15m, 6h or 1d
+	Schedule string `json:"schedule" validate:"required,max=255" example:"0 3 * * *"`
+	// Timezone of a cron schedule, UTC by default
+	Timezone string `json:"timezone" validate:"omitempty,max=64" example:"Europe/Moscow"`
+	// GraceMinutes is how late a check-in may come before the run counts as missed
+	GraceMinutes int `json:"graceMinutes" validate:"min=0,max=1440" example:"5"`
+	// MaxRuntimeMinutes is how long a run may stay in progress, null has no limit
+	MaxRuntimeMinutes *int `json:"maxRuntimeMinutes" validate:"omitempty,min=1,max=10080" example:"60"`
+	// Paused monitors accept check-ins but never miss or time out
+	Paused bool `json:"paused" example:"false"`
+}
+
+// CheckinRequest is sent by a job: in_progress when it starts, then ok or error.
+// A job sending only ok or error when it is done is a heartbeat.
+type CheckinRequest struct {
+	Status string `json:"status" validate:"required,oneof=in_progress ok error" example:"in_progress"`
+	// CheckinID finishes the run started by an in_progress check-in, the latest one by default
+	CheckinID string `json:"checkinId" validate:"omitempty,uuid" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	// Duration of the run in milliseconds, measured from the in_progress check-in by default
+	Duration *int64 `json:"duration" validate:"omitempty,min=0" example:"12000"`
+}
+
+type Entity struct {
+	ID                string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	ProjectID         string `json:"projectId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Slug              string `json:"slug" example:"nightly-backup"`
+	Name              string `json:"name" example:"Nightly backup"`
+	ScheduleType      string `json:"scheduleType" example:"cron"`
+	Schedule          string `json:"schedule" example:"0 3 * * *"`
+	Timezone          string `json:"timezone" example:"UTC"`
+	GraceMinutes      int    `json:"graceMinutes" example:"5"`
+	MaxRuntimeMinutes *int   `json:"maxRuntimeMinutes" example:"60"`
+	Paused            bool   `json:"paused" example:"false"`
+	// Status is the status of the last check-in or pending
+	Status string `json:"status" example:"ok"`
+	// NextExpectedAt is when the next check-in is due, Unix timestamp in seconds
+	NextExpectedAt int64  `json:"nextExpectedAt" example:"1704164400"`
+	LastCheckinAt  *int64 `json:"lastCheckinAt" example:"1704078000"`
+	// CheckinURL is the address jobs check in to, <key> is a public key of the project
+	CheckinURL string `json:"checkinUrl" example:"https://duckbug.io/api/ingest/a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c:<key>/checkins/nightly-backup"`
+	CreatedAt  int64  `json:"createdAt" example:"1704067200"`
+	UpdatedAt  int64  `json:"updatedAt" example:"1704067200"`
+}
+
+type CheckinEntity struct {
+	ID     string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Status string `json:"status" example:"ok"`
+	// ExpectedAt is the scheduled time of a missed run, Unix timestamp in seconds
+	ExpectedAt *int64 `json:"expectedAt,omitempty" example:"1704078000"`
+	DurationMs *int64 `json:"durationMs,omitempty" example:"12000"`
+	// ErrorID is the error recorded for a missed, timed out or failed run
+	ErrorID   *string `json:"errorId,omitempty" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	CreatedAt int64   `json:"createdAt" example:"1704078000"`
+	UpdatedAt int64   `json:"updatedAt" example:"1704078012"`
+}
+
+type CheckinList struct {
+	Count int             `json:"count"`
+	Items []CheckinEntity `json:"items"`
+}
@@ -0,0 +1,386 @@
+package monitors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	monitorColumns = `id, project_id, slug, name, schedule_type, schedule, timezone, grace_minutes, max_runtime_minutes,
+		paused, status, next_expected_at, last_checkin_at, created_at, updated_at`
+	// joinedMonitorColumns are monitorColumns of monitors joined as m
+	joinedMonitorColumns = `m.id, m.project_id, m.slug, m.name, m.schedule_type, m.schedule, m.timezone, m.grace_minutes,
+		m.max_runtime_minutes, m.paused, m.status, m.next_expected_at, m.last_checkin_at, m.created_at, m.updated_at`
+	checkinColumns = `id, monitor_id, project_id, status, expected_at, duration_ms, error_id, created_at, updated_at`
+
+	uniqueViolation = "23505"
+)
+
+type Repository interface {
+	GetAll(ctx context.Context, projectID string) ([]*Monitor, error)
+	GetByID(ctx context.Context, id string) (*Monitor, error)
+	GetBySlug(ctx context.Context, projectID, slug string) (*Monitor, error)
+	Create(ctx context.Context, monitor *Monitor) error
+	Update(ctx context.Context, monitor *Monitor) error
+	Delete(ctx context.Context, id string) error
+
+	GetCheckins(ctx context.Context, monitorID string, limit, offset int) ([]*Checkin, error)
+	CountCheckins(ctx context.Context, monitorID string) (int, error)
+	// GetOpenCheckin returns a check-in of the monitor still in progress, the latest one when id is empty
+	GetOpenCheckin(ctx context.Context, monitorID, id string) (*Checkin, error)
+	// SaveCheckin inserts or updates a check-in and moves the monitor to its status, next is the time
+	// the next check-in is due or 0 to keep it
+	SaveCheckin(ctx context.Context, checkin *Checkin, insert bool, next int64) error
+	SetErrorID(ctx context.Context, checkinID, errorID string) error
+
+	// GetDue returns monitors, not paused, whose next check-in is overdue by more than the grace period at now
+	GetDue(ctx context.Context, now int64, limit int) ([]*Monitor, error)
+	// RecordMissed moves the monitor from expectedAt to next and inserts the missed check-in.
+	// It returns false when another instance handled the run first.
+	RecordMissed(ctx context.Context, monitor *Monitor, expectedAt, next int64, checkin *Checkin) (bool, error)
+	// GetOverdue returns check-ins in progress for longer than the max runtime of their monitor at now
+	GetOverdue(ctx context.Context, now int64, limit int) ([]*Overdue, error)
+	// RecordTimeout marks a check-in still in progress as timed out, false when it finished meanwhile
+	RecordTimeout(ctx context.Context, checkin *Checkin, now int64) (bool, error)
+	// DeleteCheckinsBefore removes up to limit check-ins created before before
+	DeleteCheckinsBefore(ctx context.Context, before int64, limit int) (int, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetAll(ctx context.Context, projectID string) ([]*Monitor, error) {
+	query := `SELECT ` + monitorColumns + ` FROM monitors WHERE project_id = $1 ORDER BY name, id`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var monitors []*Monitor
+	if err := r.db.SelectContext(ctx, &monitors, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to get monitors: %w", err)
+	}
+	return monitors, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*Monitor, error) {
+	return r.get(ctx, `SELECT `+monitorColumns+` FROM monitors WHERE id = $1`, id)
+}
+
+func (r *repository) GetBySlug(ctx context.Context, projectID, slug string) (*Monitor, error) {
+	return r.get(ctx, `SELECT `+monitorColumns+` FROM monitors WHERE project_id = $1 AND slug = $2`, projectID, slug)
+}
+
+func (r *repository) get(ctx context.Context, query string, args ...interface{}) (*Monitor, error) {
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var monitor Monitor
+	if err := r.db.GetContext(ctx, &monitor, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get monitor: %w", err)
+	}
+	return &monitor, nil
+}
+
+func (r *repository) Create(ctx context.Context, monitor *Monitor) error {
+	const query = `
+		INSERT INTO monitors (` + monitorColumns + `)
+		VALUES (:id, :project_id, :slug, :name, :schedule_type, :schedule, :timezone, :grace_minutes,
+			:max_runtime_minutes, :paused, :status, :next_expected_at, :last_checkin_at, :created_at, :updated_at)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if _, err := r.db.NamedExecContext(ctx, query, monitor); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrSlugTaken
+		}
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Update(ctx context.Context, monitor *Monitor) error {
+	const query = `
+		UPDATE monitors SET name = :name, schedule_type = :schedule_type, schedule = :schedule,
+			timezone = :timezone, grace_minutes = :grace_minutes, max_runtime_minutes = :max_runtime_minutes,
+			paused = :paused, next_expected_at = :next_expected_at, updated_at = :updated_at
+		WHERE id = :id`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if _, err := r.db.NamedExecContext(ctx, query, monitor); err != nil {
+		return fmt.Errorf("failed to update monitor: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id string) error {
+	const query = `DELETE FROM monitors WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete monitor: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetCheckins(ctx context.Context, monitorID string, limit, offset int) ([]*Checkin, error) {
+	query := `SELECT ` + checkinColumns + ` FROM monitor_checkins
+		WHERE monitor_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var checkins []*Checkin
+	if err := r.db.SelectContext(ctx, &checkins, query, monitorID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to get check-ins: %w", err)
+	}
+	return checkins, nil
+}
+
+func (r *repository) CountCheckins(ctx context.Context, monitorID string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM monitor_checkins WHERE monitor_id = $1`, monitorID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count check-ins: %w", err)
+	}
+	return count, nil
+}
+
+func (r *repository) GetOpenCheckin(ctx context.Context, monitorID, id string) (*Checkin, error) {
+	query := `SELECT ` + checkinColumns + ` FROM monitor_checkins
+		WHERE monitor_id = $1 AND status = $2 AND ($3 = '' OR id = CAST(NULLIF($3, '') AS uuid))
+		ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var checkin Checkin
+	if err := r.db.GetContext(ctx, &checkin, query, monitorID, StatusInProgress, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCheckinNotFound
+		}
+		return nil, fmt.Errorf("failed to get check-in: %w", err)
+	}
+	return &checkin, nil
+}
+
+func (r *repository) SaveCheckin(ctx context.Context, checkin *Checkin, insert bool, next int64) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	if err = saveCheckin(ctx, tx, checkin, insert); err != nil {
+		return err
+	}
+
+	const monitorQuery = `
+		UPDATE monitors SET status = $2, last_checkin_at = $3, updated_at = $3,
+			next_expected_at = CASE WHEN CAST($4 AS BIGINT) = 0 THEN next_expected_at ELSE $4 END
+		WHERE id = $1`
+	if _, err = tx.ExecContext(ctx, monitorQuery, checkin.MonitorID, checkin.Status, checkin.UpdatedAt, next); err != nil {
+		return fmt.Errorf("failed to update monitor: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func saveCheckin(ctx context.Context, tx *sqlx.Tx, checkin *Checkin, insert bool) error {
+	query := `
+		UPDATE monitor_checkins SET status = :status, duration_ms = :duration_ms, updated_at = :updated_at
+		WHERE id = :id`
+	if insert {
+		query = `
+			INSERT INTO monitor_checkins (` + checkinColumns + `)
+			VALUES (:id, :monitor_id, :project_id, :status, :expected_at, :duration_ms, :error_id, :created_at, :updated_at)`
+	}
+
+	if _, err := tx.NamedExecContext(ctx, query, checkin); err != nil {
+		return fmt.Errorf("failed to save check-in: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) SetErrorID(ctx context.Context, checkinID, errorID string) error {
+	const query = `UPDATE monitor_checkins SET error_id = $2 WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, checkinID, errorID); err != nil {
+		return fmt.Errorf("failed to link error to check-in: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetDue(ctx context.Context, now int64, limit int) ([]*Monitor, error) {
+	// Monitors of deleted projects stay until the project is purged, they are not checked meanwhile
+	query := `SELECT ` + joinedMonitorColumns + ` FROM monitors m
+		JOIN projects p ON p.id = m.project_id AND p.deleted_at IS NULL
+		WHERE NOT m.paused AND m.next_expected_at <= $1 AND m.next_expected_at + m.grace_minutes * 60 <= $1
+		ORDER BY m.next_expected_at LIMIT $2`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var monitors []*Monitor
+	if err := r.db.SelectContext(ctx, &monitors, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to get due monitors: %w", err)
+	}
+	return monitors, nil
+}
+
+func (r *repository) RecordMissed(
+	ctx context.Context, monitor *Monitor, expectedAt, next int64, checkin *Checkin,
+) (claimed bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil || !claimed {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	// The run belongs to whoever moves next_expected_at away from it
+	const claimQuery = `
+		UPDATE monitors SET status = $3, next_expected_at = $4, updated_at = $5
+		WHERE id = $1 AND next_expected_at = $2 AND NOT paused`
+	result, err := tx.ExecContext(ctx, claimQuery, monitor.ID, expectedAt, StatusMissed, next, checkin.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim missed run: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err = saveCheckin(ctx, tx, checkin, true); err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *repository) GetOverdue(ctx context.Context, now int64, limit int) ([]*Overdue, error) {
+	const query = `
+		SELECT c.id, c.monitor_id, c.project_id, c.status, c.expected_at, c.duration_ms, c.error_id,
+			c.created_at, c.updated_at, m.slug, m.max_runtime_minutes
+		FROM monitor_checkins c
+		JOIN monitors m ON m.id = c.monitor_id
+		JOIN projects p ON p.id = m.project_id AND p.deleted_at IS NULL
+		WHERE c.status = $1 AND NOT m.paused AND m.max_runtime_minutes IS NOT NULL
+			AND c.created_at + m.max_runtime_minutes * 60 <= $2
+		ORDER BY c.created_at LIMIT $3`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var overdue []*Overdue
+	if err := r.db.SelectContext(ctx, &overdue, query, StatusInProgress, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to get overdue check-ins: %w", err)
+	}
+	return overdue, nil
+}
+
+func (r *repository) RecordTimeout(ctx context.Context, checkin *Checkin, now int64) (claimed bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil || !claimed {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	const checkinQuery = `
+		UPDATE monitor_checkins SET status = $3, duration_ms = ($4 - created_at) * 1000, updated_at = $4
+		WHERE id = $1 AND status = $2`
+	result, err := tx.ExecContext(ctx, checkinQuery, checkin.ID, StatusInProgress, StatusTimeout, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to time out check-in: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	// A later run may have checked in already, then the monitor keeps its status
+	const monitorQuery = `
+		UPDATE monitors SET status = $3, updated_at = $4
+		WHERE id = $1 AND status = $2 AND last_checkin_at = $5`
+	_, err = tx.ExecContext(ctx, monitorQuery, checkin.MonitorID, StatusInProgress, StatusTimeout, now, checkin.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update monitor: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *repository) DeleteCheckinsBefore(ctx context.Context, before int64, limit int) (int, error) {
+	const query = `
+		DELETE FROM monitor_checkins WHERE id IN (
+			SELECT id FROM monitor_checkins WHERE created_at < $1 AND status <> $2 LIMIT $3
+		)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, before, StatusInProgress, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old check-ins: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
@@ -0,0 +1,28 @@
+package monitors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/duckbugio/duckbug/internal/storage/sql/sqltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerSkipsDeletedProjects(t *testing.T) {
+	db := &sqltest.Recorder{}
+	repo := NewRepository(db.DB(), logger.New("error", &bytes.Buffer{}))
+
+	_, err := repo.GetDue(context.Background(), 1735689600, 100)
+	require.NoError(t, err)
+	_, err = repo.GetOverdue(context.Background(), 1735689600, 100)
+	require.NoError(t, err)
+
+	// Monitors of a deleted project must neither be missed nor time out until the project is purged
+	require.Len(t, db.Queries, 2)
+	for _, query := range db.Queries {
+		assert.Contains(t, query.SQL, "JOIN projects p ON p.id = m.project_id AND p.deleted_at IS NULL")
+	}
+}
@@ -0,0 +1,100 @@
+package monitors
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duckbugio/duckbug/pkg/cron"
+)
+
+const (
+	minInterval = time.Minute
+	maxInterval = 366 * hoursInDay * time.Hour
+	hoursInDay  = 24
+	// earlyWindow is how early a check-in may come and still count as the run that is due next
+	earlyWindow = time.Minute
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// schedule tells when check-ins of a monitor are due
+type schedule struct {
+	cron     *cron.Schedule
+	location *time.Location
+	interval time.Duration
+}
+
+func parseSchedule(scheduleType, value, timezone string) (*schedule, error) {
+	switch scheduleType {
+	case ScheduleCron:
+		parsed, err := cron.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+		}
+		location := time.UTC
+		if timezone != "" {
+			if location, err = time.LoadLocation(timezone); err != nil {
+				return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+			}
+		}
+		if parsed.Next(time.Now().In(location)).IsZero() {
+			return nil, fmt.Errorf("%w: %q never runs", ErrInvalidSchedule, value)
+		}
+		return &schedule{cron: parsed, location: location}, nil
+	case ScheduleInterval:
+		interval, err := parseInterval(value)
+		if err != nil {
+			return nil, err
+		}
+		return &schedule{interval: interval}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown schedule type %q", ErrInvalidSchedule, scheduleType)
+	}
+}
+
+// parseInterval reads a duration like 15m or 6h, days are written as 1d
+func parseInterval(value string) (time.Duration, error) {
+	var interval time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		interval = time.Duration(n) * hoursInDay * time.Hour
+	} else {
+		interval, err = time.ParseDuration(value)
+	}
+
+	if err != nil || interval < minInterval || interval > maxInterval {
+		return 0, fmt.Errorf("%w: interval must be between 1m and 366d, got %q", ErrInvalidSchedule, value)
+	}
+	return interval, nil
+}
+
+// next returns the first due time after t
+func (s *schedule) next(t time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(t.In(s.location))
+	}
+	return t.Add(s.interval)
+}
+
+// nextAfterCheckin returns when the run after the one a check-in at t started is due.
+// A check-in shortly before the due time is that run, started a little early.
+func (s *schedule) nextAfterCheckin(t, due time.Time, grace time.Duration) time.Time {
+	if t.Before(due) && due.Sub(t) <= max(grace, earlyWindow) {
+		return s.next(due)
+	}
+	return s.next(t)
+}
+
+// nextAfterMiss returns the first due time after now for a run missed at due
+func (s *schedule) nextAfterMiss(due, now time.Time) time.Time {
+	if s.cron != nil {
+		return s.next(now)
+	}
+	periods := int64(now.Sub(due)/s.interval) + 1
+	return due.Add(time.Duration(periods) * s.interval)
+}
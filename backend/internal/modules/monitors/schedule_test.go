@@ -0,0 +1,76 @@
+package monitors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"15m", 15 * time.Minute},
+		{"6h", 6 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		interval, err := parseInterval(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, interval, tt.value)
+	}
+
+	for _, value := range []string{"", "30s", "367d", "d", "1w", "-1h"} {
+		_, err := parseInterval(value)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, value)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	_, err := parseSchedule(ScheduleCron, "0 3 * * *", "Europe/Moscow")
+	require.NoError(t, err)
+
+	_, err = parseSchedule(ScheduleCron, "0 3 * * *", "Mars/Olympus")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = parseSchedule(ScheduleCron, "0 0 30 2 *", "")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = parseSchedule("weekly", "1d", "")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
+
+func TestNextAfterCheckin(t *testing.T) {
+	sched, err := parseSchedule(ScheduleCron, "0 3 * * *", "")
+	require.NoError(t, err)
+	due := time.Date(2024, time.January, 2, 3, 0, 0, 0, time.UTC)
+
+	// A job starting a bit early runs the due run, the next one is due a day later
+	early := due.Add(-3 * time.Minute)
+	assert.Equal(t, due.AddDate(0, 0, 1), sched.nextAfterCheckin(early, due, 5*time.Minute))
+
+	// A manual run long before the due time leaves the due run expected
+	manual := due.Add(-6 * time.Hour)
+	assert.Equal(t, due, sched.nextAfterCheckin(manual, due, 5*time.Minute))
+
+	late := due.Add(2 * time.Minute)
+	assert.Equal(t, due.AddDate(0, 0, 1), sched.nextAfterCheckin(late, due, 5*time.Minute))
+}
+
+func TestNextAfterMiss(t *testing.T) {
+	sched, err := parseSchedule(ScheduleInterval, "1h", "")
+	require.NoError(t, err)
+	due := time.Date(2024, time.January, 2, 3, 0, 0, 0, time.UTC)
+
+	// Runs missed while nothing checked in are skipped, the interval keeps its phase
+	now := due.Add(150 * time.Minute)
+	assert.Equal(t, due.Add(3*time.Hour), sched.nextAfterMiss(due, now))
+
+	cronSched, err := parseSchedule(ScheduleCron, "*/15 * * * *", "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.January, 2, 5, 45, 0, 0, time.UTC), cronSched.nextAfterMiss(due, now))
+}
@@ -0,0 +1,449 @@
+package monitors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	moduleErrors "github.com/duckbugio/duckbug/internal/modules/errors"
+	"github.com/google/uuid"
+)
+
+const (
+	// batchSize bounds the monitors and check-ins handled per query of a scheduler run
+	batchSize  = 100
+	maxBatches = 10
+	// checkinHistoryDays is how long check-ins are kept
+	checkinHistoryDays = 90
+
+	// auditTarget names monitors in the audit log
+	auditTarget = "monitor"
+)
+
+// ErrorRecorder stores the errors of failed runs in the project, the errors service satisfies it
+type ErrorRecorder interface {
+	Create(ctx context.Context, req *moduleErrors.Create) (*moduleErrors.Entity, error)
+}
+
+type Service interface {
+	GetAll(ctx context.Context, projectID string) ([]Entity, error)
+	GetByID(ctx context.Context, projectID, id string) (*Entity, error)
+	Create(ctx context.Context, projectID string, req *Create) (*Entity, error)
+	Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error)
+	Delete(ctx context.Context, projectID, id string) error
+	// GetCheckins returns the history of a monitor, latest first
+	GetCheckins(ctx context.Context, projectID, id string, limit, offset int) ([]CheckinEntity, int, error)
+	// Checkin records a check-in sent by the job of the monitor with the slug
+	Checkin(ctx context.Context, projectID, slug string, req *CheckinRequest) (*CheckinEntity, error)
+	// Run records missed and timed out runs as errors and drops old check-ins, it is called by the scheduler
+	Run(ctx context.Context) error
+}
+
+type service struct {
+	repo   Repository
+	errors ErrorRecorder
+	logger Logger
+	domain string
+}
+
+func NewService(repo Repository, errorRecorder ErrorRecorder, logger Logger, domain string) Service {
+	return &service{
+		repo:   repo,
+		errors: errorRecorder,
+		logger: logger,
+		domain: domain,
+	}
+}
+
+func (s *service) GetAll(ctx context.Context, projectID string) ([]Entity, error) {
+	monitors, err := s.repo.GetAll(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]Entity, 0, len(monitors))
+	for _, monitor := range monitors {
+		entities = append(entities, s.toEntity(monitor))
+	}
+	return entities, nil
+}
+
+func (s *service) GetByID(ctx context.Context, projectID, id string) (*Entity, error) {
+	monitor, err := s.get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	entity := s.toEntity(monitor)
+	return &entity, nil
+}
+
+func (s *service) Create(ctx context.Context, projectID string, req *Create) (*Entity, error) {
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+	sched, err := parseSchedule(req.ScheduleType, req.Schedule, req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	monitor := &Monitor{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		Slug:      req.Slug,
+		Status:    StatusPending,
+		CreatedAt: now.Unix(),
+	}
+	applyUpdate(monitor, &req.Update, sched, now)
+
+	if err := s.repo.Create(ctx, monitor); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "monitor.create", auditTarget, monitor.ID, nil, toAuditState(monitor))
+	entity := s.toEntity(monitor)
+	return &entity, nil
+}
+
+func (s *service) Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error) {
+	monitor, err := s.get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := parseSchedule(req.ScheduleType, req.Schedule, req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	before := toAuditState(monitor)
+	applyUpdate(monitor, req, sched, time.Now())
+	if err := s.repo.Update(ctx, monitor); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "monitor.update", auditTarget, id, before, toAuditState(monitor))
+	entity := s.toEntity(monitor)
+	return &entity, nil
+}
+
+func (s *service) Delete(ctx context.Context, projectID, id string) error {
+	monitor, err := s.get(ctx, projectID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	middleware.Audit(ctx, "monitor.delete", auditTarget, id, toAuditState(monitor), nil)
+	return nil
+}
+
+func (s *service) GetCheckins(ctx context.Context, projectID, id string, limit, offset int) ([]CheckinEntity, int, error) {
+	if _, err := s.get(ctx, projectID, id); err != nil {
+		return nil, 0, err
+	}
+
+	checkins, err := s.repo.GetCheckins(ctx, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.repo.CountCheckins(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entities := make([]CheckinEntity, 0, len(checkins))
+	for _, checkin := range checkins {
+		entities = append(entities, toCheckinEntity(checkin))
+	}
+	return entities, count, nil
+}
+
+func (s *service) Checkin(ctx context.Context, projectID, slug string, req *CheckinRequest) (*CheckinEntity, error) {
+	monitor, err := s.repo.GetBySlug(ctx, projectID, slug)
+	if err != nil {
+		return nil, err
+	}
+	sched, err := parseSchedule(monitor.ScheduleType, monitor.Schedule, monitor.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var checkin *Checkin
+	if req.Status != StatusInProgress {
+		checkin, err = s.repo.GetOpenCheckin(ctx, monitor.ID, req.CheckinID)
+		switch {
+		case err == nil:
+			checkin.Status = req.Status
+			checkin.DurationMs = req.Duration
+			if checkin.DurationMs == nil {
+				duration := now.UnixMilli() - time.Unix(checkin.CreatedAt, 0).UnixMilli()
+				checkin.DurationMs = &duration
+			}
+		case errors.Is(err, ErrCheckinNotFound) && req.CheckinID == "":
+			// Nothing in progress, the check-in is a heartbeat
+		default:
+			return nil, err
+		}
+	}
+
+	// A check-in starting a run moves the due time of the next one
+	insert := checkin == nil
+	var next int64
+	if insert {
+		checkin = &Checkin{
+			ID:         uuid.New().String(),
+			MonitorID:  monitor.ID,
+			ProjectID:  monitor.ProjectID,
+			Status:     req.Status,
+			DurationMs: req.Duration,
+			CreatedAt:  now.Unix(),
+		}
+		grace := time.Duration(monitor.GraceMinutes) * time.Minute
+		next = sched.nextAfterCheckin(now, time.Unix(monitor.NextExpectedAt, 0), grace).Unix()
+	}
+	checkin.UpdatedAt = now.Unix()
+
+	if err := s.repo.SaveCheckin(ctx, checkin, insert, next); err != nil {
+		return nil, err
+	}
+
+	if checkin.Status == StatusError {
+		if err := s.recordError(ctx, monitor.Slug, checkin, "reported a failed run", now); err != nil {
+			return nil, err
+		}
+	}
+
+	entity := toCheckinEntity(checkin)
+	return &entity, nil
+}
+
+func (s *service) Run(ctx context.Context) error {
+	now := time.Now()
+
+	missed, missedErr := s.checkMissed(ctx, now)
+	timedOut, timeoutErr := s.checkTimeouts(ctx, now)
+	deleted, deleteErr := s.repo.DeleteCheckinsBefore(ctx, now.AddDate(0, 0, -checkinHistoryDays).Unix(), batchSize*maxBatches)
+
+	if missed > 0 || timedOut > 0 || deleted > 0 {
+		s.logger.Info(fmt.Sprintf("monitors: %d missed runs, %d timed out runs, %d old check-ins removed",
+			missed, timedOut, deleted))
+	}
+	return errors.Join(missedErr, timeoutErr, deleteErr)
+}
+
+// checkMissed records a missed run for every monitor nothing checked in for within the grace period
+func (s *service) checkMissed(ctx context.Context, now time.Time) (int, error) {
+	missed := 0
+	var errs []error
+	for range maxBatches {
+		monitors, err := s.repo.GetDue(ctx, now.Unix(), batchSize)
+		if err != nil {
+			return missed, err
+		}
+
+		for _, monitor := range monitors {
+			recorded, err := s.recordMissed(ctx, monitor, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("monitor %s: %w", monitor.ID, err))
+			}
+			if recorded {
+				missed++
+			}
+		}
+
+		if len(monitors) < batchSize || len(errs) > 0 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return missed, err
+		}
+	}
+	return missed, errors.Join(errs...)
+}
+
+func (s *service) recordMissed(ctx context.Context, monitor *Monitor, now time.Time) (bool, error) {
+	sched, err := parseSchedule(monitor.ScheduleType, monitor.Schedule, monitor.Timezone)
+	if err != nil {
+		return false, err
+	}
+
+	expectedAt := monitor.NextExpectedAt
+	next := sched.nextAfterMiss(time.Unix(expectedAt, 0), now).Unix()
+	checkin := &Checkin{
+		ID:         uuid.New().String(),
+		MonitorID:  monitor.ID,
+		ProjectID:  monitor.ProjectID,
+		Status:     StatusMissed,
+		ExpectedAt: &expectedAt,
+		CreatedAt:  now.Unix(),
+		UpdatedAt:  now.Unix(),
+	}
+
+	claimed, err := s.repo.RecordMissed(ctx, monitor, expectedAt, next, checkin)
+	if err != nil || !claimed {
+		return false, err
+	}
+	return true, s.recordError(ctx, monitor.Slug, checkin, "missed a check-in", now)
+}
+
+// checkTimeouts records a timeout for every run in progress for longer than the max runtime of its monitor
+func (s *service) checkTimeouts(ctx context.Context, now time.Time) (int, error) {
+	timedOut := 0
+	var errs []error
+	for range maxBatches {
+		overdue, err := s.repo.GetOverdue(ctx, now.Unix(), batchSize)
+		if err != nil {
+			return timedOut, err
+		}
+
+		for _, run := range overdue {
+			claimed, err := s.repo.RecordTimeout(ctx, &run.Checkin, now.Unix())
+			if err == nil && claimed {
+				timedOut++
+				run.Status = StatusTimeout
+				reason := fmt.Sprintf("exceeded its max runtime of %d minutes", run.MaxRuntimeMinutes)
+				err = s.recordError(ctx, run.Slug, &run.Checkin, reason, now)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("check-in %s: %w", run.ID, err))
+			}
+		}
+
+		if len(overdue) < batchSize || len(errs) > 0 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return timedOut, err
+		}
+	}
+	return timedOut, errors.Join(errs...)
+}
+
+// recordError stores a failed run as an error of the project and links it to the check-in.
+// Failed runs of a monitor for the same reason share an error group.
+func (s *service) recordError(ctx context.Context, slug string, checkin *Checkin, reason string, at time.Time) error {
+	var stacktrace interface{} = []interface{}{}
+	details := map[string]interface{}{
+		"monitor":   slug,
+		"monitorId": checkin.MonitorID,
+		"checkinId": checkin.ID,
+		"status":    checkin.Status,
+	}
+	if checkin.ExpectedAt != nil {
+		details["expectedAt"] = *checkin.ExpectedAt
+	}
+	var errorContext interface{} = details
+
+	entity, err := s.errors.Create(ctx, &moduleErrors.Create{
+		Time:       at.UnixMilli(),
+		Message:    fmt.Sprintf("Monitor %s %s", slug, reason),
+		Stacktrace: &stacktrace,
+		File:       "monitors/" + slug,
+		Context:    &errorContext,
+		ProjectID:  checkin.ProjectID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record monitor error: %w", err)
+	}
+	return s.repo.SetErrorID(ctx, checkin.ID, entity.ID)
+}
+
+// get returns a monitor of the project, monitors of other projects are not found
+func (s *service) get(ctx context.Context, projectID, id string) (*Monitor, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	monitor, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if monitor.ProjectID != projectID {
+		return nil, ErrNotFound
+	}
+	return monitor, nil
+}
+
+// applyUpdate sets the settings of req, a new schedule or a resumed monitor is due from now on
+func applyUpdate(monitor *Monitor, req *Update, sched *schedule, now time.Time) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	rescheduled := monitor.NextExpectedAt == 0 || monitor.ScheduleType != req.ScheduleType ||
+		monitor.Schedule != req.Schedule || monitor.Timezone != timezone || (monitor.Paused && !req.Paused)
+
+	monitor.Name = req.Name
+	monitor.ScheduleType = req.ScheduleType
+	monitor.Schedule = req.Schedule
+	monitor.Timezone = timezone
+	monitor.GraceMinutes = req.GraceMinutes
+	monitor.MaxRuntimeMinutes = req.MaxRuntimeMinutes
+	monitor.Paused = req.Paused
+	monitor.UpdatedAt = now.Unix()
+	if rescheduled {
+		monitor.NextExpectedAt = sched.next(now).Unix()
+	}
+}
+
+func (s *service) toEntity(monitor *Monitor) Entity {
+	return Entity{
+		ID:                monitor.ID,
+		ProjectID:         monitor.ProjectID,
+		Slug:              monitor.Slug,
+		Name:              monitor.Name,
+		ScheduleType:      monitor.ScheduleType,
+		Schedule:          monitor.Schedule,
+		Timezone:          monitor.Timezone,
+		GraceMinutes:      monitor.GraceMinutes,
+		MaxRuntimeMinutes: monitor.MaxRuntimeMinutes,
+		Paused:            monitor.Paused,
+		Status:            monitor.Status,
+		NextExpectedAt:    monitor.NextExpectedAt,
+		LastCheckinAt:     monitor.LastCheckinAt,
+		CheckinURL:        "https://" + s.domain + "/api/ingest/" + monitor.ProjectID + ":<key>/checkins/" + monitor.Slug,
+		CreatedAt:         monitor.CreatedAt,
+		UpdatedAt:         monitor.UpdatedAt,
+	}
+}
+
+func toCheckinEntity(checkin *Checkin) CheckinEntity {
+	return CheckinEntity{
+		ID:         checkin.ID,
+		Status:     checkin.Status,
+		ExpectedAt: checkin.ExpectedAt,
+		DurationMs: checkin.DurationMs,
+		ErrorID:    checkin.ErrorID,
+		CreatedAt:  checkin.CreatedAt,
+		UpdatedAt:  checkin.UpdatedAt,
+	}
+}
+
+type auditState struct {
+	Slug              string `json:"slug"`
+	Name              string `json:"name"`
+	ScheduleType      string `json:"scheduleType"`
+	Schedule          string `json:"schedule"`
+	Timezone          string `json:"timezone"`
+	GraceMinutes      int    `json:"graceMinutes"`
+	MaxRuntimeMinutes *int   `json:"maxRuntimeMinutes"`
+	Paused            bool   `json:"paused"`
+}
+
+func toAuditState(monitor *Monitor) auditState {
+	return auditState{
+		Slug:              monitor.Slug,
+		Name:              monitor.Name,
+		ScheduleType:      monitor.ScheduleType,
+		Schedule:          monitor.Schedule,
+		Timezone:          monitor.Timezone,
+		GraceMinutes:      monitor.GraceMinutes,
+		MaxRuntimeMinutes: monitor.MaxRuntimeMinutes,
+		Paused:            monitor.Paused,
+	}
+}
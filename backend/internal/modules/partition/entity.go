@@ -0,0 +1,22 @@
+package partition
+
+// Partition is a child table with its bounds in milliseconds, nil bounds are MINVALUE/MAXVALUE
+type Partition struct {
+	Name      string
+	From      *int64
+	To        *int64
+	IsDefault bool
+}
+
+func (p *Partition) overlaps(from, to int64) bool {
+	if p.IsDefault {
+		return false
+	}
+	if p.From != nil && *p.From >= to {
+		return false
+	}
+	if p.To != nil && *p.To <= from {
+		return false
+	}
+	return true
+}
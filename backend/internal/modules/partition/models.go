@@ -0,0 +1,32 @@
+package partition
+
+import "context"
+
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// Tables lists the event tables partitioned by range of time in milliseconds
+var Tables = []string{"logs", "errors"}
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type Config struct {
+	// Interval is the size of a partition, day or week
+	Interval string
+	// Premake is the number of partitions created ahead of the current one
+	Premake int
+	// RetentionDays drops partitions that only hold older events, 0 keeps everything.
+	// A project retention setting that is longer postpones the drop, a project without one prevents it.
+	RetentionDays int
+}
@@ -0,0 +1,248 @@
+package partition
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrNotPartitioned = errors.New("table is not partitioned")
+
+// retentionColumns are the per-project retention settings of the partitioned tables
+var retentionColumns = map[string]string{
+	"logs":   "logs_retention_days",
+	"errors": "errors_retention_days",
+}
+
+// boundPattern matches the output of pg_get_expr for range partitions,
+// e.g. FOR VALUES FROM (MINVALUE) TO ('1700000000000')
+var boundPattern = regexp.MustCompile(`FROM \('?([^')]+)'?\) TO \('?([^')]+)'?\)`)
+
+type Repository interface {
+	GetLegacyUntil(ctx context.Context, table string) (int64, error)
+	GetPartitions(ctx context.Context, table string) ([]*Partition, error)
+	// GetMaxRetentionDays returns the longest retention a project has set for the table, 0 when there are
+	// no projects. Unlimited tells that a project has none set and keeps its events forever.
+	GetMaxRetentionDays(ctx context.Context, table string) (days int, unlimited bool, err error)
+	// CreatePartition creates the partition unless another replica already did, and tells which happened
+	CreatePartition(ctx context.Context, table, name string, from, to int64) (bool, error)
+	DropPartition(ctx context.Context, name string) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetLegacyUntil(ctx context.Context, table string) (int64, error) {
+	const query = `SELECT legacy_until FROM partitioning WHERE table_name = $1`
+
+	var legacyUntil int64
+	err := r.db.GetContext(ctx, &legacyUntil, query, table)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotPartitioned
+		}
+		return 0, fmt.Errorf("failed to get legacy partition bound: %w", err)
+	}
+	return legacyUntil, nil
+}
+
+func (r *repository) GetPartitions(ctx context.Context, table string) ([]*Partition, error) {
+	const query = `
+		SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = CAST($1 AS regclass)
+		ORDER BY c.relname`
+
+	var rows []struct {
+		Name  string `db:"name"`
+		Bound string `db:"bound"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions of %s: %w", table, err)
+	}
+
+	partitions := make([]*Partition, 0, len(rows))
+	for _, row := range rows {
+		partition, err := parseBound(row.Name, row.Bound)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions, nil
+}
+
+func (r *repository) GetMaxRetentionDays(ctx context.Context, table string) (days int, unlimited bool, err error) {
+	column, ok := retentionColumns[table]
+	if !ok {
+		return 0, false, nil
+	}
+
+	// Deleted projects count too, their data has to outlive the restore window
+	query := "SELECT COALESCE(MAX(" + column + "), 0), COALESCE(bool_or(" + column + " IS NULL), false) FROM projects"
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if err := r.db.QueryRowContext(ctx, query).Scan(&days, &unlimited); err != nil {
+		return 0, false, fmt.Errorf("failed to get retention of %s: %w", table, err)
+	}
+	return days, unlimited, nil
+}
+
+// CreatePartition attaches a new range partition. Rows of that range which already landed in the
+// default partition are moved over in the same transaction, Postgres refuses to create it otherwise.
+// An advisory lock per table serialises the workers of several replicas.
+func (r *repository) CreatePartition(ctx context.Context, table, name string, from, to int64) (created bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "partition:"+table); err != nil {
+		return false, fmt.Errorf("failed to lock partitions of %s: %w", table, err)
+	}
+
+	var exists bool
+	if err = tx.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", name); err != nil {
+		return false, fmt.Errorf("failed to check partition %s: %w", name, err)
+	}
+	if exists {
+		if err = tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return false, nil
+	}
+
+	parent := pq.QuoteIdentifier(table)
+	defaultPartition := pq.QuoteIdentifier(table + "_default")
+
+	_, err = tx.ExecContext(ctx, "LOCK TABLE "+defaultPartition+" IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return false, fmt.Errorf("failed to lock default partition: %w", err)
+	}
+
+	var pending bool
+	err = tx.GetContext(ctx, &pending,
+		"SELECT EXISTS (SELECT 1 FROM "+defaultPartition+" WHERE time >= $1 AND time < $2)", from, to)
+	if err != nil {
+		return false, fmt.Errorf("failed to check default partition: %w", err)
+	}
+
+	var columns string
+	if pending {
+		// Generated columns can not be written, so they are left out of the copy
+		const columnsQuery = `
+			SELECT string_agg(quote_ident(column_name), ', ' ORDER BY ordinal_position)
+			FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'`
+
+		err = tx.GetContext(ctx, &columns, columnsQuery, table)
+		if err != nil {
+			return false, fmt.Errorf("failed to get columns of %s: %w", table, err)
+		}
+
+		query := "CREATE TEMP TABLE partition_move ON COMMIT DROP AS SELECT " + columns +
+			" FROM " + defaultPartition + " WHERE time >= $1 AND time < $2"
+		r.logger.DebugContext(ctx, "sql query", "query", query)
+		if _, err = tx.ExecContext(ctx, query, from, to); err != nil {
+			return false, fmt.Errorf("failed to copy rows from default partition: %w", err)
+		}
+
+		query = "DELETE FROM " + defaultPartition + " WHERE time >= $1 AND time < $2"
+		if _, err = tx.ExecContext(ctx, query, from, to); err != nil {
+			return false, fmt.Errorf("failed to delete rows from default partition: %w", err)
+		}
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d)",
+		pq.QuoteIdentifier(name), parent, from, to)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return false, fmt.Errorf("failed to create partition %s: %w", name, err)
+	}
+
+	if pending {
+		query = "INSERT INTO " + parent + " (" + columns + ") SELECT " + columns + " FROM partition_move"
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return false, fmt.Errorf("failed to move rows into partition %s: %w", name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *repository) DropPartition(ctx context.Context, name string) error {
+	query := "DROP TABLE IF EXISTS " + pq.QuoteIdentifier(name)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	_, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+	return nil
+}
+
+func parseBound(name, bound string) (*Partition, error) {
+	if bound == "DEFAULT" {
+		return &Partition{Name: name, IsDefault: true}, nil
+	}
+
+	matches := boundPattern.FindStringSubmatch(bound)
+	if matches == nil {
+		return nil, fmt.Errorf("unexpected bound of partition %s: %s", name, bound)
+	}
+
+	from, err := parseBoundValue(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected bound of partition %s: %w", name, err)
+	}
+
+	to, err := parseBoundValue(matches[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected bound of partition %s: %w", name, err)
+	}
+
+	return &Partition{Name: name, From: from, To: to}, nil
+}
+
+func parseBoundValue(value string) (*int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "MINVALUE" || value == "MAXVALUE" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
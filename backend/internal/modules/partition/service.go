@@ -0,0 +1,148 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPremake = 7
+	daysInWeek     = 7
+	hoursInDay     = 24
+)
+
+type Service interface {
+	// Maintain creates upcoming partitions and drops the ones past retention
+	Maintain(ctx context.Context) error
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+	config Config
+}
+
+func NewService(repo Repository, logger Logger, config Config) Service {
+	if config.Interval != IntervalWeek {
+		config.Interval = IntervalDay
+	}
+	if config.Premake < 1 {
+		config.Premake = defaultPremake
+	}
+
+	return &service{
+		repo:   repo,
+		logger: logger,
+		config: config,
+	}
+}
+
+func (s *service) Maintain(ctx context.Context) error {
+	var errs []error
+	for _, table := range Tables {
+		if err := s.maintainTable(ctx, table, time.Now().UTC()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", table, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *service) maintainTable(ctx context.Context, table string, now time.Time) error {
+	legacyUntil, err := s.repo.GetLegacyUntil(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	partitions, err := s.repo.GetPartitions(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	start := s.periodStart(now)
+	for i := 0; i <= s.config.Premake; i++ {
+		periodFrom := s.addPeriods(start, i)
+		from := periodFrom.UnixMilli()
+		to := s.addPeriods(start, i+1).UnixMilli()
+
+		// The first managed partition starts where the legacy one ends
+		if to <= legacyUntil {
+			continue
+		}
+		from = max(from, legacyUntil)
+
+		if hasOverlap(partitions, from, to) {
+			continue
+		}
+
+		name := fmt.Sprintf("%s_p%s", table, periodFrom.Format("20060102"))
+		created, err := s.repo.CreatePartition(ctx, table, name, from, to)
+		if err != nil {
+			return err
+		}
+		if created {
+			s.logger.Info(fmt.Sprintf("created partition %s", name))
+		}
+	}
+
+	if s.config.RetentionDays <= 0 {
+		return nil
+	}
+
+	// Projects keeping their events longer than the instance keep the partitions too, a project without
+	// a retention keeps them forever. The retention worker removes the rows of the others.
+	projectDays, unlimited, err := s.repo.GetMaxRetentionDays(ctx, table)
+	if err != nil {
+		return err
+	}
+	if unlimited {
+		return nil
+	}
+	days := max(s.config.RetentionDays, projectDays)
+
+	// Group counters and first seen times are not touched by a drop. Every project has a retention
+	// no longer than the cutoff here, so the retention worker has already deleted these events with
+	// their bookkeeping, a drop only catches up with rows it has not reached yet.
+
+	cutoff := now.Add(-time.Duration(days) * hoursInDay * time.Hour).UnixMilli()
+	for _, partition := range partitions {
+		if partition.IsDefault || partition.To == nil || *partition.To > cutoff {
+			continue
+		}
+
+		if err := s.repo.DropPartition(ctx, partition.Name); err != nil {
+			return err
+		}
+		s.logger.Info(fmt.Sprintf("dropped partition %s past retention", partition.Name))
+	}
+
+	return nil
+}
+
+// periodStart truncates to the start of the UTC day, or to Monday for weekly partitions
+func (s *service) periodStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if s.config.Interval != IntervalWeek {
+		return day
+	}
+
+	offset := (int(day.Weekday()) + daysInWeek - int(time.Monday)) % daysInWeek
+	return day.AddDate(0, 0, -offset)
+}
+
+func (s *service) addPeriods(t time.Time, n int) time.Time {
+	if s.config.Interval == IntervalWeek {
+		return t.AddDate(0, 0, n*daysInWeek)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+func hasOverlap(partitions []*Partition, from, to int64) bool {
+	for _, partition := range partitions {
+		if partition.overlaps(from, to) {
+			return true
+		}
+	}
+	return false
+}
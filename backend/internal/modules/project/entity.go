@@ -9,4 +9,9 @@ type Project struct {
 	CreatedAt    int64  `db:"created_at"`
 	UpdatedAt    int64  `db:"updated_at"`
 	DeletedAt    *int64 `db:"deleted_at"`
+	// Retention in days, nil keeps data forever
+	LogsRetentionDays        *int `db:"logs_retention_days"`
+	ErrorsRetentionDays      *int `db:"errors_retention_days"`
+	LogGroupsRetentionDays   *int `db:"log_groups_retention_days"`
+	ErrorGroupsRetentionDays *int `db:"error_groups_retention_days"`
 }
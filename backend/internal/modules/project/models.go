@@ -1,10 +1,16 @@
 package project
 
+import "context"
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
 type GetAllParams struct {
@@ -23,6 +29,14 @@ type Update struct {
 	TechnologyID int    `json:"technologyId" validate:"required" example:"1"`
 }
 
+// Retention is how many days of data a project keeps, null keeps data forever
+type Retention struct {
+	LogsDays        *int `json:"logsDays" validate:"omitempty,min=1" example:"30"`
+	ErrorsDays      *int `json:"errorsDays" validate:"omitempty,min=1" example:"90"`
+	LogGroupsDays   *int `json:"logGroupsDays" validate:"omitempty,min=1" example:"30"`
+	ErrorGroupsDays *int `json:"errorGroupsDays" validate:"omitempty,min=1" example:"180"`
+}
+
 type Entity struct {
 	ID           string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
 	Name         string `json:"name" example:"New project"`
@@ -32,6 +46,20 @@ type Entity struct {
 	LogsLast24h int `json:"logsLast24h" example:"42"`
 }
 
+// DeletedEntity is a deleted project which can still be restored until PurgeAt
+type DeletedEntity struct {
+	ID           string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Name         string `json:"name" example:"New project"`
+	TechnologyID int    `json:"technologyId" example:"1"`
+	DeletedAt    int64  `json:"deletedAt" example:"1735689600"`
+	PurgeAt      int64  `json:"purgeAt" example:"1738281600"`
+}
+
+type DeletedEntityList struct {
+	Count int             `json:"count"`
+	Items []DeletedEntity `json:"items"`
+}
+
 type EntityList struct {
 	Count int      `json:"count"`
 	Items []Entity `json:"items"`
@@ -16,6 +16,8 @@ import (
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrNoDSNKey means every key allowed to send everything was revoked, rotated away or expires
+	ErrNoDSNKey = errors.New("project has no active key with scope all, create one to get a DSN")
 	KeyLength   = 64
 )
 
@@ -25,7 +27,14 @@ type Repository interface {
 	GetByID(ctx context.Context, id string) (*Project, error)
 	Create(ctx context.Context, project *Project) error
 	Update(ctx context.Context, id string, project *Project) error
+	UpdateRetention(ctx context.Context, id string, project *Project) error
+	// Delete marks the project deleted, its data is purged by the retention worker after the restore window
 	Delete(ctx context.Context, id string) error
+	// GetDeleted lists projects of the user deleted at or after since (seconds)
+	GetDeleted(ctx context.Context, since int64) ([]*Project, error)
+	// Restore brings back a project of the user deleted at or after since (seconds), any project without a user
+	Restore(ctx context.Context, id string, since int64) error
+	GetDSNKey(ctx context.Context, projectID string) (string, error)
 }
 
 type repository struct {
@@ -68,7 +77,7 @@ func (r *repository) GetAll(ctx context.Context, params GetAllParams) ([]*Projec
 
 	query = r.db.Rebind(query)
 
-	r.logger.Debug(query)
+	r.logger.DebugContext(ctx, "sql query", "query", query)
 
 	var projects []*Project
 	err = r.db.SelectContext(ctx, &projects, query, namedArgs...)
@@ -107,7 +116,8 @@ func (r *repository) Count(ctx context.Context) (int, error) {
 }
 
 func (r *repository) GetByID(ctx context.Context, id string) (*Project, error) {
-	const query = `SELECT id, name, public_key, technology_id, created_at, updated_at, deleted_at 
+	const query = `SELECT id, name, public_key, technology_id, created_at, updated_at, deleted_at,
+		logs_retention_days, errors_retention_days, log_groups_retention_days, error_groups_retention_days
 		FROM projects WHERE id = $1 AND deleted_at IS NULL`
 
 	var entity Project
@@ -141,19 +151,62 @@ func (r *repository) Create(ctx context.Context, p *Project) error {
 	p.CreatedAt = now
 	p.UpdatedAt = now
 
-	_, err := r.db.NamedExecContext(ctx, query, p)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	if _, err = tx.NamedExecContext(ctx, query, p); err != nil {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
+
+	// The public key is the first ingest key of the project, more are managed by the keys module
+	const keyQuery = `INSERT INTO project_keys (id, project_id, name, public_key, created_at)
+		VALUES ($1, $2, 'Default', $3, $4)`
+	if _, err = tx.ExecContext(ctx, keyQuery, uuid.New().String(), p.ID, p.PublicKey, now); err != nil {
+		return fmt.Errorf("failed to create project key: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+// GetDSNKey returns the oldest active key allowed to send everything. The legacy project key is not a
+// fallback, it is the Default key and may have been revoked because it leaked.
+func (r *repository) GetDSNKey(ctx context.Context, projectID string) (string, error) {
+	const query = `
+		SELECT public_key FROM project_keys
+		WHERE project_id = $1 AND scope = 'all' AND revoked_at IS NULL AND expires_at IS NULL
+		ORDER BY created_at, id
+		LIMIT 1`
+
+	var key string
+	err := r.db.GetContext(ctx, &key, query, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoDSNKey
+		}
+		return "", fmt.Errorf("failed to get project key: %w", err)
+	}
+	return key, nil
+}
+
 func (r *repository) Update(ctx context.Context, id string, updated *Project) error {
 	const query = `UPDATE projects 
 		SET name = :name,
 		    technology_id = :technology_id,
 		    updated_at = :updated_at
-		WHERE id = :id`
+		WHERE id = :id AND deleted_at IS NULL`
 
 	updated.ID = id
 	updated.UpdatedAt = time.Now().Unix()
@@ -173,10 +226,37 @@ func (r *repository) Update(ctx context.Context, id string, updated *Project) er
 	return nil
 }
 
+func (r *repository) UpdateRetention(ctx context.Context, id string, updated *Project) error {
+	const query = `UPDATE projects 
+		SET logs_retention_days = :logs_retention_days,
+		    errors_retention_days = :errors_retention_days,
+		    log_groups_retention_days = :log_groups_retention_days,
+		    error_groups_retention_days = :error_groups_retention_days,
+		    updated_at = :updated_at
+		WHERE id = :id AND deleted_at IS NULL`
+
+	updated.ID = id
+	updated.UpdatedAt = time.Now().Unix()
+
+	result, err := r.db.NamedExecContext(ctx, query, updated)
+	if err != nil {
+		return fmt.Errorf("failed to update project retention: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (r *repository) Delete(ctx context.Context, id string) error {
-	const query = `DELETE FROM projects WHERE id = $1`
+	const query = `UPDATE projects SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, id, time.Now().Unix())
 	if err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
@@ -191,6 +271,50 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (r *repository) GetDeleted(ctx context.Context, since int64) ([]*Project, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	const query = `SELECT id, name, public_key, technology_id, created_at, updated_at, deleted_at
+		FROM projects
+		WHERE creator_id = $1 AND deleted_at >= $2
+		ORDER BY deleted_at DESC, id`
+
+	var projects []*Project
+	err := r.db.SelectContext(ctx, &projects, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted projects: %w", err)
+	}
+	return projects, nil
+}
+
+func (r *repository) Restore(ctx context.Context, id string, since int64) error {
+	query := `UPDATE projects SET deleted_at = NULL, updated_at = $3 WHERE id = $1 AND deleted_at >= $2`
+	args := []interface{}{id, since, time.Now().Unix()}
+
+	// Users restore their own projects, the admin CLI acts without a user and may restore any
+	if userID, ok := middleware.GetUserID(ctx); ok {
+		query += " AND creator_id = $4"
+		args = append(args, userID)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to restore project: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func generateRandomKey() string {
 	b := make([]byte, KeyLength)
 	_, err := rand.Read(b)
@@ -2,36 +2,64 @@ package project
 
 import (
 	"context"
+	"time"
 
+	"github.com/duckbugio/duckbug/internal/middleware"
 	moduleErrors "github.com/duckbugio/duckbug/internal/modules/errors"
 	moduleErrorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
 	moduleLog "github.com/duckbugio/duckbug/internal/modules/log"
 	"github.com/google/uuid"
 )
 
+const (
+	// auditTarget names projects in the audit log
+	auditTarget = "project"
+	// DefaultRestoreDays is how long a deleted project can be restored before its data is purged
+	DefaultRestoreDays = 30
+	hoursInDay         = 24
+)
+
+// Invalidator drops cached keys of a project, so a deleted project stops accepting events at once
+type Invalidator interface {
+	Invalidate(projectID string)
+}
+
 type Service interface {
 	GetByID(ctx context.Context, id string) (*Entity, error)
 	GetDSNByID(ctx context.Context, id string) (string, error)
 	GetAll(ctx context.Context, params GetAllParams) ([]*Entity, int, error)
 	Create(ctx context.Context, req *Create) (*Entity, error)
 	Update(ctx context.Context, id string, req *Update) (*Entity, error)
+	GetRetention(ctx context.Context, id string) (*Retention, error)
+	UpdateRetention(ctx context.Context, id string, req *Retention) (*Retention, error)
 	Delete(ctx context.Context, id string) error
+	// GetDeleted lists deleted projects of the user which can still be restored
+	GetDeleted(ctx context.Context) ([]*DeletedEntity, error)
+	Restore(ctx context.Context, id string) (*Entity, error)
 }
 
 type service struct {
 	repo            Repository
+	invalidator     Invalidator
 	logger          Logger
 	domain          string
+	restoreWindow   time.Duration
 	errorsRepo      moduleErrors.Repository
 	errorGroupsRepo moduleErrorsGroup.Repository
 	logsRepo        moduleLog.Repository
 }
 
-func NewService(repo Repository, logger Logger, domain string) Service {
+func NewService(repo Repository, invalidator Invalidator, logger Logger, domain string, restoreDays int) Service {
+	if restoreDays < 1 {
+		restoreDays = DefaultRestoreDays
+	}
+
 	return &service{
-		repo:   repo,
-		logger: logger,
-		domain: domain,
+		repo:          repo,
+		invalidator:   invalidator,
+		logger:        logger,
+		domain:        domain,
+		restoreWindow: time.Duration(restoreDays) * hoursInDay * time.Hour,
 	}
 }
 
@@ -56,7 +84,12 @@ func (s *service) GetDSNByID(ctx context.Context, id string) (string, error) {
 		return "", err
 	}
 
-	dsn := "https://" + s.domain + "/api/ingest/" + project.ID + ":" + project.PublicKey
+	key, err := s.repo.GetDSNKey(ctx, project.ID)
+	if err != nil {
+		return "", err
+	}
+
+	dsn := "https://" + s.domain + "/api/ingest/" + project.ID + ":" + key
 
 	return dsn, nil
 }
@@ -122,6 +155,7 @@ func (s *service) Create(ctx context.Context, req *Create) (*Entity, error) {
 		return nil, err
 	}
 
+	middleware.Audit(ctx, "project.create", auditTarget, project.ID, nil, toResponse(project))
 	return toResponse(project), nil
 }
 
@@ -131,6 +165,7 @@ func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity,
 		return nil, err
 	}
 
+	before := toResponse(project)
 	if req.Name != "" {
 		project.Name = req.Name
 	}
@@ -142,11 +177,91 @@ func (s *service) Update(ctx context.Context, id string, req *Update) (*Entity,
 		return nil, err
 	}
 
+	middleware.Audit(ctx, "project.update", auditTarget, id, before, toResponse(project))
 	return toResponse(project), nil
 }
 
+func (s *service) GetRetention(ctx context.Context, id string) (*Retention, error) {
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toRetention(project), nil
+}
+
+func (s *service) UpdateRetention(ctx context.Context, id string, req *Retention) (*Retention, error) {
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := toRetention(project)
+	project.LogsRetentionDays = req.LogsDays
+	project.ErrorsRetentionDays = req.ErrorsDays
+	project.LogGroupsRetentionDays = req.LogGroupsDays
+	project.ErrorGroupsRetentionDays = req.ErrorGroupsDays
+
+	if err := s.repo.UpdateRetention(ctx, id, project); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "project.retention.update", auditTarget, id, before, toRetention(project))
+	return toRetention(project), nil
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidator.Invalidate(id)
+
+	middleware.Audit(ctx, "project.delete", auditTarget, id, toResponse(project), nil)
+	return nil
+}
+
+func (s *service) GetDeleted(ctx context.Context) ([]*DeletedEntity, error) {
+	projects, err := s.repo.GetDeleted(ctx, s.restorableSince())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*DeletedEntity, 0, len(projects))
+	for _, project := range projects {
+		deletedAt := *project.DeletedAt
+		responses = append(responses, &DeletedEntity{
+			ID:           project.ID,
+			Name:         project.Name,
+			TechnologyID: project.TechnologyID,
+			DeletedAt:    deletedAt,
+			PurgeAt:      time.Unix(deletedAt, 0).Add(s.restoreWindow).Unix(),
+		})
+	}
+	return responses, nil
+}
+
+func (s *service) Restore(ctx context.Context, id string) (*Entity, error) {
+	if err := s.repo.Restore(ctx, id, s.restorableSince()); err != nil {
+		return nil, err
+	}
+	s.invalidator.Invalidate(id)
+
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "project.restore", auditTarget, id, nil, toResponse(project))
+	return toResponse(project), nil
+}
+
+// restorableSince is the oldest deletion time of a project which is not purged yet
+func (s *service) restorableSince() int64 {
+	return time.Now().Add(-s.restoreWindow).Unix()
 }
 
 func toResponse(p *Project) *Entity {
@@ -156,3 +271,12 @@ func toResponse(p *Project) *Entity {
 		TechnologyID: p.TechnologyID,
 	}
 }
+
+func toRetention(p *Project) *Retention {
+	return &Retention{
+		LogsDays:        p.LogsRetentionDays,
+		ErrorsDays:      p.ErrorsRetentionDays,
+		LogGroupsDays:   p.LogGroupsRetentionDays,
+		ErrorGroupsDays: p.ErrorGroupsRetentionDays,
+	}
+}
@@ -0,0 +1,36 @@
+package release
+
+type Session struct {
+	ID          string  `db:"id"`
+	ProjectID   string  `db:"project_id"`
+	Release     string  `db:"release"`
+	Environment string  `db:"environment"`
+	UserHash    *string `db:"user_hash"`
+	Status      string  `db:"status"`
+	StartedAt   int64   `db:"started_at"`
+	EndedAt     *int64  `db:"ended_at"`
+}
+
+// Health counts the sessions of a release
+type Health struct {
+	Release         string `db:"release"`
+	Sessions        int    `db:"sessions"`
+	CrashedSessions int    `db:"crashed_sessions"`
+	Users           int    `db:"users"`
+	CrashedUsers    int    `db:"crashed_users"`
+	FirstSessionAt  int64  `db:"first_session_at"`
+	LastSessionAt   int64  `db:"last_session_at"`
+}
+
+// GroupChange is an error group seen in the compared release, with its events in both releases
+type GroupChange struct {
+	ID          string `db:"id"`
+	Message     string `db:"message"`
+	File        string `db:"file"`
+	Line        int    `db:"line"`
+	Status      string `db:"status"`
+	Change      string `db:"change"`
+	BaseCount   int    `db:"base_count"`
+	TargetCount int    `db:"target_count"`
+	FirstSeenAt int64  `db:"first_seen_at"`
+}
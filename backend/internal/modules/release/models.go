@@ -0,0 +1,113 @@
+package release
+
+import (
+	"context"
+	"errors"
+)
+
+// Session statuses, a session starts and ends or crashes. An ended session may still be marked crashed,
+// a crashed one stays crashed.
+const (
+	StatusStarted = "started"
+	StatusEnded   = "ended"
+	StatusCrashed = "crashed"
+)
+
+// Changes of an error group between two releases
+const (
+	// ChangeNew groups have events in the compared release only
+	ChangeNew = "new"
+	// ChangeRegressed groups have events in both releases and came back after being resolved
+	// while the compared release was live
+	ChangeRegressed = "regressed"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+var ErrSameRelease = errors.New("base and target must be different releases")
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Create is a session update sent by an SDK when a session starts and when it ends or crashes
+type Create struct {
+	// ID is generated by the SDK, updates with the same ID change the same session
+	ID          string `json:"id" validate:"required,max=64" example:"5f0c1a8e-3c4d-4c37-9d0e-2a6b8d1f4e21"`
+	Status      string `json:"status" validate:"required,oneof=started ended crashed" example:"started"`
+	Release     string `json:"release" validate:"required,max=255" example:"1.4.2"`
+	Environment string `json:"environment" validate:"omitempty,max=64" example:"production"`
+	// UserID is the end user of the session, only its hash is stored to count crash free users
+	UserID string `json:"userId" validate:"omitempty,max=256" example:"42"`
+	// Time of the update, defaults to the time it is received. Unix timestamp in milliseconds.
+	Time int64 `json:"time" validate:"omitempty,min=0" example:"1704067200000" format:"int64"`
+	// Started is the start of the session for an update that ends it, when the start was not sent
+	Started   int64  `json:"started" validate:"omitempty,min=0" example:"1704067100000" format:"int64"`
+	ProjectID string `json:"-"`
+}
+
+type HealthParams struct {
+	ProjectID   string
+	Environment string
+	// TimeFrom and TimeTo bound the session starts, in milliseconds
+	TimeFrom int64
+	TimeTo   int64
+	// Releases limits the result to these releases
+	Releases []string
+	Limit    int
+}
+
+type CompareParams struct {
+	ProjectID string
+	// Base is the release compared against, usually the previous one
+	Base   string
+	Target string
+	// Environment only applies to the session counts, errors carry no environment
+	Environment string
+	Limit       int
+}
+
+type HealthEntity struct {
+	Release         string `json:"release" example:"1.4.2"`
+	Sessions        int    `json:"sessions" example:"1200"`
+	CrashedSessions int    `json:"crashedSessions" example:"6"`
+	// Users counts the distinct users of sessions reporting a userId
+	Users        int `json:"users" example:"300"`
+	CrashedUsers int `json:"crashedUsers" example:"4"`
+	// CrashFreeSessions and CrashFreeUsers are percentages, null without sessions or users
+	CrashFreeSessions *float64 `json:"crashFreeSessions" example:"99.5"`
+	CrashFreeUsers    *float64 `json:"crashFreeUsers" example:"98.667"`
+	// FirstSessionAt and LastSessionAt are Unix timestamps in milliseconds
+	FirstSessionAt int64 `json:"firstSessionAt" example:"1704067200000"`
+	LastSessionAt  int64 `json:"lastSessionAt" example:"1704153600000"`
+}
+
+type GroupChangeEntity struct {
+	ID      string `json:"id" example:"5d41402abc4b2a76b9719d911017c592"`
+	Message string `json:"message" example:"Undefined index: user"`
+	File    string `json:"file" example:"index.php"`
+	Line    int    `json:"line" example:"42"`
+	Status  string `json:"status" example:"unresolved"`
+	// Change is new or regressed
+	Change      string `json:"change" example:"new"`
+	BaseCount   int    `json:"baseCount" example:"0"`
+	TargetCount int    `json:"targetCount" example:"18"`
+	// FirstSeenAt is the first event of the group in the target release, Unix timestamp in milliseconds
+	FirstSeenAt int64 `json:"firstSeenAt" example:"1704067200000"`
+}
+
+type Comparison struct {
+	Base   HealthEntity        `json:"base"`
+	Target HealthEntity        `json:"target"`
+	Groups []GroupChangeEntity `json:"groups"`
+}
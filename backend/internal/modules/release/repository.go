@@ -0,0 +1,150 @@
+package release
+
+import (
+	"context"
+	"fmt"
+
+	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	// Save inserts a session or applies an update to it, the status never goes back to started
+	// and a crashed session stays crashed
+	Save(ctx context.Context, session *Session) error
+	// GetHealth counts the sessions per release, the most recent releases first
+	GetHealth(ctx context.Context, params HealthParams) ([]*Health, error)
+	// CompareGroups returns the error groups of the target release which are new or regressed against base
+	CompareGroups(ctx context.Context, projectID, base, target string, limit int) ([]*GroupChange, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, session *Session) error {
+	const query = `
+		INSERT INTO sessions (id, project_id, release, environment, user_hash, status, started_at, ended_at)
+		VALUES (:id, :project_id, :release, :environment, :user_hash, :status, :started_at, :ended_at)
+		ON CONFLICT (project_id, id) DO UPDATE SET
+			status = CASE
+				WHEN sessions.status = 'crashed' OR EXCLUDED.status = 'started' THEN sessions.status
+				ELSE EXCLUDED.status
+			END,
+			started_at = LEAST(sessions.started_at, EXCLUDED.started_at),
+			ended_at = GREATEST(sessions.ended_at, EXCLUDED.ended_at),
+			user_hash = COALESCE(sessions.user_hash, EXCLUDED.user_hash)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if _, err := r.db.NamedExecContext(ctx, query, session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetHealth(ctx context.Context, params HealthParams) ([]*Health, error) {
+	query := `
+		SELECT
+			release,
+			COUNT(*) AS sessions,
+			COUNT(*) FILTER (WHERE status = 'crashed') AS crashed_sessions,
+			COUNT(DISTINCT user_hash) AS users,
+			COUNT(DISTINCT user_hash) FILTER (WHERE status = 'crashed') AS crashed_users,
+			MIN(started_at) AS first_session_at,
+			MAX(started_at) AS last_session_at
+		FROM sessions
+		WHERE project_id = :projectId`
+
+	args := map[string]interface{}{
+		"projectId": params.ProjectID,
+		"limit":     params.Limit,
+	}
+
+	if params.Environment != "" {
+		query += " AND environment = :environment"
+		args["environment"] = params.Environment
+	}
+
+	if params.TimeFrom != 0 {
+		query += " AND started_at >= :timeFrom"
+		args["timeFrom"] = params.TimeFrom
+	}
+
+	if params.TimeTo != 0 {
+		query += " AND started_at <= :timeTo"
+		args["timeTo"] = params.TimeTo
+	}
+
+	if len(params.Releases) > 0 {
+		query += " AND release = ANY(:releases)"
+		args["releases"] = pq.Array(params.Releases)
+	}
+
+	query += " GROUP BY release ORDER BY last_session_at DESC, release LIMIT :limit"
+
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var health []*Health
+	if err = r.db.SelectContext(ctx, &health, query, namedArgs...); err != nil {
+		return nil, fmt.Errorf("failed to get release health: %w", err)
+	}
+	return health, nil
+}
+
+func (r *repository) CompareGroups(ctx context.Context, projectID, base, target string, limit int) ([]*GroupChange, error) {
+	// A group regressed when it was resolved and came back after its first event in the target release
+	const query = `
+		WITH in_target AS (
+			SELECT fingerprint, COUNT(*) AS events, MIN(time) AS first_time
+			FROM errors
+			WHERE project_id = $1 AND release = $3 AND fingerprint IS NOT NULL
+			GROUP BY fingerprint
+		), in_base AS (
+			SELECT fingerprint, COUNT(*) AS events
+			FROM errors
+			WHERE project_id = $1 AND release = $2 AND fingerprint IN (SELECT fingerprint FROM in_target)
+			GROUP BY fingerprint
+		)
+		SELECT
+			g.id, g.message, g.file, g.line, g.status,
+			CASE WHEN in_base.fingerprint IS NULL THEN $4 ELSE $5 END AS change,
+			COALESCE(in_base.events, 0) AS base_count,
+			in_target.events AS target_count,
+			in_target.first_time AS first_seen_at
+		FROM in_target
+		JOIN error_groups g ON g.id = in_target.fingerprint
+		LEFT JOIN in_base ON in_base.fingerprint = in_target.fingerprint
+		WHERE in_base.fingerprint IS NULL OR EXISTS (
+			SELECT 1 FROM error_group_activity a
+			WHERE a.group_id = g.id AND a.type = $6 AND a.created_at >= in_target.first_time / 1000
+		)
+		ORDER BY change, target_count DESC, g.id
+		LIMIT $7`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var changes []*GroupChange
+	err := r.db.SelectContext(ctx, &changes, query,
+		projectID, base, target, ChangeNew, ChangeRegressed, errorsGroup.ActivityRegressed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare releases: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,154 @@
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"time"
+)
+
+const (
+	percent = 100
+	// rateScale rounds crash free rates to three decimals
+	rateScale = 1000
+)
+
+type Service interface {
+	// Record stores a session update received through ingest
+	Record(ctx context.Context, req *Create) error
+	GetHealth(ctx context.Context, params HealthParams) ([]HealthEntity, error)
+	// Compare returns the health of both releases and the error groups new or regressed in the target
+	Compare(ctx context.Context, params CompareParams) (*Comparison, error)
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+}
+
+func NewService(repo Repository, logger Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Record(ctx context.Context, req *Create) error {
+	return s.repo.Save(ctx, newSession(req, time.Now()))
+}
+
+func (s *service) GetHealth(ctx context.Context, params HealthParams) ([]HealthEntity, error) {
+	health, err := s.repo.GetHealth(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]HealthEntity, 0, len(health))
+	for _, h := range health {
+		entities = append(entities, toHealthEntity(h))
+	}
+	return entities, nil
+}
+
+func (s *service) Compare(ctx context.Context, params CompareParams) (*Comparison, error) {
+	if params.Base == params.Target {
+		return nil, ErrSameRelease
+	}
+
+	releases := []string{params.Base, params.Target}
+	health, err := s.repo.GetHealth(ctx, HealthParams{
+		ProjectID:   params.ProjectID,
+		Environment: params.Environment,
+		Releases:    releases,
+		Limit:       len(releases),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.CompareGroups(ctx, params.ProjectID, params.Base, params.Target, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &Comparison{
+		Base:   HealthEntity{Release: params.Base},
+		Target: HealthEntity{Release: params.Target},
+		Groups: make([]GroupChangeEntity, 0, len(changes)),
+	}
+	for _, h := range health {
+		if h.Release == params.Base {
+			comparison.Base = toHealthEntity(h)
+		} else {
+			comparison.Target = toHealthEntity(h)
+		}
+	}
+	for _, change := range changes {
+		comparison.Groups = append(comparison.Groups, GroupChangeEntity{
+			ID:          change.ID,
+			Message:     change.Message,
+			File:        change.File,
+			Line:        change.Line,
+			Status:      change.Status,
+			Change:      change.Change,
+			BaseCount:   change.BaseCount,
+			TargetCount: change.TargetCount,
+			FirstSeenAt: change.FirstSeenAt,
+		})
+	}
+	return comparison, nil
+}
+
+// newSession turns an update into the row it is merged into, an update ending the session
+// without its start starts it at Started or at the time of the update
+func newSession(req *Create, now time.Time) *Session {
+	at := req.Time
+	if at == 0 {
+		at = now.UnixMilli()
+	}
+
+	session := &Session{
+		ID:          req.ID,
+		ProjectID:   req.ProjectID,
+		Release:     req.Release,
+		Environment: req.Environment,
+		Status:      req.Status,
+		StartedAt:   at,
+	}
+	if req.Status != StatusStarted {
+		session.EndedAt = &at
+		if req.Started != 0 && req.Started < at {
+			session.StartedAt = req.Started
+		}
+	}
+	if req.UserID != "" {
+		sum := sha256.Sum256([]byte(req.UserID))
+		hash := hex.EncodeToString(sum[:])
+		session.UserHash = &hash
+	}
+	return session
+}
+
+func toHealthEntity(h *Health) HealthEntity {
+	return HealthEntity{
+		Release:           h.Release,
+		Sessions:          h.Sessions,
+		CrashedSessions:   h.CrashedSessions,
+		Users:             h.Users,
+		CrashedUsers:      h.CrashedUsers,
+		CrashFreeSessions: crashFree(h.Sessions, h.CrashedSessions),
+		CrashFreeUsers:    crashFree(h.Users, h.CrashedUsers),
+		FirstSessionAt:    h.FirstSessionAt,
+		LastSessionAt:     h.LastSessionAt,
+	}
+}
+
+// crashFree returns the share of total without a crash in percent, rounded to three decimals
+func crashFree(total, crashed int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	rate := math.Round(float64(total-crashed)/float64(total)*percent*rateScale) / rateScale
+	return &rate
+}
@@ -0,0 +1,41 @@
+package release
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrashFree(t *testing.T) {
+	assert.Nil(t, crashFree(0, 0))
+
+	rate := crashFree(300, 4)
+	require.NotNil(t, rate)
+	assert.InDelta(t, 98.667, *rate, 0.0001)
+
+	rate = crashFree(10, 0)
+	require.NotNil(t, rate)
+	assert.InDelta(t, 100.0, *rate, 0.0001)
+}
+
+func TestNewSession(t *testing.T) {
+	now := time.UnixMilli(1704067200000)
+
+	started := newSession(&Create{ID: "s1", Status: StatusStarted, Release: "1.0.0", UserID: "42"}, now)
+	assert.Equal(t, now.UnixMilli(), started.StartedAt)
+	assert.Nil(t, started.EndedAt)
+	require.NotNil(t, started.UserHash)
+	assert.Len(t, *started.UserHash, 64)
+	assert.NotContains(t, *started.UserHash, "42")
+
+	crashed := newSession(&Create{ID: "s1", Status: StatusCrashed, Release: "1.0.0", Time: 1704067260000, Started: 1704067200000}, now)
+	require.NotNil(t, crashed.EndedAt)
+	assert.Equal(t, int64(1704067260000), *crashed.EndedAt)
+	assert.Equal(t, int64(1704067200000), crashed.StartedAt)
+	assert.Nil(t, crashed.UserHash)
+
+	ended := newSession(&Create{ID: "s2", Status: StatusEnded, Release: "1.0.0", Time: 1704067260000}, now)
+	assert.Equal(t, int64(1704067260000), ended.StartedAt, "an end without a start starts the session at its end")
+}
@@ -0,0 +1,45 @@
+package retention
+
+// Policy is the retention of a single project in days, nil keeps data forever
+type Policy struct {
+	ProjectID       string `db:"id"`
+	LogsDays        *int   `db:"logs_retention_days"`
+	ErrorsDays      *int   `db:"errors_retention_days"`
+	LogGroupsDays   *int   `db:"log_groups_retention_days"`
+	ErrorGroupsDays *int   `db:"error_groups_retention_days"`
+}
+
+// eventTable describes an events table and the groups table its fingerprints point to
+type eventTable struct {
+	events string
+	groups string
+	// fingerprintType matches the fingerprint column so that its index is used
+	fingerprintType string
+}
+
+var (
+	logsTable = eventTable{
+		events:          "logs",
+		groups:          "log_groups",
+		fingerprintType: "varchar",
+	}
+	errorsTable = eventTable{
+		events:          "errors",
+		groups:          "error_groups",
+		fingerprintType: "bpchar",
+	}
+)
+
+// projectTables hold settings and counters of a project, they are removed with the project itself
+var projectTables = []string{
+	"scrubbing_settings",
+	"scrubbing_rules",
+	"ingest_limits",
+	"ingest_stats",
+	"project_keys",
+	"error_group_comments",
+	"error_group_activity",
+	"saved_searches",
+	"monitor_checkins",
+	"monitors",
+}
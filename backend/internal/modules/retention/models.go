@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type Config struct {
+	// BatchSize is the number of rows removed per statement
+	BatchSize int
+	// MaxBatches bounds the work done for a single project and kind of data in one run
+	MaxBatches int
+	// DeletedProjectDays is how long a deleted project can be restored before its data is purged
+	DeletedProjectDays int
+}
+
+// Report counts what a purge run removed
+type Report struct {
+	Logs        int
+	Errors      int
+	LogGroups   int
+	ErrorGroups int
+	Sessions    int
+	Spans       int
+	// Projects counts deleted projects purged for good
+	Projects int
+}
+
+func (r Report) IsEmpty() bool {
+	return r.Logs == 0 && r.Errors == 0 && r.LogGroups == 0 && r.ErrorGroups == 0 && r.Sessions == 0 &&
+		r.Spans == 0 && r.Projects == 0
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d logs, %d errors, %d log groups, %d error groups, %d sessions, %d spans, %d projects",
+		r.Logs, r.Errors, r.LogGroups, r.ErrorGroups, r.Sessions, r.Spans, r.Projects)
+}
+
+func (r *Report) Add(other Report) {
+	r.Logs += other.Logs
+	r.Errors += other.Errors
+	r.LogGroups += other.LogGroups
+	r.ErrorGroups += other.ErrorGroups
+	r.Sessions += other.Sessions
+	r.Spans += other.Spans
+	r.Projects += other.Projects
+}
@@ -0,0 +1,271 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetPolicies(ctx context.Context) ([]*Policy, error)
+	// DeleteEventsBefore removes up to limit events older than before (ms) and returns how many were removed
+	// per fingerprint, events without one count under the empty fingerprint
+	DeleteEventsBefore(ctx context.Context, table eventTable, projectID string, before int64, limit int) (map[string]int, error)
+	// DeleteStaleGroupEvents removes up to limit events of groups last seen before lastSeenBefore (seconds)
+	DeleteStaleGroupEvents(ctx context.Context, table eventTable, projectID string, lastSeenBefore int64, limit int) (int, error)
+	// DeleteStaleGroups removes up to limit groups last seen before lastSeenBefore (seconds),
+	// their events have to be removed by DeleteStaleGroupEvents first
+	DeleteStaleGroups(ctx context.Context, table eventTable, projectID string, lastSeenBefore int64, limit int) (int, error)
+	// GetDeletedProjects returns projects deleted before deletedBefore (seconds)
+	GetDeletedProjects(ctx context.Context, deletedBefore int64) ([]string, error)
+	// DeleteProjectEvents removes up to limit events of a project
+	DeleteProjectEvents(ctx context.Context, table eventTable, projectID string, limit int) (int, error)
+	// DeleteProjectGroups removes up to limit groups of a project
+	DeleteProjectGroups(ctx context.Context, table eventTable, projectID string, limit int) (int, error)
+	// DeleteSessionsBefore removes up to limit sessions started before before (ms), 0 removes any session
+	DeleteSessionsBefore(ctx context.Context, projectID string, before int64, limit int) (int, error)
+	// DeleteSpansBefore removes up to limit spans started before before (ms), 0 removes any span
+	DeleteSpansBefore(ctx context.Context, projectID string, before int64, limit int) (int, error)
+	// DeleteProject removes a deleted project with its settings, its events and groups must be purged first.
+	// It returns false when the project was restored meanwhile.
+	DeleteProject(ctx context.Context, projectID string) (bool, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetPolicies(ctx context.Context) ([]*Policy, error) {
+	const query = `
+		SELECT id, logs_retention_days, errors_retention_days, log_groups_retention_days, error_groups_retention_days
+		FROM projects
+		WHERE deleted_at IS NULL AND (
+			logs_retention_days IS NOT NULL OR errors_retention_days IS NOT NULL OR
+			log_groups_retention_days IS NOT NULL OR error_groups_retention_days IS NOT NULL
+		)`
+
+	var policies []*Policy
+	err := r.db.SelectContext(ctx, &policies, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+func (r *repository) DeleteEventsBefore(
+	ctx context.Context, table eventTable, projectID string, before int64, limit int,
+) (map[string]int, error) {
+	// (id, time) is the primary key of the partitioned tables, ctid is not unique across partitions
+	query := fmt.Sprintf(`
+		WITH deleted AS (
+			DELETE FROM %[1]s WHERE (id, time) IN (
+				SELECT id, time FROM %[1]s WHERE project_id = $1 AND time < $2 LIMIT $3
+			)
+			RETURNING coalesce(fingerprint, '') AS fingerprint
+		)
+		SELECT fingerprint, count(*) AS removed FROM deleted GROUP BY fingerprint`, table.events)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var rows []struct {
+		Fingerprint string `db:"fingerprint"`
+		Removed     int    `db:"removed"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, projectID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete old %s: %w", table.events, err)
+	}
+
+	removed := make(map[string]int, len(rows))
+	for _, row := range rows {
+		removed[row.Fingerprint] = row.Removed
+	}
+	return removed, nil
+}
+
+func (r *repository) DeleteStaleGroupEvents(
+	ctx context.Context, table eventTable, projectID string, lastSeenBefore int64, limit int,
+) (int, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE (id, time) IN (
+			SELECT e.id, e.time FROM %[1]s e
+			JOIN %[2]s g ON e.fingerprint = CAST(g.id AS %[3]s)
+			WHERE e.project_id = $1 AND g.project_id = $1 AND g.last_seen_at < $2
+			LIMIT $3
+		)`, table.events, table.groups, table.fingerprintType)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, projectID, lastSeenBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %s of stale groups: %w", table.events, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
+
+func (r *repository) DeleteStaleGroups(
+	ctx context.Context, table eventTable, projectID string, lastSeenBefore int64, limit int,
+) (int, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE id IN (
+			SELECT id FROM %[1]s WHERE project_id = $1 AND last_seen_at < $2 LIMIT $3
+		)`, table.groups)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, projectID, lastSeenBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale %s: %w", table.groups, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
+
+func (r *repository) GetDeletedProjects(ctx context.Context, deletedBefore int64) ([]string, error) {
+	const query = `SELECT id FROM projects WHERE deleted_at < $1 ORDER BY deleted_at`
+
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, query, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted projects: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *repository) DeleteProjectEvents(ctx context.Context, table eventTable, projectID string, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE (id, time) IN (
+			SELECT id, time FROM %[1]s WHERE project_id = $1 LIMIT $2
+		)`, table.events)
+
+	return r.deleteRows(ctx, query, table.events, projectID, limit)
+}
+
+func (r *repository) DeleteProjectGroups(ctx context.Context, table eventTable, projectID string, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s WHERE id IN (
+			SELECT id FROM %[1]s WHERE project_id = $1 LIMIT $2
+		)`, table.groups)
+
+	return r.deleteRows(ctx, query, table.groups, projectID, limit)
+}
+
+func (r *repository) DeleteSessionsBefore(ctx context.Context, projectID string, before int64, limit int) (int, error) {
+	const query = `
+		DELETE FROM sessions WHERE (project_id, id) IN (
+			SELECT project_id, id FROM sessions WHERE project_id = $1 AND (CAST($2 AS BIGINT) = 0 OR started_at < $2) LIMIT $3
+		)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, projectID, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
+
+func (r *repository) DeleteSpansBefore(ctx context.Context, projectID string, before int64, limit int) (int, error) {
+	const query = `
+		DELETE FROM spans WHERE (project_id, trace_id, span_id) IN (
+			SELECT project_id, trace_id, span_id FROM spans
+			WHERE project_id = $1 AND (CAST($2 AS BIGINT) = 0 OR start_time < $2) LIMIT $3
+		)`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, projectID, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete spans: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
+
+func (r *repository) deleteRows(ctx context.Context, query, table, projectID string, limit int) (int, error) {
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	result, err := r.db.ExecContext(ctx, query, projectID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %s of deleted project: %w", table, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(affected), nil
+}
+
+func (r *repository) DeleteProject(ctx context.Context, projectID string) (deleted bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	// A project restored in the meantime keeps everything
+	err = tx.GetContext(ctx, &deleted,
+		`SELECT deleted_at IS NOT NULL FROM projects WHERE id = $1 FOR UPDATE`, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock deleted project: %w", err)
+	}
+	if !deleted {
+		return false, tx.Commit()
+	}
+
+	for _, table := range projectTables {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE project_id = $1`, table)
+		if _, err = tx.ExecContext(ctx, query, projectID); err != nil {
+			return false, fmt.Errorf("failed to delete %s of deleted project: %w", table, err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, projectID); err != nil {
+		return false, fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,298 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultBatchSize  = 1000
+	defaultMaxBatches = 100
+	// defaultDeletedProjectDays matches the restore window of the project module
+	defaultDeletedProjectDays = 30
+	hoursInDay                = 24
+)
+
+// GroupCounter keeps group counters in sync after events were removed
+type GroupCounter interface {
+	Decrement(ctx context.Context, removed map[string]int) error
+}
+
+type Service interface {
+	// Purge removes data past the retention of every project and deleted projects past their restore window,
+	// it reports the totals
+	Purge(ctx context.Context) (Report, error)
+}
+
+type service struct {
+	repo        Repository
+	logGroups   GroupCounter
+	errorGroups GroupCounter
+	logger      Logger
+	config      Config
+}
+
+func NewService(repo Repository, logGroups, errorGroups GroupCounter, logger Logger, config Config) Service {
+	if config.BatchSize < 1 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.MaxBatches < 1 {
+		config.MaxBatches = defaultMaxBatches
+	}
+	if config.DeletedProjectDays < 1 {
+		config.DeletedProjectDays = defaultDeletedProjectDays
+	}
+
+	return &service{
+		repo:        repo,
+		logGroups:   logGroups,
+		errorGroups: errorGroups,
+		logger:      logger,
+		config:      config,
+	}
+}
+
+func (s *service) Purge(ctx context.Context) (Report, error) {
+	var total Report
+
+	policies, err := s.repo.GetPolicies(ctx)
+	if err != nil {
+		return total, err
+	}
+
+	var errs []error
+	now := time.Now()
+	for _, policy := range policies {
+		report, err := s.purgeProject(ctx, policy, now)
+		total.Add(report)
+
+		if !report.IsEmpty() {
+			s.logger.Info(fmt.Sprintf("retention purged project %s: %s", policy.ProjectID, report))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("project %s: %w", policy.ProjectID, err))
+		}
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			return total, errors.Join(errs...)
+		}
+	}
+
+	report, err := s.purgeDeletedProjects(ctx, now)
+	total.Add(report)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return total, errors.Join(errs...)
+}
+
+// purgeDeletedProjects removes everything of projects deleted before the restore window,
+// a project with more data than one run removes is finished by the next runs
+func (s *service) purgeDeletedProjects(ctx context.Context, now time.Time) (Report, error) {
+	var total Report
+
+	ids, err := s.repo.GetDeletedProjects(ctx, cutoff(now, s.config.DeletedProjectDays).Unix())
+	if err != nil {
+		return total, err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		report, err := s.purgeDeletedProject(ctx, id)
+		total.Add(report)
+
+		if !report.IsEmpty() {
+			s.logger.Info(fmt.Sprintf("retention purged deleted project %s: %s", id, report))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleted project %s: %w", id, err))
+		}
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+	}
+
+	return total, errors.Join(errs...)
+}
+
+func (s *service) purgeDeletedProject(ctx context.Context, projectID string) (Report, error) {
+	var report Report
+
+	// Events go first, so groups are never left without counters of existing events
+	steps := []struct {
+		removed *int
+		remove  func(limit int) (int, error)
+	}{
+		{&report.Logs, func(limit int) (int, error) {
+			return s.repo.DeleteProjectEvents(ctx, logsTable, projectID, limit)
+		}},
+		{&report.Errors, func(limit int) (int, error) {
+			return s.repo.DeleteProjectEvents(ctx, errorsTable, projectID, limit)
+		}},
+		{&report.LogGroups, func(limit int) (int, error) {
+			return s.repo.DeleteProjectGroups(ctx, logsTable, projectID, limit)
+		}},
+		{&report.ErrorGroups, func(limit int) (int, error) {
+			return s.repo.DeleteProjectGroups(ctx, errorsTable, projectID, limit)
+		}},
+		{&report.Sessions, func(limit int) (int, error) {
+			return s.repo.DeleteSessionsBefore(ctx, projectID, 0, limit)
+		}},
+		{&report.Spans, func(limit int) (int, error) {
+			return s.repo.DeleteSpansBefore(ctx, projectID, 0, limit)
+		}},
+	}
+
+	for _, step := range steps {
+		removed, done, err := s.deleteBatches(ctx, step.remove)
+		*step.removed += removed
+		if err != nil || !done {
+			return report, err
+		}
+	}
+
+	deleted, err := s.repo.DeleteProject(ctx, projectID)
+	if err != nil {
+		return report, err
+	}
+	if deleted {
+		report.Projects = 1
+	}
+	return report, nil
+}
+
+// deleteBatches calls remove until it removes less than a batch, done is false when MaxBatches ran out first
+func (s *service) deleteBatches(ctx context.Context, remove func(limit int) (int, error)) (removed int, done bool, err error) {
+	for range s.config.MaxBatches {
+		count, err := remove(s.config.BatchSize)
+		removed += count
+		if err != nil {
+			return removed, false, err
+		}
+
+		if count < s.config.BatchSize {
+			return removed, true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return removed, false, err
+		}
+	}
+	return removed, false, nil
+}
+
+func (s *service) purgeProject(ctx context.Context, policy *Policy, now time.Time) (Report, error) {
+	var report Report
+	var err error
+
+	if policy.LogsDays != nil {
+		report.Logs, err = s.purgeEvents(ctx, logsTable, policy.ProjectID, cutoff(now, *policy.LogsDays).UnixMilli())
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if policy.ErrorsDays != nil {
+		before := cutoff(now, *policy.ErrorsDays).UnixMilli()
+		report.Errors, err = s.purgeEvents(ctx, errorsTable, policy.ProjectID, before)
+		if err != nil {
+			return report, err
+		}
+
+		// Sessions are the denominator of the error rates of a release, so they are kept as long as errors
+		report.Sessions, _, err = s.deleteBatches(ctx, func(limit int) (int, error) {
+			return s.repo.DeleteSessionsBefore(ctx, policy.ProjectID, before, limit)
+		})
+		if err != nil {
+			return report, err
+		}
+
+		// Errors link to their traces, so spans are kept as long as errors too
+		report.Spans, _, err = s.deleteBatches(ctx, func(limit int) (int, error) {
+			return s.repo.DeleteSpansBefore(ctx, policy.ProjectID, before, limit)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if policy.LogGroupsDays != nil {
+		groups, events, err := s.purgeGroups(ctx, logsTable, policy.ProjectID, cutoff(now, *policy.LogGroupsDays).Unix())
+		report.LogGroups += groups
+		report.Logs += events
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if policy.ErrorGroupsDays != nil {
+		groups, events, err := s.purgeGroups(ctx, errorsTable, policy.ProjectID, cutoff(now, *policy.ErrorGroupsDays).Unix())
+		report.ErrorGroups += groups
+		report.Errors += events
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// purgeEvents deletes old events batch by batch and keeps the counters of their groups in sync
+func (s *service) purgeEvents(ctx context.Context, table eventTable, projectID string, before int64) (int, error) {
+	removed := 0
+	for range s.config.MaxBatches {
+		counts, err := s.repo.DeleteEventsBefore(ctx, table, projectID, before, s.config.BatchSize)
+		if err != nil {
+			return removed, err
+		}
+		batch := 0
+		for _, count := range counts {
+			batch += count
+		}
+		removed += batch
+
+		delete(counts, "")
+		if err := s.groupsOf(table).Decrement(ctx, counts); err != nil {
+			return removed, err
+		}
+
+		if batch < s.config.BatchSize {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// purgeGroups deletes the events of stale groups batch by batch and the groups once they have none left,
+// so hot groups do not end up in a single huge delete
+func (s *service) purgeGroups(ctx context.Context, table eventTable, projectID string, lastSeenBefore int64) (int, int, error) {
+	events, done, err := s.deleteBatches(ctx, func(limit int) (int, error) {
+		return s.repo.DeleteStaleGroupEvents(ctx, table, projectID, lastSeenBefore, limit)
+	})
+	if err != nil || !done {
+		// The groups are removed in a later run, after the rest of their events
+		return 0, events, err
+	}
+
+	groups, _, err := s.deleteBatches(ctx, func(limit int) (int, error) {
+		return s.repo.DeleteStaleGroups(ctx, table, projectID, lastSeenBefore, limit)
+	})
+	return groups, events, err
+}
+
+func (s *service) groupsOf(table eventTable) GroupCounter {
+	if table == errorsTable {
+		return s.errorGroups
+	}
+	return s.logGroups
+}
+
+func cutoff(now time.Time, days int) time.Time {
+	return now.Add(-time.Duration(days) * hoursInDay * time.Hour)
+}
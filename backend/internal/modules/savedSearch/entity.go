@@ -0,0 +1,16 @@
+package savedsearch
+
+type SavedSearch struct {
+	ID        string `db:"id"`
+	ProjectID string `db:"project_id"`
+	OwnerID   string `db:"owner_id"`
+	Name      string `db:"name"`
+	Target    string `db:"target"`
+	// Filter is a raw JSON object of list query params
+	Filter    string `db:"filter"`
+	Shared    bool   `db:"shared"`
+	IsDefault bool   `db:"is_default"`
+	Code      string `db:"code"`
+	CreatedAt int64  `db:"created_at"`
+	UpdatedAt int64  `db:"updated_at"`
+}
@@ -0,0 +1,107 @@
+package savedsearch
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	periodParam   = "period"
+	timeFromParam = "timeFrom"
+	timeToParam   = "timeTo"
+
+	maxValueLength = 1000
+	maxPeriod      = 365 * 24 * time.Hour
+	hoursInDay     = 24
+)
+
+// commonParams are accepted by every list and its export
+var commonParams = []string{"search", "sort", timeFromParam, timeToParam, periodParam, "format", "columns"}
+
+// targetParams are the other query params of each list
+var targetParams = map[string][]string{
+	TargetLogs:        {"groupId", "level", "searchMode", "orderBy"},
+	TargetErrors:      {"groupId", "searchMode", "orderBy"},
+	TargetErrorGroups: {"status", "assignee"},
+	TargetLogGroups:   {"level"},
+}
+
+func validateFilter(target string, filter map[string]string) error {
+	accepted, ok := targetParams[target]
+	if !ok {
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidFilter, target)
+	}
+
+	for key, value := range filter {
+		if !slices.Contains(commonParams, key) && !slices.Contains(accepted, key) {
+			return fmt.Errorf("%w: %s is not a filter of %s", ErrInvalidFilter, key, target)
+		}
+		if len(value) > maxValueLength {
+			return fmt.Errorf("%w: %s is too long", ErrInvalidFilter, key)
+		}
+	}
+
+	for _, key := range []string{timeFromParam, timeToParam} {
+		if value, ok := filter[key]; ok {
+			if t, err := strconv.ParseInt(value, 10, 64); err != nil || t < 0 {
+				return fmt.Errorf("%w: %s is not a timestamp", ErrInvalidFilter, key)
+			}
+		}
+	}
+
+	if value, ok := filter[periodParam]; ok {
+		if _, err := parsePeriod(value); err != nil {
+			return err
+		}
+		if _, ok := filter[timeFromParam]; ok {
+			return fmt.Errorf("%w: period replaces timeFrom", ErrInvalidFilter)
+		}
+	}
+	return nil
+}
+
+// parsePeriod reads a duration like 15m or 24h, days are written as 7d
+func parsePeriod(value string) (time.Duration, error) {
+	var period time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		period = time.Duration(n) * hoursInDay * time.Hour
+	} else {
+		period, err = time.ParseDuration(value)
+	}
+
+	if err != nil || period <= 0 || period > maxPeriod {
+		return 0, fmt.Errorf("%w: invalid period %q", ErrInvalidFilter, value)
+	}
+	return period, nil
+}
+
+// query returns the query params of the list for the search, a period starts now minus the period.
+// The logs list takes milliseconds, the other lists seconds.
+func query(search *SavedSearch, filter map[string]string, now time.Time) url.Values {
+	values := url.Values{}
+	values.Set("projectId", search.ProjectID)
+	for key, value := range filter {
+		if key != periodParam {
+			values.Set(key, value)
+		}
+	}
+
+	if value, ok := filter[periodParam]; ok {
+		if period, err := parsePeriod(value); err == nil {
+			from := now.Add(-period)
+			if search.Target == TargetLogs {
+				values.Set(timeFromParam, strconv.FormatInt(from.UnixMilli(), 10))
+			} else {
+				values.Set(timeFromParam, strconv.FormatInt(from.Unix(), 10))
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,75 @@
+package savedsearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		filter map[string]string
+		valid  bool
+	}{
+		{
+			name:   "Logs of the last day",
+			target: TargetLogs,
+			filter: map[string]string{"level": "ERROR", "search": "checkout", "period": "24h"},
+			valid:  true,
+		},
+		{
+			name:   "Unresolved groups of a week",
+			target: TargetErrorGroups,
+			filter: map[string]string{"status": "unresolved", "period": "7d", "columns": "id,message"},
+			valid:  true,
+		},
+		{
+			name:   "Filter of another list",
+			target: TargetErrors,
+			filter: map[string]string{"level": "ERROR"},
+		},
+		{
+			name:   "Invalid period",
+			target: TargetLogs,
+			filter: map[string]string{"period": "yesterday"},
+		},
+		{
+			name:   "Period and timeFrom",
+			target: TargetLogs,
+			filter: map[string]string{"period": "1h", "timeFrom": "1704067200000"},
+		},
+		{
+			name:   "Invalid timestamp",
+			target: TargetLogGroups,
+			filter: map[string]string{"timeTo": "today"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFilter(tt.target, tt.filter)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidFilter)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	now := time.Unix(1704153600, 0)
+	filter := map[string]string{"search": "checkout", "period": "1d"}
+
+	logs := query(&SavedSearch{ProjectID: "p", Target: TargetLogs}, filter, now)
+	assert.Empty(t, logs.Get("period"))
+	assert.Equal(t, "checkout", logs.Get("search"))
+	assert.Equal(t, "p", logs.Get("projectId"))
+	assert.Equal(t, "1704067200000", logs.Get("timeFrom"), "the logs list takes milliseconds")
+
+	errors := query(&SavedSearch{ProjectID: "p", Target: TargetErrors}, filter, now)
+	assert.Equal(t, "1704067200", errors.Get("timeFrom"))
+}
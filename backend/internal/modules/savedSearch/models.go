@@ -0,0 +1,70 @@
+package savedsearch
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	TargetLogs        = "logs"
+	TargetErrors      = "errors"
+	TargetErrorGroups = "error-groups"
+	TargetLogGroups   = "log-groups"
+
+	// QueryParam selects a saved search on the lists and exports
+	QueryParam = "savedSearch"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrForbidden      = errors.New("only the owner can change a saved search")
+	ErrInvalidFilter  = errors.New("invalid filter")
+	ErrTargetMismatch = errors.New("saved search is for another list")
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type Create struct {
+	Name   string `json:"name" validate:"required,max=255" example:"Checkout errors"`
+	Target string `json:"target" validate:"required,oneof=logs errors error-groups log-groups" example:"errors"`
+	// Filter holds query params of the list, period is a time range relative to now like 15m, 24h or 7d
+	Filter map[string]string `json:"filter" validate:"max=20" example:"search:checkout,period:24h"`
+	// Shared lists the search to every user of the project, otherwise only the link shares it
+	Shared bool `json:"shared" example:"true"`
+	// IsDefault makes it the view the list opens with, replacing the previous default of the user
+	IsDefault bool `json:"isDefault" example:"false"`
+}
+
+type Update struct {
+	Name      string            `json:"name" validate:"required,max=255" example:"Checkout errors"`
+	Filter    map[string]string `json:"filter" validate:"max=20" example:"search:checkout,period:24h"`
+	Shared    bool              `json:"shared" example:"true"`
+	IsDefault bool              `json:"isDefault" example:"false"`
+}
+
+type Entity struct {
+	ID        string            `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	ProjectID string            `json:"projectId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	OwnerID   string            `json:"ownerId" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	Name      string            `json:"name" example:"Checkout errors"`
+	Target    string            `json:"target" example:"errors"`
+	Filter    map[string]string `json:"filter"`
+	Shared    bool              `json:"shared" example:"true"`
+	// IsDefault is set on the default of the current user
+	IsDefault bool   `json:"isDefault" example:"false"`
+	Code      string `json:"code" example:"Xq3vR8kLm2Pa"`
+	Link      string `json:"link" example:"https://duckbug.io/s/Xq3vR8kLm2Pa"`
+	// Query is the query string of the list, a period is resolved at the time of the request
+	Query     string `json:"query" example:"projectId=a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c&search=checkout&timeFrom=1704067200"`
+	CreatedAt int64  `json:"createdAt" example:"1700000000"`
+	UpdatedAt int64  `json:"updatedAt" example:"1700000000"`
+}
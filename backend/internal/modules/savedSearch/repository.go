@@ -0,0 +1,150 @@
+package savedsearch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const searchColumns = `id, project_id, owner_id, name, target, filter, shared, is_default, code, created_at, updated_at`
+
+type Repository interface {
+	// GetAll returns the searches of the project owned by the user or shared, target may be empty
+	GetAll(ctx context.Context, projectID, target, userID string) ([]*SavedSearch, error)
+	GetByID(ctx context.Context, id string) (*SavedSearch, error)
+	GetByCode(ctx context.Context, code string) (*SavedSearch, error)
+	GetDefault(ctx context.Context, projectID, target, userID string) (*SavedSearch, error)
+	// Create and Update clear the previous default of the owner when the search is the default
+	Create(ctx context.Context, search *SavedSearch) error
+	Update(ctx context.Context, search *SavedSearch) error
+	Delete(ctx context.Context, id string) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetAll(ctx context.Context, projectID, target, userID string) ([]*SavedSearch, error) {
+	query := `SELECT ` + searchColumns + ` FROM saved_searches
+		WHERE project_id = $1 AND ($2 = '' OR target = $2) AND (shared OR owner_id = CAST($3 AS uuid))
+		ORDER BY name, id`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var searches []*SavedSearch
+	err := r.db.SelectContext(ctx, &searches, query, projectID, target, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*SavedSearch, error) {
+	return r.get(ctx, `SELECT `+searchColumns+` FROM saved_searches WHERE id = $1`, id)
+}
+
+func (r *repository) GetByCode(ctx context.Context, code string) (*SavedSearch, error) {
+	return r.get(ctx, `SELECT `+searchColumns+` FROM saved_searches WHERE code = $1`, code)
+}
+
+func (r *repository) GetDefault(ctx context.Context, projectID, target, userID string) (*SavedSearch, error) {
+	return r.get(ctx, `SELECT `+searchColumns+` FROM saved_searches
+		WHERE project_id = $1 AND target = $2 AND owner_id = CAST($3 AS uuid) AND is_default`,
+		projectID, target, userID)
+}
+
+func (r *repository) get(ctx context.Context, query string, args ...interface{}) (*SavedSearch, error) {
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var search SavedSearch
+	err := r.db.GetContext(ctx, &search, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	return &search, nil
+}
+
+func (r *repository) Create(ctx context.Context, search *SavedSearch) error {
+	const query = `
+		INSERT INTO saved_searches (` + searchColumns + `)
+		VALUES (:id, :project_id, :owner_id, :name, :target, CAST(:filter AS jsonb), :shared, :is_default, :code,
+			:created_at, :updated_at)`
+
+	return r.save(ctx, query, search)
+}
+
+func (r *repository) Update(ctx context.Context, search *SavedSearch) error {
+	const query = `
+		UPDATE saved_searches SET name = :name, filter = CAST(:filter AS jsonb), shared = :shared,
+			is_default = :is_default, updated_at = :updated_at
+		WHERE id = :id`
+
+	return r.save(ctx, query, search)
+}
+
+func (r *repository) save(ctx context.Context, query string, search *SavedSearch) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	if search.IsDefault {
+		const clearQuery = `
+			UPDATE saved_searches SET is_default = FALSE
+			WHERE owner_id = $1 AND project_id = $2 AND target = $3 AND is_default AND id <> $4`
+		if _, err = tx.ExecContext(ctx, clearQuery, search.OwnerID, search.ProjectID, search.Target, search.ID); err != nil {
+			return fmt.Errorf("failed to clear default search: %w", err)
+		}
+	}
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	if _, err = tx.NamedExecContext(ctx, query, search); err != nil {
+		return fmt.Errorf("failed to save search: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id string) error {
+	const query = `DELETE FROM saved_searches WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package savedsearch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// codeBytes makes a 12 character link code
+const codeBytes = 9
+
+// auditTarget names saved searches in the audit log
+const auditTarget = "saved_search"
+
+type auditState struct {
+	Name      string            `json:"name"`
+	Target    string            `json:"target"`
+	Filter    map[string]string `json:"filter"`
+	Shared    bool              `json:"shared"`
+	IsDefault bool              `json:"isDefault"`
+}
+
+type Service interface {
+	// GetAll lists the searches of the project owned by the current user or shared, target may be empty
+	GetAll(ctx context.Context, projectID, target string) ([]Entity, error)
+	// GetDefault returns the default search of the current user for the list of the project
+	GetDefault(ctx context.Context, projectID, target string) (*Entity, error)
+	Create(ctx context.Context, projectID string, req *Create) (*Entity, error)
+	// Update and Delete are allowed to the owner only
+	Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error)
+	Delete(ctx context.Context, projectID, id string) error
+	// GetByCode resolves a short link, a link opens the search for every user it was shared with
+	GetByCode(ctx context.Context, code string) (*Entity, error)
+	// Query returns the query params of the list for a search visible to the current user
+	Query(ctx context.Context, id, target string) (url.Values, error)
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+	domain string
+}
+
+func NewService(repo Repository, logger Logger, domain string) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+		domain: domain,
+	}
+}
+
+func (s *service) GetAll(ctx context.Context, projectID, target string) ([]Entity, error) {
+	userID, _ := middleware.GetUserID(ctx)
+	searches, err := s.repo.GetAll(ctx, projectID, target, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entities := make([]Entity, 0, len(searches))
+	for _, search := range searches {
+		entity, err := s.toEntity(search, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, *entity)
+	}
+	return entities, nil
+}
+
+func (s *service) GetDefault(ctx context.Context, projectID, target string) (*Entity, error) {
+	userID, _ := middleware.GetUserID(ctx)
+	search, err := s.repo.GetDefault(ctx, projectID, target, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toEntity(search, userID, time.Now())
+}
+
+func (s *service) Create(ctx context.Context, projectID string, req *Create) (*Entity, error) {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		return nil, ErrForbidden
+	}
+	if err := validateFilter(req.Target, req.Filter); err != nil {
+		return nil, err
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := encodeFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	search := &SavedSearch{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		OwnerID:   userID,
+		Name:      req.Name,
+		Target:    req.Target,
+		Filter:    filter,
+		Shared:    req.Shared,
+		IsDefault: req.IsDefault,
+		Code:      code,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+	}
+	if err = s.repo.Create(ctx, search); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "saved_search.create", auditTarget, search.ID, nil, toAuditState(search, req.Filter))
+	return s.toEntity(search, userID, now)
+}
+
+func (s *service) Update(ctx context.Context, projectID, id string, req *Update) (*Entity, error) {
+	search, userID, err := s.owned(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateFilter(search.Target, req.Filter); err != nil {
+		return nil, err
+	}
+
+	before, err := decodeFilter(search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	beforeState := toAuditState(search, before)
+
+	filter, err := encodeFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	search.Name = req.Name
+	search.Filter = filter
+	search.Shared = req.Shared
+	search.IsDefault = req.IsDefault
+	search.UpdatedAt = now.Unix()
+	if err = s.repo.Update(ctx, search); err != nil {
+		return nil, err
+	}
+
+	middleware.Audit(ctx, "saved_search.update", auditTarget, id, beforeState, toAuditState(search, req.Filter))
+	return s.toEntity(search, userID, now)
+}
+
+func (s *service) Delete(ctx context.Context, projectID, id string) error {
+	if _, _, err := s.owned(ctx, projectID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	middleware.Audit(ctx, "saved_search.delete", auditTarget, id, nil, nil)
+	return nil
+}
+
+func (s *service) GetByCode(ctx context.Context, code string) (*Entity, error) {
+	search, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := middleware.GetUserID(ctx)
+	return s.toEntity(search, userID, time.Now())
+}
+
+func (s *service) Query(ctx context.Context, id, target string) (url.Values, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	search, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := middleware.GetUserID(ctx)
+	if !search.Shared && search.OwnerID != userID {
+		return nil, ErrNotFound
+	}
+	if search.Target != target {
+		return nil, ErrTargetMismatch
+	}
+
+	filter, err := decodeFilter(search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return query(search, filter, time.Now()), nil
+}
+
+// owned returns a search of the project the current user may change, others get ErrNotFound for
+// searches they cannot see and ErrForbidden for shared ones
+func (s *service) owned(ctx context.Context, projectID, id string) (*SavedSearch, string, error) {
+	userID, _ := middleware.GetUserID(ctx)
+	search, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if search.ProjectID != projectID || (!search.Shared && search.OwnerID != userID) {
+		return nil, "", ErrNotFound
+	}
+	if search.OwnerID != userID {
+		return nil, "", ErrForbidden
+	}
+	return search, userID, nil
+}
+
+func (s *service) toEntity(search *SavedSearch, userID string, now time.Time) (*Entity, error) {
+	filter, err := decodeFilter(search.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entity{
+		ID:        search.ID,
+		ProjectID: search.ProjectID,
+		OwnerID:   search.OwnerID,
+		Name:      search.Name,
+		Target:    search.Target,
+		Filter:    filter,
+		Shared:    search.Shared,
+		IsDefault: search.IsDefault && search.OwnerID == userID,
+		Code:      search.Code,
+		Link:      "https://" + s.domain + "/s/" + search.Code,
+		Query:     query(search, filter, now).Encode(),
+		CreatedAt: search.CreatedAt,
+		UpdatedAt: search.UpdatedAt,
+	}, nil
+}
+
+func toAuditState(search *SavedSearch, filter map[string]string) auditState {
+	return auditState{
+		Name:      search.Name,
+		Target:    search.Target,
+		Filter:    filter,
+		Shared:    search.Shared,
+		IsDefault: search.IsDefault,
+	}
+}
+
+func newCode() (string, error) {
+	b := make([]byte, codeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate link code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func encodeFilter(filter map[string]string) (string, error) {
+	if filter == nil {
+		filter = map[string]string{}
+	}
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode filter: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func decodeFilter(raw string) (map[string]string, error) {
+	filter := map[string]string{}
+	if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+		return nil, fmt.Errorf("failed to decode filter: %w", err)
+	}
+	return filter, nil
+}
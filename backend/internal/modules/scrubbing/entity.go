@@ -0,0 +1,15 @@
+package scrubbing
+
+type Settings struct {
+	ProjectID string `db:"project_id"`
+	StripIP   bool   `db:"strip_ip"`
+	UpdatedAt int64  `db:"updated_at"`
+}
+
+type Rule struct {
+	ID        string `db:"id"`
+	ProjectID string `db:"project_id"`
+	Kind      string `db:"kind"`
+	Pattern   string `db:"pattern"`
+	CreatedAt int64  `db:"created_at"`
+}
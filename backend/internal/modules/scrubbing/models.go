@@ -0,0 +1,33 @@
+package scrubbing
+
+import "context"
+
+const (
+	// KindKey filters values of fields whose name contains the pattern
+	KindKey = "key"
+	// KindRegex filters the parts of string values matching the pattern
+	KindRegex = "regex"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+type RuleEntity struct {
+	Kind    string `json:"kind" validate:"required,oneof=key regex" example:"key"`
+	Pattern string `json:"pattern" validate:"required,max=512" example:"ssn"`
+}
+
+// Config is the per-project scrubbing setup, applied on top of the built-in denylist
+type Config struct {
+	// StripIP drops the client IP and filters the X-Forwarded-For, X-Real-IP, Forwarded and CF-Connecting-IP headers
+	StripIP bool         `json:"stripIp" example:"true"`
+	Rules   []RuleEntity `json:"rules" validate:"max=100,dive"`
+}
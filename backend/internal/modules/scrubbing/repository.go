@@ -0,0 +1,114 @@
+package scrubbing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	GetSettings(ctx context.Context, projectID string) (*Settings, error)
+	GetRules(ctx context.Context, projectID string) ([]*Rule, error)
+	// Save replaces the settings and all rules of a project
+	Save(ctx context.Context, settings *Settings, rules []*Rule) error
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetSettings(ctx context.Context, projectID string) (*Settings, error) {
+	const query = `SELECT project_id, strip_ip, updated_at FROM scrubbing_settings WHERE project_id = $1`
+
+	var settings Settings
+	err := r.db.GetContext(ctx, &settings, query, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &Settings{ProjectID: projectID}, nil
+		}
+		return nil, fmt.Errorf("failed to get scrubbing settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *repository) GetRules(ctx context.Context, projectID string) ([]*Rule, error) {
+	const query = `SELECT id, project_id, kind, pattern, created_at
+		FROM scrubbing_rules WHERE project_id = $1 ORDER BY created_at, id`
+
+	var rules []*Rule
+	err := r.db.SelectContext(ctx, &rules, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrubbing rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *repository) Save(ctx context.Context, settings *Settings, rules []*Rule) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	const settingsQuery = `
+		INSERT INTO scrubbing_settings (project_id, strip_ip, updated_at)
+		VALUES (:project_id, :strip_ip, :updated_at)
+		ON CONFLICT (project_id) DO UPDATE
+		SET strip_ip = EXCLUDED.strip_ip, updated_at = EXCLUDED.updated_at`
+
+	now := time.Now().Unix()
+	settings.UpdatedAt = now
+
+	_, err = tx.NamedExecContext(ctx, settingsQuery, settings)
+	if err != nil {
+		return fmt.Errorf("failed to save scrubbing settings: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM scrubbing_rules WHERE project_id = $1`, settings.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scrubbing rules: %w", err)
+	}
+
+	const ruleQuery = `
+		INSERT INTO scrubbing_rules (id, project_id, kind, pattern, created_at)
+		VALUES (:id, :project_id, :kind, :pattern, :created_at)`
+
+	for _, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		rule.ProjectID = settings.ProjectID
+		rule.CreatedAt = now
+
+		_, err = tx.NamedExecContext(ctx, ruleQuery, rule)
+		if err != nil {
+			return fmt.Errorf("failed to create scrubbing rule: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
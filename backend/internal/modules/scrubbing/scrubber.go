@@ -0,0 +1,276 @@
+package scrubbing
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filtered replaces every scrubbed value
+const Filtered = "[Filtered]"
+
+const (
+	minCardDigits = 13
+	maxCardDigits = 19
+	ibanModulus   = 97
+	// ibanLetterBase is the numeric value of A when letters are expanded to digits
+	ibanLetterBase = 10
+	decimalBase    = 10
+	maxDigit       = 9
+)
+
+var ErrInvalidPattern = errors.New("invalid scrubbing pattern")
+
+// defaultKeys are matched against normalized field names, see normalizeKey
+var defaultKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"authorization",
+	"cookie",
+	"credential",
+	"privatekey",
+	"accesskey",
+	"sessionid",
+}
+
+// ipHeaders carry the client address set by proxies, normalized like keys
+var ipHeaders = []string{
+	"xforwardedfor",
+	"xrealip",
+	"forwarded",
+	"cfconnectingip",
+}
+
+var (
+	authSchemePattern     = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]+`)
+	urlCredentialsPattern = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^:/?#@\s]*:)[^@/?#\s]+@`)
+	cardCandidatePattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	ibanCandidatePattern  = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+)
+
+// Scrubber filters sensitive data out of event payloads in place
+type Scrubber struct {
+	keys     []string
+	patterns []*regexp.Regexp
+	stripIP  bool
+}
+
+// Default returns a scrubber with the built-in denylist only
+func Default() *Scrubber {
+	return &Scrubber{keys: defaultKeys}
+}
+
+// New builds a scrubber from the built-in denylist extended by the project settings and rules
+func New(settings *Settings, rules []*Rule) (*Scrubber, error) {
+	s := Default()
+	if settings != nil {
+		s.stripIP = settings.StripIP
+	}
+
+	for _, rule := range rules {
+		switch rule.Kind {
+		case KindKey:
+			if key := normalizeKey(rule.Pattern); key != "" {
+				s.keys = append(s.keys, key)
+			}
+		case KindRegex:
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+			}
+			s.patterns = append(s.patterns, pattern)
+		default:
+			return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidPattern, rule.Kind)
+		}
+	}
+
+	return s, nil
+}
+
+// StripIP reports whether client IP addresses must not be stored
+func (s *Scrubber) StripIP() bool {
+	return s.stripIP
+}
+
+// IsSensitiveKey reports whether values of a field with that name must be filtered
+func (s *Scrubber) IsSensitiveKey(key string) bool {
+	normalized := normalizeKey(key)
+	for _, denied := range s.keys {
+		if strings.Contains(normalized, denied) {
+			return true
+		}
+	}
+	return false
+}
+
+// String filters credentials, card numbers, IBANs and custom patterns out of free text
+func (s *Scrubber) String(value string) string {
+	value = authSchemePattern.ReplaceAllString(value, "$1 "+Filtered)
+	value = urlCredentialsPattern.ReplaceAllString(value, "${1}"+Filtered+"@")
+	value = cardCandidatePattern.ReplaceAllStringFunc(value, func(candidate string) string {
+		if isCardNumber(candidate) {
+			return Filtered
+		}
+		return candidate
+	})
+	value = ibanCandidatePattern.ReplaceAllStringFunc(value, func(candidate string) string {
+		if isIBAN(candidate) {
+			return Filtered
+		}
+		return candidate
+	})
+
+	for _, pattern := range s.patterns {
+		value = pattern.ReplaceAllString(value, Filtered)
+	}
+	return value
+}
+
+// Value walks decoded JSON and returns it with sensitive keys and strings filtered
+func (s *Scrubber) Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.Map(v)
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = s.Value(v[i])
+		}
+		return v
+	case string:
+		return s.String(v)
+	default:
+		return v
+	}
+}
+
+// Map filters a decoded JSON object in place
+func (s *Scrubber) Map(m map[string]interface{}) {
+	for key, value := range m {
+		if s.IsSensitiveKey(key) {
+			m[key] = Filtered
+			continue
+		}
+		m[key] = s.Value(value)
+	}
+}
+
+// Headers filters request headers like Map, with StripIP the proxy headers carrying the client address too
+func (s *Scrubber) Headers(m map[string]interface{}) {
+	s.Map(m)
+	if !s.stripIP {
+		return
+	}
+	for key := range m {
+		if slices.Contains(ipHeaders, normalizeKey(key)) {
+			m[key] = Filtered
+		}
+	}
+}
+
+// Redact filters every value of a map and keeps only the keys, used for cookies
+func (s *Scrubber) Redact(m map[string]interface{}) {
+	for key := range m {
+		m[key] = Filtered
+	}
+}
+
+// URL filters sensitive query parameters and credentials of a URL
+func (s *Scrubber) URL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return s.String(raw)
+	}
+
+	if parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), Filtered)
+		}
+	}
+
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for key, values := range query {
+			for i := range values {
+				if s.IsSensitiveKey(key) {
+					values[i] = Filtered
+				} else {
+					values[i] = s.String(values[i])
+				}
+			}
+		}
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.String()
+}
+
+// normalizeKey lower-cases a field name and drops separators, so X-Api-Key matches apikey
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '.', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// isCardNumber checks the length and the Luhn checksum
+func isCardNumber(candidate string) bool {
+	digits := make([]int, 0, maxCardDigits)
+	for _, r := range candidate {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < minCardDigits || len(digits) > maxCardDigits {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := digits[i]
+		if double {
+			digit *= 2
+			if digit > maxDigit {
+				digit -= maxDigit
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%decimalBase == 0
+}
+
+// isIBAN checks the ISO 13616 mod 97 checksum
+func isIBAN(candidate string) bool {
+	compact := strings.ReplaceAll(candidate, " ", "")
+	rearranged := compact[4:] + compact[:4]
+
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&numeric, "%d", r-'A'+ibanLetterBase)
+		default:
+			return false
+		}
+	}
+
+	value, ok := new(big.Int).SetString(numeric.String(), decimalBase)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(value, big.NewInt(ibanModulus)).Int64() == 1
+}
@@ -0,0 +1,121 @@
+package scrubbing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cacheTTL bounds how long other instances keep applying rules that were changed
+const cacheTTL = 30 * time.Second
+
+type Service interface {
+	// ForProject returns the scrubber to apply to incoming events of a project
+	ForProject(ctx context.Context, projectID string) (*Scrubber, error)
+	Get(ctx context.Context, projectID string) (*Config, error)
+	Update(ctx context.Context, projectID string, req *Config) (*Config, error)
+}
+
+type cachedScrubber struct {
+	scrubber  *Scrubber
+	expiresAt time.Time
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+
+	mu    sync.RWMutex
+	cache map[string]cachedScrubber
+}
+
+func NewService(repo Repository, logger Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+		cache:  make(map[string]cachedScrubber),
+	}
+}
+
+func (s *service) ForProject(ctx context.Context, projectID string) (*Scrubber, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[projectID]
+	s.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.scrubber, nil
+	}
+
+	settings, err := s.repo.GetSettings(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.repo.GetRules(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	scrubber, err := New(settings, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[projectID] = cachedScrubber{scrubber: scrubber, expiresAt: time.Now().Add(cacheTTL)}
+	s.mu.Unlock()
+
+	return scrubber, nil
+}
+
+func (s *service) Get(ctx context.Context, projectID string) (*Config, error) {
+	settings, err := s.repo.GetSettings(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.repo.GetRules(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toResponse(settings, rules), nil
+}
+
+func (s *service) Update(ctx context.Context, projectID string, req *Config) (*Config, error) {
+	settings := &Settings{
+		ProjectID: projectID,
+		StripIP:   req.StripIP,
+	}
+
+	rules := make([]*Rule, 0, len(req.Rules))
+	for _, rule := range req.Rules {
+		rules = append(rules, &Rule{Kind: rule.Kind, Pattern: rule.Pattern})
+	}
+
+	// Compiling up front rejects broken regexes before they are stored
+	if _, err := New(settings, rules); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, settings, rules); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, projectID)
+	s.mu.Unlock()
+
+	return toResponse(settings, rules), nil
+}
+
+func toResponse(settings *Settings, rules []*Rule) *Config {
+	config := &Config{
+		StripIP: settings.StripIP,
+		Rules:   make([]RuleEntity, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		config.Rules = append(config.Rules, RuleEntity{Kind: rule.Kind, Pattern: rule.Pattern})
+	}
+	return config
+}
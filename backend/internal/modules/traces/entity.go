@@ -0,0 +1,50 @@
+package traces
+
+type Span struct {
+	ProjectID    string  `db:"project_id"`
+	TraceID      string  `db:"trace_id"`
+	SpanID       string  `db:"span_id"`
+	ParentSpanID *string `db:"parent_span_id"`
+	// IsTransaction marks the span a service handles a request or a job with
+	IsTransaction bool    `db:"is_transaction"`
+	Name          string  `db:"name"`
+	Op            string  `db:"op"`
+	Service       string  `db:"service"`
+	Status        string  `db:"status"`
+	Release       *string `db:"release"`
+	Environment   string  `db:"environment"`
+	StartTime     int64   `db:"start_time"`
+	Duration      float64 `db:"duration"`
+	Attributes    *string `db:"attributes"`
+}
+
+// TransactionStats aggregates the transactions of a name, durations are in milliseconds
+type TransactionStats struct {
+	Name       string  `db:"name"`
+	Count      int     `db:"count"`
+	Errors     int     `db:"errors"`
+	Avg        float64 `db:"avg"`
+	P50        float64 `db:"p50"`
+	P95        float64 `db:"p95"`
+	P99        float64 `db:"p99"`
+	LastSeenAt int64   `db:"last_seen_at"`
+}
+
+// SeriesRow aggregates the transactions of a name started within a bucket
+type SeriesRow struct {
+	Bucket int64   `db:"bucket"`
+	Count  int     `db:"count"`
+	P50    float64 `db:"p50"`
+	P95    float64 `db:"p95"`
+	P99    float64 `db:"p99"`
+}
+
+// TraceError is an error reported with the trace id in its context
+type TraceError struct {
+	ID          string `db:"id"`
+	Fingerprint string `db:"fingerprint"`
+	Message     string `db:"message"`
+	File        string `db:"file"`
+	Line        int    `db:"line"`
+	Time        int64  `db:"time"`
+}
@@ -0,0 +1,201 @@
+package traces
+
+import (
+	"context"
+	"errors"
+)
+
+// Span statuses, OTLP spans without a status are unset
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+	StatusUnset = "unset"
+)
+
+// Formats of OTLP/HTTP export requests
+const (
+	FormatProtobuf = "protobuf"
+	FormatJSON     = "json"
+)
+
+// Orders of the transaction list, the slowest or the busiest names first
+const (
+	SortP50    = "p50"
+	SortP95    = "p95"
+	SortP99    = "p99"
+	SortAvg    = "avg"
+	SortCount  = "count"
+	SortErrors = "errors"
+)
+
+// Orders of the samples of a transaction name
+const (
+	SampleSortDuration = "duration"
+	SampleSortTime     = "time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+	// MaxSpans bounds the spans of a single ingest request
+	MaxSpans = 10000
+)
+
+var (
+	ErrNotFound          = errors.New("trace not found")
+	ErrInvalidID         = errors.New("trace ids must be 32 and span ids 16 hex digits, not all zero")
+	ErrTooManySpans      = errors.New("too many spans in one request")
+	ErrInvalidOTLP       = errors.New("invalid OTLP trace export request")
+	ErrUnsupportedFormat = errors.New("OTLP requests must be application/x-protobuf or application/json")
+	ErrTooManyBuckets    = errors.New("too many buckets, use a larger interval")
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Create is a transaction with its spans in the DuckBug JSON format, SDKs without OTLP send it
+type Create struct {
+	TraceID string `json:"traceId" validate:"required,len=32" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	SpanID  string `json:"spanId" validate:"required,len=16" example:"00f067aa0ba902b7"`
+	// ParentSpanID is the span of the caller when the transaction continues a trace of another service
+	ParentSpanID string `json:"parentSpanId" validate:"omitempty,len=16" example:"53995c3f42cd8ad8"`
+	// Name groups transactions in the aggregates, use the route rather than the URL
+	Name        string  `json:"name" validate:"required,max=255" example:"GET /api/users/{id}"`
+	Op          string  `json:"op" validate:"omitempty,max=64" example:"http.server"`
+	Service     string  `json:"service" validate:"omitempty,max=255" example:"api"`
+	Status      string  `json:"status" validate:"omitempty,oneof=ok error unset" example:"ok"`
+	Release     *string `json:"release,omitempty" validate:"omitempty,max=255" example:"1.4.2"`
+	Environment string  `json:"environment" validate:"omitempty,max=64" example:"production"`
+	// StartTime is a Unix timestamp in milliseconds
+	StartTime int64 `json:"startTime" validate:"required,min=0" example:"1704067200000" format:"int64"`
+	// Duration in milliseconds
+	Duration   float64                `json:"duration" validate:"min=0" example:"184.5"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Spans      []CreateSpan           `json:"spans" validate:"max=10000,dive"`
+	ProjectID  string                 `json:"-"`
+}
+
+type CreateSpan struct {
+	SpanID string `json:"spanId" validate:"required,len=16" example:"b7ad6b7169203331"`
+	// ParentSpanID defaults to the transaction
+	ParentSpanID string                 `json:"parentSpanId" validate:"omitempty,len=16" example:"00f067aa0ba902b7"`
+	Name         string                 `json:"name" validate:"required,max=255" example:"SELECT users"`
+	Op           string                 `json:"op" validate:"omitempty,max=64" example:"db"`
+	Status       string                 `json:"status" validate:"omitempty,oneof=ok error unset" example:"ok"`
+	StartTime    int64                  `json:"startTime" validate:"required,min=0" example:"1704067200012" format:"int64"`
+	Duration     float64                `json:"duration" validate:"min=0" example:"35.2"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type SummaryParams struct {
+	ProjectID   string
+	Environment string
+	Release     string
+	// Search matches a part of the transaction name
+	Search string
+	// TimeFrom and TimeTo bound the transaction starts, in milliseconds
+	TimeFrom int64
+	TimeTo   int64
+	Sort     string
+	Limit    int
+}
+
+type SeriesParams struct {
+	ProjectID   string
+	Name        string
+	Environment string
+	Release     string
+	TimeFrom    int64
+	TimeTo      int64
+	// Interval is the bucket size in milliseconds
+	Interval int64
+}
+
+type SamplesParams struct {
+	ProjectID   string
+	Name        string
+	Environment string
+	TimeFrom    int64
+	TimeTo      int64
+	Sort        string
+	Limit       int
+}
+
+// TransactionEntity aggregates the transactions of a name, durations are in milliseconds
+type TransactionEntity struct {
+	Name  string `json:"name" example:"GET /api/users/{id}"`
+	Count int    `json:"count" example:"12000"`
+	// Errors counts the transactions with the error status
+	Errors    int     `json:"errors" example:"18"`
+	ErrorRate float64 `json:"errorRate" example:"0.15"`
+	// Throughput is the number of transactions per minute
+	Throughput float64 `json:"throughput" example:"8.333"`
+	Avg        float64 `json:"avg" example:"96.4"`
+	P50        float64 `json:"p50" example:"72.1"`
+	P95        float64 `json:"p95" example:"310.8"`
+	P99        float64 `json:"p99" example:"1204.3"`
+	// LastSeenAt is a Unix timestamp in milliseconds
+	LastSeenAt int64 `json:"lastSeenAt" example:"1704153600000"`
+}
+
+type SeriesPoint struct {
+	// Time is the start of the bucket, Unix timestamp in milliseconds
+	Time       int64   `json:"time" example:"1704067200000"`
+	Count      int     `json:"count" example:"500"`
+	Throughput float64 `json:"throughput" example:"8.333"`
+	// P50, P95 and P99 are null for buckets without transactions
+	P50 *float64 `json:"p50" example:"72.1"`
+	P95 *float64 `json:"p95" example:"310.8"`
+	P99 *float64 `json:"p99" example:"1204.3"`
+}
+
+type Series struct {
+	Name     string        `json:"name" example:"GET /api/users/{id}"`
+	Interval int64         `json:"interval" example:"3600000"` // Bucket size in milliseconds
+	Points   []SeriesPoint `json:"points"`
+}
+
+type SpanEntity struct {
+	TraceID       string  `json:"traceId" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	SpanID        string  `json:"spanId" example:"00f067aa0ba902b7"`
+	ParentSpanID  *string `json:"parentSpanId" example:"53995c3f42cd8ad8"`
+	IsTransaction bool    `json:"isTransaction" example:"true"`
+	Name          string  `json:"name" example:"GET /api/users/{id}"`
+	Op            string  `json:"op" example:"http.server"`
+	Service       string  `json:"service" example:"api"`
+	Status        string  `json:"status" example:"ok"`
+	Release       *string `json:"release" example:"1.4.2"`
+	Environment   string  `json:"environment" example:"production"`
+	// StartTime is a Unix timestamp in milliseconds, Duration is in milliseconds
+	StartTime  int64                  `json:"startTime" example:"1704067200000"`
+	Duration   float64                `json:"duration" example:"184.5"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type TraceErrorEntity struct {
+	ID      string `json:"id" example:"a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"`
+	GroupID string `json:"groupId" example:"5d41402abc4b2a76b9719d911017c592"`
+	Message string `json:"message" example:"Undefined index: user"`
+	File    string `json:"file" example:"index.php"`
+	Line    int    `json:"line" example:"42"`
+	// Time is a Unix timestamp in milliseconds
+	Time int64 `json:"time" example:"1704067200100"`
+}
+
+// TraceEntity is a trace with all its spans by start time and the errors reported within it
+type TraceEntity struct {
+	TraceID string `json:"traceId" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	// StartTime is the start of the first span, Duration spans from it to the end of the last one
+	StartTime int64              `json:"startTime" example:"1704067200000"`
+	Duration  float64            `json:"duration" example:"184.5"`
+	Spans     []SpanEntity       `json:"spans"`
+	Errors    []TraceErrorEntity `json:"errors"`
+}
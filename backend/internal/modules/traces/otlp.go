@@ -0,0 +1,330 @@
+package traces
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const nanosInMilli = 1e6
+
+// Resource attributes of the OpenTelemetry semantic conventions a span takes its service, release and environment from
+const (
+	attrServiceName       = "service.name"
+	attrServiceVersion    = "service.version"
+	attrEnvironment       = "deployment.environment.name"
+	attrLegacyEnvironment = "deployment.environment"
+)
+
+// otlpProtocols are attributes telling the protocol of a span, in the order they are looked up
+var otlpProtocols = []struct{ attribute, protocol string }{
+	{"http.request.method", "http"},
+	{"http.method", "http"},
+	{"db.system.name", "db"},
+	{"db.system", "db"},
+	{"messaging.system", "messaging"},
+	{"rpc.system", "rpc"},
+}
+
+// otlpKinds name the span kinds, the name is the op of a span unless its attributes tell more
+var otlpKinds = map[int32]string{
+	int32(tracepb.Span_SPAN_KIND_INTERNAL): "internal",
+	int32(tracepb.Span_SPAN_KIND_SERVER):   "server",
+	int32(tracepb.Span_SPAN_KIND_CLIENT):   "client",
+	int32(tracepb.Span_SPAN_KIND_PRODUCER): "producer",
+	int32(tracepb.Span_SPAN_KIND_CONSUMER): "consumer",
+}
+
+// otlpSpan is a span of either encoding of an export request, ids are hex and times are in nanoseconds
+type otlpSpan struct {
+	traceID, spanID, parentSpanID string
+	name                          string
+	kind                          int32
+	start, end                    int64
+	statusCode                    int32
+	attributes                    map[string]interface{}
+}
+
+// otlpResource holds the spans of a resource, usually a single service
+type otlpResource struct {
+	attributes map[string]interface{}
+	spans      []otlpSpan
+}
+
+// decodeOTLP reads an OTLP/HTTP trace export request
+func decodeOTLP(body []byte, format string) ([]otlpResource, error) {
+	switch format {
+	case FormatProtobuf:
+		return decodeOTLPProtobuf(body)
+	case FormatJSON:
+		return decodeOTLPJSON(body)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func decodeOTLPProtobuf(body []byte) ([]otlpResource, error) {
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOTLP, err)
+	}
+
+	resources := make([]otlpResource, 0, len(req.GetResourceSpans()))
+	for _, resourceSpans := range req.GetResourceSpans() {
+		resource := otlpResource{attributes: protoAttributes(resourceSpans.GetResource().GetAttributes())}
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				resource.spans = append(resource.spans, otlpSpan{
+					traceID:      hex.EncodeToString(span.GetTraceId()),
+					spanID:       hex.EncodeToString(span.GetSpanId()),
+					parentSpanID: hex.EncodeToString(span.GetParentSpanId()),
+					name:         span.GetName(),
+					kind:         int32(span.GetKind()),
+					start:        int64(span.GetStartTimeUnixNano()), //nolint:gosec // Unix nanoseconds fit until 2262
+					end:          int64(span.GetEndTimeUnixNano()),   //nolint:gosec // Unix nanoseconds fit until 2262
+					statusCode:   int32(span.GetStatus().GetCode()),
+					attributes:   protoAttributes(span.GetAttributes()),
+				})
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+func protoAttributes(attributes []*commonpb.KeyValue) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.GetKey()] = protoValue(attribute.GetValue())
+	}
+	return values
+}
+
+func protoValue(value *commonpb.AnyValue) interface{} {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, protoValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		return protoAttributes(v.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
+
+// OTLP/JSON differs from the canonical protobuf JSON mapping: ids are hex and 64 bit integers may be strings
+type otlpJSONRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpJSONKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []otlpJSONSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type otlpJSONSpan struct {
+	TraceID           string             `json:"traceId"`
+	SpanID            string             `json:"spanId"`
+	ParentSpanID      string             `json:"parentSpanId"`
+	Name              string             `json:"name"`
+	Kind              int32              `json:"kind"`
+	StartTimeUnixNano otlpJSONInt        `json:"startTimeUnixNano"`
+	EndTimeUnixNano   otlpJSONInt        `json:"endTimeUnixNano"`
+	Attributes        []otlpJSONKeyValue `json:"attributes"`
+	Status            struct {
+		Code int32 `json:"code"`
+	} `json:"status"`
+}
+
+type otlpJSONKeyValue struct {
+	Key   string        `json:"key"`
+	Value otlpJSONValue `json:"value"`
+}
+
+type otlpJSONValue struct {
+	StringValue *string      `json:"stringValue"`
+	BoolValue   *bool        `json:"boolValue"`
+	IntValue    *otlpJSONInt `json:"intValue"`
+	DoubleValue *float64     `json:"doubleValue"`
+	BytesValue  *string      `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []otlpJSONValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []otlpJSONKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// otlpJSONInt is a 64 bit integer written as a number or a string
+type otlpJSONInt int64
+
+func (i *otlpJSONInt) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = otlpJSONInt(value)
+	return nil
+}
+
+func decodeOTLPJSON(body []byte) ([]otlpResource, error) {
+	var req otlpJSONRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOTLP, err)
+	}
+
+	resources := make([]otlpResource, 0, len(req.ResourceSpans))
+	for _, resourceSpans := range req.ResourceSpans {
+		resource := otlpResource{attributes: jsonAttributes(resourceSpans.Resource.Attributes)}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				resource.spans = append(resource.spans, otlpSpan{
+					traceID:      strings.ToLower(span.TraceID),
+					spanID:       strings.ToLower(span.SpanID),
+					parentSpanID: strings.ToLower(span.ParentSpanID),
+					name:         span.Name,
+					kind:         span.Kind,
+					start:        int64(span.StartTimeUnixNano),
+					end:          int64(span.EndTimeUnixNano),
+					statusCode:   span.Status.Code,
+					attributes:   jsonAttributes(span.Attributes),
+				})
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+func jsonAttributes(attributes []otlpJSONKeyValue) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.Key] = jsonValue(attribute.Value)
+	}
+	return values
+}
+
+func jsonValue(value otlpJSONValue) interface{} {
+	switch {
+	case value.StringValue != nil:
+		return *value.StringValue
+	case value.BoolValue != nil:
+		return *value.BoolValue
+	case value.IntValue != nil:
+		return int64(*value.IntValue)
+	case value.DoubleValue != nil:
+		return *value.DoubleValue
+	case value.BytesValue != nil:
+		return *value.BytesValue
+	case value.ArrayValue != nil:
+		values := make([]interface{}, 0, len(value.ArrayValue.Values))
+		for _, item := range value.ArrayValue.Values {
+			values = append(values, jsonValue(item))
+		}
+		return values
+	case value.KvlistValue != nil:
+		return jsonAttributes(value.KvlistValue.Values)
+	default:
+		return nil
+	}
+}
+
+// fromOTLP turns spans of an export request into spans of the project. A span without a parent or one
+// a service receives a request or a message with is a transaction.
+func fromOTLP(projectID string, resources []otlpResource) ([]*Span, error) {
+	var spans []*Span
+	for _, resource := range resources {
+		service, _ := resource.attributes[attrServiceName].(string)
+		var release *string
+		if version, ok := resource.attributes[attrServiceVersion].(string); ok && version != "" {
+			release = &version
+		}
+		environment, _ := resource.attributes[attrEnvironment].(string)
+		if environment == "" {
+			environment, _ = resource.attributes[attrLegacyEnvironment].(string)
+		}
+
+		for _, otlp := range resource.spans {
+			if len(spans) == MaxSpans {
+				return nil, ErrTooManySpans
+			}
+
+			span := &Span{
+				ProjectID:   projectID,
+				TraceID:     otlp.traceID,
+				SpanID:      otlp.spanID,
+				Name:        otlp.name,
+				Op:          otlpOp(otlp.kind, otlp.attributes),
+				Service:     service,
+				Status:      otlpStatus(otlp.statusCode),
+				Release:     release,
+				Environment: environment,
+				StartTime:   otlp.start / nanosInMilli,
+			}
+			if otlp.end > otlp.start {
+				span.Duration = float64(otlp.end-otlp.start) / nanosInMilli
+			}
+			if otlp.parentSpanID != "" && otlp.parentSpanID != zeroSpanID {
+				span.ParentSpanID = &otlp.parentSpanID
+			}
+			span.IsTransaction = span.ParentSpanID == nil ||
+				otlp.kind == int32(tracepb.Span_SPAN_KIND_SERVER) || otlp.kind == int32(tracepb.Span_SPAN_KIND_CONSUMER)
+
+			attributes, err := marshalAttributes(otlp.attributes)
+			if err != nil {
+				return nil, err
+			}
+			span.Attributes = attributes
+
+			spans = append(spans, span)
+		}
+	}
+	return spans, nil
+}
+
+// otlpOp prefixes the kind of a span with the protocol its attributes belong to, like http.server or db.client
+func otlpOp(kind int32, attributes map[string]interface{}) string {
+	op := otlpKinds[kind]
+	for _, p := range otlpProtocols {
+		if _, ok := attributes[p.attribute]; ok {
+			if op == "" {
+				return p.protocol
+			}
+			return p.protocol + "." + op
+		}
+	}
+	return op
+}
+
+func otlpStatus(code int32) string {
+	switch code {
+	case int32(tracepb.Status_STATUS_CODE_OK):
+		return StatusOK
+	case int32(tracepb.Status_STATUS_CODE_ERROR):
+		return StatusError
+	default:
+		return StatusUnset
+	}
+}
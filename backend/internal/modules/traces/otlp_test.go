@@ -0,0 +1,131 @@
+package traces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const otlpJSON = `{
+  "resourceSpans": [{
+    "resource": {"attributes": [
+      {"key": "service.name", "value": {"stringValue": "api"}},
+      {"key": "service.version", "value": {"stringValue": "1.4.2"}},
+      {"key": "deployment.environment", "value": {"stringValue": "production"}}
+    ]},
+    "scopeSpans": [{"spans": [
+      {
+        "traceId": "4BF92F3577B34DA6A3CE929D0E0E4736",
+        "spanId": "00f067aa0ba902b7",
+        "parentSpanId": "",
+        "name": "GET /api/users/{id}",
+        "kind": 2,
+        "startTimeUnixNano": "1704067200000000000",
+        "endTimeUnixNano": "1704067200184500000",
+        "attributes": [
+          {"key": "http.request.method", "value": {"stringValue": "GET"}},
+          {"key": "http.response.status_code", "value": {"intValue": "200"}}
+        ],
+        "status": {"code": 1}
+      },
+      {
+        "traceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+        "spanId": "b7ad6b7169203331",
+        "parentSpanId": "00f067aa0ba902b7",
+        "name": "SELECT users",
+        "kind": 3,
+        "startTimeUnixNano": 1704067200012000000,
+        "endTimeUnixNano": 1704067200047200000,
+        "attributes": [{"key": "db.system", "value": {"stringValue": "postgresql"}}],
+        "status": {"code": 2}
+      }
+    ]}]
+  }]
+}`
+
+func TestFromOTLPJSON(t *testing.T) {
+	resources, err := decodeOTLP([]byte(otlpJSON), FormatJSON)
+	require.NoError(t, err)
+
+	spans, err := fromOTLP("project", resources)
+	require.NoError(t, err)
+	require.Len(t, spans, 2)
+
+	transaction := spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", transaction.TraceID)
+	assert.True(t, transaction.IsTransaction)
+	assert.Nil(t, transaction.ParentSpanID)
+	assert.Equal(t, "http.server", transaction.Op)
+	assert.Equal(t, "api", transaction.Service)
+	assert.Equal(t, "1.4.2", *transaction.Release)
+	assert.Equal(t, "production", transaction.Environment)
+	assert.Equal(t, StatusOK, transaction.Status)
+	assert.Equal(t, int64(1704067200000), transaction.StartTime)
+	assert.InDelta(t, 184.5, transaction.Duration, 1e-9)
+	assert.JSONEq(t, `{"http.request.method": "GET", "http.response.status_code": 200}`, *transaction.Attributes)
+
+	span := spans[1]
+	assert.False(t, span.IsTransaction)
+	assert.Equal(t, "00f067aa0ba902b7", *span.ParentSpanID)
+	assert.Equal(t, "db.client", span.Op)
+	assert.Equal(t, StatusError, span.Status)
+	assert.Equal(t, int64(1704067200012), span.StartTime)
+	assert.InDelta(t, 35.2, span.Duration, 1e-9)
+}
+
+func TestFromOTLPProtobuf(t *testing.T) {
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+				Key:   "service.name",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "worker"}},
+			}}},
+			ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+				TraceId:           []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+				SpanId:            []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+				ParentSpanId:      []byte{0x53, 0x99, 0x5c, 0x3f, 0x42, 0xcd, 0x8a, 0xd8},
+				Name:              "process order",
+				Kind:              tracepb.Span_SPAN_KIND_CONSUMER,
+				StartTimeUnixNano: 1704067200000000000,
+				EndTimeUnixNano:   1704067201000000000,
+			}}}},
+		}},
+	}
+	body, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	resources, err := decodeOTLP(body, FormatProtobuf)
+	require.NoError(t, err)
+	spans, err := fromOTLP("project", resources)
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+
+	// A consumer span continuing the trace of a producer is a transaction of the worker
+	span := spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", *span.ParentSpanID)
+	assert.True(t, span.IsTransaction)
+	assert.Equal(t, "consumer", span.Op)
+	assert.Equal(t, "worker", span.Service)
+	assert.Equal(t, StatusUnset, span.Status)
+	assert.Nil(t, span.Release)
+	assert.Nil(t, span.Attributes)
+	assert.InDelta(t, 1000.0, span.Duration, 1e-9)
+}
+
+func TestDecodeOTLPInvalid(t *testing.T) {
+	_, err := decodeOTLP([]byte("{"), FormatJSON)
+	assert.ErrorIs(t, err, ErrInvalidOTLP)
+
+	_, err = decodeOTLP([]byte{0xff, 0xff}, FormatProtobuf)
+	assert.ErrorIs(t, err, ErrInvalidOTLP)
+
+	_, err = decodeOTLP(nil, "xml")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
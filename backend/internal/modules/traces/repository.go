@@ -0,0 +1,250 @@
+package traces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertBatch keeps a multi-row insert of spans well below the limit of parameters of a statement
+const insertBatch = 1000
+
+// sortColumns are the aggregates the transaction list can be ordered by
+var sortColumns = map[string]string{
+	SortP50:    "p50",
+	SortP95:    "p95",
+	SortP99:    "p99",
+	SortAvg:    "avg",
+	SortCount:  "count",
+	SortErrors: "errors",
+}
+
+type Repository interface {
+	// Save stores spans, spans already stored are skipped so a retried request does not count twice
+	Save(ctx context.Context, spans []*Span) error
+	// GetTransactions aggregates the transactions per name
+	GetTransactions(ctx context.Context, params SummaryParams) ([]*TransactionStats, error)
+	// GetSeries aggregates the transactions of a name per bucket, buckets without transactions are left out
+	GetSeries(ctx context.Context, params SeriesParams) ([]*SeriesRow, error)
+	// GetSamples returns transactions of a name, the slowest or the latest first
+	GetSamples(ctx context.Context, params SamplesParams) ([]*Span, error)
+	// GetTrace returns up to limit spans of a trace by start time
+	GetTrace(ctx context.Context, projectID, traceID string, limit int) ([]*Span, error)
+	// GetTraceErrors returns up to limit errors carrying the trace id, oldest first
+	GetTraceErrors(ctx context.Context, projectID, traceID string, limit int) ([]*TraceError, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger Logger
+}
+
+func NewRepository(db *sqlx.DB, logger Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Save(ctx context.Context, spans []*Span) (err error) {
+	const query = `
+		INSERT INTO spans (
+			project_id, trace_id, span_id, parent_span_id, is_transaction, name, op, service, status,
+			release, environment, start_time, duration, attributes
+		) VALUES (
+			:project_id, :trace_id, :span_id, :parent_span_id, :is_transaction, :name, :op, :service, :status,
+			:release, :environment, :start_time, :duration, :attributes
+		)
+		ON CONFLICT (project_id, trace_id, span_id) DO NOTHING`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				r.logger.WarnContext(ctx, "failed to rollback transaction", "error", rbErr)
+			}
+		}
+	}()
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	for start := 0; start < len(spans); start += insertBatch {
+		batch := spans[start:min(start+insertBatch, len(spans))]
+		if _, err = tx.NamedExecContext(ctx, query, batch); err != nil {
+			return fmt.Errorf("failed to save spans: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetTransactions(ctx context.Context, params SummaryParams) ([]*TransactionStats, error) {
+	query := `
+		SELECT
+			name,
+			COUNT(*) AS count,
+			COUNT(*) FILTER (WHERE status = 'error') AS errors,
+			AVG(duration) AS avg,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY duration) AS p50,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY duration) AS p95,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY duration) AS p99,
+			MAX(start_time) AS last_seen_at
+		FROM spans
+		WHERE project_id = :projectId AND is_transaction AND start_time >= :timeFrom AND start_time < :timeTo`
+
+	args := rangeArgs(params.ProjectID, params.TimeFrom, params.TimeTo)
+	args["limit"] = params.Limit
+	query, args = applyFilters(query, params.Environment, params.Release, args)
+
+	if params.Search != "" {
+		query += " AND name ILIKE :search"
+		args["search"] = "%" + params.Search + "%"
+	}
+
+	sortColumn, ok := sortColumns[params.Sort]
+	if !ok {
+		sortColumn = sortColumns[SortP95]
+	}
+	query += " GROUP BY name ORDER BY " + sortColumn + " DESC, name LIMIT :limit"
+
+	var stats []*TransactionStats
+	if err := r.selectNamed(ctx, &stats, query, args); err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	return stats, nil
+}
+
+func (r *repository) GetSeries(ctx context.Context, params SeriesParams) ([]*SeriesRow, error) {
+	query := `
+		SELECT
+			start_time / :interval * :interval AS bucket,
+			COUNT(*) AS count,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY duration) AS p50,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY duration) AS p95,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY duration) AS p99
+		FROM spans
+		WHERE project_id = :projectId AND is_transaction AND name = :name
+			AND start_time >= :timeFrom AND start_time < :timeTo`
+
+	args := rangeArgs(params.ProjectID, params.TimeFrom, params.TimeTo)
+	args["name"] = params.Name
+	args["interval"] = params.Interval
+	query, args = applyFilters(query, params.Environment, params.Release, args)
+	query += " GROUP BY bucket ORDER BY bucket"
+
+	var rows []*SeriesRow
+	if err := r.selectNamed(ctx, &rows, query, args); err != nil {
+		return nil, fmt.Errorf("failed to get transaction series: %w", err)
+	}
+	return rows, nil
+}
+
+func (r *repository) GetSamples(ctx context.Context, params SamplesParams) ([]*Span, error) {
+	query := `
+		SELECT
+			project_id, trace_id, span_id, parent_span_id, is_transaction, name, op, service, status,
+			release, environment, start_time, duration, attributes
+		FROM spans
+		WHERE project_id = :projectId AND is_transaction AND name = :name
+			AND start_time >= :timeFrom AND start_time < :timeTo`
+
+	args := rangeArgs(params.ProjectID, params.TimeFrom, params.TimeTo)
+	args["name"] = params.Name
+	args["limit"] = params.Limit
+	query, args = applyFilters(query, params.Environment, "", args)
+
+	if params.Sort == SampleSortTime {
+		query += " ORDER BY start_time DESC, span_id"
+	} else {
+		query += " ORDER BY duration DESC, span_id"
+	}
+	query += " LIMIT :limit"
+
+	var spans []*Span
+	if err := r.selectNamed(ctx, &spans, query, args); err != nil {
+		return nil, fmt.Errorf("failed to get transaction samples: %w", err)
+	}
+	return spans, nil
+}
+
+func (r *repository) GetTrace(ctx context.Context, projectID, traceID string, limit int) ([]*Span, error) {
+	const query = `
+		SELECT
+			project_id, trace_id, span_id, parent_span_id, is_transaction, name, op, service, status,
+			release, environment, start_time, duration, attributes
+		FROM spans
+		WHERE project_id = $1 AND trace_id = $2
+		ORDER BY start_time, span_id
+		LIMIT $3`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var spans []*Span
+	if err := r.db.SelectContext(ctx, &spans, query, projectID, traceID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	return spans, nil
+}
+
+func (r *repository) GetTraceErrors(ctx context.Context, projectID, traceID string, limit int) ([]*TraceError, error) {
+	const query = `
+		SELECT id, fingerprint, message, file, line, time
+		FROM errors
+		WHERE project_id = $1 AND trace_id = $2
+		ORDER BY time, id
+		LIMIT $3`
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	var traceErrors []*TraceError
+	if err := r.db.SelectContext(ctx, &traceErrors, query, projectID, traceID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get errors of trace: %w", err)
+	}
+	return traceErrors, nil
+}
+
+func (r *repository) selectNamed(ctx context.Context, dest interface{}, query string, args map[string]interface{}) error {
+	query, namedArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare named query: %w", err)
+	}
+
+	query = r.db.Rebind(query)
+
+	r.logger.DebugContext(ctx, "sql query", "query", query)
+
+	return r.db.SelectContext(ctx, dest, query, namedArgs...)
+}
+
+// rangeArgs are the arguments of the project and the time range every aggregate is bound to
+func rangeArgs(projectID string, timeFrom, timeTo int64) map[string]interface{} {
+	return map[string]interface{}{
+		"projectId": projectID,
+		"timeFrom":  timeFrom,
+		"timeTo":    timeTo,
+	}
+}
+
+func applyFilters(query, environment, release string, args map[string]interface{}) (string, map[string]interface{}) {
+	if environment != "" {
+		query += " AND environment = :environment"
+		args["environment"] = environment
+	}
+
+	if release != "" {
+		query += " AND release = :release"
+		args["release"] = release
+	}
+
+	return query, args
+}
@@ -0,0 +1,94 @@
+package traces
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/duckbugio/duckbug/internal/storage/sql/sqltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProject = "a08929b5-d4f0-4ceb-9cfe-bb4fc05b030c"
+	testTrace   = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+func newRecordedRepository() (Repository, *sqltest.Recorder) {
+	db := &sqltest.Recorder{}
+	return NewRepository(db.DB(), logger.New("error", &bytes.Buffer{})), db
+}
+
+func TestGetTransactionsQuery(t *testing.T) {
+	repo, db := newRecordedRepository()
+
+	_, err := repo.GetTransactions(context.Background(), SummaryParams{
+		ProjectID:   testProject,
+		Environment: "production",
+		Search:      "users",
+		TimeFrom:    1000,
+		TimeTo:      2000,
+		Sort:        SortP99,
+		Limit:       50,
+	})
+	require.NoError(t, err)
+	require.Len(t, db.Queries, 1)
+
+	query := db.Queries[0]
+	for _, percentile := range []string{"0.5", "0.95", "0.99"} {
+		assert.Contains(t, query.SQL, "percentile_cont("+percentile+") WITHIN GROUP (ORDER BY duration)")
+	}
+	assert.Contains(t, query.SQL, "AND environment = $4 AND name ILIKE $5")
+	assert.NotContains(t, query.SQL, "release =")
+	assert.Contains(t, query.SQL, "ORDER BY p99 DESC, name LIMIT $6")
+	assert.Equal(t, []interface{}{testProject, int64(1000), int64(2000), "production", "%users%", int64(50)}, query.Args)
+}
+
+func TestGetTransactionsQueryDefaultSort(t *testing.T) {
+	repo, db := newRecordedRepository()
+
+	_, err := repo.GetTransactions(context.Background(), SummaryParams{ProjectID: testProject, Sort: "name; DROP TABLE spans"})
+	require.NoError(t, err)
+	require.Len(t, db.Queries, 1)
+	assert.Contains(t, db.Queries[0].SQL, "ORDER BY p95 DESC, name")
+}
+
+func TestGetSeriesQuery(t *testing.T) {
+	repo, db := newRecordedRepository()
+
+	_, err := repo.GetSeries(context.Background(), SeriesParams{
+		ProjectID: testProject,
+		Name:      "GET /users",
+		Release:   "1.2.0",
+		TimeFrom:  0,
+		TimeTo:    7200000,
+		Interval:  3600000,
+	})
+	require.NoError(t, err)
+	require.Len(t, db.Queries, 1)
+
+	query := db.Queries[0]
+	// Both uses of the interval are bound, a bucket is the start of its interval
+	assert.Contains(t, query.SQL, "start_time / $1 * $2 AS bucket")
+	assert.Contains(t, query.SQL, "percentile_cont(0.95) WITHIN GROUP (ORDER BY duration) AS p95")
+	assert.Contains(t, query.SQL, "AND release = $7 GROUP BY bucket ORDER BY bucket")
+	assert.Equal(t, []interface{}{
+		int64(3600000), int64(3600000), testProject, "GET /users", int64(0), int64(7200000), "1.2.0",
+	}, query.Args)
+}
+
+func TestGetTraceErrorsQuery(t *testing.T) {
+	repo, db := newRecordedRepository()
+
+	_, err := repo.GetTraceErrors(context.Background(), testProject, testTrace, maxTraceErrors)
+	require.NoError(t, err)
+	require.Len(t, db.Queries, 1)
+
+	query := db.Queries[0]
+	assert.Contains(t, query.SQL, "FROM errors")
+	assert.Contains(t, query.SQL, "WHERE project_id = $1 AND trace_id = $2")
+	assert.Contains(t, query.SQL, "ORDER BY time, id")
+	assert.Equal(t, []interface{}{testProject, testTrace, int64(maxTraceErrors)}, query.Args)
+}
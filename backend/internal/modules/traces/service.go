@@ -0,0 +1,346 @@
+package traces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	traceIDLength = 32
+	spanIDLength  = 16
+	zeroSpanID    = "0000000000000000"
+
+	// maxTraceSpans and maxTraceErrors bound what a trace shows
+	maxTraceSpans  = 10000
+	maxTraceErrors = 100
+	maxBuckets     = 1500
+
+	// Stored names are cut to the size of their columns
+	maxNameLength    = 255
+	maxOpLength      = 64
+	maxServiceLength = 255
+
+	percent = 100
+	// rateScale keeps three decimals of rates and latencies
+	rateScale = 1000
+)
+
+var hexPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+type Service interface {
+	// Create stores a transaction with its spans sent in the DuckBug JSON format
+	Create(ctx context.Context, req *Create) error
+	// CreateOTLP stores the spans of an OTLP/HTTP trace export request and returns how many there were
+	CreateOTLP(ctx context.Context, projectID string, body []byte, format string) (int, error)
+	// GetTransactions returns latency percentiles, throughput and errors per transaction name
+	GetTransactions(ctx context.Context, params SummaryParams) ([]TransactionEntity, error)
+	// GetSeries returns the latency percentiles and the throughput of a transaction name over time
+	GetSeries(ctx context.Context, params SeriesParams) (*Series, error)
+	// GetSamples returns transactions of a name to open their traces from
+	GetSamples(ctx context.Context, params SamplesParams) ([]SpanEntity, error)
+	// GetTrace returns the spans of a trace and the errors reported within it
+	GetTrace(ctx context.Context, projectID, traceID string) (*TraceEntity, error)
+}
+
+type service struct {
+	repo   Repository
+	logger Logger
+}
+
+func NewService(repo Repository, logger Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Create(ctx context.Context, req *Create) error {
+	transaction := &Span{
+		ProjectID:     req.ProjectID,
+		TraceID:       strings.ToLower(req.TraceID),
+		SpanID:        strings.ToLower(req.SpanID),
+		IsTransaction: true,
+		Name:          req.Name,
+		Op:            req.Op,
+		Service:       req.Service,
+		Status:        statusOrUnset(req.Status),
+		Release:       req.Release,
+		Environment:   req.Environment,
+		StartTime:     req.StartTime,
+		Duration:      req.Duration,
+	}
+	if req.ParentSpanID != "" {
+		parent := strings.ToLower(req.ParentSpanID)
+		transaction.ParentSpanID = &parent
+	}
+	attributes, err := marshalAttributes(req.Attributes)
+	if err != nil {
+		return err
+	}
+	transaction.Attributes = attributes
+
+	spans := make([]*Span, 0, len(req.Spans)+1)
+	spans = append(spans, transaction)
+	for _, child := range req.Spans {
+		parent := transaction.SpanID
+		if child.ParentSpanID != "" {
+			parent = strings.ToLower(child.ParentSpanID)
+		}
+		span := &Span{
+			ProjectID:    req.ProjectID,
+			TraceID:      transaction.TraceID,
+			SpanID:       strings.ToLower(child.SpanID),
+			ParentSpanID: &parent,
+			Name:         child.Name,
+			Op:           child.Op,
+			Service:      req.Service,
+			Status:       statusOrUnset(child.Status),
+			Release:      req.Release,
+			Environment:  req.Environment,
+			StartTime:    child.StartTime,
+			Duration:     child.Duration,
+		}
+		if span.Attributes, err = marshalAttributes(child.Attributes); err != nil {
+			return err
+		}
+		spans = append(spans, span)
+	}
+
+	return s.save(ctx, spans)
+}
+
+func (s *service) CreateOTLP(ctx context.Context, projectID string, body []byte, format string) (int, error) {
+	resources, err := decodeOTLP(body, format)
+	if err != nil {
+		return 0, err
+	}
+
+	spans, err := fromOTLP(projectID, resources)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.save(ctx, spans); err != nil {
+		return 0, err
+	}
+	return len(spans), nil
+}
+
+// save checks the ids of spans and stores them, names too long for their columns are cut
+func (s *service) save(ctx context.Context, spans []*Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	if len(spans) > MaxSpans {
+		return ErrTooManySpans
+	}
+
+	for _, span := range spans {
+		if !ValidTraceID(span.TraceID) || !validID(span.SpanID, spanIDLength) ||
+			(span.ParentSpanID != nil && !validID(*span.ParentSpanID, spanIDLength)) {
+			return ErrInvalidID
+		}
+		span.Name = truncate(span.Name, maxNameLength)
+		span.Op = truncate(span.Op, maxOpLength)
+		span.Service = truncate(span.Service, maxServiceLength)
+	}
+
+	return s.repo.Save(ctx, spans)
+}
+
+func (s *service) GetTransactions(ctx context.Context, params SummaryParams) ([]TransactionEntity, error) {
+	stats, err := s.repo.GetTransactions(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]TransactionEntity, 0, len(stats))
+	for _, stat := range stats {
+		entities = append(entities, TransactionEntity{
+			Name:       stat.Name,
+			Count:      stat.Count,
+			Errors:     stat.Errors,
+			ErrorRate:  round(float64(stat.Errors) * percent / float64(stat.Count)),
+			Throughput: throughput(stat.Count, params.TimeTo-params.TimeFrom),
+			Avg:        round(stat.Avg),
+			P50:        round(stat.P50),
+			P95:        round(stat.P95),
+			P99:        round(stat.P99),
+			LastSeenAt: stat.LastSeenAt,
+		})
+	}
+	return entities, nil
+}
+
+func (s *service) GetSeries(ctx context.Context, params SeriesParams) (*Series, error) {
+	if params.Interval <= 0 || (params.TimeTo-params.TimeFrom)/params.Interval > maxBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	rows, err := s.repo.GetSeries(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	byBucket := make(map[int64]*SeriesRow, len(rows))
+	for _, row := range rows {
+		byBucket[row.Bucket] = row
+	}
+
+	// Every bucket of the range is returned, so charts show gaps as zero throughput
+	series := &Series{Name: params.Name, Interval: params.Interval, Points: []SeriesPoint{}}
+	for bucket := params.TimeFrom / params.Interval * params.Interval; bucket < params.TimeTo; bucket += params.Interval {
+		point := SeriesPoint{Time: bucket}
+		if row, ok := byBucket[bucket]; ok {
+			p50, p95, p99 := round(row.P50), round(row.P95), round(row.P99)
+			point.Count = row.Count
+			point.Throughput = throughput(row.Count, params.Interval)
+			point.P50, point.P95, point.P99 = &p50, &p95, &p99
+		}
+		series.Points = append(series.Points, point)
+	}
+	return series, nil
+}
+
+func (s *service) GetSamples(ctx context.Context, params SamplesParams) ([]SpanEntity, error) {
+	spans, err := s.repo.GetSamples(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]SpanEntity, 0, len(spans))
+	for _, span := range spans {
+		entities = append(entities, toSpanEntity(span))
+	}
+	return entities, nil
+}
+
+func (s *service) GetTrace(ctx context.Context, projectID, traceID string) (*TraceEntity, error) {
+	traceID = strings.ToLower(traceID)
+	if !ValidTraceID(traceID) {
+		return nil, ErrNotFound
+	}
+
+	spans, err := s.repo.GetTrace(ctx, projectID, traceID, maxTraceSpans)
+	if err != nil {
+		return nil, err
+	}
+	traceErrors, err := s.repo.GetTraceErrors(ctx, projectID, traceID, maxTraceErrors)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 && len(traceErrors) == 0 {
+		return nil, ErrNotFound
+	}
+
+	trace := &TraceEntity{
+		TraceID: traceID,
+		Spans:   make([]SpanEntity, 0, len(spans)),
+		Errors:  make([]TraceErrorEntity, 0, len(traceErrors)),
+	}
+
+	var end float64
+	for i, span := range spans {
+		if i == 0 {
+			trace.StartTime = span.StartTime
+		}
+		end = math.Max(end, float64(span.StartTime-trace.StartTime)+span.Duration)
+		trace.Spans = append(trace.Spans, toSpanEntity(span))
+	}
+	trace.Duration = round(end)
+
+	for _, traceError := range traceErrors {
+		trace.Errors = append(trace.Errors, TraceErrorEntity{
+			ID:      traceError.ID,
+			GroupID: traceError.Fingerprint,
+			Message: traceError.Message,
+			File:    traceError.File,
+			Line:    traceError.Line,
+			Time:    traceError.Time,
+		})
+	}
+	return trace, nil
+}
+
+func toSpanEntity(span *Span) SpanEntity {
+	entity := SpanEntity{
+		TraceID:       span.TraceID,
+		SpanID:        span.SpanID,
+		ParentSpanID:  span.ParentSpanID,
+		IsTransaction: span.IsTransaction,
+		Name:          span.Name,
+		Op:            span.Op,
+		Service:       span.Service,
+		Status:        span.Status,
+		Release:       span.Release,
+		Environment:   span.Environment,
+		StartTime:     span.StartTime,
+		Duration:      span.Duration,
+	}
+	if span.Attributes != nil {
+		// Attributes are written by marshalAttributes, a broken value is left out rather than failing the trace
+		_ = json.Unmarshal([]byte(*span.Attributes), &entity.Attributes)
+	}
+	return entity
+}
+
+func marshalAttributes(attributes map[string]interface{}) (*string, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal span attributes: %w", err)
+	}
+
+	value := string(data)
+	return &value, nil
+}
+
+// ValidTraceID tells whether id is a W3C trace id, 32 lowercase hex digits and not all zero
+func ValidTraceID(id string) bool {
+	return validID(id, traceIDLength)
+}
+
+// validID tells whether id is lowercase hex of the length and not all zero, which W3C trace context forbids
+func validID(id string, length int) bool {
+	return len(id) == length && hexPattern.MatchString(id) && strings.Trim(id, "0") != ""
+}
+
+func statusOrUnset(status string) string {
+	if status == "" {
+		return StatusUnset
+	}
+	return status
+}
+
+// throughput is the number of transactions per minute over a period in milliseconds
+func throughput(count int, period int64) float64 {
+	if period <= 0 {
+		return 0
+	}
+	return round(float64(count) / (float64(period) / float64(time.Minute.Milliseconds())))
+}
+
+func round(value float64) float64 {
+	return math.Round(value*rateScale) / rateScale
+}
+
+// truncate cuts value to at most limit bytes without splitting a character
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	value = value[:limit]
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
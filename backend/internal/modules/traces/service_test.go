@@ -0,0 +1,156 @@
+package traces
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository returns the rows it was given and keeps the trace id it was asked for
+type fakeRepository struct {
+	stats       []*TransactionStats
+	series      []*SeriesRow
+	spans       []*Span
+	traceErrors []*TraceError
+	traceID     string
+}
+
+func (f *fakeRepository) Save(context.Context, []*Span) error {
+	return nil
+}
+
+func (f *fakeRepository) GetTransactions(context.Context, SummaryParams) ([]*TransactionStats, error) {
+	return f.stats, nil
+}
+
+func (f *fakeRepository) GetSeries(context.Context, SeriesParams) ([]*SeriesRow, error) {
+	return f.series, nil
+}
+
+func (f *fakeRepository) GetSamples(context.Context, SamplesParams) ([]*Span, error) {
+	return f.spans, nil
+}
+
+func (f *fakeRepository) GetTrace(_ context.Context, _, traceID string, _ int) ([]*Span, error) {
+	f.traceID = traceID
+	return f.spans, nil
+}
+
+func (f *fakeRepository) GetTraceErrors(_ context.Context, _, traceID string, _ int) ([]*TraceError, error) {
+	f.traceID = traceID
+	return f.traceErrors, nil
+}
+
+func newTestService(repo *fakeRepository) Service {
+	return NewService(repo, logger.New("error", &bytes.Buffer{}))
+}
+
+func TestValidID(t *testing.T) {
+	assert.True(t, ValidTraceID(testTrace))
+	assert.False(t, ValidTraceID("4BF92F3577B34DA6A3CE929D0E0E4736"))
+	assert.True(t, validID("4bf92f3577b34da6a3ce929d0e0e4736", traceIDLength))
+	assert.False(t, validID("00000000000000000000000000000000", traceIDLength))
+	assert.False(t, validID("4bf92f3577b34da6", traceIDLength))
+	assert.False(t, validID("0xf067aa0ba902b7", spanIDLength))
+}
+
+func TestThroughput(t *testing.T) {
+	assert.InDelta(t, 8.333, throughput(500, 60*60*1000), 1e-9)
+	assert.Zero(t, throughput(500, 0))
+}
+
+func TestGetTransactions(t *testing.T) {
+	s := newTestService(&fakeRepository{stats: []*TransactionStats{
+		{Name: "GET /users", Count: 600, Errors: 9, Avg: 96.40004, P50: 72.1234, P95: 310.8, P99: 1204.30049, LastSeenAt: 42},
+	}})
+
+	transactions, err := s.GetTransactions(context.Background(), SummaryParams{TimeFrom: 0, TimeTo: 60 * 60 * 1000})
+	require.NoError(t, err)
+	assert.Equal(t, []TransactionEntity{{
+		Name:       "GET /users",
+		Count:      600,
+		Errors:     9,
+		ErrorRate:  1.5,
+		Throughput: 10,
+		Avg:        96.4,
+		P50:        72.123,
+		P95:        310.8,
+		P99:        1204.3,
+		LastSeenAt: 42,
+	}}, transactions)
+}
+
+func TestGetSeries(t *testing.T) {
+	const interval = 60 * 1000
+	s := newTestService(&fakeRepository{series: []*SeriesRow{
+		{Bucket: interval, Count: 30, P50: 10, P95: 20.00049, P99: 40},
+	}})
+
+	series, err := s.GetSeries(context.Background(), SeriesParams{
+		Name:     "GET /users",
+		TimeFrom: interval / 2,
+		TimeTo:   3 * interval,
+		Interval: interval,
+	})
+	require.NoError(t, err)
+
+	// The range starts at the bucket it falls into and buckets without transactions have no percentiles
+	require.Len(t, series.Points, 3)
+	assert.Equal(t, SeriesPoint{Time: 0}, series.Points[0])
+	assert.Equal(t, SeriesPoint{Time: 2 * interval}, series.Points[2])
+
+	point := series.Points[1]
+	assert.Equal(t, int64(interval), point.Time)
+	assert.Equal(t, 30, point.Count)
+	assert.InDelta(t, 30.0, point.Throughput, 1e-9)
+	require.NotNil(t, point.P95)
+	assert.InDelta(t, 20.0, *point.P95, 1e-9)
+
+	_, err = s.GetSeries(context.Background(), SeriesParams{TimeTo: (maxBuckets + 1) * interval, Interval: interval})
+	assert.ErrorIs(t, err, ErrTooManyBuckets)
+	_, err = s.GetSeries(context.Background(), SeriesParams{TimeTo: interval})
+	assert.ErrorIs(t, err, ErrTooManyBuckets)
+}
+
+func TestGetTrace(t *testing.T) {
+	repo := &fakeRepository{
+		spans: []*Span{
+			{TraceID: testTrace, SpanID: "00f067aa0ba902b7", IsTransaction: true, StartTime: 1000, Duration: 50},
+			{TraceID: testTrace, SpanID: "53995c3f42cd8ad8", StartTime: 1010, Duration: 120.5},
+		},
+		traceErrors: []*TraceError{
+			{ID: "error", Fingerprint: "group", Message: "Timeout", File: "app.go", Line: 42, Time: 1100},
+		},
+	}
+	s := newTestService(repo)
+
+	trace, err := s.GetTrace(context.Background(), testProject, "4BF92F3577B34DA6A3CE929D0E0E4736")
+	require.NoError(t, err)
+	assert.Equal(t, testTrace, repo.traceID, "trace ids are looked up in lowercase")
+	assert.Equal(t, int64(1000), trace.StartTime)
+	assert.InDelta(t, 130.5, trace.Duration, 1e-9, "the trace ends with the span ending last")
+	assert.Len(t, trace.Spans, 2)
+	assert.Equal(t, []TraceErrorEntity{
+		{ID: "error", GroupID: "group", Message: "Timeout", File: "app.go", Line: 42, Time: 1100},
+	}, trace.Errors)
+}
+
+func TestGetTraceNotFound(t *testing.T) {
+	s := newTestService(&fakeRepository{})
+
+	_, err := s.GetTrace(context.Background(), testProject, testTrace)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.GetTrace(context.Background(), testProject, "00000000000000000000000000000000")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Errors alone are enough for a trace, its spans may not have been sent
+	trace, err := newTestService(&fakeRepository{traceErrors: []*TraceError{{ID: "error"}}}).
+		GetTrace(context.Background(), testProject, testTrace)
+	require.NoError(t, err)
+	assert.Empty(t, trace.Spans)
+	assert.Len(t, trace.Errors, 1)
+}
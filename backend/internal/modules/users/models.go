@@ -1,10 +1,30 @@
 package users
 
+import (
+	"context"
+	"errors"
+)
+
+const (
+	RoleUser = "user"
+	// RoleAdmin grants access to instance wide operations such as erasure requests
+	RoleAdmin = "admin"
+)
+
+var (
+	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound = errors.New("user not found")
+)
+
 type Logger interface {
 	Debug(msg string)
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
 type Signup struct {
@@ -13,6 +13,7 @@ import (
 type Repository interface {
 	Create(ctx context.Context, user *User) error
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	UpdatePassword(ctx context.Context, email, hash string, updatedAt int64) error
 }
 
 type repository struct {
@@ -62,3 +63,21 @@ func (r *repository) FindByEmail(ctx context.Context, email string) (*User, erro
 
 	return &user, nil
 }
+
+func (r *repository) UpdatePassword(ctx context.Context, email, hash string, updatedAt int64) error {
+	const query = `UPDATE users SET password = $1, updated_at = $2 WHERE email = $3`
+
+	result, err := r.db.ExecContext(ctx, query, hash, updatedAt, email)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
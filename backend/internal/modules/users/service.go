@@ -3,8 +3,10 @@ package users
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/duckbugio/duckbug/internal/middleware"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -12,9 +14,21 @@ import (
 
 const tokenTTL = 7 * 24 * time.Hour
 
+// auditTarget names users in the audit log
+const auditTarget = "user"
+
+// auditState is what the audit log keeps of a user, never the password
+type auditState struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
 type Service interface {
 	Signup(ctx context.Context, req *Signup) error
 	Login(ctx context.Context, req *Login) (*Token, error)
+	// CreateAdmin creates a user with the admin role, used by the admin CLI
+	CreateAdmin(ctx context.Context, req *Signup) error
+	ResetPassword(ctx context.Context, email, password string) error
 }
 
 type service struct {
@@ -32,13 +46,29 @@ func NewService(r Repository, key []byte, logger Logger) Service {
 }
 
 func (s *service) Signup(ctx context.Context, req *Signup) error {
+	return s.create(ctx, req, RoleUser)
+}
+
+func (s *service) CreateAdmin(ctx context.Context, req *Signup) error {
+	return s.create(ctx, req, RoleAdmin)
+}
+
+func (s *service) ResetPassword(ctx context.Context, email, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.repo.UpdatePassword(ctx, email, string(hash), time.Now().Unix())
+}
+
+func (s *service) create(ctx context.Context, req *Signup, role string) error {
 	user, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return err
 	}
 
 	if user != nil {
-		return errors.New("user already exists")
+		return ErrUserExists
 	}
 
 	hash, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -49,11 +79,16 @@ func (s *service) Signup(ctx context.Context, req *Signup) error {
 		ID:        uuid.New().String(),
 		Email:     req.Email,
 		Password:  string(hash),
-		Role:      "user",
+		Role:      role,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	return s.repo.Create(ctx, user)
+	if err = s.repo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	middleware.Audit(ctx, "user.signup", auditTarget, user.ID, nil, auditState{Email: user.Email, Role: user.Role})
+	return nil
 }
 
 func (s *service) Login(ctx context.Context, req *Login) (*Token, error) {
@@ -63,18 +98,20 @@ func (s *service) Login(ctx context.Context, req *Login) (*Token, error) {
 	}
 
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	if !checkPassword(req.Password, user.Password) {
 		return nil, errors.New("invalid credentials")
 	}
 
-	accessToken, err := generateJWT(user.ID, s.jwtKey)
+	accessToken, err := generateJWT(user.ID, user.Role, s.jwtKey)
 	if err != nil {
 		return nil, err
 	}
 
+	middleware.Audit(ctx, "user.login", auditTarget, user.ID, nil, nil)
+
 	token := &Token{
 		AccessToken:  accessToken,
 		RefreshToken: "todo",
@@ -88,9 +125,10 @@ func checkPassword(password, hash string) bool {
 	return err == nil
 }
 
-func generateJWT(userID string, jwtKey []byte) (string, error) {
+func generateJWT(userID, role string, jwtKey []byte) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"role":    role,
 		"exp":     time.Now().Add(tokenTTL).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
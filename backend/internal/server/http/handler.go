@@ -4,12 +4,24 @@ import (
 	"net/http"
 
 	"github.com/duckbugio/duckbug/internal/modules/app"
+	"github.com/duckbugio/duckbug/internal/modules/audit"
+	"github.com/duckbugio/duckbug/internal/modules/backup"
+	"github.com/duckbugio/duckbug/internal/modules/bulk"
+	"github.com/duckbugio/duckbug/internal/modules/erasure"
 	"github.com/duckbugio/duckbug/internal/modules/errors"
 	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/internal/modules/jobs"
+	"github.com/duckbugio/duckbug/internal/modules/keys"
 	"github.com/duckbugio/duckbug/internal/modules/log"
 	logGroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	"github.com/duckbugio/duckbug/internal/modules/monitors"
 	"github.com/duckbugio/duckbug/internal/modules/project"
+	"github.com/duckbugio/duckbug/internal/modules/release"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
+	"github.com/duckbugio/duckbug/internal/modules/scrubbing"
 	"github.com/duckbugio/duckbug/internal/modules/technology"
+	"github.com/duckbugio/duckbug/internal/modules/traces"
 	"github.com/duckbugio/duckbug/internal/modules/users"
 	"github.com/duckbugio/duckbug/internal/server/http/handlers"
 	"github.com/gorilla/mux"
@@ -26,8 +38,20 @@ func NewHandler(
 	errorGroupService errorsGroup.Service,
 	technologyService technology.Service,
 	projectService project.Service,
+	scrubbingService scrubbing.Service,
+	jobService jobs.Service,
+	erasureService erasure.Service,
+	ingestService ingest.Service,
+	keyService keys.Service,
+	auditService audit.Service,
+	backupService backup.Service,
+	bulkService bulk.Service,
+	savedSearchService savedSearch.Service,
+	releaseService release.Service,
+	monitorService monitors.Service,
+	traceService traces.Service,
 	jwtKey []byte,
-) http.Handler {
+) *mux.Router {
 	r := mux.NewRouter()
 	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
 	r.NotFoundHandler = http.HandlerFunc(methodNotFoundHandler)
@@ -36,12 +60,24 @@ func NewHandler(
 
 	handlers.RegisterAppHandlers(r, logger, appService)
 	handlers.RegisterAuthHandlers(r, logger, userService)
-	handlers.RegisterLogHandlers(r, logger, logService, jwtKey)
-	handlers.RegisterLogGroupHandlers(r, logger, logGroupService, jwtKey)
-	handlers.RegisterErrorHandlers(r, logger, errorService, jwtKey)
-	handlers.RegisterErrorGroupHandlers(r, logger, errorGroupService, jwtKey)
+	handlers.RegisterLogHandlers(r, logger, logService, ingestService, savedSearchService, jwtKey)
+	handlers.RegisterLogGroupHandlers(r, logger, logGroupService, savedSearchService, jwtKey)
+	handlers.RegisterErrorHandlers(r, logger, errorService, ingestService, savedSearchService, jwtKey)
+	handlers.RegisterErrorGroupHandlers(r, logger, errorGroupService, savedSearchService, jwtKey)
 	handlers.RegisterTechnologyHandlers(r, logger, technologyService)
 	handlers.RegisterProjectHandlers(r, logger, projectService, jwtKey)
+	handlers.RegisterScrubbingHandlers(r, logger, scrubbingService, jwtKey)
+	handlers.RegisterJobHandlers(r, logger, jobService, jwtKey)
+	handlers.RegisterErasureHandlers(r, logger, erasureService, jwtKey)
+	handlers.RegisterIngestHandlers(r, logger, ingestService, jwtKey)
+	handlers.RegisterKeyHandlers(r, logger, keyService, jwtKey)
+	handlers.RegisterAuditHandlers(r, logger, auditService, jwtKey)
+	handlers.RegisterBackupHandlers(r, logger, backupService, jwtKey)
+	handlers.RegisterBulkHandlers(r, logger, bulkService, jwtKey)
+	handlers.RegisterSavedSearchHandlers(r, logger, savedSearchService, jwtKey)
+	handlers.RegisterReleaseHandlers(r, logger, releaseService, ingestService, jwtKey)
+	handlers.RegisterMonitorHandlers(r, logger, monitorService, ingestService, jwtKey)
+	handlers.RegisterTraceHandlers(r, logger, traceService, ingestService, jwtKey)
 
 	return r
 }
@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/duckbugio/duckbug/internal/modules/app"
+	"github.com/duckbugio/duckbug/pkg/httputils"
 	"github.com/gorilla/mux"
 )
 
@@ -24,6 +24,8 @@ func RegisterAppHandlers(
 	}
 
 	r.HandleFunc("/health", h.Health).Methods(http.MethodGet)
+	r.HandleFunc("/health/live", h.Live).Methods(http.MethodGet)
+	r.HandleFunc("/health/ready", h.Ready).Methods(http.MethodGet)
 }
 
 func (h *appHandler) Health(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +33,35 @@ func (h *appHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 	_, err := w.Write(response)
 	if err != nil {
-		h.logger.Error(fmt.Sprintf("Health - response error: %s", err))
+		h.logger.ErrorContext(r.Context(), "Health - response error", "error", err)
 	}
 }
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Answers as long as the process serves requests, dependencies are not checked
+// @Tags health
+// @Produce json
+// @Success 200 {object} app.Liveness
+// @Router /health/live [get].
+func (h *appHandler) Live(w http.ResponseWriter, r *http.Request) {
+	httputils.RespondWithJSON(w, http.StatusOK, h.service.Live(r.Context()))
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Pings the database, checks the schema version and reports the state of every background worker
+// @Tags health
+// @Produce json
+// @Success 200 {object} app.Readiness
+// @Failure 503 {object} app.Readiness "Database unavailable or schema not migrated"
+// @Router /health/ready [get].
+func (h *appHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	readiness := h.service.Ready(r.Context())
+
+	code := http.StatusOK
+	if readiness.Status != app.StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	httputils.RespondWithJSON(w, code, readiness)
+}
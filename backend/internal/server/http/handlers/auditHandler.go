@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/audit"
+	"github.com/duckbugio/duckbug/internal/modules/users"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/duckbugio/duckbug/pkg/utils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type auditHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  audit.Service
+}
+
+func RegisterAuditHandlers(
+	r *mux.Router,
+	logger Logger,
+	service audit.Service,
+	jwtKey []byte,
+) {
+	h := &auditHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/audit").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.RequireRole(users.RoleAdmin))
+
+	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/export", h.Export).Methods(http.MethodGet)
+}
+
+// GetAll godoc
+// @Summary Get the audit log
+// @Description Lists mutating API requests with actor, action, target, changed fields, IP and user agent. Newest first, admins only.
+// @Tags admin
+// @Produce json
+// @Param actorId query string false "User ID of the actor"
+// @Param action query string false "Action, e.g. project.delete"
+// @Param targetType query string false "Target type, e.g. project"
+// @Param targetId query string false "Target ID"
+// @Param timeFrom query int false "Entries from, unix seconds"
+// @Param timeTo query int false "Entries to, unix seconds"
+// @Param limit query int false "Items per page" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} audit.EntityList
+// @Failure 400 {object} string "Invalid filter"
+// @Failure 403 {object} string "Forbidden"
+// @Security BearerAuth
+// @Router /v1/audit [get].
+func (h *auditHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	filter, ok := h.parseFilter(w, queryParams)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = httputils.DefaultLimit
+	}
+
+	offset, err := strconv.Atoi(queryParams.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = httputils.DefaultOffset
+	}
+
+	items, totalCount, err := h.service.GetAll(r.Context(), audit.GetAllParams{
+		FilterParams: filter,
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, items))
+}
+
+// Export godoc
+// @Summary Export the audit log
+// @Description Streams the matching entries as NDJSON, one audit.Entity per line, oldest first. Admins only.
+// @Tags admin
+// @Produce application/x-ndjson
+// @Param actorId query string false "User ID of the actor"
+// @Param action query string false "Action, e.g. project.delete"
+// @Param targetType query string false "Target type, e.g. project"
+// @Param targetId query string false "Target ID"
+// @Param timeFrom query int false "Entries from, unix seconds"
+// @Param timeTo query int false "Entries to, unix seconds"
+// @Success 200 {string} string "NDJSON of audit entries"
+// @Failure 400 {object} string "Invalid filter"
+// @Failure 403 {object} string "Forbidden"
+// @Security BearerAuth
+// @Router /v1/audit/export [get].
+func (h *auditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseFilter(w, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+
+	// Headers are sent with the first line, a failure later can only cut the stream short
+	if err := h.service.Export(r.Context(), filter, w); err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to export audit log", "error", err)
+	}
+}
+
+func (h *auditHandler) parseFilter(w http.ResponseWriter, queryParams url.Values) (audit.FilterParams, bool) {
+	filter := audit.FilterParams{
+		ActorID:    queryParams.Get("actorId"),
+		Action:     queryParams.Get("action"),
+		TargetType: queryParams.Get("targetType"),
+		TargetID:   queryParams.Get("targetId"),
+	}
+	if filter.ActorID != "" && h.validate.Var(filter.ActorID, "uuid") != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "invalid actorId")
+		return filter, false
+	}
+
+	if timeFrom, err := utils.ParseTimeParam(queryParams.Get("timeFrom")); err == nil {
+		filter.TimeFrom = timeFrom
+	}
+	if timeTo, err := utils.ParseTimeParam(queryParams.Get("timeTo")); err == nil {
+		filter.TimeTo = timeTo
+	}
+	return filter, true
+}
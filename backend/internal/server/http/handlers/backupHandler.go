@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/backup"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/duckbugio/duckbug/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+type backupHandler struct {
+	logger  Logger
+	service backup.Service
+}
+
+func RegisterBackupHandlers(
+	r *mux.Router,
+	logger Logger,
+	service backup.Service,
+	jwtKey []byte,
+) {
+	h := &backupHandler{
+		logger:  logger,
+		service: service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/projects").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("/import", h.Import).Methods(http.MethodPost)
+	routerV1.HandleFunc("/{id}/export", h.Export).Methods(http.MethodGet)
+}
+
+// Export godoc
+// @Summary Export a project
+// @Description Streams the project settings, groups with their statuses, comments and activity, and events
+// @Description as a gzip compressed NDJSON archive. Events can be limited to a time range.
+// @Tags projects
+// @Produce application/gzip
+// @Param id path string true "Project ID"
+// @Param timeFrom query int false "Events from, unix milliseconds"
+// @Param timeTo query int false "Events to, unix milliseconds"
+// @Success 200 {file} file "Archive"
+// @Failure 404 {object} string "Project not found"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/export [get].
+func (h *backupHandler) Export(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	params := backup.ExportParams{ProjectID: id}
+
+	queryParams := r.URL.Query()
+	if timeFrom, err := utils.ParseTimeParam(queryParams.Get("timeFrom")); err == nil {
+		params.TimeFrom = timeFrom
+	}
+	if timeTo, err := utils.ParseTimeParam(queryParams.Get("timeTo")); err == nil {
+		params.TimeTo = timeTo
+	}
+
+	archive := &downloadWriter{w: w, controller: http.NewResponseController(w), contentType: "application/gzip", filename: "duckbug-project-" + id + ".ndjson.gz"}
+	if _, err := h.service.Dump(r.Context(), params, archive); err != nil {
+		// Once the archive started, a failure can only cut it short
+		if archive.started {
+			h.logger.ErrorContext(r.Context(), "failed to export project", "project_id", id, "error", err)
+			return
+		}
+		respondBackupError(w, err)
+	}
+}
+
+// Import godoc
+// @Summary Import a project
+// @Description Imports an archive written by the export into an existing project, or into a new project of the user
+// @Description when projectId is not set. Rows get new ids, importing the same archive again skips what was imported.
+// @Description Ingest keys are not imported, a new project gets its own DSN.
+// @Tags projects
+// @Accept application/gzip
+// @Produce json
+// @Param projectId query string false "Project to import into, a new project is created when empty"
+// @Param name query string false "Name of the new project, the archived name by default"
+// @Param archive body string true "Archive"
+// @Success 200 {object} backup.Report
+// @Failure 400 {object} string "Invalid archive"
+// @Failure 404 {object} string "Project not found"
+// @Failure 409 {object} string "Project is deleted"
+// @Security BearerAuth
+// @Router /v1/projects/import [post].
+func (h *backupHandler) Import(w http.ResponseWriter, r *http.Request) {
+	params := backup.ImportParams{
+		ProjectID: r.URL.Query().Get("projectId"),
+		Name:      r.URL.Query().Get("name"),
+	}
+
+	controller := http.NewResponseController(w)
+	report, err := h.service.Import(r.Context(), &uploadReader{r: r.Body, controller: controller}, params)
+
+	// The server write timeout counts from the start of the request, a long import would lose its answer
+	if deadlineErr := controller.SetWriteDeadline(time.Now().Add(streamTimeout)); deadlineErr != nil {
+		h.logger.WarnContext(r.Context(), "failed to extend write deadline", "error", deadlineErr)
+	}
+	if err != nil {
+		respondBackupError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, report)
+}
+
+func respondBackupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, backup.ErrInvalidArchive):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, backup.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, backup.ErrProjectDeleted):
+		httputils.RespondWithPlainError(w, http.StatusConflict, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/bulk"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type bulkHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  bulk.Service
+}
+
+func RegisterBulkHandlers(
+	r *mux.Router,
+	logger Logger,
+	service bulk.Service,
+	jwtKey []byte,
+) {
+	h := &bulkHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/bulk").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
+}
+
+// Create godoc
+// @Summary Run a bulk operation by filter
+// @Description Queues a job which deletes the errors, logs, error groups or log groups of a project matching the filter, or sets the status of matching error groups. Deleting groups also deletes their events. Filters work like the filters of the lists, times are Unix seconds, and filters which do not apply to the target are rejected. With dryRun the matching rows are only counted. Poll the job for its progress and report.
+// @Tags bulk
+// @Accept json
+// @Produce json
+// @Param request body bulk.Request true "Operation and filter"
+// @Success 200 {object} bulk.DryRunResult "Matching rows of a dry run"
+// @Success 202 {object} jobs.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/bulk [post].
+func (h *bulkHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req bulk.Request
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	if req.DryRun {
+		matched, err := h.service.Count(r.Context(), &req)
+		if err != nil {
+			respondBulkError(w, err)
+			return
+		}
+		httputils.RespondWithJSON(w, http.StatusOK, bulk.DryRunResult{Matched: matched})
+		return
+	}
+
+	job, err := h.service.Request(r.Context(), &req)
+	if err != nil {
+		respondBulkError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusAccepted, job)
+}
+
+func respondBulkError(w http.ResponseWriter, err error) {
+	if errors.Is(err, bulk.ErrInvalidRequest) {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+}
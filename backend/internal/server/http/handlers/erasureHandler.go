@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/erasure"
+	"github.com/duckbugio/duckbug/internal/modules/users"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type erasureHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  erasure.Service
+}
+
+func RegisterErasureHandlers(
+	r *mux.Router,
+	logger Logger,
+	service erasure.Service,
+	jwtKey []byte,
+) {
+	h := &erasureHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/admin/erasure").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.RequireRole(users.RoleAdmin))
+
+	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
+}
+
+// Create godoc
+// @Summary Request erasure of an end user's data
+// @Description Queues a job which erases every error and log of all projects mentioning one of the identifiers in its message, context, session, headers, URL, params, cookies or client IP, and every trace span mentioning one in its name or attributes. Identifiers match as whole tokens, case-insensitively. Poll the job for the audit report, it holds SHA-256 hashes instead of the identifiers. Admins only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body erasure.Request true "Identifiers of the end user"
+// @Success 202 {object} jobs.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 403 {object} string "Forbidden"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/admin/erasure [post].
+func (h *erasureHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req erasure.Request
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	job, err := h.service.Request(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, erasure.ErrNoIdentifiers) {
+			httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusAccepted, job)
+}
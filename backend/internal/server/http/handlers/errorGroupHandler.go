@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/duckbugio/duckbug/internal/middleware"
 	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
 	"github.com/duckbugio/duckbug/pkg/httputils"
 	"github.com/duckbugio/duckbug/pkg/utils"
 	v "github.com/go-playground/validator/v10"
@@ -18,10 +21,11 @@ type errorGroupHandler struct {
 	service  errorsGroup.Service
 }
 
-func RegisterErrorGroupHandlers(
+func RegisterErrorGroupHandlers( //nolint:dupl
 	r *mux.Router,
 	logger Logger,
 	service errorsGroup.Service,
+	searches savedSearch.Service,
 	jwtKey []byte,
 ) {
 	h := &errorGroupHandler{
@@ -31,12 +35,17 @@ func RegisterErrorGroupHandlers(
 	}
 
 	routerV1 := r.PathPrefix("/v1/error-groups").Subrouter()
-	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.Auth(jwtKey), withSavedSearch(searches, savedSearch.TargetErrorGroups))
 
 	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/export", h.Export).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}/status", h.UpdateStatus).Methods(http.MethodPatch)
 	routerV1.HandleFunc("/status:batch", h.BatchUpdateStatus).Methods(http.MethodPost)
+	routerV1.HandleFunc("/{id}/assignee", h.Assign).Methods(http.MethodPut)
+	routerV1.HandleFunc("/{id}/activity", h.GetActivity).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}/comments", h.GetComments).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}/comments", h.CreateComment).Methods(http.MethodPost)
 }
 
 // GetByID godoc
@@ -73,17 +82,71 @@ func (h *errorGroupHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Accept  json
 // @Produce  json
 // @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
 // @Param timeFrom query int false "Time errors from"
 // @Param timeTo query int false "Time errors to"
 // @Param search query string false "Search in message field"
 // @Param status query string false "Filter by status"
+// @Param assignee query string false "Filter by assignee: me, none or a user ID"
 // @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
 // @Param limit query int false "Items per page" default(50)
 // @Param offset query int false "Offset for pagination" default(0)
 // @Success 200 {object} errorsgroup.EntityList "Successfully retrieved list of errors"
 // @Security BearerAuth
 // @Router /v1/error-groups [get].
-func (h *errorGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nolint:dupl
+func (h *errorGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	params, err := h.parseQuery(r)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entities, totalCount, err := h.service.GetAll(r.Context(), params)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+}
+
+// Export godoc
+// @Summary Export error groups
+// @Description Streams all error groups matching the filters of the list as CSV or NDJSON, ordered by last seen
+// @Tags error-groups
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
+// @Param timeFrom query int false "Time errors from"
+// @Param timeTo query int false "Time errors to"
+// @Param search query string false "Search in message field"
+// @Param status query string false "Filter by status"
+// @Param assignee query string false "Filter by assignee: me, none or a user ID"
+// @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
+// @Param format query string false "Export format" default(csv) Enums(csv, ndjson)
+// @Param columns query string false "Comma separated CSV columns"
+// @Success 200 {file} file "Error groups export"
+// @Failure 400 {object} string "Invalid filter, format or columns"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/error-groups/export [get].
+func (h *errorGroupHandler) Export(w http.ResponseWriter, r *http.Request) {
+	params, err := h.parseQuery(r)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	streamExport(w, r, h.logger, "error-groups", errorsGroup.ExportColumns, func(ctx context.Context, write func(entity interface{}) error) error {
+		return h.service.Export(ctx, params, func(entity *errorsGroup.Entity) error {
+			return write(entity)
+		})
+	})
+}
+
+// parseQuery reads the filters of an error groups list, they are the same for an export
+func (h *errorGroupHandler) parseQuery(r *http.Request) (errorsGroup.GetAllParams, error) {
 	queryParams := r.URL.Query()
 
 	limit, err := strconv.Atoi(queryParams.Get("limit"))
@@ -116,26 +179,30 @@ func (h *errorGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) { //n
 	search := queryParams.Get("search")
 	status := queryParams.Get("status")
 
-	params := errorsGroup.GetAllParams{
+	assignee := queryParams.Get("assignee")
+	switch assignee {
+	case "", errorsGroup.AssigneeNone:
+	case "me":
+		assignee, _ = middleware.GetUserID(r.Context())
+	default:
+		if h.validate.Var(assignee, "uuid") != nil {
+			return errorsGroup.GetAllParams{}, errors.New("invalid assignee")
+		}
+	}
+
+	return errorsGroup.GetAllParams{
 		FilterParams: errorsGroup.FilterParams{
 			ProjectID: projectID,
 			TimeFrom:  timeFrom,
 			TimeTo:    timeTo,
 			Search:    search,
 			Status:    status,
+			Assignee:  assignee,
 		},
 		SortOrder: sortOrder,
 		Limit:     limit,
 		Offset:    offset,
-	}
-
-	entities, totalCount, err := h.service.GetAll(r.Context(), params)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+	}, nil
 }
 
 // UpdateStatus godoc
@@ -166,7 +233,7 @@ func (h *errorGroupHandler) UpdateStatus(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.service.UpdateStatus(r.Context(), id, errorsGroup.Status(body.Status)); err != nil {
-		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		respondErrorGroupError(w, err)
 		return
 	}
 
@@ -200,3 +267,148 @@ func (h *errorGroupHandler) BatchUpdateStatus(w http.ResponseWriter, r *http.Req
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Assign godoc
+// @Summary Assign an error group
+// @Description Assigns the group to a user or, with a null assigneeId, unassigns it. The change is put on the activity timeline.
+// @Tags error-groups
+// @Accept json
+// @Produce json
+// @Param id path string true "Error Group ID"
+// @Param request body errorsgroup.AssignRequest true "Assignee"
+// @Success 200 {object} errorsgroup.Entity
+// @Failure 400 {object} string "Invalid input data or unknown user"
+// @Failure 404 {object} string "Error group not found"
+// @Security BearerAuth
+// @Router /v1/error-groups/{id}/assignee [put].
+func (h *errorGroupHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req errorsGroup.AssignRequest
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Assign(r.Context(), id, &req)
+	if err != nil {
+		respondErrorGroupError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// GetActivity godoc
+// @Summary Get the activity of an error group
+// @Description Returns the timeline of the group, oldest first: first seen, regressions, status changes, assignments and comments
+// @Tags error-groups
+// @Produce json
+// @Param id path string true "Error Group ID"
+// @Param limit query int false "Items per page" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} errorsgroup.ActivityList
+// @Failure 404 {object} string "Error group not found"
+// @Security BearerAuth
+// @Router /v1/error-groups/{id}/activity [get].
+func (h *errorGroupHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	queryParams := r.URL.Query()
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = httputils.DefaultLimit
+	}
+
+	offset, err := strconv.Atoi(queryParams.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = httputils.DefaultOffset
+	}
+
+	entities, totalCount, err := h.service.GetActivity(r.Context(), id, limit, offset)
+	if err != nil {
+		respondErrorGroupError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+}
+
+// GetComments godoc
+// @Summary Get the comments of an error group
+// @Description Returns the comment threads of the group, replies are nested in the comment they answer
+// @Tags error-groups
+// @Produce json
+// @Param id path string true "Error Group ID"
+// @Success 200 {object} errorsgroup.CommentList
+// @Failure 404 {object} string "Error group not found"
+// @Security BearerAuth
+// @Router /v1/error-groups/{id}/comments [get].
+func (h *errorGroupHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	entities, totalCount, err := h.service.GetComments(r.Context(), id)
+	if err != nil {
+		respondErrorGroupError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+}
+
+// CreateComment godoc
+// @Summary Comment on an error group
+// @Description Adds a comment or, with parentId, a reply. Users are mentioned as @email.
+// @Tags error-groups
+// @Accept json
+// @Produce json
+// @Param id path string true "Error Group ID"
+// @Param request body errorsgroup.CreateCommentRequest true "Comment"
+// @Success 201 {object} errorsgroup.CommentEntity
+// @Failure 400 {object} string "Invalid input data or unknown parent comment"
+// @Failure 404 {object} string "Error group not found"
+// @Security BearerAuth
+// @Router /v1/error-groups/{id}/comments [post].
+func (h *errorGroupHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req errorsGroup.CreateCommentRequest
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.CreateComment(r.Context(), id, &req)
+	if err != nil {
+		respondErrorGroupError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusCreated, entity)
+}
+
+func respondErrorGroupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errorsGroup.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, errorsGroup.ErrUserNotFound), errors.Is(err, errorsGroup.ErrParentNotFound):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
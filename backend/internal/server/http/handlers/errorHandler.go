@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
+	stdErrors "errors"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/duckbugio/duckbug/internal/middleware"
 	"github.com/duckbugio/duckbug/internal/modules/errors"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
 	"github.com/duckbugio/duckbug/pkg/httputils"
 	"github.com/duckbugio/duckbug/pkg/utils"
 	v "github.com/go-playground/validator/v10"
@@ -16,27 +21,33 @@ type errorHandler struct {
 	logger   Logger
 	validate *v.Validate
 	service  errors.Service
+	ingest   ingest.Service
 }
 
 func RegisterErrorHandlers( //nolint:dupl
 	r *mux.Router,
 	logger Logger,
 	service errors.Service,
+	ingestService ingest.Service,
+	searches savedSearch.Service,
 	jwtKey []byte,
 ) {
 	h := &errorHandler{
 		logger:   logger,
 		validate: v.New(),
 		service:  service,
+		ingest:   ingestService,
 	}
 
 	r.HandleFunc("/ingest/{projectID}:{key}/errors", h.Create).Methods(http.MethodPost)
 
 	routerV1 := r.PathPrefix("/v1/errors").Subrouter()
-	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.Auth(jwtKey), withSavedSearch(searches, savedSearch.TargetErrors))
 
 	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/export", h.Export).Methods(http.MethodGet)
 	routerV1.HandleFunc("/stats", h.GetStats).Methods(http.MethodGet)
+	routerV1.HandleFunc("/histogram", h.GetHistogram).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.Update).Methods(http.MethodPut)
 	routerV1.HandleFunc("/{id}", h.Delete).Methods(http.MethodDelete)
@@ -76,19 +87,90 @@ func (h *errorHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
 // @Param groupId query string false "Group ID"
 // @Param timeFrom query int false "Time errors from"
 // @Param timeTo query int false "Time errors to"
 // @Param search query string false "Search in message field"
+// @Param searchMode query string false "Search mode: substring match or full-text query" default(substring) Enums(substring, fulltext)
+// @Param orderBy query string false "Order by time or by relevance (full-text search and offset pagination only)" default(time) Enums(time, relevance)
 // @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
 // @Param limit query int false "Items per page" default(50)
 // @Param offset query int false "Offset for pagination" default(0)
+// @Param pagination query string false "Set to cursor to use keyset pagination, the response is then a errors.EntityPage" Enums(offset, cursor)
+// @Param cursor query string false "Opaque cursor from nextCursor or prevCursor, implies keyset pagination"
+// @Param count query string false "Total count mode for keyset pagination" default(estimated) Enums(exact, estimated, none)
 // @Success 200 {object} errors.EntityList "Successfully retrieved list of errors"
 // @Security BearerAuth
 // @Router /v1/errors [get].
-func (h *errorHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+func (h *errorHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nolint:dupl
 	queryParams := r.URL.Query()
+	params := parseErrorQuery(queryParams)
 
+	cursorParams, err := parseCursorQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if cursorParams.Enabled {
+		params.Cursor = cursorParams.Cursor
+		params.Offset = 0
+
+		page, err := h.service.GetPage(r.Context(), params, cursorParams.CountMode)
+		if err != nil {
+			httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		httputils.RespondWithJSON(w, http.StatusOK, httputils.NewCursorListResponse(
+			page.Count, page.CountEstimated, page.Items, page.NextCursor, page.PrevCursor,
+		))
+		return
+	}
+
+	entities, totalCount, err := h.service.GetAll(r.Context(), params)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+}
+
+// Export godoc
+// @Summary Export errors
+// @Description Streams all errors matching the filters of the list as CSV or NDJSON, ordered by time
+// @Tags errors
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
+// @Param groupId query string false "Group ID"
+// @Param timeFrom query int false "Time errors from"
+// @Param timeTo query int false "Time errors to"
+// @Param search query string false "Search in message field"
+// @Param searchMode query string false "Search mode: substring match or full-text query" default(substring) Enums(substring, fulltext)
+// @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
+// @Param format query string false "Export format" default(csv) Enums(csv, ndjson)
+// @Param columns query string false "Comma separated CSV columns, nested context fields with dots, e.g. context.user.id"
+// @Success 200 {file} file "Errors export"
+// @Failure 400 {object} string "Invalid format or columns"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/errors/export [get].
+func (h *errorHandler) Export(w http.ResponseWriter, r *http.Request) {
+	params := parseErrorQuery(r.URL.Query())
+
+	streamExport(w, r, h.logger, "errors", errors.ExportColumns, func(ctx context.Context, write func(entity interface{}) error) error {
+		return h.service.Export(ctx, params, func(entity *errors.Entity) error {
+			return write(entity)
+		})
+	})
+}
+
+// parseErrorQuery reads the filters of an errors list, they are the same for an export
+func parseErrorQuery(queryParams url.Values) errors.GetAllParams {
 	limit, err := strconv.Atoi(queryParams.Get("limit"))
 	if err != nil || limit < 1 {
 		limit = httputils.DefaultLimit
@@ -119,26 +201,30 @@ func (h *errorHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 	search := queryParams.Get("search")
 
-	params := errors.GetAllParams{
+	searchMode := queryParams.Get("searchMode")
+	if searchMode != errors.SearchModeFullText {
+		searchMode = errors.SearchModeSubstring
+	}
+
+	orderBy := queryParams.Get("orderBy")
+	if orderBy != errors.OrderByRelevance {
+		orderBy = errors.OrderByTime
+	}
+
+	return errors.GetAllParams{
 		FilterParams: errors.FilterParams{
 			ProjectID:   projectID,
 			Fingerprint: groupID,
 			TimeFrom:    utils.SecondsToMilliseconds(timeFrom),
 			TimeTo:      utils.SecondsToMilliseconds(timeTo),
 			Search:      search,
+			SearchMode:  searchMode,
 		},
 		SortOrder: sortOrder,
+		OrderBy:   orderBy,
 		Limit:     limit,
 		Offset:    offset,
 	}
-
-	entities, totalCount, err := h.service.GetAll(r.Context(), params)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
 }
 
 // GetStats godoc
@@ -167,6 +253,69 @@ func (h *errorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	httputils.RespondWithJSON(w, http.StatusOK, stats)
 }
 
+// GetHistogram godoc
+// @Summary Get errors histogram
+// @Description Retrieves errors counts bucketed by time interval, optionally split by group or release
+// @Tags errors
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param groupId query string false "Group ID"
+// @Param timeFrom query int false "Time errors from (Unix seconds), defaults to 24 hours before timeTo"
+// @Param timeTo query int false "Time errors to (Unix seconds), defaults to now"
+// @Param interval query string false "Bucket size" default(1h) Enums(1m, 5m, 15m, 30m, 1h, 3h, 6h, 12h, 1d)
+// @Param splitBy query string false "Split counts into series" Enums(group, release)
+// @Param seriesLimit query int false "Maximum number of series, the rest is folded into other" default(10)
+// @Success 200 {object} errors.Histogram "Successfully retrieved histogram of errors"
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/errors/histogram [get].
+func (h *errorHandler) GetHistogram(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID := queryParams.Get("projectId")
+	if projectID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId is required")
+		return
+	}
+
+	histogramParams, err := parseHistogramQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := errors.HistogramParams{
+		FilterParams: errors.FilterParams{
+			ProjectID:   projectID,
+			Fingerprint: queryParams.Get("groupId"),
+			TimeFrom:    histogramParams.TimeFrom,
+			TimeTo:      histogramParams.TimeTo,
+		},
+		Interval:    histogramParams.Interval,
+		SplitBy:     histogramParams.SplitBy,
+		SeriesLimit: histogramParams.SeriesLimit,
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		httputils.HandleValidatorError(w, err)
+		return
+	}
+
+	histogram, err := h.service.GetHistogram(r.Context(), params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if stdErrors.Is(err, errors.ErrTooManyBuckets) {
+			status = http.StatusBadRequest
+		}
+		httputils.RespondWithPlainError(w, status, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, histogram)
+}
+
 // Create godoc
 // @Summary Create a new error entry
 // @Description Creates a new error entry in the system
@@ -178,22 +327,24 @@ func (h *errorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 // @Param   request body errors.Create true "Error entry creation data"
 // @Success 201 {object} errors.Entity "Successfully created error entry"
 // @Failure 400 {object} string "Invalid input data"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
 // @Failure 500 {object} string "Internal server error"
 // @Router /ingest/{projectID}:{key}/errors [post].
 func (h *errorHandler) Create(w http.ResponseWriter, r *http.Request) {
-	projectID, err := getProjectIDAndKey(r)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindErrors)
+	if !ok {
 		return
 	}
 
 	var req errors.Create
-	if err := httputils.DecodeRequest(w, r, &req); err != nil {
+	if !decodeIngest(w, r, h.validate, &req) {
 		return
 	}
 
-	if err := h.validate.Struct(req); err != nil {
-		httputils.HandleValidatorError(w, err)
+	if !h.ingest.Keep(projectID, ingest.KindErrors, "") {
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
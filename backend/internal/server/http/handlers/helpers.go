@@ -1,18 +1,257 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/pkg/export"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/duckbugio/duckbug/pkg/pagination"
+	"github.com/duckbugio/duckbug/pkg/utils"
+	v "github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
 )
 
-func getProjectIDAndKey(r *http.Request) (projectID string, err error) {
+var tracer = otel.Tracer("github.com/duckbugio/duckbug/internal/server/http/handlers")
+
+const (
+	defaultHistogramInterval    = "1h"
+	defaultHistogramRange       = 24 * time.Hour
+	defaultHistogramSeriesLimit = 10
+	paginationCursor            = "cursor"
+	orderByRelevance            = "relevance"
+	// streamTimeout bounds each chunk of a streamed download or upload, the server timeouts are far too short
+	// for a large body as a whole
+	streamTimeout = 30 * time.Second
+)
+
+// histogramQuery holds the common histogram query params, times are in milliseconds
+type histogramQuery struct {
+	TimeFrom    int64
+	TimeTo      int64
+	Interval    int64
+	SplitBy     string
+	SeriesLimit int
+}
+
+// cursorQuery holds the keyset pagination query params
+type cursorQuery struct {
+	Enabled   bool
+	Cursor    *pagination.Cursor
+	CountMode string
+}
+
+// admitIngest checks the ingest key and the limits of the project before the body is read,
+// on failure the error response is already written
+func admitIngest(w http.ResponseWriter, r *http.Request, gate ingest.Service, kind string) (string, bool) {
 	vars := mux.Vars(r)
-	projectID = vars["projectID"]
+	projectID := vars["projectID"]
 	key := vars["key"]
 	if projectID == "" || key == "" {
-		return "", fmt.Errorf("invalid ingest")
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "invalid ingest")
+		return "", false
+	}
+
+	err := gate.Admit(r.Context(), projectID, key, kind, r.Header.Get("Origin"))
+	if err == nil {
+		return projectID, true
+	}
+
+	var limitErr *ingest.LimitError
+	switch {
+	case errors.Is(err, ingest.ErrInvalidKey):
+		httputils.RespondWithPlainError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, ingest.ErrKeyScope), errors.Is(err, ingest.ErrOriginNotAllowed):
+		httputils.RespondWithPlainError(w, http.StatusForbidden, err.Error())
+	case errors.As(err, &limitErr):
+		retryAfter := max(1, int(math.Ceil(limitErr.RetryAfter.Seconds())))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		httputils.RespondWithPlainError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+	return "", false
+}
+
+// decodeAndValidate reads the JSON body into req and validates it, on failure the error response is already written
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, validate *v.Validate, req interface{}) bool {
+	if err := httputils.DecodeRequest(w, r, req); err != nil {
+		return false
+	}
+
+	if err := validate.Struct(req); err != nil {
+		httputils.HandleValidatorError(w, err)
+		return false
+	}
+
+	return true
+}
+
+// decodeIngest is decodeAndValidate of an event in its own span, large payloads make decoding a notable part of ingest
+func decodeIngest(w http.ResponseWriter, r *http.Request, validate *v.Validate, req interface{}) bool {
+	_, span := tracer.Start(r.Context(), "ingest.decode")
+	defer span.End()
+
+	return decodeAndValidate(w, r, validate, req)
+}
+
+func parseHistogramQuery(queryParams url.Values) (histogramQuery, error) {
+	intervalParam := queryParams.Get("interval")
+	if intervalParam == "" {
+		intervalParam = defaultHistogramInterval
+	}
+
+	interval, err := utils.ParseIntervalParam(intervalParam)
+	if err != nil {
+		return histogramQuery{}, err
+	}
+
+	timeTo, err := utils.ParseTimeParam(queryParams.Get("timeTo"))
+	if err != nil || timeTo == 0 {
+		timeTo = time.Now().Unix()
+	}
+
+	timeFrom, err := utils.ParseTimeParam(queryParams.Get("timeFrom"))
+	if err != nil || timeFrom == 0 {
+		timeFrom = timeTo - int64(defaultHistogramRange.Seconds())
+	}
+
+	if timeFrom > timeTo {
+		return histogramQuery{}, fmt.Errorf("timeFrom must not be after timeTo")
+	}
+
+	seriesLimit, err := strconv.Atoi(queryParams.Get("seriesLimit"))
+	if err != nil || seriesLimit < 1 {
+		seriesLimit = defaultHistogramSeriesLimit
+	}
+
+	return histogramQuery{
+		TimeFrom:    utils.SecondsToMilliseconds(timeFrom),
+		TimeTo:      utils.SecondsToMilliseconds(timeTo),
+		Interval:    interval,
+		SplitBy:     queryParams.Get("splitBy"),
+		SeriesLimit: seriesLimit,
+	}, nil
+}
+
+// parseCursorQuery switches a list to keyset pagination when a cursor is passed or pagination=cursor is set.
+// The total is estimated unless asked otherwise, an exact count would scan what the cursor avoids.
+func parseCursorQuery(queryParams url.Values) (cursorQuery, error) {
+	cursorParam := queryParams.Get("cursor")
+	if cursorParam == "" && queryParams.Get("pagination") != paginationCursor {
+		return cursorQuery{}, nil
+	}
+
+	// Cursors are positions in (time, id) order, ranks have no stable position to continue from
+	if queryParams.Get("orderBy") == orderByRelevance {
+		return cursorQuery{}, fmt.Errorf("orderBy=relevance is not supported with cursor pagination")
+	}
+
+	countMode := queryParams.Get("count")
+	if countMode == "" {
+		countMode = pagination.CountEstimated
+	}
+	if !pagination.IsValidCountMode(countMode) {
+		return cursorQuery{}, fmt.Errorf("invalid count mode: %s", countMode)
+	}
+
+	query := cursorQuery{
+		Enabled:   true,
+		CountMode: countMode,
+	}
+
+	if cursorParam != "" {
+		cursor, err := pagination.Decode(cursorParam)
+		if err != nil {
+			return cursorQuery{}, err
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+// downloadWriter sends the download headers with the first bytes, so errors before can still be answered.
+// With a controller every write gets streamTimeout of its own instead of the server timeout of the request.
+type downloadWriter struct {
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if d.controller != nil {
+		if err := d.controller.SetWriteDeadline(time.Now().Add(streamTimeout)); err != nil {
+			return 0, fmt.Errorf("failed to extend write deadline: %w", err)
+		}
+	}
+	if !d.started {
+		d.started = true
+		d.w.Header().Set("Content-Type", d.contentType)
+		d.w.Header().Set("Content-Disposition", `attachment; filename="`+d.filename+`"`)
+		d.w.WriteHeader(http.StatusOK)
+	}
+	return d.w.Write(p)
+}
+
+// uploadReader gives every read of a request body streamTimeout of its own, so uploads are not limited
+// by the server timeout of the request as a whole
+type uploadReader struct {
+	r          io.Reader
+	controller *http.ResponseController
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if err := u.controller.SetReadDeadline(time.Now().Add(streamTimeout)); err != nil {
+		return 0, fmt.Errorf("failed to extend read deadline: %w", err)
+	}
+	return u.r.Read(p)
+}
+
+// streamExport writes the entities passed by scan to write as CSV or NDJSON while they are read,
+// format and columns are query params. A failure after the first row can only cut the download short.
+func streamExport(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger Logger,
+	name string,
+	defaultColumns []string,
+	scan func(ctx context.Context, write func(entity interface{}) error) error,
+) {
+	queryParams := r.URL.Query()
+	format := queryParams.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	download := &downloadWriter{w: w, controller: http.NewResponseController(w)}
+	writer, err := export.NewWriter(download, format, export.ParseColumns(queryParams.Get("columns"), defaultColumns))
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	download.contentType = writer.ContentType()
+	download.filename = name + "." + writer.Extension()
+
+	if err = scan(r.Context(), writer.Write); err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		if download.started {
+			logger.ErrorContext(r.Context(), "failed to export "+name, "error", err)
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
 	}
-	return projectID, nil
 }
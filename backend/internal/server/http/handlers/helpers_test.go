@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/duckbugio/duckbug/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamExportOutlivesWriteTimeout(t *testing.T) {
+	const (
+		rows         = 5
+		writeTimeout = 100 * time.Millisecond
+	)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamExport(w, r, logger.New("error", &bytes.Buffer{}), "rows", []string{"id"},
+			func(_ context.Context, write func(entity interface{}) error) error {
+				for i := range rows {
+					time.Sleep(writeTimeout / 2)
+					if err := write(map[string]int{"id": i}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+	}))
+	server.Config.WriteTimeout = writeTimeout
+	server.Start()
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "?format=ndjson")
+	require.NoError(t, err)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	// The stream takes longer than the write timeout and still has to arrive complete
+	var lines int
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, rows, lines)
+}
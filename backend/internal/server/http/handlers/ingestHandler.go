@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultIngestStatsDays = 30
+	maxIngestStatsDays     = 366
+)
+
+type ingestHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  ingest.Service
+}
+
+func RegisterIngestHandlers(
+	r *mux.Router,
+	logger Logger,
+	service ingest.Service,
+	jwtKey []byte,
+) {
+	h := &ingestHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/projects/{id}/ingest").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("/limits", h.GetLimits).Methods(http.MethodGet)
+	routerV1.HandleFunc("/limits", h.UpdateLimits).Methods(http.MethodPut)
+	routerV1.HandleFunc("/stats", h.GetStats).Methods(http.MethodGet)
+	routerV1.HandleFunc("/origins", h.GetAllowedOrigins).Methods(http.MethodGet)
+	routerV1.HandleFunc("/origins", h.UpdateAllowedOrigins).Methods(http.MethodPut)
+}
+
+// GetLimits godoc
+// @Summary Get project ingest limits
+// @Description Get the rate limits, quotas and log sample rates of a project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} ingest.LimitsEntity
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/ingest/limits [get].
+func (h *ingestHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	limits, err := h.service.GetLimits(r.Context(), id)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, limits)
+}
+
+// UpdateLimits godoc
+// @Summary Update project ingest limits
+// @Description Replaces the ingest limits of a project. Events over a rate limit or a quota are rejected with 429 and a Retry-After header. Rate limits apply per instance.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body ingest.LimitsEntity true "Ingest limits"
+// @Success 200 {object} ingest.LimitsEntity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/ingest/limits [put].
+func (h *ingestHandler) UpdateLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req ingest.LimitsEntity
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	limits, err := h.service.UpdateLimits(r.Context(), id, &req)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, limits)
+}
+
+// GetStats godoc
+// @Summary Get project ingest stats
+// @Description Daily counts of accepted, rate limited, over quota and sampled out events. Counters are written every few seconds.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param days query int false "Number of days including today" default(30)
+// @Success 200 {array} ingest.StatEntity
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/ingest/stats [get].
+func (h *ingestHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = defaultIngestStatsDays
+	}
+	days = min(days, maxIngestStatsDays)
+
+	stats, err := h.service.GetStats(r.Context(), id, days)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, stats)
+}
+
+// GetAllowedOrigins godoc
+// @Summary Get project allowed origins
+// @Description Get the browser origins allowed to send events to the ingest endpoints of a project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} ingest.AllowedOrigins
+// @Failure 404 {object} string "Project not found"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/ingest/origins [get].
+func (h *ingestHandler) GetAllowedOrigins(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	origins, err := h.service.GetAllowedOrigins(r.Context(), id)
+	if err != nil {
+		respondOriginsError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, origins)
+}
+
+// UpdateAllowedOrigins godoc
+// @Summary Update project allowed origins
+// @Description Replaces the browser origins allowed to send events to any key of a project, e.g. https://app.example.com. * allows every origin. Keys with own allowed origins only accept those.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body ingest.AllowedOrigins true "Allowed origins"
+// @Success 200 {object} ingest.AllowedOrigins
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 404 {object} string "Project not found"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/ingest/origins [put].
+func (h *ingestHandler) UpdateAllowedOrigins(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req ingest.AllowedOrigins
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	origins, err := h.service.UpdateAllowedOrigins(r.Context(), id, &req)
+	if err != nil {
+		respondOriginsError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, origins)
+}
+
+func respondOriginsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ingest.ErrInvalidOrigin):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ingest.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/jobs"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/gorilla/mux"
+)
+
+type jobHandler struct {
+	logger  Logger
+	service jobs.Service
+}
+
+func RegisterJobHandlers(
+	r *mux.Router,
+	logger Logger,
+	service jobs.Service,
+	jwtKey []byte,
+) {
+	h := &jobHandler{
+		logger:  logger,
+		service: service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/jobs").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
+}
+
+// GetAll godoc
+// @Summary Get background jobs
+// @Description Lists jobs started by the current user, admins see every job. Newest first.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param limit query int false "Items per page" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} jobs.EntityList
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/jobs [get].
+func (h *jobHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = httputils.DefaultLimit
+	}
+
+	offset, err := strconv.Atoi(queryParams.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = httputils.DefaultOffset
+	}
+
+	items, totalCount, err := h.service.GetAll(r.Context(), jobs.GetAllParams{Limit: limit, Offset: offset})
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, items))
+}
+
+// GetByID godoc
+// @Summary Get a background job
+// @Description Get the status, the progress and the result of a job
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobs.Entity
+// @Failure 404 {object} string "Not found"
+// @Security BearerAuth
+// @Router /v1/jobs/{id} [get].
+func (h *jobHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	entity, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
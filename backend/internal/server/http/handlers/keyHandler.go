@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/keys"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type keyHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  keys.Service
+}
+
+func RegisterKeyHandlers(
+	r *mux.Router,
+	logger Logger,
+	service keys.Service,
+	jwtKey []byte,
+) {
+	h := &keyHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/projects/{id}/keys").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
+	routerV1.HandleFunc("/{keyID}", h.Update).Methods(http.MethodPut)
+	routerV1.HandleFunc("/{keyID}", h.Revoke).Methods(http.MethodDelete)
+	routerV1.HandleFunc("/{keyID}/rotate", h.Rotate).Methods(http.MethodPost)
+}
+
+// GetAll godoc
+// @Summary Get project ingest keys
+// @Description Lists all ingest keys of a project, including rotated and revoked ones
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} keys.Entity
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/keys [get].
+func (h *keyHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	entities, err := h.service.GetAll(r.Context(), id)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entities)
+}
+
+// Create godoc
+// @Summary Create a project ingest key
+// @Description Creates an additional named ingest key, optionally limited to logs or errors and to browser origins
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body keys.Create true "Key settings"
+// @Success 201 {object} keys.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/keys [post].
+func (h *keyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req keys.Create
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Create(r.Context(), id, &req)
+	if err != nil {
+		respondKeyError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusCreated, entity)
+}
+
+// Update godoc
+// @Summary Update a project ingest key
+// @Description Renames a key or changes its scope and allowed origins
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param keyID path string true "Key ID"
+// @Param request body keys.Update true "Key settings"
+// @Success 200 {object} keys.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 404 {object} string "Key not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/keys/{keyID} [put].
+func (h *keyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, keyID := vars["id"], vars["keyID"]
+	if id == "" || keyID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id and keyID are required")
+		return
+	}
+
+	var req keys.Update
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Update(r.Context(), id, keyID, &req)
+	if err != nil {
+		respondKeyError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// Rotate godoc
+// @Summary Rotate a project ingest key
+// @Description Issues a new key with the same settings. The old key keeps working for the overlap, so clients can be redeployed without losing events.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param keyID path string true "Key ID"
+// @Param request body keys.Rotate true "Rotation overlap"
+// @Success 201 {object} keys.Entity "The new key"
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 404 {object} string "Key not found"
+// @Failure 409 {object} string "Key is revoked or expired"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/keys/{keyID}/rotate [post].
+func (h *keyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, keyID := vars["id"], vars["keyID"]
+	if id == "" || keyID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id and keyID are required")
+		return
+	}
+
+	var req keys.Rotate
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Rotate(r.Context(), id, keyID, &req)
+	if err != nil {
+		respondKeyError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusCreated, entity)
+}
+
+// Revoke godoc
+// @Summary Revoke a project ingest key
+// @Description Revokes a key, events sent with it are rejected at once
+// @Tags projects
+// @Param id path string true "Project ID"
+// @Param keyID path string true "Key ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} string "Key not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/keys/{keyID} [delete].
+func (h *keyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, keyID := vars["id"], vars["keyID"]
+	if id == "" || keyID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id and keyID are required")
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id, keyID); err != nil {
+		respondKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func respondKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, keys.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, keys.ErrInactive):
+		httputils.RespondWithPlainError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, keys.ErrInvalidOrigin):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/duckbugio/duckbug/internal/middleware"
 	logGroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
 	"github.com/duckbugio/duckbug/pkg/httputils"
 	"github.com/duckbugio/duckbug/pkg/utils"
 	v "github.com/go-playground/validator/v10"
@@ -22,6 +25,7 @@ func RegisterLogGroupHandlers(
 	r *mux.Router,
 	logger Logger,
 	service logGroup.Service,
+	searches savedSearch.Service,
 	jwtKey []byte,
 ) {
 	h := &logGroupHandler{
@@ -31,9 +35,10 @@ func RegisterLogGroupHandlers(
 	}
 
 	routerV1 := r.PathPrefix("/v1/log-groups").Subrouter()
-	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.Auth(jwtKey), withSavedSearch(searches, savedSearch.TargetLogGroups))
 
 	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/export", h.Export).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
 }
 
@@ -71,6 +76,7 @@ func (h *logGroupHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Accept  json
 // @Produce  json
 // @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
 // @Param timeFrom query int false "Time logs from"
 // @Param timeTo query int false "Time logs to"
 // @Param level query string false "Filter by log level" Enums(DEBUG, INFO, WARN, ERROR)
@@ -81,9 +87,51 @@ func (h *logGroupHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} loggroup.EntityList "Successfully retrieved list of logs"
 // @Security BearerAuth
 // @Router /v1/log-groups [get].
-func (h *logGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nolint:dupl
+func (h *logGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
+	params := parseLogGroupQuery(queryParams)
 
+	entities, totalCount, err := h.service.GetAll(r.Context(), params)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
+}
+
+// Export godoc
+// @Summary Export log groups
+// @Description Streams all log groups matching the filters of the list as CSV or NDJSON, ordered by last seen
+// @Tags log-groups
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
+// @Param timeFrom query int false "Time logs from"
+// @Param timeTo query int false "Time logs to"
+// @Param level query string false "Filter by log level" Enums(DEBUG, INFO, WARN, ERROR)
+// @Param search query string false "Search in message field"
+// @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
+// @Param format query string false "Export format" default(csv) Enums(csv, ndjson)
+// @Param columns query string false "Comma separated CSV columns"
+// @Success 200 {file} file "Log groups export"
+// @Failure 400 {object} string "Invalid format or columns"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/log-groups/export [get].
+func (h *logGroupHandler) Export(w http.ResponseWriter, r *http.Request) {
+	params := parseLogGroupQuery(r.URL.Query())
+
+	streamExport(w, r, h.logger, "log-groups", logGroup.ExportColumns, func(ctx context.Context, write func(entity interface{}) error) error {
+		return h.service.Export(ctx, params, func(entity *logGroup.Entity) error {
+			return write(entity)
+		})
+	})
+}
+
+// parseLogGroupQuery reads the filters of a log groups list, they are the same for an export
+func parseLogGroupQuery(queryParams url.Values) logGroup.GetAllParams {
 	limit, err := strconv.Atoi(queryParams.Get("limit"))
 	if err != nil || limit < 1 {
 		limit = httputils.DefaultLimit
@@ -114,7 +162,7 @@ func (h *logGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nol
 	level := queryParams.Get("level")
 	search := queryParams.Get("search")
 
-	params := logGroup.GetAllParams{
+	return logGroup.GetAllParams{
 		FilterParams: logGroup.FilterParams{
 			ProjectID: projectID,
 			TimeFrom:  timeFrom,
@@ -126,12 +174,4 @@ func (h *logGroupHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nol
 		Limit:     limit,
 		Offset:    offset,
 	}
-
-	entities, totalCount, err := h.service.GetAll(r.Context(), params)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, entities))
 }
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
+	stdErrors "errors"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
 	"github.com/duckbugio/duckbug/internal/modules/log"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
 	"github.com/duckbugio/duckbug/pkg/httputils"
 	"github.com/duckbugio/duckbug/pkg/utils"
 	v "github.com/go-playground/validator/v10"
@@ -16,27 +21,33 @@ type logHandler struct {
 	logger   Logger
 	validate *v.Validate
 	service  log.Service
+	ingest   ingest.Service
 }
 
 func RegisterLogHandlers( //nolint:dupl
 	r *mux.Router,
 	logger Logger,
 	service log.Service,
+	ingestService ingest.Service,
+	searches savedSearch.Service,
 	jwtKey []byte,
 ) {
 	h := &logHandler{
 		logger:   logger,
 		validate: v.New(),
 		service:  service,
+		ingest:   ingestService,
 	}
 
 	r.HandleFunc("/ingest/{projectID}:{key}/logs", h.Create).Methods(http.MethodPost)
 
 	routerV1 := r.PathPrefix("/v1/logs").Subrouter()
-	routerV1.Use(middleware.Auth(jwtKey))
+	routerV1.Use(middleware.Auth(jwtKey), withSavedSearch(searches, savedSearch.TargetLogs))
 
 	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/export", h.Export).Methods(http.MethodGet)
 	routerV1.HandleFunc("/stats", h.GetStats).Methods(http.MethodGet)
+	routerV1.HandleFunc("/histogram", h.GetHistogram).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}", h.Update).Methods(http.MethodPut)
 	routerV1.HandleFunc("/{id}", h.Delete).Methods(http.MethodDelete)
@@ -76,20 +87,92 @@ func (h *logHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 // @Accept  json
 // @Produce  json
 // @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
 // @Param groupId query string false "Group ID"
 // @Param timeFrom query int false "Time logs from"
 // @Param timeTo query int false "Time logs to"
 // @Param level query string false "Filter by log level" Enums(DEBUG, INFO, WARN, ERROR)
 // @Param search query string false "Search in message field"
+// @Param searchMode query string false "Search mode: substring match or full-text query" default(substring) Enums(substring, fulltext)
+// @Param orderBy query string false "Order by time or by relevance (full-text search and offset pagination only)" default(time) Enums(time, relevance)
 // @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
 // @Param limit query int false "Items per page" default(50)
 // @Param offset query int false "Offset for pagination" default(0)
+// @Param pagination query string false "Set to cursor to use keyset pagination, the response is then a log.EntityPage" Enums(offset, cursor)
+// @Param cursor query string false "Opaque cursor from nextCursor or prevCursor, implies keyset pagination"
+// @Param count query string false "Total count mode for keyset pagination" default(estimated) Enums(exact, estimated, none)
 // @Success 200 {object} log.EntityList "Successfully retrieved list of logs"
 // @Security BearerAuth
 // @Router /v1/logs [get].
-func (h *logHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+func (h *logHandler) GetAll(w http.ResponseWriter, r *http.Request) { //nolint:dupl
 	queryParams := r.URL.Query()
+	params := parseLogQuery(queryParams)
 
+	cursorParams, err := parseCursorQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if cursorParams.Enabled {
+		params.Cursor = cursorParams.Cursor
+		params.Offset = 0
+
+		page, err := h.service.GetPage(r.Context(), params, cursorParams.CountMode)
+		if err != nil {
+			httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		httputils.RespondWithJSON(w, http.StatusOK, httputils.NewCursorListResponse(
+			page.Count, page.CountEstimated, page.Items, page.NextCursor, page.PrevCursor,
+		))
+		return
+	}
+
+	logs, totalCount, err := h.service.GetAll(r.Context(), params)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, logs))
+}
+
+// Export godoc
+// @Summary Export logs
+// @Description Streams all logs matching the filters of the list as CSV or NDJSON, ordered by time
+// @Tags logs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param projectId query string false "Project ID"
+// @Param savedSearch query string false "Saved search ID, params of the request override its filter"
+// @Param groupId query string false "Group ID"
+// @Param timeFrom query int false "Time logs from"
+// @Param timeTo query int false "Time logs to"
+// @Param level query string false "Filter by log level" Enums(DEBUG, INFO, WARN, ERROR)
+// @Param search query string false "Search in message field"
+// @Param searchMode query string false "Search mode: substring match or full-text query" default(substring) Enums(substring, fulltext)
+// @Param sort query string false "Sort order (asc or desc)" default(desc) Enums(asc, desc)
+// @Param format query string false "Export format" default(csv) Enums(csv, ndjson)
+// @Param columns query string false "Comma separated CSV columns, nested context fields with dots, e.g. context.user.id"
+// @Success 200 {file} file "Logs export"
+// @Failure 400 {object} string "Invalid format or columns"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/logs/export [get].
+func (h *logHandler) Export(w http.ResponseWriter, r *http.Request) {
+	params := parseLogQuery(r.URL.Query())
+
+	streamExport(w, r, h.logger, "logs", log.ExportColumns, func(ctx context.Context, write func(entity interface{}) error) error {
+		return h.service.Export(ctx, params, func(entity *log.Entity) error {
+			return write(entity)
+		})
+	})
+}
+
+// parseLogQuery reads the filters of a logs list, they are the same for an export
+func parseLogQuery(queryParams url.Values) log.GetAllParams {
 	limit, err := strconv.Atoi(queryParams.Get("limit"))
 	if err != nil || limit < 1 {
 		limit = httputils.DefaultLimit
@@ -121,7 +204,17 @@ func (h *logHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	level := queryParams.Get("level")
 	search := queryParams.Get("search")
 
-	params := log.GetAllParams{
+	searchMode := queryParams.Get("searchMode")
+	if searchMode != log.SearchModeFullText {
+		searchMode = log.SearchModeSubstring
+	}
+
+	orderBy := queryParams.Get("orderBy")
+	if orderBy != log.OrderByRelevance {
+		orderBy = log.OrderByTime
+	}
+
+	return log.GetAllParams{
 		FilterParams: log.FilterParams{
 			ProjectID:   projectID,
 			Fingerprint: groupID,
@@ -129,19 +222,13 @@ func (h *logHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 			TimeTo:      timeTo,
 			Level:       level,
 			Search:      search,
+			SearchMode:  searchMode,
 		},
 		SortOrder: sortOrder,
+		OrderBy:   orderBy,
 		Limit:     limit,
 		Offset:    offset,
 	}
-
-	logs, totalCount, err := h.service.GetAll(r.Context(), params)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, logs))
 }
 
 // GetStats godoc
@@ -170,6 +257,71 @@ func (h *logHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	httputils.RespondWithJSON(w, http.StatusOK, stats)
 }
 
+// GetHistogram godoc
+// @Summary Get logs histogram
+// @Description Retrieves logs counts bucketed by time interval, optionally split by level, group or release
+// @Tags logs
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param groupId query string false "Group ID"
+// @Param level query string false "Filter by log level" Enums(DEBUG, INFO, WARN, ERROR)
+// @Param timeFrom query int false "Time logs from (Unix seconds), defaults to 24 hours before timeTo"
+// @Param timeTo query int false "Time logs to (Unix seconds), defaults to now"
+// @Param interval query string false "Bucket size" default(1h) Enums(1m, 5m, 15m, 30m, 1h, 3h, 6h, 12h, 1d)
+// @Param splitBy query string false "Split counts into series" Enums(level, group, release)
+// @Param seriesLimit query int false "Maximum number of series, the rest is folded into other" default(10)
+// @Success 200 {object} log.Histogram "Successfully retrieved histogram of logs"
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/logs/histogram [get].
+func (h *logHandler) GetHistogram(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID := queryParams.Get("projectId")
+	if projectID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId is required")
+		return
+	}
+
+	histogramParams, err := parseHistogramQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := log.HistogramParams{
+		FilterParams: log.FilterParams{
+			ProjectID:   projectID,
+			Fingerprint: queryParams.Get("groupId"),
+			TimeFrom:    histogramParams.TimeFrom,
+			TimeTo:      histogramParams.TimeTo,
+			Level:       queryParams.Get("level"),
+		},
+		Interval:    histogramParams.Interval,
+		SplitBy:     histogramParams.SplitBy,
+		SeriesLimit: histogramParams.SeriesLimit,
+	}
+
+	if err := h.validate.Struct(params); err != nil {
+		httputils.HandleValidatorError(w, err)
+		return
+	}
+
+	histogram, err := h.service.GetHistogram(r.Context(), params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if stdErrors.Is(err, log.ErrTooManyBuckets) {
+			status = http.StatusBadRequest
+		}
+		httputils.RespondWithPlainError(w, status, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, histogram)
+}
+
 // Create godoc
 // @Summary Create a new log entry
 // @Description Creates a new log entry in the system
@@ -180,23 +332,26 @@ func (h *logHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 // @Param        key         path      string  true  "Public key"
 // @Param   request body log.Create true "Log entry creation data"
 // @Success 201 {object} log.Entity "Successfully created log entry"
+// @Success 202 "Sampled out by the log sample rate of the level, not stored"
 // @Failure 400 {object} string "Invalid input data"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
 // @Failure 500 {object} string "Internal server error"
 // @Router /ingest/{projectID}:{key}/logs [post].
 func (h *logHandler) Create(w http.ResponseWriter, r *http.Request) {
-	projectID, err := getProjectIDAndKey(r)
-	if err != nil {
-		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindLogs)
+	if !ok {
 		return
 	}
 
 	var req log.Create
-	if err := httputils.DecodeRequest(w, r, &req); err != nil {
+	if !decodeIngest(w, r, h.validate, &req) {
 		return
 	}
 
-	if err := h.validate.Struct(req); err != nil {
-		httputils.HandleValidatorError(w, err)
+	if !h.ingest.Keep(projectID, ingest.KindLogs, req.Level) {
+		w.WriteHeader(http.StatusAccepted)
 		return
 	}
 
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/internal/modules/monitors"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type monitorHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  monitors.Service
+	ingest   ingest.Service
+}
+
+func RegisterMonitorHandlers(
+	r *mux.Router,
+	logger Logger,
+	service monitors.Service,
+	ingestService ingest.Service,
+	jwtKey []byte,
+) {
+	h := &monitorHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+		ingest:   ingestService,
+	}
+
+	r.HandleFunc("/ingest/{projectID}:{key}/checkins/{slug}", h.Checkin).Methods(http.MethodPost)
+
+	routerV1 := r.PathPrefix("/v1/projects/{id}/monitors").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
+	routerV1.HandleFunc("/{monitorID}", h.GetByID).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{monitorID}", h.Update).Methods(http.MethodPut)
+	routerV1.HandleFunc("/{monitorID}", h.Delete).Methods(http.MethodDelete)
+	routerV1.HandleFunc("/{monitorID}/checkins", h.GetCheckins).Methods(http.MethodGet)
+}
+
+// Checkin godoc
+// @Summary Check in a monitored job
+// @Description A job sends in_progress when it starts and ok or error when it is done, a job sending only ok or error is a heartbeat. A failed run is recorded as an error of the project. Keys of the errors scope may send check-ins.
+// @Tags ingest
+// @Accept  json
+// @Produce json
+// @Param        projectID   path      string  true  "Project ID"
+// @Param        key         path      string  true  "Public key"
+// @Param        slug        path      string  true  "Monitor slug"
+// @Param   request body monitors.CheckinRequest true "Check-in"
+// @Success 202 {object} monitors.CheckinEntity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 404 {object} string "Unknown monitor or no such check-in in progress"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
+// @Failure 500 {object} string "Internal server error"
+// @Router /ingest/{projectID}:{key}/checkins/{slug} [post].
+func (h *monitorHandler) Checkin(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindCheckins)
+	if !ok {
+		return
+	}
+
+	var req monitors.CheckinRequest
+	if !decodeIngest(w, r, h.validate, &req) {
+		return
+	}
+
+	if !h.ingest.Keep(projectID, ingest.KindCheckins, "") {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	entity, err := h.service.Checkin(r.Context(), projectID, mux.Vars(r)["slug"], &req)
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusAccepted, entity)
+}
+
+// GetAll godoc
+// @Summary Get monitors
+// @Description Lists the cron and heartbeat monitors of a project with the status of their last check-in
+// @Tags monitors
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} monitors.Entity
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors [get].
+func (h *monitorHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	entities, err := h.service.GetAll(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entities)
+}
+
+// GetByID godoc
+// @Summary Get a monitor
+// @Tags monitors
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param monitorID path string true "Monitor ID"
+// @Success 200 {object} monitors.Entity
+// @Failure 404 {object} string "Monitor not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors/{monitorID} [get].
+func (h *monitorHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	entity, err := h.service.GetByID(r.Context(), vars["id"], vars["monitorID"])
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// Create godoc
+// @Summary Create a monitor
+// @Description Creates a monitor expecting check-ins on a cron schedule or an interval. A run nothing checked in for within the grace period is missed, a run in progress for longer than the max runtime times out, both are recorded as errors of the project.
+// @Tags monitors
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body monitors.Create true "Monitor"
+// @Success 201 {object} monitors.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 409 {object} string "Slug is taken"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors [post].
+func (h *monitorHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req monitors.Create
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Create(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusCreated, entity)
+}
+
+// Update godoc
+// @Summary Update a monitor
+// @Description Changes the settings of a monitor, a new schedule or a resumed monitor is due from now on
+// @Tags monitors
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param monitorID path string true "Monitor ID"
+// @Param request body monitors.Update true "Monitor"
+// @Success 200 {object} monitors.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 404 {object} string "Monitor not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors/{monitorID} [put].
+func (h *monitorHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req monitors.Update
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Update(r.Context(), vars["id"], vars["monitorID"], &req)
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// Delete godoc
+// @Summary Delete a monitor
+// @Description Deletes a monitor and its check-ins, errors recorded for it stay
+// @Tags monitors
+// @Param id path string true "Project ID"
+// @Param monitorID path string true "Monitor ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} string "Monitor not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors/{monitorID} [delete].
+func (h *monitorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.service.Delete(r.Context(), vars["id"], vars["monitorID"]); err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCheckins godoc
+// @Summary Get check-ins of a monitor
+// @Description Returns the check-in history of a monitor including missed and timed out runs, latest first
+// @Tags monitors
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param monitorID path string true "Monitor ID"
+// @Param limit query int false "Items per page" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} monitors.CheckinList
+// @Failure 404 {object} string "Monitor not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/monitors/{monitorID}/checkins [get].
+func (h *monitorHandler) GetCheckins(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	queryParams := r.URL.Query()
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = httputils.DefaultLimit
+	}
+
+	offset, err := strconv.Atoi(queryParams.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = httputils.DefaultOffset
+	}
+
+	items, totalCount, err := h.service.GetCheckins(r.Context(), vars["id"], vars["monitorID"], limit, offset)
+	if err != nil {
+		respondMonitorError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(totalCount, items))
+}
+
+func respondMonitorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, monitors.ErrNotFound), errors.Is(err, monitors.ErrCheckinNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitors.ErrSlugTaken):
+		httputils.RespondWithPlainError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, monitors.ErrInvalidSlug), errors.Is(err, monitors.ErrInvalidSchedule):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,7 +18,7 @@ type projectHandler struct {
 	service  project.Service
 }
 
-func RegisterProjectHandlers(
+func RegisterProjectHandlers( //nolint:dupl
 	r *mux.Router,
 	logger Logger,
 	service project.Service,
@@ -34,8 +35,12 @@ func RegisterProjectHandlers(
 
 	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
 	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("/deleted", h.GetDeleted).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}/restore", h.Restore).Methods(http.MethodPost)
 	routerV1.HandleFunc("/{id}", h.GetByID).Methods(http.MethodGet)
 	routerV1.HandleFunc("/{id}/dsn", h.GetDSNByID).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}/retention", h.GetRetention).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{id}/retention", h.UpdateRetention).Methods(http.MethodPut)
 	routerV1.HandleFunc("/{id}", h.Update).Methods(http.MethodPut)
 	routerV1.HandleFunc("/{id}", h.Delete).Methods(http.MethodDelete)
 }
@@ -69,11 +74,12 @@ func (h *projectHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 // GetDSNByID godoc
 // @Summary Get a project DSN
-// @Description Get a project DSN
+// @Description Get a project DSN built from its oldest active key with scope all
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Success 200 {object} string
+// @Failure 404 {object} string "Project not found or without an active key with scope all"
 // @Param id path string true "Project ID"
 // @Security BearerAuth
 // @Router /v1/projects/{id}/dsn [get].
@@ -219,15 +225,85 @@ func (h *projectHandler) Update(w http.ResponseWriter, r *http.Request) {
 	httputils.RespondWithJSON(w, http.StatusOK, entity)
 }
 
+// GetRetention godoc
+// @Summary Get project retention
+// @Description Get how many days of logs, errors and groups a project keeps
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} project.Retention
+// @Failure 404 {object} string "Project not found"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/retention [get].
+func (h *projectHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	retention, err := h.service.GetRetention(r.Context(), id)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, retention)
+}
+
+// UpdateRetention godoc
+// @Summary Update project retention
+// @Description Sets how many days of data a project keeps, null keeps data forever. Older data is purged in the background.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body project.Retention true "Retention in days"
+// @Success 200 {object} project.Retention
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 404 {object} string "Project not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/retention [put].
+func (h *projectHandler) UpdateRetention(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req project.Retention
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	retention, err := h.service.UpdateRetention(r.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, project.ErrNotFound) {
+			httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, retention)
+}
+
 // Delete godoc
 // @Summary Delete a project entry
-// @Description Delete a project entry by its ID
+// @Description Deletes a project entry by its ID. The project stops accepting events and can be restored
+// @Description until the restore window ends, then all of its data is purged.
 // @Tags projects
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Project entry ID"
 // @Success 204 "No Content"
 // @Failure 400 {object} string "Bad Request - when ID is not provided"
+// @Failure 404 {object} string "Project not found"
 // @Failure 500 {object} string "Internal Server Error - when something goes wrong"
 // @Security BearerAuth
 // @Router /v1/projects/{id} [delete]
@@ -240,9 +316,56 @@ func (h *projectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, project.ErrNotFound) {
+			httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetDeleted godoc
+// @Summary Get deleted projects
+// @Description Lists deleted projects of the user which can still be restored, with the time their data is purged
+// @Tags projects
+// @Produce  json
+// @Success 200 {object} project.DeletedEntityList
+// @Security BearerAuth
+// @Router /v1/projects/deleted [get].
+func (h *projectHandler) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.service.GetDeleted(r.Context())
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, httputils.NewListResponse(len(projects), projects))
+}
+
+// Restore godoc
+// @Summary Restore a deleted project
+// @Description Restores a deleted project with all of its data while its restore window lasts
+// @Tags projects
+// @Produce  json
+// @Param id path string true "Project ID"
+// @Success 200 {object} project.Entity
+// @Failure 404 {object} string "No deleted project to restore"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/restore [post].
+func (h *projectHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	entity, err := h.service.Restore(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, project.ErrNotFound) {
+			httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/internal/modules/release"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	"github.com/duckbugio/duckbug/pkg/utils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type releaseHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  release.Service
+	ingest   ingest.Service
+}
+
+func RegisterReleaseHandlers(
+	r *mux.Router,
+	logger Logger,
+	service release.Service,
+	ingestService ingest.Service,
+	jwtKey []byte,
+) {
+	h := &releaseHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+		ingest:   ingestService,
+	}
+
+	r.HandleFunc("/ingest/{projectID}:{key}/sessions", h.CreateSession).Methods(http.MethodPost)
+
+	routerV1 := r.PathPrefix("/v1/releases").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.GetHealth).Methods(http.MethodGet)
+	routerV1.HandleFunc("/compare", h.Compare).Methods(http.MethodGet)
+}
+
+// CreateSession godoc
+// @Summary Send a session update
+// @Description Starts, ends or crashes a session of a release. Updates with the same id change the same session, a crashed session stays crashed. Keys of the errors scope may send sessions.
+// @Tags ingest
+// @Accept  json
+// @Param        projectID   path      string  true  "Project ID"
+// @Param        key         path      string  true  "Public key"
+// @Param   request body release.Create true "Session update"
+// @Success 204 "Session updated"
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
+// @Failure 500 {object} string "Internal server error"
+// @Router /ingest/{projectID}:{key}/sessions [post].
+func (h *releaseHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindSessions)
+	if !ok {
+		return
+	}
+
+	var req release.Create
+	if !decodeIngest(w, r, h.validate, &req) {
+		return
+	}
+
+	if !h.ingest.Keep(projectID, ingest.KindSessions, "") {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	req.ProjectID = projectID
+
+	if err := h.service.Record(r.Context(), &req); err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetHealth godoc
+// @Summary Get release health
+// @Description Returns sessions, crashed sessions and crash free session and user rates per release, the releases with the latest sessions first
+// @Tags releases
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param environment query string false "Only sessions of this environment"
+// @Param timeFrom query int false "Sessions started since, Unix timestamp in seconds"
+// @Param timeTo query int false "Sessions started until, Unix timestamp in seconds"
+// @Param release query string false "Only this release"
+// @Param limit query int false "Number of releases" default(50)
+// @Success 200 {array} release.HealthEntity
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/releases [get].
+func (h *releaseHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID := queryParams.Get("projectId")
+	if projectID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId is required")
+		return
+	}
+
+	timeFrom, err := utils.ParseTimeParam(queryParams.Get("timeFrom"))
+	if err != nil {
+		timeFrom = 0
+	}
+
+	timeTo, err := utils.ParseTimeParam(queryParams.Get("timeTo"))
+	if err != nil {
+		timeTo = 0
+	}
+
+	params := release.HealthParams{
+		ProjectID:   projectID,
+		Environment: queryParams.Get("environment"),
+		TimeFrom:    utils.SecondsToMilliseconds(timeFrom),
+		TimeTo:      utils.SecondsToMilliseconds(timeTo),
+		Limit:       parseReleaseLimit(queryParams.Get("limit")),
+	}
+	if name := queryParams.Get("release"); name != "" {
+		params.Releases = []string{name}
+	}
+
+	health, err := h.service.GetHealth(r.Context(), params)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, health)
+}
+
+// Compare godoc
+// @Summary Compare two releases
+// @Description Returns the health of both releases and the error groups of the target release which are new (no events in base) or regressed (events in base, resolved and seen again while target was live). The environment only applies to the session counts.
+// @Tags releases
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param base query string true "Release compared against, e.g. the previous one"
+// @Param target query string true "Compared release"
+// @Param environment query string false "Only sessions of this environment"
+// @Param limit query int false "Number of groups" default(50)
+// @Success 200 {object} release.Comparison
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/releases/compare [get].
+func (h *releaseHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	params := release.CompareParams{
+		ProjectID:   queryParams.Get("projectId"),
+		Base:        queryParams.Get("base"),
+		Target:      queryParams.Get("target"),
+		Environment: queryParams.Get("environment"),
+		Limit:       parseReleaseLimit(queryParams.Get("limit")),
+	}
+	if params.ProjectID == "" || params.Base == "" || params.Target == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId, base and target are required")
+		return
+	}
+
+	comparison, err := h.service.Compare(r.Context(), params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, release.ErrSameRelease) {
+			status = http.StatusBadRequest
+		}
+		httputils.RespondWithPlainError(w, status, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, comparison)
+}
+
+func parseReleaseLimit(value string) int {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return release.DefaultLimit
+	}
+	return min(limit, release.MaxLimit)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type savedSearchHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  savedSearch.Service
+}
+
+func RegisterSavedSearchHandlers(
+	r *mux.Router,
+	logger Logger,
+	service savedSearch.Service,
+	jwtKey []byte,
+) {
+	h := &savedSearchHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/projects/{id}/saved-searches").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.GetAll).Methods(http.MethodGet)
+	routerV1.HandleFunc("", h.Create).Methods(http.MethodPost)
+	routerV1.HandleFunc("/default", h.GetDefault).Methods(http.MethodGet)
+	routerV1.HandleFunc("/{searchID}", h.Update).Methods(http.MethodPut)
+	routerV1.HandleFunc("/{searchID}", h.Delete).Methods(http.MethodDelete)
+
+	linkRouter := r.PathPrefix("/v1/s").Subrouter()
+	linkRouter.Use(middleware.Auth(jwtKey))
+
+	linkRouter.HandleFunc("/{code}", h.GetByCode).Methods(http.MethodGet)
+}
+
+// GetAll godoc
+// @Summary Get saved searches
+// @Description Lists the saved searches of a project owned by the current user or shared with the project
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param target query string false "Only searches of this list" Enums(logs, errors, error-groups, log-groups)
+// @Success 200 {array} savedsearch.Entity
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/saved-searches [get].
+func (h *savedSearchHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	entities, err := h.service.GetAll(r.Context(), id, r.URL.Query().Get("target"))
+	if err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entities)
+}
+
+// GetDefault godoc
+// @Summary Get the default view
+// @Description Returns the saved search the list of the project opens with for the current user
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param target query string true "List" Enums(logs, errors, error-groups, log-groups)
+// @Success 200 {object} savedsearch.Entity
+// @Failure 404 {object} string "No default view"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/saved-searches/default [get].
+func (h *savedSearchHandler) GetDefault(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	entity, err := h.service.GetDefault(r.Context(), id, r.URL.Query().Get("target"))
+	if err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// Create godoc
+// @Summary Save a search
+// @Description Saves filters of a list. Filter keys are the query params of the list, period is a time range relative to the time the search is used, e.g. 24h or 7d.
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body savedsearch.Create true "Search"
+// @Success 201 {object} savedsearch.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/saved-searches [post].
+func (h *savedSearchHandler) Create(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req savedSearch.Create
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Create(r.Context(), id, &req)
+	if err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusCreated, entity)
+}
+
+// Update godoc
+// @Summary Update a saved search
+// @Description Changes a saved search of the current user
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param searchID path string true "Saved search ID"
+// @Param request body savedsearch.Update true "Search"
+// @Success 200 {object} savedsearch.Entity
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 403 {object} string "Search of another user"
+// @Failure 404 {object} string "Saved search not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/saved-searches/{searchID} [put].
+func (h *savedSearchHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req savedSearch.Update
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	entity, err := h.service.Update(r.Context(), vars["id"], vars["searchID"], &req)
+	if err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+// Delete godoc
+// @Summary Delete a saved search
+// @Description Deletes a saved search of the current user, its link stops working
+// @Tags saved-searches
+// @Param id path string true "Project ID"
+// @Param searchID path string true "Saved search ID"
+// @Success 204 "No Content"
+// @Failure 403 {object} string "Search of another user"
+// @Failure 404 {object} string "Saved search not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/saved-searches/{searchID} [delete].
+func (h *savedSearchHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.service.Delete(r.Context(), vars["id"], vars["searchID"]); err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetByCode godoc
+// @Summary Open a search link
+// @Description Resolves the short link of a saved search to its filter and the query of the list
+// @Tags saved-searches
+// @Accept json
+// @Produce json
+// @Param code path string true "Link code"
+// @Success 200 {object} savedsearch.Entity
+// @Failure 404 {object} string "Unknown link"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/s/{code} [get].
+func (h *savedSearchHandler) GetByCode(w http.ResponseWriter, r *http.Request) {
+	entity, err := h.service.GetByCode(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entity)
+}
+
+func respondSavedSearchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, savedSearch.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, savedSearch.ErrForbidden):
+		httputils.RespondWithPlainError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, savedSearch.ErrInvalidFilter), errors.Is(err, savedSearch.ErrTargetMismatch):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// withSavedSearch fills the query of a list from the saved search named by the savedSearch param,
+// params of the request win over the saved ones
+func withSavedSearch(searches savedSearch.Service, target string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params := r.URL.Query()
+			id := params.Get(savedSearch.QueryParam)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			query, err := searches.Query(r.Context(), id, target)
+			if err != nil {
+				respondSavedSearchError(w, err)
+				return
+			}
+
+			params.Del(savedSearch.QueryParam)
+			for key, values := range params {
+				query[key] = values
+			}
+			r.URL.RawQuery = query.Encode()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/scrubbing"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type scrubbingHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  scrubbing.Service
+}
+
+func RegisterScrubbingHandlers(
+	r *mux.Router,
+	logger Logger,
+	service scrubbing.Service,
+	jwtKey []byte,
+) {
+	h := &scrubbingHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+	}
+
+	routerV1 := r.PathPrefix("/v1/projects/{id}/scrubbing").Subrouter()
+	routerV1.Use(middleware.Auth(jwtKey))
+
+	routerV1.HandleFunc("", h.Get).Methods(http.MethodGet)
+	routerV1.HandleFunc("", h.Update).Methods(http.MethodPut)
+}
+
+// Get godoc
+// @Summary Get project scrubbing rules
+// @Description Get the custom scrubbing rules of a project. The built-in denylist (passwords, secrets, tokens, authorization headers, cookies, card numbers and IBANs) always applies on top of them.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} scrubbing.Config
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/scrubbing [get].
+func (h *scrubbingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	config, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, config)
+}
+
+// Update godoc
+// @Summary Update project scrubbing rules
+// @Description Replaces the custom scrubbing rules of a project. Key rules filter fields whose name contains the pattern, regex rules filter matching parts of string values.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param request body scrubbing.Config true "Scrubbing rules"
+// @Success 200 {object} scrubbing.Config
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/projects/{id}/scrubbing [put].
+func (h *scrubbingHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req scrubbing.Config
+	if !decodeAndValidate(w, r, h.validate, &req) {
+		return
+	}
+
+	config, err := h.service.Update(r.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, scrubbing.ErrInvalidPattern) {
+			httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, config)
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/internal/modules/traces"
+	"github.com/duckbugio/duckbug/pkg/httputils"
+	v "github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// maxOTLPBodySize bounds an OTLP export request after decompression
+const maxOTLPBodySize = 16 << 20
+
+type traceHandler struct {
+	logger   Logger
+	validate *v.Validate
+	service  traces.Service
+	ingest   ingest.Service
+}
+
+func RegisterTraceHandlers(
+	r *mux.Router,
+	logger Logger,
+	service traces.Service,
+	ingestService ingest.Service,
+	jwtKey []byte,
+) {
+	h := &traceHandler{
+		logger:   logger,
+		validate: v.New(),
+		service:  service,
+		ingest:   ingestService,
+	}
+
+	r.HandleFunc("/ingest/{projectID}:{key}/transactions", h.CreateTransaction).Methods(http.MethodPost)
+	r.HandleFunc("/ingest/{projectID}:{key}/v1/traces", h.CreateOTLP).Methods(http.MethodPost)
+
+	transactionsRouter := r.PathPrefix("/v1/transactions").Subrouter()
+	transactionsRouter.Use(middleware.Auth(jwtKey))
+
+	transactionsRouter.HandleFunc("", h.GetTransactions).Methods(http.MethodGet)
+	transactionsRouter.HandleFunc("/series", h.GetSeries).Methods(http.MethodGet)
+	transactionsRouter.HandleFunc("/samples", h.GetSamples).Methods(http.MethodGet)
+
+	tracesRouter := r.PathPrefix("/v1/traces").Subrouter()
+	tracesRouter.Use(middleware.Auth(jwtKey))
+
+	tracesRouter.HandleFunc("/{traceID}", h.GetTrace).Methods(http.MethodGet)
+}
+
+// CreateTransaction godoc
+// @Summary Send a transaction
+// @Description Stores a transaction with its spans in the DuckBug JSON format. Spans already stored are skipped, so a request can be retried. Keys of the errors scope may send transactions.
+// @Tags ingest
+// @Accept  json
+// @Param        projectID   path      string  true  "Project ID"
+// @Param        key         path      string  true  "Public key"
+// @Param   request body traces.Create true "Transaction"
+// @Success 204 "Transaction stored"
+// @Failure 400 {object} string "Invalid input data"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
+// @Failure 500 {object} string "Internal server error"
+// @Router /ingest/{projectID}:{key}/transactions [post].
+func (h *traceHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindTraces)
+	if !ok {
+		return
+	}
+
+	var req traces.Create
+	if !decodeIngest(w, r, h.validate, &req) {
+		return
+	}
+
+	if !h.ingest.Keep(projectID, ingest.KindTraces, "") {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	req.ProjectID = projectID
+
+	if err := h.service.Create(r.Context(), &req); err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateOTLP godoc
+// @Summary Send OTLP traces
+// @Description OTLP/HTTP trace export endpoint. Set https://<host>/api/ingest/{projectID}:{key} as OTEL_EXPORTER_OTLP_ENDPOINT, exporters append /v1/traces. Accepts protobuf and JSON, optionally gzip compressed. Spans without a parent and server and consumer spans are transactions. service.name, service.version and deployment.environment(.name) of the resource become the service, the release and the environment.
+// @Tags ingest
+// @Accept  application/x-protobuf,json
+// @Produce application/x-protobuf,json
+// @Param        projectID   path      string  true  "Project ID"
+// @Param        key         path      string  true  "Public key"
+// @Success 200 "Export accepted, the body is an empty ExportTraceServiceResponse"
+// @Failure 400 {object} string "Invalid export request"
+// @Failure 401 {object} string "Invalid ingest key"
+// @Failure 403 {object} string "Key scope or allowed origins do not permit the request"
+// @Failure 413 {object} string "Request too large"
+// @Failure 415 {object} string "Unsupported content type"
+// @Failure 429 {object} string "Rate limit or quota exceeded, see the Retry-After header"
+// @Failure 500 {object} string "Internal server error"
+// @Router /ingest/{projectID}:{key}/v1/traces [post].
+func (h *traceHandler) CreateOTLP(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := admitIngest(w, r, h.ingest, ingest.KindTraces)
+	if !ok {
+		return
+	}
+
+	// Exporters may add parameters like charset to the media type
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var format string
+	switch mediaType {
+	case "application/x-protobuf":
+		format = traces.FormatProtobuf
+	case "application/json":
+		format = traces.FormatJSON
+	default:
+		httputils.RespondWithPlainError(w, http.StatusUnsupportedMediaType, traces.ErrUnsupportedFormat.Error())
+		return
+	}
+
+	body, ok := readOTLPBody(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.ingest.Keep(projectID, ingest.KindTraces, "") {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if _, err := h.service.CreateOTLP(r.Context(), projectID, body, format); err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	if format == traces.FormatProtobuf {
+		// An empty ExportTraceServiceResponse encodes to no bytes
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	httputils.RespondWithJSON(w, http.StatusOK, struct{}{})
+}
+
+// readOTLPBody reads an export request, on failure the error response is already written
+func readOTLPBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	_, span := tracer.Start(r.Context(), "ingest.decode")
+	defer span.End()
+
+	reader := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httputils.RespondWithPlainError(w, http.StatusBadRequest, "invalid gzip body")
+			return nil, false
+		}
+		reader = gz
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, maxOTLPBodySize+1))
+	switch {
+	case err != nil:
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "failed to read body")
+		return nil, false
+	case len(body) > maxOTLPBodySize:
+		httputils.RespondWithPlainError(w, http.StatusRequestEntityTooLarge, "request too large")
+		return nil, false
+	}
+	return body, true
+}
+
+// GetTransactions godoc
+// @Summary Get transactions
+// @Description Returns latency percentiles, throughput per minute and the error rate of every transaction name, the slowest first
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param timeFrom query int false "Transactions started since (Unix seconds), defaults to 24 hours before timeTo"
+// @Param timeTo query int false "Transactions started until (Unix seconds), defaults to now"
+// @Param environment query string false "Only transactions of this environment"
+// @Param release query string false "Only transactions of this release"
+// @Param search query string false "Search in transaction names"
+// @Param sort query string false "Order, descending" default(p95) Enums(p50, p95, p99, avg, count, errors)
+// @Param limit query int false "Number of names" default(50)
+// @Success 200 {array} traces.TransactionEntity
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/transactions [get].
+func (h *traceHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID := queryParams.Get("projectId")
+	if projectID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId is required")
+		return
+	}
+
+	rangeParams, err := parseHistogramQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entities, err := h.service.GetTransactions(r.Context(), traces.SummaryParams{
+		ProjectID:   projectID,
+		Environment: queryParams.Get("environment"),
+		Release:     queryParams.Get("release"),
+		Search:      queryParams.Get("search"),
+		TimeFrom:    rangeParams.TimeFrom,
+		TimeTo:      rangeParams.TimeTo,
+		Sort:        queryParams.Get("sort"),
+		Limit:       parseTraceLimit(queryParams.Get("limit")),
+	})
+	if err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, entities)
+}
+
+// GetSeries godoc
+// @Summary Get the latency of a transaction over time
+// @Description Returns latency percentiles and throughput per minute of a transaction name per bucket, buckets without transactions have null percentiles
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param name query string true "Transaction name"
+// @Param timeFrom query int false "Transactions started since (Unix seconds), defaults to 24 hours before timeTo"
+// @Param timeTo query int false "Transactions started until (Unix seconds), defaults to now"
+// @Param interval query string false "Bucket size" default(1h) Enums(1m, 5m, 15m, 30m, 1h, 3h, 6h, 12h, 1d)
+// @Param environment query string false "Only transactions of this environment"
+// @Param release query string false "Only transactions of this release"
+// @Success 200 {object} traces.Series
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/transactions/series [get].
+func (h *traceHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID, name := queryParams.Get("projectId"), queryParams.Get("name")
+	if projectID == "" || name == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId and name are required")
+		return
+	}
+
+	histogramParams, err := parseHistogramQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := h.service.GetSeries(r.Context(), traces.SeriesParams{
+		ProjectID:   projectID,
+		Name:        name,
+		Environment: queryParams.Get("environment"),
+		Release:     queryParams.Get("release"),
+		TimeFrom:    histogramParams.TimeFrom,
+		TimeTo:      histogramParams.TimeTo,
+		Interval:    histogramParams.Interval,
+	})
+	if err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, series)
+}
+
+// GetSamples godoc
+// @Summary Get sample transactions
+// @Description Returns transactions of a name, the slowest or the latest first, to open their traces
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param projectId query string true "Project ID"
+// @Param name query string true "Transaction name"
+// @Param timeFrom query int false "Transactions started since (Unix seconds), defaults to 24 hours before timeTo"
+// @Param timeTo query int false "Transactions started until (Unix seconds), defaults to now"
+// @Param environment query string false "Only transactions of this environment"
+// @Param sort query string false "Order, descending" default(duration) Enums(duration, time)
+// @Param limit query int false "Number of transactions" default(50)
+// @Success 200 {array} traces.SpanEntity
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/transactions/samples [get].
+func (h *traceHandler) GetSamples(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	projectID, name := queryParams.Get("projectId"), queryParams.Get("name")
+	if projectID == "" || name == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId and name are required")
+		return
+	}
+
+	rangeParams, err := parseHistogramQuery(queryParams)
+	if err != nil {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	samples, err := h.service.GetSamples(r.Context(), traces.SamplesParams{
+		ProjectID:   projectID,
+		Name:        name,
+		Environment: queryParams.Get("environment"),
+		TimeFrom:    rangeParams.TimeFrom,
+		TimeTo:      rangeParams.TimeTo,
+		Sort:        queryParams.Get("sort"),
+		Limit:       parseTraceLimit(queryParams.Get("limit")),
+	})
+	if err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, samples)
+}
+
+// GetTrace godoc
+// @Summary Get a trace
+// @Description Returns the spans of a trace by start time and the errors reported with its trace_id in their context
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param traceID path string true "Trace ID, 32 hex digits"
+// @Param projectId query string true "Project ID"
+// @Success 200 {object} traces.TraceEntity
+// @Failure 400 {object} string "Invalid query params"
+// @Failure 404 {object} string "Trace not found"
+// @Failure 500 {object} string "Internal server error"
+// @Security BearerAuth
+// @Router /v1/traces/{traceID} [get].
+func (h *traceHandler) GetTrace(w http.ResponseWriter, r *http.Request) {
+	projectID := r.URL.Query().Get("projectId")
+	if projectID == "" {
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, "projectId is required")
+		return
+	}
+
+	trace, err := h.service.GetTrace(r.Context(), projectID, mux.Vars(r)["traceID"])
+	if err != nil {
+		respondTraceError(w, err)
+		return
+	}
+
+	httputils.RespondWithJSON(w, http.StatusOK, trace)
+}
+
+func parseTraceLimit(value string) int {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return traces.DefaultLimit
+	}
+	return min(limit, traces.MaxLimit)
+}
+
+func respondTraceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, traces.ErrNotFound):
+		httputils.RespondWithPlainError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, traces.ErrUnsupportedFormat):
+		httputils.RespondWithPlainError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, traces.ErrInvalidID), errors.Is(err, traces.ErrInvalidOTLP),
+		errors.Is(err, traces.ErrTooManySpans), errors.Is(err, traces.ErrTooManyBuckets):
+		httputils.RespondWithPlainError(w, http.StatusBadRequest, err.Error())
+	default:
+		httputils.RespondWithPlainError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,13 +1,28 @@
 package server
 
 import (
-	"fmt"
+	"context"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/duckbugio/duckbug/internal/middleware"
+	"github.com/duckbugio/duckbug/internal/modules/audit"
 	"github.com/duckbugio/duckbug/internal/server/http/handlers"
+	"github.com/duckbugio/duckbug/pkg/requestid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// corsMaxAge lets browsers cache preflight answers for ten minutes
+const corsMaxAge = "600"
+
 type LoggingResponseWriter struct {
 	http.ResponseWriter
 	ResponseCode int
@@ -18,7 +33,17 @@ func (l *LoggingResponseWriter) WriteHeader(code int) {
 	l.ResponseWriter.WriteHeader(code)
 }
 
-func loggingMiddleware(logger handlers.Logger, next http.Handler) http.Handler {
+// Unwrap lets http.ResponseController reach the connection, handlers streaming large bodies extend its deadlines
+func (l *LoggingResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
+// RequestObserver receives every served request labeled with the template of its route
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+func loggingMiddleware(logger handlers.Logger, router *mux.Router, observer RequestObserver, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 
@@ -29,55 +54,255 @@ func loggingMiddleware(logger handlers.Logger, next http.Handler) http.Handler {
 		next.ServeHTTP(&lrw, r)
 		latency := time.Since(startTime)
 
-		logger.Info(
-			fmt.Sprintf(
-				"%s [%s] %s %s HTTP/%s %d %s \"%s\"",
-				r.RemoteAddr,
-				time.Now().Format(time.RFC1123Z),
-				r.Method, r.URL.Path, r.Proto,
-				lrw.ResponseCode,
-				latency,
-				r.Header.Get("User-Agent"),
+		route := routeTemplate(router, r)
+		if observer != nil {
+			observer.ObserveRequest(r.Method, route, lrw.ResponseCode, latency)
+		}
+
+		logger.InfoContext(r.Context(), "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route,
+			"status", lrw.ResponseCode,
+			"latency_ms", latency.Milliseconds(),
+			"remote_addr", r.RemoteAddr,
+			"proto", r.Proto,
+			"user_agent", r.Header.Get("User-Agent"),
+		)
+	})
+}
+
+// AuditRecorder stores finished mutating requests in the audit log
+type AuditRecorder interface {
+	Record(ctx context.Context, record *audit.Record) error
+}
+
+// auditMiddleware records every mutating /v1 request with its actor and outcome. Services describe
+// the change with middleware.Audit, other requests are named by method and route.
+func auditMiddleware(router *mux.Router, recorder AuditRecorder, logger handlers.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(router, r)
+		if !isAudited(r, route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		record := &middleware.AuditRecord{}
+		lrw := LoggingResponseWriter{
+			ResponseWriter: w,
+			ResponseCode:   http.StatusOK,
+		}
+		next.ServeHTTP(&lrw, r.WithContext(middleware.WithAuditRecord(r.Context(), record)))
+
+		action, targetType, targetID, before, after := record.Action()
+		if action == "" {
+			action = r.Method + " " + route
+			targetType, targetID = routeTarget(router, r)
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		requestID, _ := requestid.Get(r.Context())
+
+		// The entry is written even when the client went away before the response
+		err = recorder.Record(context.WithoutCancel(r.Context()), &audit.Record{
+			ActorID:      record.ActorID(),
+			Action:       action,
+			Method:       r.Method,
+			Route:        route,
+			TargetType:   targetType,
+			TargetID:     targetID,
+			Before:       before,
+			After:        after,
+			Status:       lrw.ResponseCode,
+			IP:           ip,
+			ForwardedFor: r.Header.Get("X-Forwarded-For"),
+			UserAgent:    r.Header.Get("User-Agent"),
+			RequestID:    requestID,
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "failed to record audit entry", "action", action, "error", err)
+		}
+	})
+}
+
+func isAudited(r *http.Request, route string) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return strings.HasPrefix(route, "/v1/")
+	default:
+		return false
+	}
+}
+
+// routeTarget names the target of a request which did not describe its change, by the resource of
+// the path and the id in it
+func routeTarget(router *mux.Router, r *http.Request) (string, string) {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) {
+		return "", ""
+	}
+	resource, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	return resource, match.Vars["id"]
+}
+
+var tracer = otel.Tracer("github.com/duckbugio/duckbug/internal/server/http")
+
+// tracingMiddleware records a server span per request, continuing the trace of a traceparent header
+func tracingMiddleware(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := routeTemplate(router, r)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.Header.Get("User-Agent")),
 			),
 		)
+		defer span.End()
+
+		lrw := LoggingResponseWriter{
+			ResponseWriter: w,
+			ResponseCode:   http.StatusOK,
+		}
+		next.ServeHTTP(&lrw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(lrw.ResponseCode))
+		if lrw.ResponseCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(lrw.ResponseCode))
+		}
 	})
 }
 
-func CORS(next http.Handler) http.Handler {
+// routeTemplate keeps ids out of metric labels, requests matching no route share one label
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// OriginChecker decides which browser origins may send events to an ingest key
+type OriginChecker interface {
+	AllowsOrigin(ctx context.Context, projectID, key, origin string) (bool, error)
+}
+
+// corsMethods are probed against the router to answer preflight requests per route
+var corsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORS allows the configured origins on the management API and the per-project origins on ingest routes
+func CORS(router *mux.Router, allowedOrigins []string, ingest OriginChecker, logger handlers.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		w.Header().Add("Vary", "Origin")
 
-		// Разрешаем запросы с 127.0.0.1 и localhost (с любым портом)
-		if origin != "" {
-			if isAllowedOrigin(origin) {
+		if strings.HasPrefix(r.URL.Path, "/ingest/") {
+			if origin != "" && ingestAllowsOrigin(router, r, ingest, origin, logger) {
+				// SDKs send no cookies, so credentials are not allowed on ingest
 				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, traceparent, tracestate")
+				w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			}
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
 			}
+			router.ServeHTTP(w, r)
+			return
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		if origin != "" && isAllowedOrigin(allowedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			// Lets the frontend show the request id next to an error
+			w.Header().Set("Access-Control-Expose-Headers", requestid.Header)
+		}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+		if preflight {
+			methods := routeMethods(router, r)
+			if len(methods) == 0 {
+				http.Error(w, "404 page not found", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(append(methods, http.MethodOptions), ", "))
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, traceparent, tracestate, "+requestid.Header)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		router.ServeHTTP(w, r)
 	})
 }
 
-func isAllowedOrigin(origin string) bool {
-	allowedPrefixes := []string{
-		"http://127.0.0.1",
-		"http://localhost",
+func ingestAllowsOrigin(router *mux.Router, r *http.Request, ingest OriginChecker, origin string, logger handlers.Logger) bool {
+	// Preflight requests carry the key in the path as well, it is resolved through the POST route
+	probe := r.Clone(r.Context())
+	probe.Method = http.MethodPost
+
+	var match mux.RouteMatch
+	if !router.Match(probe, &match) || match.MatchErr != nil {
+		return false
 	}
 
-	for _, prefix := range allowedPrefixes {
-		if len(origin) >= len(prefix) && origin[:len(prefix)] == prefix {
-			return true
+	allowed, err := ingest.AllowsOrigin(r.Context(), match.Vars["projectID"], match.Vars["key"], origin)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "failed to check ingest origin", "error", err)
+		return false
+	}
+	return allowed
+}
+
+// routeMethods returns the methods the router serves on the path of the request
+func routeMethods(router *mux.Router, r *http.Request) []string {
+	var methods []string
+	for _, method := range corsMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			methods = append(methods, method)
 		}
 	}
+	return methods
+}
+
+// isAllowedOrigin matches origins exactly, an entry without a port allows any port of that host
+func isAllowedOrigin(allowedOrigins []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range allowedOrigins {
+		allowed = strings.ToLower(strings.TrimRight(allowed, "/"))
+		if origin == allowed {
+			return true
+		}
 
+		port, ok := strings.CutPrefix(origin, allowed+":")
+		if ok && isPort(port) && !hasPort(allowed) {
+			return true
+		}
+	}
 	return false
 }
+
+func isPort(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 16)
+	return err == nil
+}
+
+func hasPort(origin string) bool {
+	host := origin[strings.Index(origin, "://")+len("://"):]
+	_, port, err := net.SplitHostPort(host)
+	return err == nil && port != ""
+}
@@ -7,13 +7,27 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/duckbugio/duckbug/internal/metrics"
+	"github.com/duckbugio/duckbug/internal/middleware"
 	"github.com/duckbugio/duckbug/internal/modules/app"
+	"github.com/duckbugio/duckbug/internal/modules/audit"
+	"github.com/duckbugio/duckbug/internal/modules/backup"
+	"github.com/duckbugio/duckbug/internal/modules/bulk"
+	"github.com/duckbugio/duckbug/internal/modules/erasure"
 	"github.com/duckbugio/duckbug/internal/modules/errors"
 	errorsGroup "github.com/duckbugio/duckbug/internal/modules/errorsGroup"
+	"github.com/duckbugio/duckbug/internal/modules/ingest"
+	"github.com/duckbugio/duckbug/internal/modules/jobs"
+	"github.com/duckbugio/duckbug/internal/modules/keys"
 	"github.com/duckbugio/duckbug/internal/modules/log"
 	logGroup "github.com/duckbugio/duckbug/internal/modules/logGroup"
+	"github.com/duckbugio/duckbug/internal/modules/monitors"
 	"github.com/duckbugio/duckbug/internal/modules/project"
+	"github.com/duckbugio/duckbug/internal/modules/release"
+	savedSearch "github.com/duckbugio/duckbug/internal/modules/savedSearch"
+	"github.com/duckbugio/duckbug/internal/modules/scrubbing"
 	"github.com/duckbugio/duckbug/internal/modules/technology"
+	"github.com/duckbugio/duckbug/internal/modules/traces"
 	"github.com/duckbugio/duckbug/internal/modules/users"
 	"github.com/duckbugio/duckbug/internal/server/http/handlers"
 )
@@ -38,11 +52,27 @@ func New(
 	errorGroupService errorsGroup.Service,
 	technologyService technology.Service,
 	projectService project.Service,
+	scrubbingService scrubbing.Service,
+	jobService jobs.Service,
+	erasureService erasure.Service,
+	ingestService ingest.Service,
+	keyService keys.Service,
+	auditService audit.Service,
+	backupService backup.Service,
+	bulkService bulk.Service,
+	savedSearchService savedSearch.Service,
+	releaseService release.Service,
+	monitorService monitors.Service,
+	traceService traces.Service,
+	metricsCollector *metrics.Metrics,
 	host string,
 	port int,
 	jwtKey []byte,
+	corsOrigins []string,
+	metricsToken string,
+	metricsPublic bool,
 ) *Server {
-	handler := NewHandler(
+	router := NewHandler(
 		logger,
 		appService,
 		userService,
@@ -52,12 +82,29 @@ func New(
 		errorGroupService,
 		technologyService,
 		projectService,
+		scrubbingService,
+		jobService,
+		erasureService,
+		ingestService,
+		keyService,
+		auditService,
+		backupService,
+		bulkService,
+		savedSearchService,
+		releaseService,
+		monitorService,
+		traceService,
 		jwtKey,
 	)
+	router.Handle("/metrics", metricsCollector.Handler(metricsToken, metricsPublic)).Methods(http.MethodGet)
 
 	servers := &http.Server{
-		Addr:         net.JoinHostPort(host, strconv.Itoa(port)),
-		Handler:      CORS(loggingMiddleware(logger, handler)),
+		Addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler: middleware.RequestID(tracingMiddleware(router,
+			loggingMiddleware(logger, router, metricsCollector,
+				auditMiddleware(router, auditService, logger, CORS(router, corsOrigins, ingestService, logger)),
+			),
+		)),
 		ReadTimeout:  defaultReadTimeout,
 		WriteTimeout: defaultWriteTimeout,
 	}
@@ -0,0 +1,183 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryObserver receives the duration of every statement sent to the database
+type QueryObserver interface {
+	ObserveQuery(operation, table string, duration time.Duration, err error)
+}
+
+var tracer = otel.Tracer("github.com/duckbugio/duckbug/internal/storage/sql")
+
+// Connect opens a postgres pool and checks it. Every statement gets a span, its duration is also
+// reported to observer unless it is nil.
+func Connect(ctx context.Context, dsn string, observer QueryObserver) (*sqlx.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+	}
+
+	db := sqlx.NewDb(stdsql.OpenDB(&observedConnector{Connector: connector, observer: observer}), "postgres")
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type observedConnector struct {
+	driver.Connector
+	observer QueryObserver
+}
+
+func (c *observedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &observedConn{Conn: conn, observer: c.observer}, nil
+}
+
+// observedConn times queries and execs, everything else is passed to the postgres connection as is
+type observedConn struct {
+	driver.Conn
+	observer QueryObserver
+}
+
+func (c *observedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, finish := c.observe(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	finish(err)
+	return rows, err
+}
+
+func (c *observedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, finish := c.observe(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	finish(err)
+	return result, err
+}
+
+func (c *observedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Prepare(query)
+}
+
+func (c *observedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Begin()
+}
+
+func (c *observedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *observedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *observedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// observe starts the span of a statement, the returned function ends it and records the duration
+func (c *observedConn) observe(ctx context.Context, query string) (context.Context, func(err error)) {
+	operation, table := statementLabels(query)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+
+	// Statements are parameterized, so the text carries no event data
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	}
+	if table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(table))
+	}
+	ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	startedAt := time.Now()
+
+	return ctx, func(err error) {
+		defer span.End()
+		if errors.Is(err, driver.ErrSkip) {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		if c.observer != nil {
+			c.observer.ObserveQuery(operation, table, time.Since(startedAt), err)
+		}
+	}
+}
+
+var (
+	statementKeyword = regexp.MustCompile(`^\s*([A-Za-z]+)`)
+	statementTables  = map[string]*regexp.Regexp{
+		"select": regexp.MustCompile(`(?i)\bFROM\s+([A-Za-z_][A-Za-z0-9_.]*)`),
+		"insert": regexp.MustCompile(`(?i)\bINTO\s+([A-Za-z_][A-Za-z0-9_.]*)`),
+		"update": regexp.MustCompile(`(?i)\bUPDATE\s+([A-Za-z_][A-Za-z0-9_.]*)`),
+		"delete": regexp.MustCompile(`(?i)\bFROM\s+([A-Za-z_][A-Za-z0-9_.]*)`),
+	}
+)
+
+// statementLabels names the operation and the first table of a statement. Only plain DML is given
+// a table, so partition DDL does not create a label per partition.
+func statementLabels(query string) (operation, table string) {
+	keyword := statementKeyword.FindStringSubmatch(query)
+	if keyword == nil {
+		return "other", ""
+	}
+	operation = strings.ToLower(keyword[1])
+
+	pattern, ok := statementTables[operation]
+	if !ok {
+		return operation, ""
+	}
+	if match := pattern.FindStringSubmatch(query); match != nil {
+		table = strings.ToLower(match[1])
+	}
+	return operation, table
+}
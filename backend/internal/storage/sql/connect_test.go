@@ -0,0 +1,30 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementLabels(t *testing.T) {
+	cases := []struct {
+		query     string
+		operation string
+		table     string
+	}{
+		{"SELECT id FROM projects WHERE id = $1", "select", "projects"},
+		{"\n\t\tSELECT COUNT(*) FROM (SELECT 1 FROM errors) AS t", "select", "errors"},
+		{"INSERT INTO error_groups (id) VALUES ($1)", "insert", "error_groups"},
+		{"update jobs SET status = $1", "update", "jobs"},
+		{"DELETE FROM logs WHERE time < $1", "delete", "logs"},
+		{"CREATE TABLE IF NOT EXISTS logs_p20250101 PARTITION OF logs", "create", ""},
+		{"WITH batch AS (SELECT id FROM logs) DELETE FROM logs", "with", ""},
+		{"", "other", ""},
+	}
+
+	for _, c := range cases {
+		operation, table := statementLabels(c.query)
+		assert.Equal(t, c.operation, operation, c.query)
+		assert.Equal(t, c.table, table, c.query)
+	}
+}
@@ -1,9 +1,11 @@
 package sql
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
 
 	m "github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -19,28 +21,134 @@ type Logger interface {
 	Info(msg string)
 	Warn(msg string)
 	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
 }
 
-func RunMigrations(db *sqlx.DB, log Logger) error {
-	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+func RunMigrations(ctx context.Context, db *sqlx.DB, log Logger) error {
+	mig, err := newMigrate(ctx, db)
 	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
+		return err
+	}
+	defer closeMigrate(mig)
+
+	if err := mig.Up(); err != nil && !errors.Is(err, m.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	log.Info("Database migrations applied successfully")
+	return nil
+}
+
+// RollbackMigrations reverts the given number of the latest applied migrations
+func RollbackMigrations(ctx context.Context, db *sqlx.DB, steps int) error {
+	if steps < 1 {
+		return errors.New("steps must be at least 1")
+	}
+
+	mig, err := newMigrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer closeMigrate(mig)
+
+	if err := mig.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// MigrationVersion returns the applied schema version, zero when nothing was applied yet.
+// A dirty version means a migration failed halfway and has to be fixed and forced.
+func MigrationVersion(ctx context.Context, db *sqlx.DB) (version uint, dirty bool, err error) {
+	mig, err := newMigrate(ctx, db)
+	if err != nil {
+		return 0, false, err
+	}
+	defer closeMigrate(mig)
+
+	version, dirty, err = mig.Version()
+	if errors.Is(err, m.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// ForceMigrationVersion records version as applied and clean without running anything
+func ForceMigrationVersion(ctx context.Context, db *sqlx.DB, version int) error {
+	mig, err := newMigrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer closeMigrate(mig)
+
+	if err := mig.Force(version); err != nil {
+		return fmt.Errorf("failed to force migration version: %w", err)
+	}
+	return nil
+}
+
+// newMigrate runs migrations over a connection of its own taken from the pool of db. The callers close it
+// with closeMigrate, which returns the connection and leaves db open.
+func newMigrate(ctx context.Context, db *sqlx.DB) (*m.Migrate, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration connection: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
 	source, err := iofs.New(migrationsFS, "migrations")
 	if err != nil {
-		return fmt.Errorf("failed to create migration source: %w", err)
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to create migration source: %w", err)
 	}
 
 	mig, err := m.NewWithInstance("iofs", source, "postgres", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		_ = source.Close()
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
+	return mig, nil
+}
 
-	if err := mig.Up(); err != nil && !errors.Is(err, m.ErrNoChange) {
-		return fmt.Errorf("failed to apply migrations: %w", err)
+func closeMigrate(mig *m.Migrate) {
+	// Both parts are released either way, there is nothing left to do about a failure
+	_, _ = mig.Close()
+}
+
+// LatestVersion is the version of the newest embedded migration, the schema version this build expects
+func LatestVersion() (uint, error) {
+	source, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create migration source: %w", err)
 	}
+	defer func() {
+		_ = source.Close()
+	}()
 
-	log.Info("Database migrations applied successfully")
-	return nil
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		version = next
+	}
 }
@@ -0,0 +1,11 @@
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS errors_search_vector ON errors;
+DROP TRIGGER IF EXISTS logs_search_vector ON logs;
+DROP FUNCTION IF EXISTS errors_search_vector_update();
+DROP FUNCTION IF EXISTS logs_search_vector_update();
+DROP FUNCTION IF EXISTS errors_search_vector(TEXT, TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS logs_search_vector(TEXT, TEXT);
+
+ALTER TABLE errors DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE logs DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,42 @@
+-- +migrate Up
+
+-- Search vectors. The message carries the highest weight, attached context and stacktrace text
+-- are truncated so that a single huge payload cannot blow up the vector. The columns are plain
+-- and nullable, so adding them does not rewrite the tables: triggers fill new rows and
+-- `duckbugctl search backfill` fills existing ones in batches and indexes them concurrently.
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS search_vector tsvector;
+ALTER TABLE errors ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION logs_search_vector(message TEXT, context TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(message, '')), 'A') ||
+           setweight(to_tsvector('simple', left(coalesce(context, ''), 32768)), 'B')
+$$;
+
+CREATE OR REPLACE FUNCTION errors_search_vector(message TEXT, file TEXT, context TEXT, stacktrace TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(message, '')), 'A') ||
+           setweight(to_tsvector('simple', coalesce(file, '')), 'B') ||
+           setweight(to_tsvector('simple', left(coalesce(context, ''), 32768)), 'B') ||
+           setweight(to_tsvector('simple', left(coalesce(stacktrace, ''), 32768)), 'C')
+$$;
+
+CREATE OR REPLACE FUNCTION logs_search_vector_update() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := logs_search_vector(NEW.message, NEW.context);
+    RETURN NEW;
+END $$;
+
+CREATE OR REPLACE FUNCTION errors_search_vector_update() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := errors_search_vector(NEW.message, NEW.file, NEW.context, NEW.stacktrace);
+    RETURN NEW;
+END $$;
+
+CREATE TRIGGER logs_search_vector BEFORE INSERT OR UPDATE OF message, context ON logs
+    FOR EACH ROW EXECUTE FUNCTION logs_search_vector_update();
+
+CREATE TRIGGER errors_search_vector BEFORE INSERT OR UPDATE OF message, file, context, stacktrace ON errors
+    FOR EACH ROW EXECUTE FUNCTION errors_search_vector_update();
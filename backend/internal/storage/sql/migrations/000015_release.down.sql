@@ -0,0 +1,7 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_logs_project_release_time;
+DROP INDEX IF EXISTS idx_errors_project_release_time;
+
+ALTER TABLE logs DROP COLUMN IF EXISTS release;
+ALTER TABLE errors DROP COLUMN IF EXISTS release;
@@ -0,0 +1,8 @@
+-- +migrate Up
+
+-- Optional application release reported by SDKs, used to split histograms and compare releases
+ALTER TABLE errors ADD COLUMN IF NOT EXISTS release VARCHAR(255);
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS release VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_errors_project_release_time ON errors(project_id, release, time);
+CREATE INDEX IF NOT EXISTS idx_logs_project_release_time ON logs(project_id, release, time);
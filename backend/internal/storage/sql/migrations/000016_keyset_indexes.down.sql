@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_errors_project_time_id;
+DROP INDEX IF EXISTS idx_logs_project_time_id;
@@ -0,0 +1,5 @@
+-- +migrate Up
+
+-- Keyset pagination walks (time, id) within a project, the id breaks ties between equal timestamps
+CREATE INDEX IF NOT EXISTS idx_logs_project_time_id ON logs(project_id, time, id);
+CREATE INDEX IF NOT EXISTS idx_errors_project_time_id ON errors(project_id, time, id);
@@ -0,0 +1,3 @@
+-- +migrate Down
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_logs_id_time;
@@ -0,0 +1,5 @@
+-- +migrate Up
+
+-- Partitioned tables need the partition key in the primary key. The index is built
+-- concurrently so ingestion keeps running, which requires it to be the only statement in the file.
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_logs_id_time ON logs(id, time);
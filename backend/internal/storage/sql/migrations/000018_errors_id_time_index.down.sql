@@ -0,0 +1,3 @@
+-- +migrate Down
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_errors_id_time;
@@ -0,0 +1,4 @@
+-- +migrate Up
+
+-- See 000017, built concurrently for the same reason
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_errors_id_time ON errors(id, time);
@@ -0,0 +1,6 @@
+-- +migrate Down
+
+ALTER TABLE errors DROP CONSTRAINT IF EXISTS errors_legacy_time_check;
+ALTER TABLE logs DROP CONSTRAINT IF EXISTS logs_legacy_time_check;
+
+DROP TABLE IF EXISTS partitioning;
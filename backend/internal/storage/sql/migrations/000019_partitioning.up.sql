@@ -0,0 +1,37 @@
+-- +migrate Up
+
+-- Existing rows stay in the current tables, which become the first ("legacy") partition.
+-- Everything before legacy_until belongs to it, managed partitions start there. The boundary
+-- is placed at least a day ahead and after every stored event, so that rows written while the
+-- following migrations run still satisfy the constraint.
+CREATE TABLE IF NOT EXISTS partitioning (
+    table_name VARCHAR(64) PRIMARY KEY,
+    legacy_until BIGINT NOT NULL
+);
+
+INSERT INTO partitioning (table_name, legacy_until)
+SELECT 'logs', GREATEST(
+    CAST(EXTRACT(EPOCH FROM date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '2 days') * 1000 AS BIGINT),
+    COALESCE((SELECT MAX(time) + 1 FROM logs), 0)
+)
+ON CONFLICT (table_name) DO NOTHING;
+
+INSERT INTO partitioning (table_name, legacy_until)
+SELECT 'errors', GREATEST(
+    CAST(EXTRACT(EPOCH FROM date_trunc('day', now() AT TIME ZONE 'UTC') + INTERVAL '2 days') * 1000 AS BIGINT),
+    COALESCE((SELECT MAX(time) + 1 FROM errors), 0)
+)
+ON CONFLICT (table_name) DO NOTHING;
+
+-- NOT VALID only checks new rows, the scan of existing ones happens in 000020 without blocking writes
+DO $$
+DECLARE
+    logs_until BIGINT;
+    errors_until BIGINT;
+BEGIN
+    SELECT legacy_until INTO logs_until FROM partitioning WHERE table_name = 'logs';
+    SELECT legacy_until INTO errors_until FROM partitioning WHERE table_name = 'errors';
+
+    EXECUTE format('ALTER TABLE logs ADD CONSTRAINT logs_legacy_time_check CHECK (time < %s) NOT VALID', logs_until);
+    EXECUTE format('ALTER TABLE errors ADD CONSTRAINT errors_legacy_time_check CHECK (time < %s) NOT VALID', errors_until);
+END $$;
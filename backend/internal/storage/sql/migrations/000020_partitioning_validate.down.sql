@@ -0,0 +1,4 @@
+-- +migrate Down
+
+-- Validation has nothing to undo
+SELECT 1;
@@ -0,0 +1,6 @@
+-- +migrate Up
+
+-- Validation holds a SHARE UPDATE EXCLUSIVE lock, inserts and selects keep working while it scans.
+-- A valid constraint lets ATTACH PARTITION in 000021 skip its own scan.
+ALTER TABLE logs VALIDATE CONSTRAINT logs_legacy_time_check;
+ALTER TABLE errors VALIDATE CONSTRAINT errors_legacy_time_check;
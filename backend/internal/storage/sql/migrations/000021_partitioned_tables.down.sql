@@ -0,0 +1,46 @@
+-- +migrate Down
+
+-- errors: fold every partition back into the legacy heap
+ALTER TABLE errors DETACH PARTITION errors_legacy;
+ALTER TABLE errors_legacy DROP CONSTRAINT IF EXISTS errors_legacy_time_check;
+-- The trigger cloned from the parent may or may not survive the detach depending on the server version
+DROP TRIGGER IF EXISTS errors_search_vector ON errors_legacy;
+CREATE TRIGGER errors_search_vector BEFORE INSERT OR UPDATE OF message, file, context, stacktrace ON errors_legacy
+    FOR EACH ROW EXECUTE FUNCTION errors_search_vector_update();
+INSERT INTO errors_legacy (id, project_id, fingerprint, message, stacktrace, file, line, context, time, created_at, updated_at, ip, url, method, headers, query_params, body_params, cookies, session, files, env, release)
+SELECT id, project_id, fingerprint, message, stacktrace, file, line, context, time, created_at, updated_at, ip, url, method, headers, query_params, body_params, cookies, session, files, env, release FROM errors;
+DROP TABLE errors;
+
+ALTER TABLE errors_legacy RENAME TO errors;
+ALTER TABLE errors DROP CONSTRAINT errors_legacy_pkey;
+ALTER TABLE errors ADD CONSTRAINT errors_pkey PRIMARY KEY (id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_errors_id_time ON errors(id, time);
+ALTER INDEX idx_errors_legacy_fingerprint RENAME TO idx_errors_fingerprint;
+ALTER INDEX idx_errors_legacy_time RENAME TO idx_errors_time;
+ALTER INDEX idx_errors_legacy_project_time RENAME TO idx_errors_project_time;
+ALTER INDEX idx_errors_legacy_project_fingerprint_time RENAME TO idx_errors_project_fingerprint_time;
+ALTER INDEX IF EXISTS idx_errors_legacy_search_vector RENAME TO idx_errors_search_vector;
+ALTER INDEX idx_errors_legacy_project_release_time RENAME TO idx_errors_project_release_time;
+ALTER INDEX idx_errors_legacy_project_time_id RENAME TO idx_errors_project_time_id;
+
+-- logs: fold every partition back into the legacy heap
+ALTER TABLE logs DETACH PARTITION logs_legacy;
+ALTER TABLE logs_legacy DROP CONSTRAINT IF EXISTS logs_legacy_time_check;
+DROP TRIGGER IF EXISTS logs_search_vector ON logs_legacy;
+CREATE TRIGGER logs_search_vector BEFORE INSERT OR UPDATE OF message, context ON logs_legacy
+    FOR EACH ROW EXECUTE FUNCTION logs_search_vector_update();
+INSERT INTO logs_legacy (id, project_id, level, message, context, time, created_at, updated_at, fingerprint, release)
+SELECT id, project_id, level, message, context, time, created_at, updated_at, fingerprint, release FROM logs;
+DROP TABLE logs;
+
+ALTER TABLE logs_legacy RENAME TO logs;
+ALTER TABLE logs DROP CONSTRAINT logs_legacy_pkey;
+ALTER TABLE logs ADD CONSTRAINT logs_pkey PRIMARY KEY (id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_logs_id_time ON logs(id, time);
+ALTER INDEX idx_logs_legacy_time RENAME TO idx_logs_time;
+ALTER INDEX idx_logs_legacy_level RENAME TO idx_logs_level;
+ALTER INDEX idx_logs_legacy_project_time RENAME TO idx_logs_project_time;
+ALTER INDEX idx_logs_legacy_project_fingerprint_time RENAME TO idx_logs_project_fingerprint_time;
+ALTER INDEX IF EXISTS idx_logs_legacy_search_vector RENAME TO idx_logs_search_vector;
+ALTER INDEX idx_logs_legacy_project_release_time RENAME TO idx_logs_project_release_time;
+ALTER INDEX idx_logs_legacy_project_time_id RENAME TO idx_logs_project_time_id;
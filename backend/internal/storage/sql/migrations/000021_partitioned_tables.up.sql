@@ -0,0 +1,73 @@
+-- +migrate Up
+
+-- Swap the heap tables for range partitioned ones. Only catalog changes happen here: the legacy
+-- tables are attached as they are, their constraints from 000019 spare the validation scan.
+
+-- logs: keep the old heap as the legacy partition, its indexes are reused on attach
+ALTER TABLE logs RENAME TO logs_legacy;
+ALTER TABLE logs_legacy DROP CONSTRAINT logs_pkey;
+ALTER TABLE logs_legacy ADD CONSTRAINT logs_legacy_pkey PRIMARY KEY USING INDEX idx_logs_id_time;
+ALTER INDEX idx_logs_time RENAME TO idx_logs_legacy_time;
+ALTER INDEX idx_logs_level RENAME TO idx_logs_legacy_level;
+ALTER INDEX idx_logs_project_time RENAME TO idx_logs_legacy_project_time;
+ALTER INDEX idx_logs_project_fingerprint_time RENAME TO idx_logs_legacy_project_fingerprint_time;
+ALTER INDEX IF EXISTS idx_logs_search_vector RENAME TO idx_logs_legacy_search_vector;
+ALTER INDEX idx_logs_project_release_time RENAME TO idx_logs_legacy_project_release_time;
+ALTER INDEX idx_logs_project_time_id RENAME TO idx_logs_legacy_project_time_id;
+
+CREATE TABLE logs (LIKE logs_legacy INCLUDING DEFAULTS INCLUDING GENERATED) PARTITION BY RANGE (time);
+ALTER TABLE logs ADD CONSTRAINT logs_pkey PRIMARY KEY (id, time);
+
+-- The search vector trigger moves to the parent, which clones it to every partition including the legacy one
+DROP TRIGGER IF EXISTS logs_search_vector ON logs_legacy;
+CREATE TRIGGER logs_search_vector BEFORE INSERT OR UPDATE OF message, context ON logs
+    FOR EACH ROW EXECUTE FUNCTION logs_search_vector_update();
+
+CREATE INDEX idx_logs_time ON logs(time);
+CREATE INDEX idx_logs_level ON logs(level);
+CREATE INDEX idx_logs_project_time ON logs(project_id, time);
+CREATE INDEX idx_logs_project_fingerprint_time ON logs(project_id, fingerprint, time);
+CREATE INDEX idx_logs_project_release_time ON logs(project_id, release, time);
+CREATE INDEX idx_logs_project_time_id ON logs(project_id, time, id);
+
+-- errors: keep the old heap as the legacy partition, its indexes are reused on attach
+ALTER TABLE errors RENAME TO errors_legacy;
+ALTER TABLE errors_legacy DROP CONSTRAINT errors_pkey;
+ALTER TABLE errors_legacy ADD CONSTRAINT errors_legacy_pkey PRIMARY KEY USING INDEX idx_errors_id_time;
+ALTER INDEX idx_errors_fingerprint RENAME TO idx_errors_legacy_fingerprint;
+ALTER INDEX idx_errors_time RENAME TO idx_errors_legacy_time;
+ALTER INDEX idx_errors_project_time RENAME TO idx_errors_legacy_project_time;
+ALTER INDEX idx_errors_project_fingerprint_time RENAME TO idx_errors_legacy_project_fingerprint_time;
+ALTER INDEX IF EXISTS idx_errors_search_vector RENAME TO idx_errors_legacy_search_vector;
+ALTER INDEX idx_errors_project_release_time RENAME TO idx_errors_legacy_project_release_time;
+ALTER INDEX idx_errors_project_time_id RENAME TO idx_errors_legacy_project_time_id;
+
+CREATE TABLE errors (LIKE errors_legacy INCLUDING DEFAULTS INCLUDING GENERATED) PARTITION BY RANGE (time);
+ALTER TABLE errors ADD CONSTRAINT errors_pkey PRIMARY KEY (id, time);
+
+DROP TRIGGER IF EXISTS errors_search_vector ON errors_legacy;
+CREATE TRIGGER errors_search_vector BEFORE INSERT OR UPDATE OF message, file, context, stacktrace ON errors
+    FOR EACH ROW EXECUTE FUNCTION errors_search_vector_update();
+
+CREATE INDEX idx_errors_fingerprint ON errors(fingerprint);
+CREATE INDEX idx_errors_time ON errors(time);
+CREATE INDEX idx_errors_project_time ON errors(project_id, time);
+CREATE INDEX idx_errors_project_fingerprint_time ON errors(project_id, fingerprint, time);
+CREATE INDEX idx_errors_project_release_time ON errors(project_id, release, time);
+CREATE INDEX idx_errors_project_time_id ON errors(project_id, time, id);
+
+DO $$
+DECLARE
+    logs_until BIGINT;
+    errors_until BIGINT;
+BEGIN
+    SELECT legacy_until INTO logs_until FROM partitioning WHERE table_name = 'logs';
+    SELECT legacy_until INTO errors_until FROM partitioning WHERE table_name = 'errors';
+
+    EXECUTE format('ALTER TABLE logs ATTACH PARTITION logs_legacy FOR VALUES FROM (MINVALUE) TO (%s)', logs_until);
+    EXECUTE format('ALTER TABLE errors ATTACH PARTITION errors_legacy FOR VALUES FROM (MINVALUE) TO (%s)', errors_until);
+END $$;
+
+-- Catches events outside the premade range, the partition manager moves them out when it creates a matching partition
+CREATE TABLE logs_default PARTITION OF logs DEFAULT;
+CREATE TABLE errors_default PARTITION OF errors DEFAULT;
@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_error_groups_project_last_seen;
+DROP INDEX IF EXISTS idx_log_groups_project_last_seen;
+
+ALTER TABLE projects DROP COLUMN IF EXISTS error_groups_retention_days;
+ALTER TABLE projects DROP COLUMN IF EXISTS log_groups_retention_days;
+ALTER TABLE projects DROP COLUMN IF EXISTS errors_retention_days;
+ALTER TABLE projects DROP COLUMN IF EXISTS logs_retention_days;
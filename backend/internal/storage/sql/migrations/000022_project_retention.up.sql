@@ -0,0 +1,11 @@
+-- +migrate Up
+
+-- Per-project retention in days, NULL keeps data forever
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS logs_retention_days INTEGER;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS errors_retention_days INTEGER;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS log_groups_retention_days INTEGER;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS error_groups_retention_days INTEGER;
+
+-- Stale group lookups by last activity
+CREATE INDEX IF NOT EXISTS idx_log_groups_project_last_seen ON log_groups(project_id, last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_error_groups_project_last_seen ON error_groups(project_id, last_seen_at);
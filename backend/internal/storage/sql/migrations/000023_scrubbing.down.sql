@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_scrubbing_rules_project_id;
+DROP TABLE IF EXISTS scrubbing_rules;
+DROP TABLE IF EXISTS scrubbing_settings;
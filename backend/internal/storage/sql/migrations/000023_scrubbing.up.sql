@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS scrubbing_settings (
+    project_id UUID PRIMARY KEY,
+    strip_ip BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at INT NOT NULL
+);
+
+-- Custom rules on top of the built-in denylist: "key" matches field names, "regex" matches values
+CREATE TABLE IF NOT EXISTS scrubbing_rules (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    pattern TEXT NOT NULL,
+    created_at INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scrubbing_rules_project_id ON scrubbing_rules(project_id);
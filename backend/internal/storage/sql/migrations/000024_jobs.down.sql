@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_jobs_created_by_created_at;
+DROP INDEX IF EXISTS idx_jobs_status_created_at;
+DROP TABLE IF EXISTS jobs;
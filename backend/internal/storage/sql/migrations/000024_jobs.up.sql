@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Tracked background jobs, params and result are job type specific JSON
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    params JSONB,
+    result JSONB,
+    error TEXT,
+    created_by UUID,
+    created_at INT NOT NULL,
+    started_at INT,
+    finished_at INT,
+    updated_at INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_by_created_at ON jobs(created_by, created_at);
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP TABLE IF EXISTS ingest_stats;
+DROP TABLE IF EXISTS ingest_limits;
@@ -0,0 +1,27 @@
+-- +migrate Up
+
+-- Per-project ingest limits, null falls back to the instance defaults or means unlimited
+CREATE TABLE IF NOT EXISTS ingest_limits (
+    project_id UUID PRIMARY KEY,
+    rate_per_second INT,
+    burst INT,
+    key_rate_per_second INT,
+    key_burst INT,
+    daily_quota BIGINT,
+    monthly_quota BIGINT,
+    -- Share of logs kept per level, e.g. {"DEBUG": 0.1}, levels not listed are kept
+    log_sample_rates JSONB,
+    updated_at INT NOT NULL
+);
+
+-- Daily ingest outcome counters, day is the unix time of the UTC day start
+CREATE TABLE IF NOT EXISTS ingest_stats (
+    project_id UUID NOT NULL,
+    day INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    accepted BIGINT NOT NULL DEFAULT 0,
+    rate_limited BIGINT NOT NULL DEFAULT 0,
+    quota_exceeded BIGINT NOT NULL DEFAULT 0,
+    sampled BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, day, kind)
+);
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_project_keys_project_id;
+DROP INDEX IF EXISTS idx_project_keys_public_key;
+DROP TABLE IF EXISTS project_keys;
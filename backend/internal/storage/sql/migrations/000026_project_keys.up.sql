@@ -0,0 +1,23 @@
+-- +migrate Up
+
+-- Ingest keys of a project, scope limits a key to logs or errors, allowed_origins to browser origins.
+-- A rotated key keeps working until expires_at, a revoked one stops at once.
+CREATE TABLE IF NOT EXISTS project_keys (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    public_key TEXT NOT NULL,
+    scope VARCHAR(16) NOT NULL DEFAULT 'all',
+    allowed_origins TEXT[] NOT NULL DEFAULT '{}',
+    expires_at INT,
+    revoked_at INT,
+    last_used_at INT,
+    created_at INT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_keys_public_key ON project_keys(public_key);
+CREATE INDEX IF NOT EXISTS idx_project_keys_project_id ON project_keys(project_id);
+
+-- The key every project had so far becomes its default key
+INSERT INTO project_keys (id, project_id, name, public_key, created_at)
+SELECT gen_random_uuid(), id, 'Default', public_key, created_at FROM projects;
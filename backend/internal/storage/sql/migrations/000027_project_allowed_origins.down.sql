@@ -0,0 +1,3 @@
+-- +migrate Down
+
+ALTER TABLE projects DROP COLUMN IF EXISTS allowed_origins;
@@ -0,0 +1,4 @@
+-- +migrate Up
+
+-- Browser origins allowed to send events to any key of the project, '*' allows every origin
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS allowed_origins TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,10 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_error_group_activity_project_id;
+DROP INDEX IF EXISTS idx_error_group_activity_group_id;
+DROP TABLE IF EXISTS error_group_activity;
+DROP INDEX IF EXISTS idx_error_group_comments_project_id;
+DROP INDEX IF EXISTS idx_error_group_comments_group_id;
+DROP TABLE IF EXISTS error_group_comments;
+DROP INDEX IF EXISTS idx_error_groups_assignee_id;
+ALTER TABLE error_groups DROP COLUMN IF EXISTS assignee_id;
@@ -0,0 +1,41 @@
+-- +migrate Up
+
+ALTER TABLE error_groups ADD COLUMN IF NOT EXISTS assignee_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_error_groups_assignee_id ON error_groups(assignee_id) WHERE assignee_id IS NOT NULL;
+
+-- Comments on an error group, a reply points to the comment it answers.
+-- mentions holds the ids of the users mentioned as @email in the body.
+CREATE TABLE IF NOT EXISTS error_group_comments (
+    id UUID PRIMARY KEY,
+    group_id CHAR(64) NOT NULL REFERENCES error_groups(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL,
+    parent_id UUID REFERENCES error_group_comments(id) ON DELETE CASCADE,
+    author_id UUID,
+    body TEXT NOT NULL,
+    mentions UUID[] NOT NULL DEFAULT '{}',
+    created_at INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_error_group_comments_group_id ON error_group_comments(group_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_error_group_comments_project_id ON error_group_comments(project_id);
+
+-- Append only timeline of an error group, rows are never updated and go away with their group.
+-- The service writes time ordered UUIDs, data holds the details of the entry, e.g. the previous and the new status.
+CREATE TABLE IF NOT EXISTS error_group_activity (
+    id UUID PRIMARY KEY,
+    group_id CHAR(64) NOT NULL REFERENCES error_groups(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    actor_id UUID,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_error_group_activity_group_id ON error_group_activity(group_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_error_group_activity_project_id ON error_group_activity(project_id);
+
+-- Groups seen so far start their timeline with the time they were first seen. It is the only entry
+-- of each group yet, so random ids do not break the order of the timeline.
+INSERT INTO error_group_activity (id, group_id, project_id, type, created_at)
+SELECT gen_random_uuid(), id, project_id, 'first_seen', first_seen_at FROM error_groups;
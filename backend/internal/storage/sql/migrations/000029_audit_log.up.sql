@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Append only log of mutating API requests. changes maps the changed fields of the target to their
+-- old and new values, forwarded_for is the X-Forwarded-For header as sent by the client.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor_id UUID,
+    action VARCHAR(100) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    target_type VARCHAR(50),
+    target_id VARCHAR(255),
+    changes JSONB,
+    status INT NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    forwarded_for TEXT,
+    user_agent TEXT,
+    request_id VARCHAR(128),
+    created_at INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
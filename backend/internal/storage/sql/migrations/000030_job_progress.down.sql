@@ -0,0 +1,4 @@
+-- +migrate Down
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS total;
+ALTER TABLE jobs DROP COLUMN IF EXISTS processed;
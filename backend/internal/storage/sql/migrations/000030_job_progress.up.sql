@@ -0,0 +1,5 @@
+-- +migrate Up
+
+-- Progress of a running job, total is what the job expects to process
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS processed BIGINT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS total BIGINT;
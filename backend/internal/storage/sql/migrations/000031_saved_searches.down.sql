@@ -0,0 +1,6 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_saved_searches_default;
+DROP INDEX IF EXISTS idx_saved_searches_project_id;
+DROP INDEX IF EXISTS idx_saved_searches_code;
+DROP TABLE IF EXISTS saved_searches;
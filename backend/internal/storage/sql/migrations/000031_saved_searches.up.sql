@@ -0,0 +1,22 @@
+-- +migrate Up
+
+-- Filters of the logs, errors and group lists saved by a user. A shared search is listed to every user
+-- of the project, code is the short link of the search. Every user has at most one default per project and list.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    owner_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    target VARCHAR(16) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    code VARCHAR(16) NOT NULL,
+    created_at INT NOT NULL,
+    updated_at INT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_searches_code ON saved_searches(code);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_project_id ON saved_searches(project_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_searches_default
+    ON saved_searches(owner_id, project_id, target) WHERE is_default;
@@ -0,0 +1,5 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_sessions_project_started_at;
+DROP INDEX IF EXISTS idx_sessions_project_release;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Sessions reported by SDKs for release health, one row per session updated as it starts and ends.
+-- status only moves forward: started, then ended or crashed. user_hash is the SHA-256 of the end user id
+-- reported by the SDK, it is only used to count distinct users. Times are in milliseconds.
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) NOT NULL,
+    project_id UUID NOT NULL,
+    release VARCHAR(255) NOT NULL,
+    environment VARCHAR(64) NOT NULL DEFAULT '',
+    user_hash CHAR(64),
+    status VARCHAR(16) NOT NULL,
+    started_at BIGINT NOT NULL,
+    ended_at BIGINT,
+    PRIMARY KEY (project_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_project_release ON sessions(project_id, release, started_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_project_started_at ON sessions(project_id, started_at);
@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_monitor_checkins_project_id;
+DROP INDEX IF EXISTS idx_monitor_checkins_in_progress;
+DROP INDEX IF EXISTS idx_monitor_checkins_monitor_id;
+DROP TABLE IF EXISTS monitor_checkins;
+DROP INDEX IF EXISTS idx_monitors_next_expected_at;
+DROP INDEX IF EXISTS idx_monitors_project_slug;
+DROP TABLE IF EXISTS monitors;
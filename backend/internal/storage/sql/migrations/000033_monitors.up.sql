@@ -0,0 +1,43 @@
+-- +migrate Up
+
+-- Cron jobs and heartbeats of a project. schedule is a cron expression evaluated in timezone or an interval
+-- like 15m or 1d. next_expected_at is the time the next check-in is due, a run is missed when nothing
+-- checked in grace_minutes after it. Times are unix seconds.
+CREATE TABLE IF NOT EXISTS monitors (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    slug VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    schedule_type VARCHAR(16) NOT NULL,
+    schedule VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    grace_minutes INT NOT NULL DEFAULT 0,
+    max_runtime_minutes INT,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL,
+    next_expected_at BIGINT NOT NULL,
+    last_checkin_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_monitors_project_slug ON monitors(project_id, slug);
+CREATE INDEX IF NOT EXISTS idx_monitors_next_expected_at ON monitors(next_expected_at) WHERE NOT paused;
+
+-- History of a monitor: check-ins of the job and the missed and timed out runs found by the scheduler.
+-- error_id points to the error recorded for a failed run.
+CREATE TABLE IF NOT EXISTS monitor_checkins (
+    id UUID PRIMARY KEY,
+    monitor_id UUID NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    expected_at BIGINT,
+    duration_ms BIGINT,
+    error_id UUID,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_monitor_checkins_monitor_id ON monitor_checkins(monitor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_monitor_checkins_in_progress ON monitor_checkins(created_at) WHERE status = 'in_progress';
+CREATE INDEX IF NOT EXISTS idx_monitor_checkins_project_id ON monitor_checkins(project_id);
@@ -0,0 +1,9 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_errors_trace_id;
+ALTER TABLE errors DROP COLUMN IF EXISTS trace_id;
+
+DROP INDEX IF EXISTS idx_spans_project_start_time;
+DROP INDEX IF EXISTS idx_spans_transaction_name;
+DROP INDEX IF EXISTS idx_spans_transactions;
+DROP TABLE IF EXISTS spans;
@@ -0,0 +1,31 @@
+-- +migrate Up
+
+-- Spans of performance traces. A transaction is the span a service starts handling a request or a job with,
+-- it is what latency and throughput are aggregated over, the other spans are only shown in the trace.
+-- Ids are lowercase hex, times are in milliseconds, duration keeps fractions of a millisecond.
+CREATE TABLE IF NOT EXISTS spans (
+    project_id UUID NOT NULL,
+    trace_id CHAR(32) NOT NULL,
+    span_id CHAR(16) NOT NULL,
+    parent_span_id CHAR(16),
+    is_transaction BOOLEAN NOT NULL DEFAULT FALSE,
+    name VARCHAR(255) NOT NULL,
+    op VARCHAR(64) NOT NULL DEFAULT '',
+    service VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    release VARCHAR(255),
+    environment VARCHAR(64) NOT NULL DEFAULT '',
+    start_time BIGINT NOT NULL,
+    duration DOUBLE PRECISION NOT NULL,
+    attributes TEXT,
+    PRIMARY KEY (project_id, trace_id, span_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_spans_transactions ON spans(project_id, start_time, name) WHERE is_transaction;
+CREATE INDEX IF NOT EXISTS idx_spans_transaction_name ON spans(project_id, name, duration) WHERE is_transaction;
+CREATE INDEX IF NOT EXISTS idx_spans_project_start_time ON spans(project_id, start_time);
+
+-- Errors reported with a trace_id in their context link to the trace
+ALTER TABLE errors ADD COLUMN IF NOT EXISTS trace_id CHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_errors_trace_id ON errors(project_id, trace_id) WHERE trace_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_errors_search_vector;
+DROP INDEX IF EXISTS idx_logs_search_vector;
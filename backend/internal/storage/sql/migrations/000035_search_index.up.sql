@@ -0,0 +1,7 @@
+-- +migrate Up
+
+-- The parent indexes are created ON ONLY, which is a catalog change: partitions created from now on
+-- get their own index right away, `duckbugctl search backfill` builds the missing ones concurrently
+-- and attaches them. Until then the parent index stays invalid and search falls back to a scan.
+CREATE INDEX IF NOT EXISTS idx_logs_search_vector ON ONLY logs USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_errors_search_vector ON ONLY errors USING GIN (search_vector);
@@ -0,0 +1,151 @@
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// searchTable is an event table with full-text search, see 000014 and 000035
+type searchTable struct {
+	name   string
+	vector string
+	index  string
+}
+
+var searchTables = []searchTable{
+	{name: "logs", vector: "logs_search_vector(t.message, t.context)", index: "idx_logs_search_vector"},
+	{
+		name:   "errors",
+		vector: "errors_search_vector(t.message, t.file, t.context, t.stacktrace)",
+		index:  "idx_errors_search_vector",
+	},
+}
+
+// BackfillSearch fills the search vectors of events stored before the triggers of 000014 existed and
+// builds the search indexes of partitions that miss them. It walks the tables in primary key order in
+// batches and creates the indexes concurrently, so it runs next to a live server and can be interrupted
+// and started again at any time.
+func BackfillSearch(ctx context.Context, db *sqlx.DB, log Logger, batchSize int) error {
+	if batchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+
+	for _, table := range searchTables {
+		filled, err := backfillSearchVectors(ctx, db, log, table, batchSize)
+		if err != nil {
+			return err
+		}
+		log.InfoContext(ctx, "search vectors filled", "table", table.name, "rows", filled)
+
+		if err := buildSearchIndexes(ctx, db, log, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func backfillSearchVectors(
+	ctx context.Context, db *sqlx.DB, log Logger, table searchTable, batchSize int,
+) (int64, error) {
+	// Installs that ran the first version of 000014 have a generated column, it is never NULL
+	// and cannot be written to
+	var generated bool
+	err := db.GetContext(ctx, &generated, `
+		SELECT is_generated = 'ALWAYS' FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'search_vector'`,
+		table.name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect %s search vector: %w", table.name, err)
+	}
+	if generated {
+		return 0, nil
+	}
+
+	// The batch is picked by primary key only, so rows filled earlier cost an index step and not a rescan
+	query := fmt.Sprintf(`
+		WITH batch AS (
+			SELECT id, time FROM %[1]s WHERE (id, time) > ($1, $2) ORDER BY id, time LIMIT $3
+		), updated AS (
+			UPDATE %[1]s t SET search_vector = %[2]s
+			FROM batch b
+			WHERE t.id = b.id AND t.time = b.time AND t.search_vector IS NULL
+			RETURNING 1
+		), last AS (
+			SELECT id, time FROM batch ORDER BY id DESC, time DESC LIMIT 1
+		)
+		SELECT (SELECT count(*) FROM updated), last.id, last.time FROM last`, table.name, table.vector)
+
+	var filled int64
+	lastID, lastTime := "00000000-0000-0000-0000-000000000000", int64(math.MinInt64)
+	for {
+		var updated int64
+		err := db.QueryRowContext(ctx, query, lastID, lastTime, batchSize).Scan(&updated, &lastID, &lastTime)
+		if errors.Is(err, stdsql.ErrNoRows) {
+			return filled, nil
+		}
+		if err != nil {
+			return filled, fmt.Errorf("failed to fill %s search vectors: %w", table.name, err)
+		}
+
+		filled += updated
+		log.DebugContext(ctx, "search vector batch", "table", table.name, "rows", filled)
+	}
+}
+
+// buildSearchIndexes creates the index of every partition that is not attached to the parent index yet
+// and attaches it. The parent index turns valid once every partition has one.
+func buildSearchIndexes(ctx context.Context, db *sqlx.DB, log Logger, table searchTable) error {
+	var partitions []string
+	err := db.SelectContext(ctx, &partitions, `
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_inherits ii
+				JOIN pg_index x ON x.indexrelid = ii.inhrelid
+				WHERE ii.inhparent = $2::regclass AND x.indrelid = c.oid
+			)
+		ORDER BY c.relname`, table.name, table.index)
+	if err != nil {
+		return fmt.Errorf("failed to list %s partitions: %w", table.name, err)
+	}
+
+	for _, partition := range partitions {
+		index := "idx_" + partition + "_search_vector"
+
+		// An interrupted concurrent build leaves an invalid index behind, it is dropped and built again
+		var valid bool
+		err := db.GetContext(ctx, &valid, `
+			SELECT x.indisvalid FROM pg_class c
+			JOIN pg_index x ON x.indexrelid = c.oid
+			WHERE c.relname = $1 AND c.relnamespace = current_schema()::regnamespace`, index)
+		if err != nil && !errors.Is(err, stdsql.ErrNoRows) {
+			return fmt.Errorf("failed to inspect index %s: %w", index, err)
+		}
+		if err == nil && !valid {
+			if _, err := db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pq.QuoteIdentifier(index)); err != nil {
+				return fmt.Errorf("failed to drop invalid index %s: %w", index, err)
+			}
+		}
+
+		log.InfoContext(ctx, "building search index", "partition", partition)
+		create := fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING GIN (search_vector)",
+			pq.QuoteIdentifier(index), pq.QuoteIdentifier(partition))
+		if _, err := db.ExecContext(ctx, create); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", index, err)
+		}
+
+		attach := fmt.Sprintf("ALTER INDEX %s ATTACH PARTITION %s",
+			pq.QuoteIdentifier(table.index), pq.QuoteIdentifier(index))
+		if _, err := db.ExecContext(ctx, attach); err != nil {
+			return fmt.Errorf("failed to attach index %s: %w", index, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package sqltest runs repositories against a database that records what they send, for tests of the
+// queries that do not need Postgres
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var errNotSupported = errors.New("not supported by the recorder")
+
+// Query is a statement sent to the recorder with its arguments
+type Query struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Recorder is a database without rows that keeps the queries it is sent
+type Recorder struct {
+	Queries []Query
+}
+
+// DB opens a Postgres flavoured connection to the recorder
+func (r *Recorder) DB() *sqlx.DB {
+	return sqlx.NewDb(sql.OpenDB(r), "postgres")
+}
+
+func (r *Recorder) Connect(context.Context) (driver.Conn, error) {
+	return &conn{recorder: r}, nil
+}
+
+func (r *Recorder) Driver() driver.Driver {
+	return nil
+}
+
+type conn struct {
+	recorder *Recorder
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	c.recorder.Queries = append(c.recorder.Queries, Query{SQL: query, Args: values})
+	return noRows{}, nil
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errNotSupported
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errNotSupported
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string {
+	return nil
+}
+
+func (noRows) Close() error {
+	return nil
+}
+
+func (noRows) Next([]driver.Value) error {
+	return io.EOF
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Logger interface {
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	InfoContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+// Task is a unit of periodic background work
+type Task func(ctx context.Context) error
+
+// Status is a snapshot of the last worker run
+type Status struct {
+	Name      string    `json:"name"`
+	Running   bool      `json:"running"`
+	LastRunAt time.Time `json:"lastRunAt"`
+	LastError string    `json:"lastError,omitempty"`
+	Runs      int       `json:"runs"`
+}
+
+// Worker runs a task right away and then on every interval until its context is canceled
+type Worker struct {
+	name     string
+	interval time.Duration
+	task     Task
+	logger   Logger
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func New(name string, interval time.Duration, task Task, logger Logger) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		task:     task,
+		logger:   logger,
+		status:   Status{Name: name},
+	}
+}
+
+func (w *Worker) Name() string {
+	return w.name
+}
+
+// Run blocks until ctx is done, call it in its own goroutine
+func (w *Worker) Run(ctx context.Context) {
+	w.setRunning(true)
+	defer w.setRunning(false)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.runOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+		}
+	}
+}
+
+func (w *Worker) Status() Status {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.status
+}
+
+func (w *Worker) runOnce(ctx context.Context) {
+	err := w.task(ctx)
+
+	w.mu.Lock()
+	w.status.LastRunAt = time.Now()
+	w.status.Runs++
+	w.status.LastError = ""
+	if err != nil {
+		w.status.LastError = err.Error()
+	}
+	w.mu.Unlock()
+
+	if err != nil && ctx.Err() == nil {
+		w.logger.Error(fmt.Sprintf("worker %s failed: %v", w.name, err))
+	}
+}
+
+func (w *Worker) setRunning(running bool) {
+	w.mu.Lock()
+	w.status.Running = running
+	w.mu.Unlock()
+}
@@ -0,0 +1,179 @@
+// Package export writes API entities as CSV or NDJSON while they are read from the database
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	// maxColumns bounds the columns of a CSV export
+	maxColumns = 100
+)
+
+var ErrInvalidFormat = errors.New("invalid export format")
+
+// Writer writes one entity per row
+type Writer interface {
+	Write(entity interface{}) error
+	// Flush writes buffered rows, it has to be called once after the last entity
+	Flush() error
+	ContentType() string
+	Extension() string
+}
+
+// NewWriter returns a writer of format. Columns of a CSV are JSON field names of the entity, nested fields are
+// selected with dots, e.g. context.user.id. A column holding an object or an array gets it as JSON.
+func NewWriter(w io.Writer, format string, columns []string) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		if len(columns) == 0 || len(columns) > maxColumns {
+			return nil, fmt.Errorf("%w: between 1 and %d columns are required", ErrInvalidFormat, maxColumns)
+		}
+		paths := make([][]string, 0, len(columns))
+		for _, column := range columns {
+			path := strings.Split(column, ".")
+			for _, key := range path {
+				if key == "" {
+					return nil, fmt.Errorf("%w: invalid column %q", ErrInvalidFormat, column)
+				}
+			}
+			paths = append(paths, path)
+		}
+		return &csvWriter{writer: csv.NewWriter(w), columns: columns, paths: paths}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q, use %s or %s", ErrInvalidFormat, format, FormatCSV, FormatNDJSON)
+	}
+}
+
+// ParseColumns splits a comma separated list of columns, defaults are used for an empty list
+func ParseColumns(param string, defaults []string) []string {
+	if strings.TrimSpace(param) == "" {
+		return defaults
+	}
+
+	columns := strings.Split(param, ",")
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+	}
+	return columns
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(entity interface{}) error {
+	return w.encoder.Encode(entity)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (w *ndjsonWriter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (w *ndjsonWriter) Extension() string {
+	return FormatNDJSON
+}
+
+type csvWriter struct {
+	writer      *csv.Writer
+	columns     []string
+	paths       [][]string
+	wroteHeader bool
+}
+
+func (w *csvWriter) Write(entity interface{}) error {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.writer.Write(w.columns); err != nil {
+			return err
+		}
+	}
+
+	// The entity goes through JSON, so columns are named like the fields of the API
+	encoded, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to encode entity: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var fields interface{}
+	if err = decoder.Decode(&fields); err != nil {
+		return fmt.Errorf("failed to encode entity: %w", err)
+	}
+
+	record := make([]string, len(w.paths))
+	for i, path := range w.paths {
+		if record[i], err = cell(lookup(fields, path)); err != nil {
+			return err
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	// An export without rows still has its header
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.writer.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (w *csvWriter) Extension() string {
+	return FormatCSV
+}
+
+func lookup(value interface{}, path []string) interface{} {
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func cell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode column: %w", err)
+		}
+		return string(encoded), nil
+	}
+}
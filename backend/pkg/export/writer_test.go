@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type entity struct {
+	ID      string       `json:"id"`
+	Time    int64        `json:"time"`
+	Message string       `json:"message"`
+	Context *interface{} `json:"context"`
+}
+
+func newEntity(id string, context interface{}) entity {
+	return entity{ID: id, Time: 1704067200000, Message: "Timeout, retrying", Context: &context}
+}
+
+func TestCSVWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []string
+		entities []entity
+		expected string
+	}{
+		{
+			name:    "Flattened context paths",
+			columns: []string{"id", "time", "context.user.id", "context.tags"},
+			entities: []entity{
+				newEntity("1", map[string]interface{}{"user": map[string]interface{}{"id": 42}, "tags": []string{"a"}}),
+				newEntity("2", "plain context"),
+			},
+			expected: "id,time,context.user.id,context.tags\n" +
+				"1,1704067200000,42,\"[\"\"a\"\"]\"\n" +
+				"2,1704067200000,,\n",
+		},
+		{
+			name:     "Quoted values",
+			columns:  []string{"message", "missing"},
+			entities: []entity{newEntity("1", nil)},
+			expected: "message,missing\n\"Timeout, retrying\",\n",
+		},
+		{
+			name:     "Header without rows",
+			columns:  []string{"id"},
+			expected: "id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, FormatCSV, tt.columns)
+			require.NoError(t, err)
+
+			for _, e := range tt.entities {
+				require.NoError(t, w.Write(e))
+			}
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatNDJSON, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(newEntity("1", map[string]interface{}{"k": "v"})))
+	require.NoError(t, w.Write(newEntity("2", nil)))
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		`{"id":"1","time":1704067200000,"message":"Timeout, retrying","context":{"k":"v"}}`+"\n"+
+			`{"id":"2","time":1704067200000,"message":"Timeout, retrying","context":null}`+"\n",
+		buf.String())
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xlsx", nil)
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = NewWriter(&bytes.Buffer{}, FormatCSV, nil)
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = NewWriter(&bytes.Buffer{}, FormatCSV, []string{"context..id"})
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestParseColumns(t *testing.T) {
+	assert.Equal(t, []string{"id"}, ParseColumns(" ", []string{"id"}))
+	assert.Equal(t, []string{"id", "context.user.id"}, ParseColumns("id, context.user.id", []string{"id"}))
+}
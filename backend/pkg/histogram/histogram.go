@@ -0,0 +1,111 @@
+package histogram
+
+import "sort"
+
+const (
+	otherSeriesKey   = "other"
+	unknownSeriesKey = "unknown"
+)
+
+// Row is a single (bucket, series) counter returned by histogram queries
+type Row struct {
+	Bucket int64  `db:"bucket"`
+	Series string `db:"series"`
+	Count  int    `db:"count"`
+}
+
+type Bucket struct {
+	Time   int64          `json:"time" example:"1704067200000"` // Bucket start, Unix timestamp in milliseconds
+	Count  int            `json:"count" example:"42"`
+	Series map[string]int `json:"series,omitempty"`
+}
+
+type Histogram struct {
+	Interval int64    `json:"interval" example:"3600000"` // Bucket size in milliseconds
+	SplitBy  string   `json:"splitBy,omitempty" example:"release"`
+	Buckets  []Bucket `json:"buckets"`
+}
+
+// Params describe the buckets of a histogram, times are in milliseconds and TimeFrom is aligned to Interval
+type Params struct {
+	TimeFrom    int64
+	TimeTo      int64
+	Interval    int64
+	SplitBy     string
+	SeriesLimit int
+}
+
+// Build spreads rows over every bucket of the range, so empty buckets are returned too. When split,
+// the SeriesLimit largest series are kept and the rest is folded into "other".
+func Build(params Params, rows []*Row) *Histogram {
+	buckets := make([]Bucket, 0, (params.TimeTo-params.TimeFrom)/params.Interval+1)
+	index := make(map[int64]int, cap(buckets))
+	for bucketTime := params.TimeFrom; bucketTime <= params.TimeTo; bucketTime += params.Interval {
+		index[bucketTime] = len(buckets)
+		buckets = append(buckets, Bucket{Time: bucketTime})
+	}
+
+	keep := topSeries(rows, params.SeriesLimit)
+
+	for _, row := range rows {
+		i, ok := index[row.Bucket]
+		if !ok {
+			continue
+		}
+
+		bucket := &buckets[i]
+		bucket.Count += row.Count
+
+		if params.SplitBy == "" {
+			continue
+		}
+
+		key := row.Series
+		if _, ok := keep[key]; !ok {
+			key = otherSeriesKey
+		} else if key == "" {
+			key = unknownSeriesKey
+		}
+
+		if bucket.Series == nil {
+			bucket.Series = make(map[string]int)
+		}
+		bucket.Series[key] += row.Count
+	}
+
+	return &Histogram{
+		Interval: params.Interval,
+		SplitBy:  params.SplitBy,
+		Buckets:  buckets,
+	}
+}
+
+// topSeries returns the keys of the largest series, everything else is folded into "other"
+func topSeries(rows []*Row, limit int) map[string]struct{} {
+	totals := make(map[string]int)
+	for _, row := range rows {
+		totals[row.Series] += row.Count
+	}
+
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	result := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		result[key] = struct{}{}
+	}
+	return result
+}
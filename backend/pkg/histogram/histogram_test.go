@@ -0,0 +1,84 @@
+package histogram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const hour = 3600000
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   Params
+		rows     []*Row
+		expected []Bucket
+	}{
+		{
+			name:   "Empty buckets are filled",
+			params: Params{TimeFrom: 0, TimeTo: 2 * hour, Interval: hour},
+			rows:   []*Row{{Bucket: hour, Count: 5}},
+			expected: []Bucket{
+				{Time: 0},
+				{Time: hour, Count: 5},
+				{Time: 2 * hour},
+			},
+		},
+		{
+			name:   "Rows outside of the range are dropped",
+			params: Params{TimeFrom: hour, TimeTo: hour, Interval: hour},
+			rows:   []*Row{{Bucket: 0, Count: 3}, {Bucket: hour, Count: 1}, {Bucket: 2 * hour, Count: 7}},
+			expected: []Bucket{
+				{Time: hour, Count: 1},
+			},
+		},
+		{
+			name:   "Series beyond the limit are folded into other",
+			params: Params{TimeFrom: 0, TimeTo: hour, Interval: hour, SplitBy: "release", SeriesLimit: 2},
+			rows: []*Row{
+				{Bucket: 0, Series: "1.0", Count: 10},
+				{Bucket: 0, Series: "1.1", Count: 4},
+				{Bucket: hour, Series: "1.1", Count: 4},
+				{Bucket: hour, Series: "0.9", Count: 2},
+				{Bucket: hour, Series: "0.8", Count: 1},
+			},
+			expected: []Bucket{
+				{Time: 0, Count: 14, Series: map[string]int{"1.0": 10, "1.1": 4}},
+				{Time: hour, Count: 7, Series: map[string]int{"1.1": 4, "other": 3}},
+			},
+		},
+		{
+			name:   "Equal series are kept by name and an empty one is unknown",
+			params: Params{TimeFrom: 0, TimeTo: 0, Interval: hour, SplitBy: "release", SeriesLimit: 2},
+			rows: []*Row{
+				{Bucket: 0, Series: "b", Count: 2},
+				{Bucket: 0, Series: "", Count: 2},
+				{Bucket: 0, Series: "a", Count: 2},
+			},
+			expected: []Bucket{
+				{Time: 0, Count: 6, Series: map[string]int{"unknown": 2, "a": 2, "other": 2}},
+			},
+		},
+		{
+			name:   "Without a limit every series is kept",
+			params: Params{TimeFrom: 0, TimeTo: 0, Interval: hour, SplitBy: "level"},
+			rows: []*Row{
+				{Bucket: 0, Series: "ERROR", Count: 2},
+				{Bucket: 0, Series: "INFO", Count: 9},
+			},
+			expected: []Bucket{
+				{Time: 0, Count: 11, Series: map[string]int{"ERROR": 2, "INFO": 9}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Build(tt.params, tt.rows)
+			assert.Equal(t, tt.params.Interval, result.Interval)
+			assert.Equal(t, tt.params.SplitBy, result.SplitBy)
+			assert.Equal(t, tt.expected, result.Buckets)
+		})
+	}
+}
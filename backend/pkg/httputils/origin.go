@@ -0,0 +1,25 @@
+package httputils
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidOrigin reports a value that is not an origin as browsers send it
+var ErrInvalidOrigin = errors.New("origin must be http(s)://host[:port]")
+
+// NormalizeOrigin compares origins the way browsers send them, lower-cased and without a trailing slash
+func NormalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(origin, "/"))
+}
+
+// ParseOrigin accepts an origin as browsers send it, http(s)://host[:port] without a path, and normalizes it
+func ParseOrigin(origin string) (string, error) {
+	parsed, err := url.Parse(NormalizeOrigin(origin))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+		parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return "", ErrInvalidOrigin
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/duckbugio/duckbug/pkg/requestid"
 	v "github.com/go-playground/validator/v10"
 )
 
@@ -25,10 +26,37 @@ func NewListResponse[T any](count int, items []T) ListResponse[T] {
 	}
 }
 
+type CursorListResponse[T any] struct {
+	// Total number of items, omitted when counting was skipped
+	Count *int `json:"count,omitempty"`
+
+	// CountEstimated is true when Count is a planner estimate
+	CountEstimated bool `json:"countEstimated,omitempty"`
+
+	// Array of items
+	Items []T `json:"items"`
+
+	// Opaque cursors of the adjacent pages, empty when there is no such page
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+func NewCursorListResponse[T any](count *int, countEstimated bool, items []T, nextCursor, prevCursor string) CursorListResponse[T] {
+	return CursorListResponse[T]{
+		Count:          count,
+		CountEstimated: countEstimated,
+		Items:          items,
+		NextCursor:     nextCursor,
+		PrevCursor:     prevCursor,
+	}
+}
+
 type ErrorResponse struct {
 	Code    int               `json:"code"`
 	Message string            `json:"message"`
 	Details map[string]string `json:"details,omitempty"`
+	// RequestID matches the X-Request-ID response header and the request_id of the server logs
+	RequestID string `json:"requestId,omitempty"`
 }
 
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -47,17 +75,19 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 
 func RespondWithPlainError(w http.ResponseWriter, code int, message string) {
 	RespondWithJSON(w, code, ErrorResponse{
-		Code:    code,
-		Message: message,
-		Details: nil,
+		Code:      code,
+		Message:   message,
+		Details:   nil,
+		RequestID: w.Header().Get(requestid.Header),
 	})
 }
 
 func RespondWithError(w http.ResponseWriter, code int, message string, details map[string]string) {
 	RespondWithJSON(w, code, ErrorResponse{
-		Code:    code,
-		Message: message,
-		Details: details,
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get(requestid.Header),
 	})
 }
 
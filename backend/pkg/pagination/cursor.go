@@ -0,0 +1,102 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CountExact runs a full COUNT(*) over the filtered rows
+	CountExact = "exact"
+	// CountEstimated uses the planner row estimate, cheap but approximate
+	CountEstimated = "estimated"
+	// CountNone skips counting entirely
+	CountNone = "none"
+)
+
+const (
+	directionNext = "n"
+	directionPrev = "p"
+	cursorParts   = 3
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered by (time, id)
+type Cursor struct {
+	Time int64
+	ID   string
+	// Backward requests the page before the position instead of the one after it
+	Backward bool
+}
+
+// Encode returns the opaque string representation handed to API clients
+func (c Cursor) Encode() string {
+	direction := directionNext
+	if c.Backward {
+		direction = directionPrev
+	}
+
+	raw := direction + ":" + strconv.FormatInt(c.Time, 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Decode(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", cursorParts)
+	if len(parts) != cursorParts || parts[2] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	if parts[0] != directionNext && parts[0] != directionPrev {
+		return nil, ErrInvalidCursor
+	}
+
+	cursorTime, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if _, err := uuid.Parse(parts[2]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return &Cursor{
+		Time:     cursorTime,
+		ID:       parts[2],
+		Backward: parts[0] == directionPrev,
+	}, nil
+}
+
+func IsValidCountMode(mode string) bool {
+	switch mode {
+	case CountExact, CountEstimated, CountNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeysetOrder returns the ORDER BY direction and the row comparison operator used to fetch
+// the page a cursor points to. Backward pages are read in reverse order and must be flipped
+// by the caller.
+func KeysetOrder(sortOrder string, cursor *Cursor) (direction, operator string) {
+	ascending := strings.EqualFold(sortOrder, "asc")
+	if cursor != nil && cursor.Backward {
+		ascending = !ascending
+	}
+
+	if ascending {
+		return "ASC", ">"
+	}
+	return "DESC", "<"
+}
@@ -0,0 +1,80 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cursorID = "a08929b5-7e0c-4f7a-9a4c-3b1f2d6c8e01"
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, cursor := range []Cursor{
+		{Time: 1704067200000, ID: cursorID},
+		{Time: 1704067200000, ID: cursorID, Backward: true},
+		{Time: 0, ID: cursorID},
+	} {
+		decoded, err := Decode(cursor.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, cursor, *decoded)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Not base64", value: "not a cursor!"},
+		{name: "Padded base64", value: base64.URLEncoding.EncodeToString([]byte("n:1:" + cursorID))},
+		{name: "Missing parts", value: encode("n:1704067200000")},
+		{name: "Empty id", value: encode("n:1704067200000:")},
+		{name: "Unknown direction", value: encode("x:1704067200000:" + cursorID)},
+		{name: "Time is not a number", value: encode("n:yesterday:" + cursorID)},
+		{name: "Id is not a UUID", value: encode("n:1704067200000:1 OR 1=1")},
+		{name: "Empty", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := Decode(tt.value)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+			assert.Nil(t, cursor)
+		})
+	}
+}
+
+func TestKeysetOrder(t *testing.T) {
+	tests := []struct {
+		sortOrder string
+		cursor    *Cursor
+		direction string
+		operator  string
+	}{
+		{sortOrder: "desc", direction: "DESC", operator: "<"},
+		{sortOrder: "ASC", direction: "ASC", operator: ">"},
+		{sortOrder: "desc", cursor: &Cursor{ID: cursorID}, direction: "DESC", operator: "<"},
+		{sortOrder: "desc", cursor: &Cursor{ID: cursorID, Backward: true}, direction: "ASC", operator: ">"},
+		{sortOrder: "asc", cursor: &Cursor{ID: cursorID, Backward: true}, direction: "DESC", operator: "<"},
+	}
+
+	for _, tt := range tests {
+		direction, operator := KeysetOrder(tt.sortOrder, tt.cursor)
+		assert.Equal(t, tt.direction, direction)
+		assert.Equal(t, tt.operator, operator)
+	}
+}
+
+func TestIsValidCountMode(t *testing.T) {
+	assert.True(t, IsValidCountMode(CountExact))
+	assert.True(t, IsValidCountMode(CountEstimated))
+	assert.True(t, IsValidCountMode(CountNone))
+	assert.False(t, IsValidCountMode("approximate"))
+	assert.False(t, IsValidCountMode(""))
+}
@@ -0,0 +1,17 @@
+package requestid
+
+import "context"
+
+// Header carries the id of a request to and from the API
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func Get(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok
+}
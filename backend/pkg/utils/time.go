@@ -3,10 +3,26 @@ package utils
 import (
 	"fmt"
 	"strconv"
+	"time"
 )
 
 const millisecondsInSecond = 1000
 
+// histogramIntervals lists the supported histogram bucket sizes
+//
+//nolint:mnd // the table itself is the definition of the sizes
+var histogramIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"3h":  3 * time.Hour,
+	"6h":  6 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
 func SecondsToMilliseconds(seconds int64) int64 {
 	return seconds * millisecondsInSecond
 }
@@ -18,3 +34,12 @@ func ParseTimeParam(param string) (int64, error) {
 	}
 	return value, nil
 }
+
+// ParseIntervalParam converts a histogram interval such as "5m" or "1d" to milliseconds
+func ParseIntervalParam(param string) (int64, error) {
+	interval, ok := histogramIntervals[param]
+	if !ok {
+		return 0, fmt.Errorf("invalid interval param: %s", param)
+	}
+	return interval.Milliseconds(), nil
+}
@@ -12,6 +12,7 @@ interface UseProjectProps {
 export const useProject = ({id}: UseProjectProps) => {
     const [project, setProject] = useState<Project | null>(null);
     const [dsn, setDsn] = useState<string | null>(null);
+    const [dsnError, setDsnError] = useState<string | null>(null);
     const [technology, setTechnology] = useState<Technology | null>(null);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
@@ -25,8 +26,15 @@ export const useProject = ({id}: UseProjectProps) => {
                 const projectData = await fetchProjectById({id: id});
                 setProject(projectData);
 
-                const dsnData = await fetchProjectDsn({id: id});
-                setDsn(dsnData.dsn);
+                // A project whose keys were all revoked has no DSN, the rest of the page still works
+                try {
+                    setDsnError(null);
+                    const dsnData = await fetchProjectDsn({id: id});
+                    setDsn(dsnData.dsn);
+                } catch (err) {
+                    setDsn(null);
+                    setDsnError(err instanceof Error ? err.message : 'Unknown error');
+                }
 
                 if (projectData.technologyId) {
                     const techData = await fetchTechnologyById({id: projectData.technologyId});
@@ -45,6 +53,7 @@ export const useProject = ({id}: UseProjectProps) => {
     return {
         project,
         dsn,
+        dsnError,
         technology,
         loading,
         error,
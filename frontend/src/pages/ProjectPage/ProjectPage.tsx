@@ -47,7 +47,7 @@ const ProjectPage = () => {
         newSearchParams.set('tab', newTab);
         setSearchParams(newSearchParams);
     };
-    const {project, dsn, technology, loading, error} = useProject({id: projectId ?? ''});
+    const {project, dsn, dsnError, technology, loading, error} = useProject({id: projectId ?? ''});
 
     const {
         logGroups: logs,
@@ -87,7 +87,7 @@ const ProjectPage = () => {
     };
 
     if (error) return <DataFetchError errorMessage={error} onRetry={handleRetry} />;
-    if (!project || !dsn || loading) return <DataLoader />;
+    if (!project || loading) return <DataLoader />;
 
     return (
         <PageContainer>
@@ -100,9 +100,12 @@ const ProjectPage = () => {
                 logsTotal={logsTotal}
             />
 
-            {activeTab === TabsState.QUICK_START && (
-                <QuickStart dsn={dsn} exampleDsnConnection={technology?.exampleDsnConnection} />
-            )}
+            {activeTab === TabsState.QUICK_START &&
+                (dsn ? (
+                    <QuickStart dsn={dsn} exampleDsnConnection={technology?.exampleDsnConnection} />
+                ) : (
+                    <GravityText color="danger">{dsnError}</GravityText>
+                ))}
 
             {activeTab === TabsState.ERRORS && (
                 <>